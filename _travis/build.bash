@@ -12,6 +12,8 @@ die() {
 
 if [[ -z "$USE_BAZEL" || "$USE_BAZEL" -eq "0" ]]; then
   must go test -v ./...
+  # Concurrent reads of a message must be race-free.
+  must go test -race -run Concurrent .
 else
   # On Travis, this will use "$HOME/bin/bazel", but don't assume this
   # for local testing of the CI script.
@@ -21,13 +21,40 @@ func Canonicalize(s Struct) ([]byte, error) {
 	if err := msg.SetRootPtr(root.ToPtr()); err != nil {
 		return nil, fmt.Errorf("canonicalize: %v", err)
 	}
-	if err := fillCanonicalStruct(root, s); err != nil {
-		return nil, fmt.Errorf("canonicalize: %v", err)
+	if err := fillCanonicalStruct(root, s, make(objectPath)); err != nil {
+		return nil, canonicalError(err, "canonicalize")
 	}
 	return seg.Data(), nil
 }
 
-func canonicalPtr(dst *Segment, p Ptr) (Ptr, error) {
+// DeterministicMarshal serializes m like Marshal, but produces the same
+// bytes for any two messages whose root structs have equal content.
+// It does so by encoding the root struct in canonical form (see
+// Canonicalize) as a single-segment message.  As with Canonicalize, the
+// root must be a struct and the message must not contain capabilities.
+//
+// Marshal is only deterministic for messages built by the same sequence
+// of operations on arenas that start out the same: objects are laid out
+// in the order they are allocated, and the segment layout depends on
+// the arena's initial buffers and limits.  Overwriting a pointer leaves
+// the old object behind in the message.  DeterministicMarshal does not
+// depend on any of these.
+func (m *Message) DeterministicMarshal() ([]byte, error) {
+	root, err := m.RootPtr()
+	if err != nil {
+		return nil, err
+	}
+	if root.IsValid() && root.flags.ptrType() != structPtrType {
+		return nil, errors.New("capnp: deterministic marshal: root is not a struct")
+	}
+	data, err := Canonicalize(root.Struct())
+	if err != nil {
+		return nil, err
+	}
+	return (&Message{Arena: SingleSegment(data)}).Marshal()
+}
+
+func canonicalPtr(dst *Segment, p Ptr, path objectPath) (Ptr, error) {
 	if !p.IsValid() {
 		return Ptr{}, nil
 	}
@@ -37,12 +64,12 @@ func canonicalPtr(dst *Segment, p Ptr) (Ptr, error) {
 		if err != nil {
 			return Ptr{}, err
 		}
-		if err := fillCanonicalStruct(ss, p.Struct()); err != nil {
+		if err := fillCanonicalStruct(ss, p.Struct(), path); err != nil {
 			return Ptr{}, err
 		}
 		return ss.ToPtr(), nil
 	case listPtrType:
-		ll, err := canonicalList(dst, p.List())
+		ll, err := canonicalList(dst, p.List(), path)
 		if err != nil {
 			return Ptr{}, err
 		}
@@ -54,16 +81,21 @@ func canonicalPtr(dst *Segment, p Ptr) (Ptr, error) {
 	}
 }
 
-func fillCanonicalStruct(dst, s Struct) error {
+func fillCanonicalStruct(dst, s Struct, path objectPath) error {
 	copy(dst.seg.slice(dst.off, dst.size.DataSize), s.seg.slice(s.off, s.size.DataSize))
+	k := objectKey{seg: s.seg, off: s.off, size: s.size}
+	if err := path.enter(k, s.seg.msg.copyDepthLimit()); err != nil {
+		return err
+	}
+	defer path.pop(k)
 	for i := uint16(0); i < dst.size.PointerCount; i++ {
 		p, err := s.Ptr(i)
 		if err != nil {
-			return fmt.Errorf("pointer %d: %v", i, err)
+			return canonicalError(err, "pointer %d", i)
 		}
-		cp, err := canonicalPtr(dst.seg, p)
+		cp, err := canonicalPtr(dst.seg, p, path)
 		if err != nil {
-			return fmt.Errorf("pointer %d: %v", i, err)
+			return canonicalError(err, "pointer %d", i)
 		}
 		if err := dst.SetPtr(i, cp); err != nil {
 			return fmt.Errorf("pointer %d: %v", i, err)
@@ -93,7 +125,7 @@ func canonicalStructSize(s Struct) ObjectSize {
 	return sz
 }
 
-func canonicalList(dst *Segment, l List) (List, error) {
+func canonicalList(dst *Segment, l List, path objectPath) (List, error) {
 	if !l.IsValid() {
 		return List{}, nil
 	}
@@ -116,6 +148,11 @@ func canonicalList(dst *Segment, l List) (List, error) {
 		copy(dst.data[newAddr:], l.seg.data[l.off:end])
 		return cl, nil
 	}
+	k := objectKey{seg: l.seg, off: l.off, size: l.size, list: true}
+	if err := path.enter(k, l.seg.msg.copyDepthLimit()); err != nil {
+		return List{}, err
+	}
+	defer path.pop(k)
 	if l.flags&isCompositeList == 0 {
 		cl, err := NewPointerList(dst, l.length)
 		if err != nil {
@@ -124,11 +161,11 @@ func canonicalList(dst *Segment, l List) (List, error) {
 		for i := 0; i < l.Len(); i++ {
 			p, err := PointerList{l}.PtrAt(i)
 			if err != nil {
-				return List{}, fmt.Errorf("element %d: %v", i, err)
+				return List{}, canonicalError(err, "element %d", i)
 			}
-			cp, err := canonicalPtr(dst, p)
+			cp, err := canonicalPtr(dst, p, path)
 			if err != nil {
-				return List{}, fmt.Errorf("element %d: %v", i, err)
+				return List{}, canonicalError(err, "element %d", i)
 			}
 			if err := cl.SetPtr(i, cp); err != nil {
 				return List{}, fmt.Errorf("element %d: %v", i, err)
@@ -153,9 +190,19 @@ func canonicalList(dst *Segment, l List) (List, error) {
 		return List{}, err
 	}
 	for i := 0; i < cl.Len(); i++ {
-		if err := fillCanonicalStruct(cl.Struct(i), l.Struct(i)); err != nil {
-			return List{}, fmt.Errorf("element %d: %v", i, err)
+		if err := fillCanonicalStruct(cl.Struct(i), l.Struct(i), path); err != nil {
+			return List{}, canonicalError(err, "element %d", i)
 		}
 	}
 	return cl, nil
 }
+
+// canonicalError prefixes err with the location given by format and
+// args.  ErrDepthLimit is returned as-is so that callers of Canonicalize
+// can compare against it.
+func canonicalError(err error, format string, args ...interface{}) error {
+	if err == ErrDepthLimit {
+		return err
+	}
+	return fmt.Errorf(format+": %v", append(args, err)...)
+}
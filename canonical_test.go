@@ -190,3 +190,180 @@ func TestCanonicalize(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalizeCycle(t *testing.T) {
+	s, err := cyclicMessage().RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if _, err := Canonicalize(s.Struct()); err == nil {
+		t.Error("Canonicalize(cyclic struct) succeeded; want error")
+	}
+}
+
+func TestCanonicalizeDepthLimit(t *testing.T) {
+	msg := deepMessage(t, 1000)
+	s, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if _, err := Canonicalize(s.Struct()); err != ErrDepthLimit {
+		t.Errorf("Canonicalize(1000-deep struct) error = %v; want %v", err, ErrDepthLimit)
+	}
+	msg.CopyDepthLimit = 1000
+	if _, err := Canonicalize(s.Struct()); err != nil {
+		t.Errorf("Canonicalize(1000-deep struct) with CopyDepthLimit = 1000: %v", err)
+	}
+}
+
+func TestDeterministicMarshal(t *testing.T) {
+	build := func(arena Arena, reorder bool) *Message {
+		msg, seg, err := NewMessage(arena)
+		if err != nil {
+			t.Fatal("NewMessage:", err)
+		}
+		root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+		if err != nil {
+			t.Fatal("NewRootStruct:", err)
+		}
+		root.SetUint64(0, 0xdeadbeef)
+		setText := func() {
+			if err := root.SetText(0, "hello, world"); err != nil {
+				t.Fatal("SetText:", err)
+			}
+		}
+		if !reorder {
+			setText()
+		} else {
+			// Leave an orphaned object behind.
+			if err := root.SetText(0, "scratch"); err != nil {
+				t.Fatal("SetText:", err)
+			}
+		}
+		sub, err := NewStruct(seg, ObjectSize{DataSize: 8})
+		if err != nil {
+			t.Fatal("NewStruct:", err)
+		}
+		sub.SetUint64(0, 42)
+		if err := root.SetPtr(1, sub.ToPtr()); err != nil {
+			t.Fatal("SetPtr:", err)
+		}
+		if reorder {
+			setText()
+		}
+		return msg
+	}
+
+	// The same construction sequence on the same kind of arena
+	// gives the same bytes, even with Marshal.
+	m1, err := build(MultiSegment(nil, MaxSegmentSize(32)), false).Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	m2, err := build(MultiSegment(nil, MaxSegmentSize(32)), false).Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	if !bytes.Equal(m1, m2) {
+		t.Errorf("Marshal of identically built messages differs:\n% 02x\n% 02x", m1, m2)
+	}
+
+	msgs := []*Message{
+		build(SingleSegment(nil), false),
+		build(SingleSegment(nil), true),
+		build(MultiSegment(nil, MaxSegmentSize(32)), false),
+		build(MultiSegment(nil, MaxSegmentSize(32)), true),
+	}
+	want, err := msgs[0].DeterministicMarshal()
+	if err != nil {
+		t.Fatal("DeterministicMarshal:", err)
+	}
+	for i, msg := range msgs[1:] {
+		got, err := msg.DeterministicMarshal()
+		if err != nil {
+			t.Errorf("msgs[%d].DeterministicMarshal(): %v", i+1, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("msgs[%d].DeterministicMarshal() =\n% 02x\nwant\n% 02x", i+1, got, want)
+		}
+	}
+
+	msg, err := Unmarshal(want)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if got := p.Struct().Uint64(0); got != 0xdeadbeef {
+		t.Errorf("root.Uint64(0) = %#x; want 0xdeadbeef", got)
+	}
+	if tp, err := p.Struct().Ptr(0); err != nil {
+		t.Errorf("root.Ptr(0): %v", err)
+	} else if got := tp.Text(); got != "hello, world" {
+		t.Errorf("root.Ptr(0).Text() = %q; want \"hello, world\"", got)
+	}
+}
+
+func TestStructRawBytes(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := func(n uint64, name string) Struct {
+		s, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetUint64(0, n)
+		if err := s.SetText(0, name); err != nil {
+			t.Fatal(err)
+		}
+		l, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < l.Len(); i++ {
+			l.Struct(i).SetUint64(0, n+uint64(i))
+			if err := l.Struct(i).SetData(0, []byte{byte(i), 0xff}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SetPtr(1, l.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	s1 := build(42, "foo")
+	s2 := build(42, "foo")
+	s3 := build(42, "bar")
+
+	b1, err := s1.RawBytes()
+	if err != nil {
+		t.Fatal("s1.RawBytes():", err)
+	}
+	b2, err := s2.RawBytes()
+	if err != nil {
+		t.Fatal("s2.RawBytes():", err)
+	}
+	b3, err := s3.RawBytes()
+	if err != nil {
+		t.Fatal("s3.RawBytes():", err)
+	}
+	// struct (24 bytes) + "foo\x00" (4 bytes) + list tag and elements
+	// (40 bytes) + two data blobs (2 bytes each)
+	if want := 24 + 4 + 40 + 2*2; len(b1) != want {
+		t.Errorf("len(s1.RawBytes()) = %d; want %d", len(b1), want)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Errorf("RawBytes of identical builds differ:\n%s\n%s", hex.Dump(b1), hex.Dump(b2))
+	}
+	if bytes.Equal(b1, b3) {
+		t.Error("RawBytes of builds with different text are equal")
+	}
+	if b, err := (Struct{}).RawBytes(); err != nil || b != nil {
+		t.Errorf("Struct{}.RawBytes() = %v, %v; want <nil>, <nil>", b, err)
+	}
+}
@@ -3,6 +3,7 @@ package capnp
 import (
 	"bytes"
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -187,6 +188,22 @@ func TestTransform(t *testing.T) {
 	}
 }
 
+func TestPipelineTransform(t *testing.T) {
+	p := NewPipeline(ErrorAnswer(errors.New("unused")))
+	if xform := p.Transform(); len(xform) != 0 {
+		t.Errorf("root pipeline Transform() = %v; want []", xform)
+	}
+	deep := p.GetPipeline(0).GetPipelineDefault(2, []byte{1}).GetPipeline(1)
+	want := []PipelineOp{{Field: 0}, {Field: 2, DefaultValue: []byte{1}}, {Field: 1}}
+	if xform := deep.Transform(); !reflect.DeepEqual(xform, want) {
+		t.Errorf("three-level pipeline Transform() = %v; want %v", xform, want)
+	}
+	// Deriving a pipeline does not change its parent.
+	if xform := p.GetPipeline(3).Transform(); !reflect.DeepEqual(xform, []PipelineOp{{Field: 3}}) {
+		t.Errorf("p.GetPipeline(3).Transform() = %v; want [get field 3]", xform)
+	}
+}
+
 func TestMethodString(t *testing.T) {
 	tests := []struct {
 		m *Method
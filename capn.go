@@ -71,21 +71,56 @@ func (s *Segment) readRawPointer(addr Address) rawPointer {
 }
 
 func (s *Segment) writeUint8(addr Address, val uint8) {
+	s.modified()
 	s.slice(addr, 1)[0] = val
 }
 
 func (s *Segment) writeUint16(addr Address, val uint16) {
+	s.modified()
 	binary.LittleEndian.PutUint16(s.slice(addr, 2), val)
 }
 
 func (s *Segment) writeUint32(addr Address, val uint32) {
+	s.modified()
 	binary.LittleEndian.PutUint32(s.slice(addr, 4), val)
 }
 
 func (s *Segment) writeUint64(addr Address, val uint64) {
+	s.modified()
 	binary.LittleEndian.PutUint64(s.slice(addr, 8), val)
 }
 
+// modified is called before writing to the segment's data.  It clears
+// the message's validated flag.  Writing to a read-only message is
+// programmer error, so modified panics with errReadOnly; methods that
+// return an error call writable first instead.
+func (s *Segment) modified() {
+	if s.msg == nil {
+		return
+	}
+	if s.msg.readOnly {
+		panic(errReadOnly)
+	}
+	s.msg.clearValidated()
+}
+
+// aliased is called when a slice of the segment is handed to the
+// caller, who may write to it.  Unlike modified, it doesn't panic for a
+// read-only message, since the slice may only be read.
+func (s *Segment) aliased() {
+	if s.msg != nil {
+		s.msg.clearValidated()
+	}
+}
+
+// writable returns errReadOnly if the segment's message is read-only.
+func (s *Segment) writable() error {
+	if s.msg != nil && s.msg.readOnly {
+		return errReadOnly
+	}
+	return nil
+}
+
 func (s *Segment) writeRawPointer(addr Address, val rawPointer) {
 	s.writeUint64(addr, uint64(val))
 }
@@ -122,7 +157,7 @@ func (s *Segment) readPtr(paddr Address, depthLimit uint) (ptr Ptr, err error) {
 		return Ptr{}, nil
 	}
 	if depthLimit == 0 {
-		return Ptr{}, errDepthLimit
+		return Ptr{}, ErrDepthLimit
 	}
 	switch val.pointerType() {
 	case structPointer:
@@ -203,9 +238,12 @@ func (s *Segment) readListPtr(base Address, val rawPointer) (List, error) {
 		if n < 0 {
 			return List{}, errListSize
 		}
-		// TODO(light): check that this has the same end address
+		// The tag must not claim more elements than the list pointer's
+		// word count allows, or the list would overlap other objects.
 		if tsize, ok := sz.totalSize().times(n); !ok {
 			return List{}, errOverflow
+		} else if tsize > lsize-wordSize {
+			return List{}, ErrOutOfBounds
 		} else if !s.regionInBounds(addr, tsize) {
 			return List{}, errPointerAddress
 		}
@@ -294,10 +332,22 @@ func (s *Segment) resolveFarPointer(paddr Address) (dst *Segment, base Address,
 }
 
 func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
+	return s.writePtrPath(off, src, forceCopy, nil)
+}
+
+// writePtrPath is writePtr with the set of structs that are being copied
+// further up the stack, used to detect pointer cycles.  path may be nil.
+func (s *Segment) writePtrPath(off Address, src Ptr, forceCopy bool, path objectPath) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
 	if !src.IsValid() {
 		s.writeRawPointer(off, 0)
 		return nil
 	}
+	if s.msg.CheckPtrOwnership && !forceCopy && src.seg.msg != s.msg {
+		return errForeignPtr
+	}
 
 	// Copy src, if needed, and process pointers where placement is
 	// irrelevant (capabilities and zero-sized structs).
@@ -324,7 +374,7 @@ func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
 				depthLimit: maxDepth,
 				// clear flags
 			}
-			if err := copyStruct(dst, st); err != nil {
+			if err := copyStructPath(dst, st, path); err != nil {
 				return err
 			}
 			st = dst
@@ -363,7 +413,7 @@ func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
 				copy(newSeg.data[dst.off:], l.seg.data[l.off:end])
 			} else {
 				for i := 0; i < l.Len(); i++ {
-					err := copyStruct(dst.Struct(i), l.Struct(i))
+					err := copyStructPath(dst.Struct(i), l.Struct(i), path)
 					if err != nil {
 						return err
 					}
@@ -414,6 +464,192 @@ func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
 	}
 }
 
+// A relocator rewrites the pointers in copies of the segments that
+// Message.Import appends to a message, so that they refer to the new
+// segment IDs and capability table indices.
+type relocator struct {
+	segs    []*Segment // indexed by ID in the source message
+	segBase SegmentID
+	capBase CapabilityID
+	rlimit  *ReadLimiter
+
+	// seen holds the pointers that have already been rewritten.  This
+	// keeps shared objects from being rewritten twice and stops cycles.
+	seen map[relocKey]bool
+}
+
+type relocKey struct {
+	seg  SegmentID
+	addr Address
+}
+
+// segment returns the copy of the source segment with the given ID.
+func (r *relocator) segment(id SegmentID) (*Segment, error) {
+	if int64(id) >= int64(len(r.segs)) {
+		return nil, errPointerAddress
+	}
+	return r.segs[id], nil
+}
+
+// relocatePtr rewrites the pointer at paddr and the pointers in the
+// object it refers to, which may be nested at most depth levels deep.
+func (r *relocator) relocatePtr(s *Segment, paddr Address, depth uint) error {
+	k := relocKey{s.id, paddr}
+	if r.seen[k] {
+		return nil
+	}
+	r.seen[k] = true
+	if !s.regionInBounds(paddr, wordSize) {
+		return errPointerAddress
+	}
+	val := s.readRawPointer(paddr)
+	if val == 0 {
+		return nil
+	}
+	switch val.pointerType() {
+	case structPointer, listPointer:
+		base, ok := paddr.addSize(wordSize)
+		if !ok {
+			return errOverflow
+		}
+		addr, ok := val.offset().resolve(base)
+		if !ok {
+			return errPointerAddress
+		}
+		return r.relocateObject(s, addr, val, depth)
+	case otherPointer:
+		if val.otherPointerType() != 0 {
+			return errOtherPointer
+		}
+		s.writeRawPointer(paddr, rawInterfacePointer(val.capabilityIndex()+r.capBase))
+		return nil
+	case farPointer:
+		padSeg, err := r.segment(val.farSegment())
+		if err != nil {
+			return err
+		}
+		s.writeRawPointer(paddr, rawFarPointer(padSeg.id+r.segBase, val.farAddress()))
+		// A far pointer's landing pad is an ordinary pointer.
+		return r.relocatePtr(padSeg, val.farAddress(), depth)
+	case doubleFarPointer:
+		padSeg, err := r.segment(val.farSegment())
+		if err != nil {
+			return err
+		}
+		padAddr := val.farAddress()
+		if !padSeg.regionInBounds(padAddr, wordSize*2) {
+			return errPointerAddress
+		}
+		far := padSeg.readRawPointer(padAddr)
+		if far.pointerType() != farPointer {
+			return errBadLandingPad
+		}
+		dst, err := r.segment(far.farSegment())
+		if err != nil {
+			return err
+		}
+		s.writeRawPointer(paddr, rawDoubleFarPointer(padSeg.id+r.segBase, padAddr))
+		k := relocKey{padSeg.id, padAddr}
+		if r.seen[k] {
+			return nil
+		}
+		r.seen[k] = true
+		padSeg.writeRawPointer(padAddr, rawFarPointer(dst.id+r.segBase, far.farAddress()))
+		tag := padSeg.readRawPointer(padAddr + Address(wordSize))
+		return r.relocateObject(dst, far.farAddress(), tag, depth)
+	default:
+		return errOtherPointer
+	}
+}
+
+// relocateObject rewrites the pointers inside the struct or list at
+// addr described by val, ignoring val's offset.  Like reading the
+// object, this counts against the source message's read limit.
+func (r *relocator) relocateObject(s *Segment, addr Address, val rawPointer, depth uint) error {
+	if depth == 0 {
+		return ErrDepthLimit
+	}
+	switch val.pointerType() {
+	case structPointer:
+		sz := val.structSize()
+		if !r.rlimit.canRead(sz.totalSize()) {
+			return errReadLimit
+		}
+		return r.relocateStruct(s, addr, sz, depth-1)
+	case listPointer:
+	default:
+		return errOtherPointer
+	}
+	switch val.listType() {
+	case pointerList:
+		n := val.numListElements()
+		sz, ok := wordSize.times(n)
+		if !ok {
+			return errOverflow
+		}
+		if !s.regionInBounds(addr, sz) {
+			return errPointerAddress
+		}
+		if !r.rlimit.canRead(sz) {
+			return errReadLimit
+		}
+		for i := int32(0); i < n; i++ {
+			if err := r.relocatePtr(s, addr+Address(i)*Address(wordSize), depth-1); err != nil {
+				return err
+			}
+		}
+	case compositeList:
+		if !s.regionInBounds(addr, wordSize) {
+			return errPointerAddress
+		}
+		tag := s.readRawPointer(addr)
+		if tag.pointerType() != structPointer {
+			return errBadTag
+		}
+		sz := tag.structSize()
+		n := int32(tag.offset())
+		if n < 0 {
+			return errListSize
+		}
+		// As in List.readSize, zero-sized elements count as a word
+		// so that a huge list of them can't be walked for free.
+		e := sz.totalSize()
+		if e == 0 {
+			e = wordSize
+		}
+		total, ok := e.times(n)
+		if !ok {
+			return errOverflow
+		}
+		if !r.rlimit.canRead(total) {
+			return errReadLimit
+		}
+		for i := int32(0); i < n; i++ {
+			elem, ok := (addr + Address(wordSize)).element(i, sz.totalSize())
+			if !ok {
+				return errOverflow
+			}
+			if err := r.relocateStruct(s, elem, sz, depth-1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *relocator) relocateStruct(s *Segment, addr Address, sz ObjectSize, depth uint) error {
+	if !s.regionInBounds(addr, sz.totalSize()) {
+		return errPointerAddress
+	}
+	for i := uint16(0); i < sz.PointerCount; i++ {
+		paddr := addr.addOffset(DataOffset(sz.DataSize)) + Address(i)*Address(wordSize)
+		if err := r.relocatePtr(s, paddr, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var (
 	errPointerAddress = errors.New("capnp: invalid pointer address")
 	errBadLandingPad  = errors.New("capnp: invalid far pointer landing pad")
@@ -422,13 +658,90 @@ var (
 	errObjectSize     = errors.New("capnp: invalid object size")
 	errElementSize    = errors.New("capnp: mismatched list element size")
 	errReadLimit      = errors.New("capnp: read traversal limit reached")
-	errDepthLimit     = errors.New("capnp: depth limit reached")
 )
 
+// ErrDepthLimit is returned when reading an object nested more deeply
+// than the message's DepthLimit, or when copying, canonicalizing, or
+// validating objects nested more deeply than its CopyDepthLimit.
+var ErrDepthLimit = errors.New("capnp: depth limit reached")
+
+// ErrOutOfBounds is returned when reading an object that extends past
+// the bounds of its enclosing object, such as a composite list whose
+// tag word claims more elements than the list has room for.  It is also
+// the panic value for out-of-range struct field and list element
+// accesses.
+var ErrOutOfBounds = errors.New("capnp: address out of bounds")
+
+// ErrTooManyPointers is returned when allocating a struct whose pointer
+// section is larger than the message's MaxPointerCount.
+var ErrTooManyPointers = errors.New("capnp: struct has too many pointers")
+
 var (
-	errOverflow    = errors.New("capnp: address or size overflow")
-	errOutOfBounds = errors.New("capnp: address out of bounds")
-	errCopyDepth   = errors.New("capnp: copy depth too large")
-	errOverlap     = errors.New("capnp: overlapping data on copy")
-	errListSize    = errors.New("capnp: invalid list size")
+	errOverflow   = errors.New("capnp: address or size overflow")
+	errCopyDepth  = errors.New("capnp: copy depth too large")
+	errCopyCycle  = errors.New("capnp: pointer cycle in copied data")
+	errOverlap    = errors.New("capnp: overlapping data on copy")
+	errForeignPtr = errors.New("capnp: pointer belongs to a different message")
+	errListSize   = errors.New("capnp: invalid list size")
+	errNotStruct  = errors.New("capnp: pointer is not a struct")
+	errPtrOverlap = errors.New("capnp: pointer target overlaps the struct it is stored in")
+	errMarshalCap = errors.New("capnp: message contains a capability pointer")
 )
+
+// An objectKey identifies an object in a message.
+type objectKey struct {
+	seg  *Segment
+	off  Address
+	size ObjectSize
+	list bool
+}
+
+// objectPath is the set of objects on the current path of a recursive
+// traversal.  Since reading a pointer is a deterministic function of the
+// containing object, revisiting an object on the path means the
+// traversal would never terminate.
+type objectPath map[objectKey]struct{}
+
+// push adds k to the path, returning false if it is already present.
+func (path objectPath) push(k objectKey) bool {
+	if _, dup := path[k]; dup {
+		return false
+	}
+	path[k] = struct{}{}
+	return true
+}
+
+// enter is like push, but first checks that the path holds fewer than
+// limit objects.  It returns ErrDepthLimit or errCopyCycle if k cannot
+// be added.
+func (path objectPath) enter(k objectKey, limit uint) error {
+	if uint(len(path)) >= limit {
+		return ErrDepthLimit
+	}
+	if !path.push(k) {
+		return errCopyCycle
+	}
+	return nil
+}
+
+// pop removes k from the path.
+func (path objectPath) pop(k objectKey) {
+	delete(path, k)
+}
+
+// hasChildPointers reports whether p points to a struct or list whose
+// copy would copy further pointers.
+func hasChildPointers(p Ptr) bool {
+	if !p.IsValid() {
+		return false
+	}
+	switch p.flags.ptrType() {
+	case structPtrType:
+		return p.Struct().size.PointerCount > 0
+	case listPtrType:
+		l := p.List()
+		return l.flags&isBitList == 0 && l.size.PointerCount > 0
+	default:
+		return false
+	}
+}
@@ -509,6 +509,303 @@ func TestSetPtrToZeroSizeStruct(t *testing.T) {
 	}
 }
 
+// cyclicMessage returns a message whose root struct has a single pointer
+// that points back to the root struct.  Limits are raised so that only
+// cycle detection stops a traversal.
+func cyclicMessage() *Message {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint64(data[0:], uint64(rawStructPointer(0, ObjectSize{PointerCount: 1})))
+	binary.LittleEndian.PutUint64(data[8:], uint64(rawStructPointer(-1, ObjectSize{PointerCount: 1})))
+	return &Message{
+		Arena:         SingleSegment(data),
+		TraverseLimit: 1 << 62,
+		DepthLimit:    1 << 30,
+	}
+}
+
+// deepMessage returns a message whose root is the first of a chain of
+// n structs, each pointing to the next.  The read limits are raised so
+// that only CopyDepthLimit stops a traversal.
+func deepMessage(t *testing.T, n int) *Message {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	msg.TraverseLimit = 1 << 62
+	msg.DepthLimit = 1 << 30
+	s, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	for i := 1; i < n; i++ {
+		next, err := NewStruct(seg, ObjectSize{PointerCount: 1})
+		if err != nil {
+			t.Fatal("NewStruct:", err)
+		}
+		if err := s.SetPtr(0, next.ToPtr()); err != nil {
+			t.Fatal("SetPtr:", err)
+		}
+		s = next
+	}
+	return msg
+}
+
+func TestSetPtrCopyDepthLimit(t *testing.T) {
+	src, err := deepMessage(t, 1000).RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	if err := root.SetPtr(0, src); err != ErrDepthLimit {
+		t.Errorf("root.SetPtr(0, 1000-deep struct) = %v; want %v", err, ErrDepthLimit)
+	}
+	msg.CopyDepthLimit = 1000
+	if err := root.SetPtr(0, src); err != nil {
+		t.Errorf("root.SetPtr(0, 1000-deep struct) with CopyDepthLimit = 1000: %v", err)
+	}
+}
+
+func TestSetPtrCopyCycle(t *testing.T) {
+	src, err := cyclicMessage().RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	if err := root.SetPtr(0, src); err != errCopyCycle {
+		t.Errorf("root.SetPtr(0, cyclic struct) = %v; want %v", err, errCopyCycle)
+	}
+}
+
+func TestSetPtrCopySharedStruct(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	src, err := NewRootStruct(seg, ObjectSize{PointerCount: 2})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	sub, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal("NewStruct:", err)
+	}
+	sub.SetUint64(0, 42)
+	// Both pointers refer to the same struct: a DAG, not a cycle.
+	if err := src.SetPtr(0, sub.ToPtr()); err != nil {
+		t.Fatal("src.SetPtr(0, sub):", err)
+	}
+	if err := src.SetPtr(1, sub.ToPtr()); err != nil {
+		t.Fatal("src.SetPtr(1, sub):", err)
+	}
+
+	_, seg2, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	dst, err := NewRootStruct(seg2, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	if err := dst.SetPtr(0, src.ToPtr()); err != nil {
+		t.Fatal("dst.SetPtr(0, src):", err)
+	}
+	p, err := dst.Ptr(0)
+	if err != nil {
+		t.Fatal("dst.Ptr(0):", err)
+	}
+	for i := uint16(0); i < 2; i++ {
+		pi, err := p.Struct().Ptr(i)
+		if err != nil {
+			t.Errorf("copy.Ptr(%d): %v", i, err)
+			continue
+		}
+		if got := pi.Struct().Uint64(0); got != 42 {
+			t.Errorf("copy.Ptr(%d).Struct().Uint64(0) = %d; want 42", i, got)
+		}
+	}
+}
+
+func TestSetPtrCheckOwnership(t *testing.T) {
+	msg1, seg1, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	msg1.CheckPtrOwnership = true
+	root, err := NewRootStruct(seg1, ObjectSize{PointerCount: 2})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	own, err := NewStruct(seg1, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal("NewStruct:", err)
+	}
+	msg2, seg2, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	foreign, err := NewStruct(seg2, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal("NewStruct:", err)
+	}
+	if m := own.ToPtr().Message(); m != msg1 {
+		t.Errorf("own.ToPtr().Message() = %p; want %p", m, msg1)
+	}
+	if m := foreign.ToPtr().Message(); m != msg2 {
+		t.Errorf("foreign.ToPtr().Message() = %p; want %p", m, msg2)
+	}
+	if m := (Ptr{}).Message(); m != nil {
+		t.Errorf("Ptr{}.Message() = %p; want nil", m)
+	}
+
+	if err := root.SetPtr(0, own.ToPtr()); err != nil {
+		t.Errorf("root.SetPtr(0, own) = %v; want <nil>", err)
+	}
+	if err := root.SetPtr(1, foreign.ToPtr()); err != errForeignPtr {
+		t.Errorf("root.SetPtr(1, foreign) = %v; want %v", err, errForeignPtr)
+	}
+	l, err := NewPointerList(seg1, 1)
+	if err != nil {
+		t.Fatal("NewPointerList:", err)
+	}
+	if err := l.SetPtr(0, foreign.ToPtr()); err != errForeignPtr {
+		t.Errorf("l.SetPtr(0, foreign) = %v; want %v", err, errForeignPtr)
+	}
+
+	msg1.CheckPtrOwnership = false
+	if err := root.SetPtr(1, foreign.ToPtr()); err != nil {
+		t.Errorf("root.SetPtr(1, foreign) without CheckPtrOwnership = %v; want <nil>", err)
+	}
+}
+
+func TestSetPtrCheckOverlap(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	msg.CheckPtrOverlap = true
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	other, err := NewStruct(seg, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal("NewStruct:", err)
+	}
+	// Hand-build a pointer in root's second slot to a struct that
+	// starts at root's data section and covers its first slot.
+	slot := root.pointerAddress(1)
+	off := pointerOffset((int64(root.off) - int64(slot) - int64(wordSize)) / int64(wordSize))
+	seg.writeRawPointer(slot, rawStructPointer(off, ObjectSize{DataSize: 16}))
+	overlap, err := root.Ptr(1)
+	if err != nil {
+		t.Fatal("root.Ptr(1):", err)
+	}
+	if overlap.Struct().off != root.off {
+		t.Fatalf("hand-built pointer points to %v; want %v", overlap.Struct().off, root.off)
+	}
+
+	if err := root.SetPtr(0, overlap); err != errPtrOverlap {
+		t.Errorf("root.SetPtr(0, overlapping struct) = %v; want %v", err, errPtrOverlap)
+	}
+	if err := root.SetPtr(0, other.ToPtr()); err != nil {
+		t.Errorf("root.SetPtr(0, other) = %v; want <nil>", err)
+	}
+	l, err := NewInt64List(seg, 2)
+	if err != nil {
+		t.Fatal("NewInt64List:", err)
+	}
+	if err := root.SetPtr(0, l.ToPtr()); err != nil {
+		t.Errorf("root.SetPtr(0, list) = %v; want <nil>", err)
+	}
+
+	msg.CheckPtrOverlap = false
+	if err := root.SetPtr(0, overlap); err != nil {
+		t.Errorf("root.SetPtr(0, overlapping struct) without CheckPtrOverlap = %v; want <nil>", err)
+	}
+}
+
+func TestMaxPointerCount(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	absurd := ObjectSize{DataSize: 8, PointerCount: 0xffff}
+	msg.MaxPointerCount = 16
+	if _, err := NewStruct(seg, absurd); err != ErrTooManyPointers {
+		t.Errorf("NewStruct(%v) error = %v; want ErrTooManyPointers", absurd, err)
+	}
+	if _, err := NewCompositeList(seg, absurd, 1); err != ErrTooManyPointers {
+		t.Errorf("NewCompositeList(%v, 1) error = %v; want ErrTooManyPointers", absurd, err)
+	}
+	if n := len(seg.Data()); n != 8 {
+		t.Errorf("after rejected allocations, len(seg.Data()) = %d; want 8 (root pointer only)", n)
+	}
+	if _, err := NewStruct(seg, ObjectSize{PointerCount: 16}); err != nil {
+		t.Errorf("NewStruct at limit: %v", err)
+	}
+	if _, err := NewCompositeList(seg, ObjectSize{PointerCount: 16}, 2); err != nil {
+		t.Errorf("NewCompositeList at limit: %v", err)
+	}
+
+	msg.MaxPointerCount = 0
+	if _, err := NewStruct(seg, absurd); err != nil {
+		t.Errorf("NewStruct(%v) without limit: %v", absurd, err)
+	}
+}
+
+func TestReadCompositeListTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		count byte // element count in tag word
+		err   error
+	}{
+		{"exact", 2, nil},
+		{"fewer elements", 1, nil},
+		{"overstated length", 3, ErrOutOfBounds},
+	}
+	for _, test := range tests {
+		data := []byte{
+			// Root pointer: struct with 0 words data and 1 pointer
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
+			// Composite list pointer: 4 words
+			0x01, 0x00, 0x00, 0x00, 0x27, 0x00, 0x00, 0x00,
+			// Tag word: count elements of 2 words data
+			test.count << 2, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+		}
+		// Make the segment large enough for an overstated list to
+		// stay within its bounds.
+		data = append(data, make([]byte, 6*8)...)
+		msg := &Message{Arena: SingleSegment(data)}
+		root, err := msg.RootPtr()
+		if err != nil {
+			t.Errorf("%s: RootPtr: %v", test.name, err)
+			continue
+		}
+		l, err := root.Struct().Ptr(0)
+		if err != test.err {
+			t.Errorf("%s: root.Ptr(0) error = %v; want %v", test.name, err, test.err)
+			continue
+		}
+		if err == nil && l.List().Len() != int(test.count) {
+			t.Errorf("%s: len(list) = %d; want %d", test.name, l.List().Len(), test.count)
+		}
+	}
+}
+
 func TestReadFarPointers(t *testing.T) {
 	msg := &Message{
 		// an rpc.capnp Message
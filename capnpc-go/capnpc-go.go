@@ -42,6 +42,9 @@ type genoptions struct {
 	promises      bool
 	schemas       bool
 	structStrings bool
+	fieldTables   bool
+	checkedEnums  bool
+	withFuncs     bool
 }
 
 type renderer interface {
@@ -458,6 +461,24 @@ func (g *generator) defineConstNodes(nodes []*node) error {
 	return nil
 }
 
+// structAccessorName returns the name of the method that reads the
+// AnyPointer field f as a struct.  It is FooStruct for a field foo,
+// or FooAsStruct if n has a field named fooStruct.  It returns the
+// empty string if both names are taken, in which case the accessor
+// is omitted.
+func structAccessorName(n *node, f field) string {
+	taken := make(map[string]bool)
+	for _, sib := range n.codeOrderFields() {
+		taken[strings.Title(sib.Name)] = true
+	}
+	for _, suffix := range []string{"Struct", "AsStruct"} {
+		if name := strings.Title(f.Name) + suffix; !taken[name] {
+			return name
+		}
+	}
+	return ""
+}
+
 func (g *generator) defineField(n *node, f field) (err error) {
 	defer func() {
 		if err != nil {
@@ -513,13 +534,32 @@ func (g *generator) defineField(n *node, f field) (err error) {
 		if err != nil {
 			return err
 		}
-		return renderStructIntField(g.r, structIntFieldParams{
+		ip := structIntFieldParams{
 			structUintFieldParams: structUintFieldParams{
 				structFieldParams: params,
 				Bits:              16,
 				Default:           uint64(def.Enum()),
 			},
 			EnumName: rn,
+		}
+		if err := renderStructIntField(g.r, ip); err != nil {
+			return err
+		}
+		if !g.opts.checkedEnums {
+			return nil
+		}
+		en := g.nodes[t.Enum().TypeId()]
+		if en == nil {
+			return fmt.Errorf("could not find enum @%#x", t.Enum().TypeId())
+		}
+		enumerants, err := en.Enum().Enumerants()
+		if err != nil {
+			return err
+		}
+		return renderStructCheckedEnumField(g.r, structCheckedEnumFieldParams{
+			structIntFieldParams: ip,
+			EnumID:               en.Id(),
+			NumValues:            enumerants.Len(),
 		})
 	case schema.Type_Which_float32:
 		return renderStructFloatField(g.r, structFloatFieldParams{
@@ -586,8 +626,11 @@ func (g *generator) defineField(n *node, f field) (err error) {
 			}
 		}
 		return renderStructPointerField(g.r, structPointerFieldParams{
-			structFieldParams: params,
-			Default:           defref,
+			structObjectFieldParams: structObjectFieldParams{
+				structFieldParams: params,
+				Default:           defref,
+			},
+			StructFunc: structAccessorName(n, f),
 		})
 
 	case schema.Type_Which_list:
@@ -798,6 +841,11 @@ func (g *generator) defineStruct(n *node) error {
 	if err := g.defineBaseStructFuncs(n); err != nil {
 		return err
 	}
+	if g.opts.withFuncs {
+		if err := g.defineStructWith(n); err != nil {
+			return err
+		}
+	}
 	if err := g.defineStructFuncs(n); err != nil {
 		return err
 	}
@@ -883,6 +931,11 @@ func (g *generator) defineStructFuncs(n *node) error {
 	if err != nil {
 		return fmt.Errorf("struct funcs for %s: %v", n, err)
 	}
+	if g.opts.fieldTables {
+		if err := g.defineStructFieldTable(n); err != nil {
+			return err
+		}
+	}
 
 	for _, f := range n.codeOrderFields() {
 		switch f.Which() {
@@ -912,6 +965,118 @@ func (g *generator) defineStructFuncs(n *node) error {
 	return nil
 }
 
+func (g *generator) defineStructFieldTable(n *node) error {
+	fields := n.codeOrderFields()
+	entries := make([]fieldTableEntry, len(fields))
+	for i, f := range fields {
+		name, _ := f.Field.Name()
+		e := fieldTableEntry{
+			Name:         name,
+			Discriminant: f.DiscriminantValue(),
+		}
+		switch f.Which() {
+		case schema.Field_Which_slot:
+			t, _ := f.Slot().Type()
+			off := f.Slot().Offset()
+			switch t.Which() {
+			case schema.Type_Which_void:
+			case schema.Type_Which_bool:
+				e.Offset, e.Bits = off, 1
+			case schema.Type_Which_int8, schema.Type_Which_uint8,
+				schema.Type_Which_int16, schema.Type_Which_uint16,
+				schema.Type_Which_int32, schema.Type_Which_uint32,
+				schema.Type_Which_int64, schema.Type_Which_uint64:
+				e.Bits = uint8(intbits(t.Which()))
+				e.Offset = off * uint32(e.Bits)
+			case schema.Type_Which_enum:
+				e.Offset, e.Bits = off*16, 16
+			case schema.Type_Which_float32:
+				e.Offset, e.Bits = off*32, 32
+			case schema.Type_Which_float64:
+				e.Offset, e.Bits = off*64, 64
+			default:
+				e.Offset, e.IsPointer = off, true
+			}
+		case schema.Field_Which_group:
+			e.IsGroup = true
+		}
+		entries[i] = e
+	}
+	err := renderStructFieldTable(g.r, structFieldTableParams{
+		G:      g,
+		Node:   n,
+		Fields: entries,
+	})
+	if err != nil {
+		return fmt.Errorf("field table for %s: %v", n, err)
+	}
+	return nil
+}
+
+// defineStructWith renders the NewFooWith constructor for n, which sets
+// its scalar, enum, and text fields.  Fields in unions and groups are
+// left out, since setting one would also pick the union member.  Since
+// only non-zero params are set, a field with a non-zero default can't
+// be set to zero by the constructor.
+func (g *generator) defineStructWith(n *node) error {
+	params, fn, err := g.structWithNames(n)
+	if err != nil {
+		return err
+	}
+	var fields []withField
+	for _, f := range n.codeOrderFields() {
+		if f.Which() != schema.Field_Which_slot || f.HasDiscriminant() {
+			continue
+		}
+		t, _ := f.Slot().Type()
+		switch t.Which() {
+		case schema.Type_Which_void, schema.Type_Which_data,
+			schema.Type_Which_list, schema.Type_Which_structType,
+			schema.Type_Which_interface, schema.Type_Which_anyPointer:
+			continue
+		}
+		ftyp, err := g.RemoteTypeName(t, n)
+		if err != nil {
+			return fmt.Errorf("constructor for %s: field %s: %v", n, f.Name, err)
+		}
+		fields = append(fields, withField{
+			Name:     f.Name,
+			Type:     ftyp,
+			Which:    t.Which(),
+			Fallible: t.Which() == schema.Type_Which_text,
+		})
+	}
+	err = renderStructWith(g.r, structWithParams{
+		G:      g,
+		Node:   n,
+		Params: params,
+		Func:   fn,
+		Fields: fields,
+	})
+	if err != nil {
+		return fmt.Errorf("constructor for %s: %v", n, err)
+	}
+	return nil
+}
+
+// structWithNames returns the names of the params struct and the
+// constructor that defineStructWith generates for n.  They are
+// FooParams and NewFooWith, unless the file has a node named FooParams
+// or FooWith, whose generated code would collide.  Then they are
+// Foo_WithParams and NewFoo_With.
+func (g *generator) structWithNames(n *node) (params, fn string, err error) {
+	taken := make(map[string]bool)
+	for _, m := range g.nodes[g.fileID].nodes {
+		taken[m.Name] = true
+	}
+	for _, suffix := range [][2]string{{"Params", "With"}, {"_WithParams", "_With"}} {
+		if !taken[n.Name+suffix[0]] && !taken[n.Name+suffix[1]] {
+			return n.Name + suffix[0], "New" + n.Name + suffix[1], nil
+		}
+	}
+	return "", "", fmt.Errorf("constructor for %s: names collide with other nodes in the file", n)
+}
+
 func (g *generator) ObjectSize(n *node) (string, error) {
 	if n.Which() != schema.Node_Which_structNode {
 		return "", fmt.Errorf("object size called for %v node", n.Which())
@@ -1167,6 +1332,9 @@ func main() {
 	flag.BoolVar(&opts.promises, "promises", true, "generate code for promises")
 	flag.BoolVar(&opts.schemas, "schemas", true, "embed schema information in generated code")
 	flag.BoolVar(&opts.structStrings, "structstrings", true, "generate String() methods for structs (-schemas must be true)")
+	flag.BoolVar(&opts.fieldTables, "fieldtables", false, "generate a table of field names and locations for each struct")
+	flag.BoolVar(&opts.checkedEnums, "checkedenums", false, "generate accessors for enum fields that return an error for unknown values")
+	flag.BoolVar(&opts.withFuncs, "withfuncs", false, "generate NewFooWith constructors that set initial field values for each struct")
 	flag.Parse()
 
 	msg, err := capnp.NewDecoder(os.Stdin).Decode()
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
@@ -336,8 +337,22 @@ func TestDefineFile(t *testing.T) {
 			schemas:       true,
 			structStrings: true,
 		}},
+		{0x832bcc6686a26d56, "aircraft.capnp.out", genoptions{
+			promises:      true,
+			schemas:       true,
+			structStrings: true,
+			fieldTables:   true,
+			checkedEnums:  true,
+			withFuncs:     true,
+		}},
+		{0x83c2b5818e83ab19, "group.capnp.out", genoptions{
+			fieldTables: true,
+		}},
 		{0x83c2b5818e83ab19, "group.capnp.out", defaultOptions},
 		{0xb312981b2552a250, "rpc.capnp.out", defaultOptions},
+		{0xb312981b2552a250, "rpc.capnp.out", genoptions{
+			withFuncs: true,
+		}},
 		{0xd68755941d99d05e, "scopes.capnp.out", defaultOptions},
 		{0xecd50d792c3d9992, "util.capnp.out", defaultOptions},
 	}
@@ -388,6 +403,322 @@ func TestDefineFile(t *testing.T) {
 	}
 }
 
+func TestDefineFile_FieldTables(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "util.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(0xecd50d792c3d9992, nodes, genoptions{fieldTables: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	const want = `// KeyValue_Fields describes the fields of KeyValue in code order.
+var KeyValue_Fields = []capnp.FieldInfo{
+	{Name: "key", Offset: 0, Bits: 0, IsPointer: true, IsGroup: false, Discriminant: 0xffff},
+	{Name: "value", Offset: 1, Bits: 0, IsPointer: true, IsGroup: false, Discriminant: 0xffff},
+}
+`
+	if !bytes.Contains(src, []byte(want)) {
+		t.Errorf("generated source does not contain field table for KeyValue; want:\n%s\ngenerated:\n%s", want, src)
+	}
+
+	g = newGenerator(0xecd50d792c3d9992, nodes, genoptions{})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	if src := g.generate(); bytes.Contains(src, []byte("_Fields")) {
+		t.Error("generated source contains field tables when fieldTables is false")
+	}
+}
+
+func TestDefineFile_CheckedEnums(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "aircraft.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(0x832bcc6686a26d56, nodes, genoptions{checkedEnums: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	const want = `func (s Z) AirportChecked() (Airport, error) {
+	v := s.Airport()
+	if uint16(v) >= 7 {
+		return v, &capnp.EnumValueError{TypeID: 0xe55d85fc1bf82f21, Value: uint16(v)}
+	}
+	return v, nil
+}
+`
+	if !bytes.Contains(src, []byte(want)) {
+		t.Errorf("generated source does not contain checked accessor for Z.airport; want:\n%s\ngenerated:\n%s", want, src)
+	}
+
+	g = newGenerator(0x832bcc6686a26d56, nodes, genoptions{})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	if src := g.generate(); bytes.Contains(src, []byte("Checked()")) {
+		t.Error("generated source contains checked enum accessors when checkedEnums is false")
+	}
+}
+
+func TestDefineFile_WithFuncs(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "rpc.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(0xb312981b2552a250, nodes, genoptions{withFuncs: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	const want = `type ExceptionParams struct {
+	Reason                 string
+	Type                   Exception_Type
+	ObsoleteIsCallersFault bool
+	ObsoleteDurability     uint16
+}
+
+// NewExceptionWith allocates a new Exception in s and sets the
+// fields that are not zero in p.
+func NewExceptionWith(s *capnp.Segment, p ExceptionParams) (Exception, error) {
+	st, err := NewException(s)
+	if err != nil {
+		return st, err
+	}
+	if p.Reason != "" {
+		if err := st.SetReason(p.Reason); err != nil {
+			return st, err
+		}
+	}
+	if p.Type != 0 {
+		st.SetType(p.Type)
+	}
+	if p.ObsoleteIsCallersFault {
+		st.SetObsoleteIsCallersFault(p.ObsoleteIsCallersFault)
+	}
+	if p.ObsoleteDurability != 0 {
+		st.SetObsoleteDurability(p.ObsoleteDurability)
+	}
+	return st, nil
+}
+`
+	if !bytes.Contains(src, []byte(want)) {
+		t.Errorf("generated source does not contain NewExceptionWith; want:\n%s\ngenerated:\n%s", want, src)
+	}
+	// Message is all union members, so it has nothing to set.
+	if bytes.Contains(src, []byte("NewMessageWith")) {
+		t.Error("generated source contains NewMessageWith for a struct with only union fields")
+	}
+
+	g = newGenerator(0xb312981b2552a250, nodes, genoptions{})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	if src := g.generate(); bytes.Contains(src, []byte("With(")) {
+		t.Error("generated source contains constructors with initial values when withFuncs is false")
+	}
+}
+
+func TestDefineFile_WithFuncsCollision(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "rpc.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	for _, n := range nodes {
+		if n.Name == "Payload" {
+			n.Name = "ExceptionParams"
+		}
+	}
+	g := newGenerator(0xb312981b2552a250, nodes, genoptions{withFuncs: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	if !bytes.Contains(src, []byte("type Exception_WithParams struct {")) {
+		t.Error("generated source does not contain Exception_WithParams")
+	}
+	if !bytes.Contains(src, []byte("func NewException_With(s *capnp.Segment, p Exception_WithParams) (Exception, error) {")) {
+		t.Error("generated source does not contain NewException_With")
+	}
+}
+
+func TestDefineFile_WithFuncsDefaults(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "aircraft.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(0x832bcc6686a26d56, nodes, genoptions{withFuncs: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	// A zero param keeps the non-zero default of Defaults.uint instead
+	// of overwriting it.
+	const want = `	if p.Uint != 0 {
+		st.SetUint(p.Uint)
+	}
+`
+	if !bytes.Contains(src, []byte(want)) {
+		t.Errorf("generated source does not skip zero Uint in NewDefaultsWith; want:\n%s", want)
+	}
+	if !bytes.Contains(src, []byte("with a non-zero default can only be set to zero after construction")) {
+		t.Error("generated DefaultsParams does not document that zero keeps the default")
+	}
+}
+
+func TestDefineFile_AnyPointerStructCollision(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "rpc.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	// Rename Provide.target so that it collides with the struct
+	// accessor for Provide.recipient.
+	var provide *node
+	for _, n := range nodes {
+		if n.Name == "Provide" {
+			provide = n
+		}
+	}
+	if provide == nil {
+		t.Fatal("no Provide node in rpc.capnp.out")
+	}
+	fields, err := provide.StructNode().Fields()
+	if err != nil {
+		t.Fatal("Provide fields:", err)
+	}
+	for i := 0; i < fields.Len(); i++ {
+		if name, _ := fields.At(i).Name(); name == "target" {
+			if err := fields.At(i).SetName("recipientStruct"); err != nil {
+				t.Fatal("SetName:", err)
+			}
+		}
+	}
+	g := newGenerator(0xb312981b2552a250, nodes, genoptions{})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	if !bytes.Contains(src, []byte("func (s Provide) RecipientStruct() (MessageTarget, error) {")) {
+		t.Error("generated source does not contain the getter for the recipientStruct field")
+	}
+	if !bytes.Contains(src, []byte("func (s Provide) RecipientAsStruct(expected capnp.ObjectSize) (capnp.Struct, error) {")) {
+		t.Error("generated source does not fall back to RecipientAsStruct")
+	}
+	if bytes.Contains(src, []byte("func (s Provide) RecipientStruct(expected")) {
+		t.Error("generated source contains a RecipientStruct accessor that collides with the recipientStruct field")
+	}
+}
+
+func TestDefineFile_MethodParamsResults(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "aircraft.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(0x832bcc6686a26d56, nodes, genoptions{promises: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	// Echo.echo has implicit parameter and result structs.  Each gets a
+	// named type with typed accessors, and the client method, server
+	// interface, and server call struct all use them.
+	wants := []string{
+		"type Echo_echo_Params struct{ capnp.Struct }\n",
+		"func (s Echo_echo_Params) In() (string, error) {\n",
+		"func (s Echo_echo_Params) SetIn(v string) error {\n",
+		"type Echo_echo_Results struct{ capnp.Struct }\n",
+		"func (s Echo_echo_Results) Out() (string, error) {\n",
+		"func (s Echo_echo_Results) SetOut(v string) error {\n",
+		"func (c Echo) Echo(ctx context.Context, params func(Echo_echo_Params) error, opts ...capnp.CallOption) Echo_echo_Results_Promise {\n",
+		"\t\tcall.ParamsFunc = func(s capnp.Struct) error { return params(Echo_echo_Params{Struct: s}) }\n",
+		"type Echo_Server interface {\n\tEcho(Echo_echo) error\n}\n",
+		"type Echo_echo struct {\n" +
+			"\tCtx     context.Context\n" +
+			"\tOptions capnp.CallOptions\n" +
+			"\tParams  Echo_echo_Params\n" +
+			"\tResults Echo_echo_Results\n" +
+			"}\n",
+		"\t\t\tcall := Echo_echo{c, opts, Echo_echo_Params{Struct: p}, Echo_echo_Results{Struct: r}}\n",
+		"func (p Echo_echo_Results_Promise) Struct() (Echo_echo_Results, error) {\n",
+	}
+	for _, want := range wants {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated source does not contain:\n%s", want)
+		}
+	}
+}
+
+func TestDefineFile_StructListHelpers(t *testing.T) {
+	req := mustReadGeneratorRequest(t, "aircraft.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(0x832bcc6686a26d56, nodes, genoptions{})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatal("format generated source:", err)
+	}
+	wants := []string{
+		"func (s Zdate_List) ToSlice() []Zdate {\n" +
+			"\tv := make([]Zdate, s.Len())\n" +
+			"\tfor i := range v {\n" +
+			"\t\tv[i] = s.At(i)\n" +
+			"\t}\n" +
+			"\treturn v\n" +
+			"}\n",
+		"func (s Zdate_List) Range(f func(i int, v Zdate) bool) {\n" +
+			"\tfor i, n := 0, s.Len(); i < n; i++ {\n" +
+			"\t\tif !f(i, s.At(i)) {\n" +
+			"\t\t\treturn\n" +
+			"\t\t}\n" +
+			"\t}\n" +
+			"}\n",
+	}
+	for _, want := range wants {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated source does not contain:\n%s", want)
+		}
+	}
+	// Enum lists are not struct lists and don't get the helpers.
+	if bytes.Contains(src, []byte("func (l Airport_List) ToSlice()")) {
+		t.Error("generated source has ToSlice for enum list Airport_List")
+	}
+}
+
 func TestSchemaVarLiteral(t *testing.T) {
 	tests := []string{
 		"",
@@ -3,6 +3,9 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"strings"
+
+	"zombiezen.com/go/capnproto2/internal/schema"
 )
 
 type annotationParams struct {
@@ -45,6 +48,52 @@ type structFuncsParams struct {
 	Node *node
 }
 
+type structFieldTableParams struct {
+	G      *generator
+	Node   *node
+	Fields []fieldTableEntry
+}
+
+// fieldTableEntry holds the values of a capnp.FieldInfo literal.
+type fieldTableEntry struct {
+	Name         string
+	Offset       uint32
+	Bits         uint8
+	IsPointer    bool
+	IsGroup      bool
+	Discriminant uint16
+}
+
+type structWithParams struct {
+	G      *generator
+	Node   *node
+	Params string // name of the params struct
+	Func   string // name of the constructor
+	Fields []withField
+}
+
+// withField is a field of a NewFooWith params struct.
+type withField struct {
+	Name     string
+	Type     string
+	Which    schema.Type_Which
+	Fallible bool // setter returns an error
+}
+
+// IsSet returns the Go condition for whether the field of the params
+// struct named v holds a non-zero value.
+func (f withField) IsSet(v string) string {
+	x := v + "." + strings.Title(f.Name)
+	switch f.Which {
+	case schema.Type_Which_bool:
+		return x
+	case schema.Type_Which_text:
+		return x + ` != ""`
+	default:
+		return x + " != 0"
+	}
+}
+
 type structGroupParams struct {
 	G     *generator
 	Node  *node
@@ -65,7 +114,6 @@ type (
 	structInterfaceFieldParams structFieldParams
 	structVoidFieldParams      structFieldParams
 	structListFieldParams      structObjectFieldParams
-	structPointerFieldParams   structObjectFieldParams
 	structStructFieldParams    structObjectFieldParams
 )
 
@@ -100,6 +148,12 @@ func (p structIntFieldParams) ReturnType() string {
 	return fmt.Sprintf("int%d", p.Bits)
 }
 
+type structCheckedEnumFieldParams struct {
+	structIntFieldParams
+	EnumID    uint64
+	NumValues int
+}
+
 type structTextFieldParams struct {
 	structFieldParams
 	Default string
@@ -116,6 +170,11 @@ type structObjectFieldParams struct {
 	Default  staticDataRef
 }
 
+type structPointerFieldParams struct {
+	structObjectFieldParams
+	StructFunc string // name of the accessor for the field as a struct, or empty if none
+}
+
 type structListParams struct {
 	G            *generator
 	Node         *node
@@ -123,7 +123,8 @@ capnpc-go will generate:
 	// to load, or the pointer recursion limit has been reached.
 	func (s Foo) Bar() (Foo, error)
 
-	// HasBar reports whether the bar field was initialized (non-null).
+	// HasBar reports whether the bar field was initialized (non-null)
+	// and its pointer is valid, so Bar will not return an error.
 	func (s Foo) HasBar() bool
 
 	// SetBar sets the value of the bar field to v.
@@ -40,6 +40,21 @@ func MarshalList(typeID uint64, l capnp.List) (string, error) {
 	return buf.String(), nil
 }
 
+// TypeName returns the display name of the node with the given ID in the
+// default registry (e.g. "rpc.capnp:Call").  It is intended for log and
+// error messages.
+func TypeName(typeID uint64) (string, error) {
+	var nodes nodemap.Map
+	n, err := nodes.Find(typeID)
+	if err != nil {
+		return "", err
+	}
+	if !n.IsValid() {
+		return "", fmt.Errorf("cannot find type %#x", typeID)
+	}
+	return n.DisplayName()
+}
+
 // An Encoder writes the text format of Cap'n Proto messages to an output stream.
 type Encoder struct {
 	w     indentWriter
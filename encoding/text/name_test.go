@@ -0,0 +1,21 @@
+package text_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2/encoding/text"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestTypeName(t *testing.T) {
+	name, err := text.TypeName(rpccapnp.Call_TypeID)
+	if err != nil {
+		t.Fatalf("TypeName(Call_TypeID): %v", err)
+	}
+	if want := "rpc.capnp:Call"; name != want {
+		t.Errorf("TypeName(Call_TypeID) = %q; want %q", name, want)
+	}
+	if _, err := text.TypeName(0xdeadbeef); err == nil {
+		t.Error("TypeName(0xdeadbeef) succeeded; want error")
+	}
+}
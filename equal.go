@@ -0,0 +1,153 @@
+package capnp
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// Equal reports whether p1 and p2 point to objects with the same
+// content.  Structs are equal if their data sections are equal and
+// their pointers point to equal objects, where fields missing from the
+// smaller of two structs are treated as zero or null.  Lists are equal
+// if they have the same length and element type and equal elements.
+// Interfaces are equal if they refer to the same Client.
+//
+// Lists of primitive values are compared in bulk, so comparing large
+// numeric lists is about as fast as comparing two byte slices.
+func Equal(p1, p2 Ptr) (bool, error) {
+	eq, err := equalPtr(p1, p2)
+	if err != nil {
+		return false, fmt.Errorf("capnp: equal: %v", err)
+	}
+	return eq, nil
+}
+
+func equalPtr(p1, p2 Ptr) (bool, error) {
+	if !p1.IsValid() || !p2.IsValid() {
+		return p1.IsValid() == p2.IsValid(), nil
+	}
+	typ := p1.flags.ptrType()
+	if typ != p2.flags.ptrType() {
+		return false, nil
+	}
+	switch typ {
+	case structPtrType:
+		return equalStruct(p1.Struct(), p2.Struct())
+	case listPtrType:
+		return equalList(p1.List(), p2.List())
+	case interfacePtrType:
+		return equalInterface(p1.Interface(), p2.Interface()), nil
+	default:
+		panic("unreachable")
+	}
+}
+
+func equalStruct(s1, s2 Struct) (bool, error) {
+	d1 := s1.seg.slice(s1.off, s1.size.DataSize)
+	d2 := s2.seg.slice(s2.off, s2.size.DataSize)
+	if len(d1) > len(d2) {
+		d1, d2 = d2, d1
+	}
+	if !bytes.Equal(d1, d2[:len(d1)]) || !isZero(d2[len(d1):]) {
+		return false, nil
+	}
+	n := s1.size.PointerCount
+	if s2.size.PointerCount > n {
+		n = s2.size.PointerCount
+	}
+	for i := uint16(0); i < n; i++ {
+		// Struct.Ptr returns a null pointer past the pointer section.
+		p1, err := s1.Ptr(i)
+		if err != nil {
+			return false, fmt.Errorf("pointer %d: %v", i, err)
+		}
+		p2, err := s2.Ptr(i)
+		if err != nil {
+			return false, fmt.Errorf("pointer %d: %v", i, err)
+		}
+		if eq, err := equalPtr(p1, p2); !eq || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func equalList(l1, l2 List) (bool, error) {
+	if l1.length != l2.length {
+		return false, nil
+	}
+	bit1, bit2 := l1.flags&isBitList != 0, l2.flags&isBitList != 0
+	comp1, comp2 := l1.flags&isCompositeList != 0, l2.flags&isCompositeList != 0
+	switch {
+	case bit1 || bit2:
+		if bit1 != bit2 {
+			return false, nil
+		}
+		b1, b2 := BitList{l1}, BitList{l2}
+		for i := 0; i < l1.Len(); i++ {
+			if b1.At(i) != b2.At(i) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case comp1 || comp2:
+		if comp1 != comp2 {
+			return false, nil
+		}
+		for i := 0; i < l1.Len(); i++ {
+			if eq, err := equalStruct(l1.Struct(i), l2.Struct(i)); !eq || err != nil {
+				if err != nil {
+					err = fmt.Errorf("element %d: %v", i, err)
+				}
+				return false, err
+			}
+		}
+		return true, nil
+	case l1.size != l2.size:
+		return false, nil
+	case l1.size.PointerCount == 0:
+		// Primitive list: compare the elements in bulk.
+		sz := l1.allocSize()
+		return bytes.Equal(l1.seg.slice(l1.off, sz), l2.seg.slice(l2.off, sz)), nil
+	default:
+		pl1, pl2 := PointerList{l1}, PointerList{l2}
+		for i := 0; i < l1.Len(); i++ {
+			p1, err := pl1.PtrAt(i)
+			if err != nil {
+				return false, fmt.Errorf("element %d: %v", i, err)
+			}
+			p2, err := pl2.PtrAt(i)
+			if err != nil {
+				return false, fmt.Errorf("element %d: %v", i, err)
+			}
+			if eq, err := equalPtr(p1, p2); !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+func equalInterface(i1, i2 Interface) bool {
+	if i1.seg.msg == i2.seg.msg && i1.cap == i2.cap {
+		return true
+	}
+	c1, c2 := i1.Client(), i2.Client()
+	if c1 == nil || c2 == nil {
+		return c1 == nil && c2 == nil
+	}
+	if !reflect.TypeOf(c1).Comparable() || !reflect.TypeOf(c2).Comparable() {
+		return false
+	}
+	return c1 == c2
+}
+
+func isZero(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package capnp
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newStruct := func(sz ObjectSize, v uint64, text string) Struct {
+		s, err := NewStruct(seg, sz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetUint64(0, v)
+		if text != "" {
+			if err := s.SetText(0, text); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return s
+	}
+	newInt64s := func(vals ...int64) List {
+		l, err := NewInt64List(seg, int32(len(vals)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range vals {
+			l.Set(i, v)
+		}
+		return l.List
+	}
+	newBits := func(vals ...bool) List {
+		l, err := NewBitList(seg, int32(len(vals)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range vals {
+			l.Set(i, v)
+		}
+		return l.List
+	}
+	newComposite := func(vals ...uint64) List {
+		l, err := NewCompositeList(seg, ObjectSize{DataSize: 8}, int32(len(vals)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range vals {
+			l.Struct(i).SetUint64(0, v)
+		}
+		return l
+	}
+	small := ObjectSize{DataSize: 8, PointerCount: 1}
+	big := ObjectSize{DataSize: 16, PointerCount: 2}
+	tests := []struct {
+		name   string
+		p1, p2 Ptr
+		equal  bool
+	}{
+		{"null", Ptr{}, Ptr{}, true},
+		{"null and struct", Ptr{}, newStruct(small, 1, "").ToPtr(), false},
+		{"struct", newStruct(small, 1, "a").ToPtr(), newStruct(small, 1, "a").ToPtr(), true},
+		{"struct data differs", newStruct(small, 1, "a").ToPtr(), newStruct(small, 2, "a").ToPtr(), false},
+		{"struct text differs", newStruct(small, 1, "a").ToPtr(), newStruct(small, 1, "b").ToPtr(), false},
+		{"struct sizes differ", newStruct(small, 1, "a").ToPtr(), newStruct(big, 1, "a").ToPtr(), true},
+		{"struct and list", newStruct(small, 0, "").ToPtr(), newInt64s(0).ToPtr(), false},
+		{"int64s", newInt64s(1, 2, 3).ToPtr(), newInt64s(1, 2, 3).ToPtr(), true},
+		{"int64s differ", newInt64s(1, 2, 3).ToPtr(), newInt64s(1, 2, 4).ToPtr(), false},
+		{"int64s lengths differ", newInt64s(1, 2).ToPtr(), newInt64s(1, 2, 3).ToPtr(), false},
+		{"bits", newBits(true, false, true).ToPtr(), newBits(true, false, true).ToPtr(), true},
+		{"bits differ", newBits(true, false, true).ToPtr(), newBits(true, true, true).ToPtr(), false},
+		{"composite", newComposite(1, 2).ToPtr(), newComposite(1, 2).ToPtr(), true},
+		{"composite differs", newComposite(1, 2).ToPtr(), newComposite(1, 3).ToPtr(), false},
+		{"composite and int64s", newComposite(1).ToPtr(), newInt64s(1).ToPtr(), false},
+	}
+	for _, test := range tests {
+		eq, err := Equal(test.p1, test.p2)
+		if err != nil {
+			t.Errorf("%s: Equal: %v", test.name, err)
+			continue
+		}
+		if eq != test.equal {
+			t.Errorf("%s: Equal = %t; want %t", test.name, eq, test.equal)
+		}
+		if eq, _ := Equal(test.p2, test.p1); eq != test.equal {
+			t.Errorf("%s: Equal (swapped) = %t; want %t", test.name, eq, test.equal)
+		}
+	}
+}
+
+func BenchmarkEqualInt64List(b *testing.B) {
+	const n = 1 << 16
+	newList := func() Int64List {
+		_, seg, err := NewMessage(SingleSegment(nil))
+		if err != nil {
+			b.Fatal(err)
+		}
+		l, err := NewInt64List(seg, n)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			l.Set(i, int64(i))
+		}
+		return l
+	}
+	l1, l2 := newList(), newList()
+	b.Run("Equal", func(b *testing.B) {
+		b.SetBytes(n * 8)
+		for i := 0; i < b.N; i++ {
+			if eq, err := Equal(l1.ToPtr(), l2.ToPtr()); !eq || err != nil {
+				b.Fatalf("Equal = %t, %v", eq, err)
+			}
+		}
+	})
+	b.Run("Elements", func(b *testing.B) {
+		b.SetBytes(n * 8)
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < n; j++ {
+				if l1.At(j) != l2.At(j) {
+					b.Fatalf("element %d differs", j)
+				}
+			}
+		}
+	})
+}
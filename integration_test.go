@@ -2,11 +2,14 @@ package capnp_test
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 	"unsafe"
@@ -14,6 +17,8 @@ import (
 	"zombiezen.com/go/capnproto2"
 	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
 	"zombiezen.com/go/capnproto2/internal/capnptool"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+	rpctwoparty "zombiezen.com/go/capnproto2/std/capnp/rpctwoparty"
 )
 
 // A marshalTest tests whether a message can be encoded then read by the
@@ -986,6 +991,55 @@ func TestInterfaceSetNull(t *testing.T) {
 	}
 }
 
+func TestInterfaceReadField(t *testing.T) {
+	t.Parallel()
+	cl := capnp.ErrorClient(errors.New("foo"))
+	msg, s, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.AddCap(nil) // ensure the field's index is not zero
+	base, err := air.NewRootEchoBase(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := base.SetEcho(air.Echo{Client: cl}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+
+	// The capability table travels out of band, as it would over RPC.
+	msg2, err := capnp.Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	msg2.CapTable = msg.CapTable
+	base2, err := air.ReadRootEchoBase(msg2)
+	if err != nil {
+		t.Fatal("ReadRootEchoBase:", err)
+	}
+	p, err := base2.Struct.Ptr(0)
+	if err != nil {
+		t.Fatal("base.Ptr(0):", err)
+	}
+	iface := p.Interface()
+	if !iface.IsValid() {
+		t.Fatal("base.Ptr(0).Interface() is not valid")
+	}
+	if id := iface.Capability(); id != 1 {
+		t.Errorf("base.Ptr(0).Interface().Capability() = %d; want 1", id)
+	}
+	if c := iface.Client(); c != cl {
+		t.Errorf("base.Ptr(0).Interface().Client() = %#v; want %#v", c, cl)
+	}
+	if e := base2.Echo(); e.Client != cl {
+		t.Errorf("base.Echo() = %#v; want %#v", e.Client, cl)
+	}
+}
+
 func TestInterfaceCopyToOtherMessage(t *testing.T) {
 	t.Parallel()
 	cl := air.Echo{Client: capnp.ErrorClient(errors.New("foo"))}
@@ -1195,6 +1249,81 @@ func TestReadNestedListOfStructWithList(t *testing.T) {
 	check(1, 1, "qubert", "rocks")
 }
 
+func TestConcurrentReads(t *testing.T) {
+	t.Parallel()
+	// Small segments force far pointers, so readers also race on
+	// looking up segments.
+	msg, seg, err := capnp.NewMessage(capnp.MultiSegment(nil, capnp.MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := air.NewRootPlaneBase(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.SetName("The Spirit of St. Louis"); err != nil {
+		t.Fatal(err)
+	}
+	homes, err := pb.NewHomes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	homes.Set(0, air.Airport_jfk)
+	homes.Set(1, air.Airport_lax)
+	homes.Set(2, air.Airport_sfo)
+	pb.SetRating(100)
+	pb.SetCanFly(true)
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg, err = capnp.Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	if msg.NumSegments() < 2 {
+		t.Fatalf("message has %d segment(s); want several", msg.NumSegments())
+	}
+
+	const (
+		readers = 16
+		reads   = 100
+	)
+	errs := make(chan error, readers)
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < reads; j++ {
+				pb, err := air.ReadRootPlaneBase(msg)
+				if err != nil {
+					errs <- fmt.Errorf("ReadRootPlaneBase: %v", err)
+					return
+				}
+				if name, err := pb.Name(); err != nil || name != "The Spirit of St. Louis" {
+					errs <- fmt.Errorf("PlaneBase.name = %q, %v; want \"The Spirit of St. Louis\", <nil>", name, err)
+					return
+				}
+				homes, err := pb.Homes()
+				if err != nil || homes.Len() != 3 || homes.At(2) != air.Airport_sfo {
+					errs <- fmt.Errorf("PlaneBase.homes = %v, %v; want [jfk, lax, sfo], <nil>", homes, err)
+					return
+				}
+				if pb.Rating() != 100 || !pb.CanFly() {
+					errs <- fmt.Errorf("PlaneBase.rating, canFly = %d, %t; want 100, true", pb.Rating(), pb.CanFly())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
 func TestDataVersioningAvoidsUnnecessaryTruncation(t *testing.T) {
 	t.Parallel()
 	in := mustEncodeTestMessage(t, "VerTwoDataTwoPtr", "(val = 9, duo = 8, ptr1 = (val = 77), ptr2 = (val = 55))", []byte{
@@ -1999,6 +2128,70 @@ func BenchmarkDecode_Reuse(b *testing.B) {
 	}
 }
 
+// BenchmarkDecodeReadOnly_Copy and BenchmarkDecodeReadOnly_Borrowed
+// compare decoding into a new buffer per message, which the caller may
+// retain, with borrowing the decoder's buffer, for a workload that only
+// reads each message.
+func BenchmarkDecodeReadOnly_Copy(b *testing.B) {
+	benchmarkDecodeReadOnly(b, false)
+}
+
+func BenchmarkDecodeReadOnly_Borrowed(b *testing.B) {
+	benchmarkDecodeReadOnly(b, true)
+}
+
+func benchmarkDecodeReadOnly(b *testing.B, borrow bool) {
+	var buf bytes.Buffer
+	r := rand.New(rand.NewSource(12345))
+	enc := capnp.NewEncoder(&buf)
+	for i := 0; i < 10000; i++ {
+		a := generateA(r)
+		msg, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+		root, _ := air.NewRootBenchmarkA(seg)
+		a.fill(root)
+		enc.Encode(msg)
+	}
+	blob := buf.Bytes()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(blob)))
+	b.ResetTimer()
+	var sum int64
+	for i := 0; i < b.N; i++ {
+		dec := capnp.NewDecoder(bytes.NewReader(blob))
+		for {
+			var (
+				msg     *capnp.Message
+				release func()
+				err     error
+			)
+			if borrow {
+				msg, release, err = dec.DecodeBorrowed()
+			} else {
+				msg, err = dec.Decode()
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			a, err := air.ReadRootBenchmarkA(msg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			name, _ := a.Name()
+			sum += int64(len(name)) + int64(a.Siblings())
+			if release != nil {
+				release()
+			}
+		}
+	}
+	if sum == 0 {
+		b.Fatal("read nothing")
+	}
+}
+
 type testArena []byte
 
 func (ta testArena) NumSegments() int64 {
@@ -2164,6 +2357,196 @@ func TestHasPointerInUnion(t *testing.T) {
 	}
 }
 
+func TestStructListAtChecked(t *testing.T) {
+	t.Parallel()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa, err := rpccapnp.NewRootPromisedAnswer(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := pa.NewTransform(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops.At(1).SetGetPointerField(3)
+	if op, err := ops.AtChecked(1); err != nil {
+		t.Errorf("ops.AtChecked(1): %v", err)
+	} else if op.Which() != rpccapnp.PromisedAnswer_Op_Which_getPointerField || op.GetPointerField() != 3 {
+		t.Errorf("ops.AtChecked(1) = %v; want (getPointerField = 3)", op)
+	}
+	if _, err := ops.AtChecked(2); err == nil {
+		t.Error("ops.AtChecked(2) on list of length 2 = <nil>; want error")
+	}
+
+	// A bit list where the schema calls for a struct list: At silently
+	// yields null structs, but AtChecked reports the problem.
+	bits, err := capnp.NewBitList(seg, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pa.Struct.SetPtr(0, bits.List.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	bad, err := pa.Transform()
+	if err != nil {
+		t.Fatal("pa.Transform():", err)
+	}
+	if op := bad.At(0); op.IsValid() {
+		t.Errorf("bad.At(0) = %v; want null struct", op)
+	}
+	if _, err := bad.AtChecked(0); err == nil {
+		t.Error("bad.AtChecked(0) on bit list = <nil>; want error")
+	}
+}
+
+func TestHasStructField(t *testing.T) {
+	t.Parallel()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	unset, err := rpccapnp.NewRootCall(seg)
+	if err != nil {
+		t.Fatal("NewRootCall:", err)
+	}
+	_, seg, err = capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	setEmpty, err := rpccapnp.NewRootCall(seg)
+	if err != nil {
+		t.Fatal("NewRootCall:", err)
+	}
+	if _, err := setEmpty.NewTarget(); err != nil {
+		t.Fatal("NewTarget:", err)
+	}
+	// Call with a target pointer far past the end of the segment.
+	msg, err := capnp.Unmarshal([]byte{
+		0, 0, 0, 0, 7, 0, 0, 0,
+		0, 0, 0, 0, 3, 0, 3, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0xa0, 0x0f, 0, 0, 1, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	})
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	corrupt, err := rpccapnp.ReadRootCall(msg)
+	if err != nil {
+		t.Fatal("ReadRootCall:", err)
+	}
+
+	tests := []struct {
+		name    string
+		call    rpccapnp.Call
+		has     bool
+		wantErr bool
+	}{
+		{"unset", unset, false, false},
+		{"set to empty struct", setEmpty, true, false},
+		{"corrupt", corrupt, false, true},
+	}
+	for _, test := range tests {
+		if has := test.call.HasTarget(); has != test.has {
+			t.Errorf("%s: HasTarget() = %t; want %t", test.name, has, test.has)
+		}
+		if _, err := test.call.Target(); (err != nil) != test.wantErr {
+			t.Errorf("%s: Target() error = %v; want error = %t", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestWalkPointers_RedactText(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := rpccapnp.NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := m.NewReturn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret.SetAnswerId(7)
+	exc, err := ret.NewException()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exc.SetReason("password=hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	exc.SetType(rpccapnp.Exception_Type_failed)
+	orig, err := m.Segment().Message().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Redact a copy, leaving the original alone.
+	cpy, err := capnp.Unmarshal(append([]byte(nil), orig...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := cpy.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visited int
+	err = capnp.WalkPointers(root.Struct(), func(p capnp.Ptr) error {
+		visited++
+		b := p.TextBytes()
+		for i := range b {
+			b[i] = '*'
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("WalkPointers:", err)
+	}
+	// Return struct, Exception struct, reason text.
+	if visited != 3 {
+		t.Errorf("visited %d pointers; want 3", visited)
+	}
+
+	rm, err := rpccapnp.ReadRootMessage(cpy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rret, err := rm.Return()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rexc, err := rret.Exception()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason, err := rexc.Reason(); err != nil {
+		t.Error("redacted Reason:", err)
+	} else if want := "****************"; reason != want {
+		t.Errorf("redacted Reason() = %q; want %q", reason, want)
+	}
+	if rret.AnswerId() != 7 || rexc.Type() != rpccapnp.Exception_Type_failed {
+		t.Errorf("redaction changed non-text fields: answerId=%d type=%v", rret.AnswerId(), rexc.Type())
+	}
+
+	if reason, err := exc.Reason(); err != nil || reason != "password=hunter2" {
+		t.Errorf("original Reason() = %q, %v; want \"password=hunter2\", <nil>", reason, err)
+	}
+
+	stop := errors.New("stop")
+	err = capnp.WalkPointers(root.Struct(), func(capnp.Ptr) error { return stop })
+	if err != stop {
+		t.Errorf("WalkPointers with failing visit = %v; want %v", err, stop)
+	}
+}
+
 func TestSetNilBlob(t *testing.T) {
 	t.Parallel()
 	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
@@ -2394,3 +2777,301 @@ func BenchmarkSmallMessage_SingleSegment(b *testing.B) {
 func BenchmarkSmallMessage_MultiSegment(b *testing.B) {
 	benchmarkSmallMessage(b, func() capnp.Arena { return capnp.MultiSegment(nil) })
 }
+
+func BenchmarkNestedFieldAccess(b *testing.B) {
+	const accesses = 1000
+	msg, seg, err := capnp.NewMessage(capnp.MultiSegment(nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	root, err := air.NewRootZ(seg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	nested, err := air.NewZ(seg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	nested.SetF64(3.5)
+	if err := root.SetZz(nested); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			msg.ReadLimiter().Reset(1 << 62)
+			for j := 0; j < accesses; j++ {
+				zz, err := root.Zz()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if zz.F64() != 3.5 {
+					b.Fatal("wrong value")
+				}
+			}
+		}
+	})
+	b.Run("Cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			msg.ReadLimiter().Reset(1 << 62)
+			zz, err := root.Zz()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for j := 0; j < accesses; j++ {
+				if zz.F64() != 3.5 {
+					b.Fatal("wrong value")
+				}
+			}
+		}
+	})
+}
+
+// newAccessorPlaneBase returns a PlaneBase reached through a Z, for
+// measuring generated accessors.
+func newAccessorPlaneBase(tb testing.TB) (*capnp.Message, air.Z) {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	root, err := air.NewRootZ(seg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	pb, err := root.NewPlanebase()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := pb.SetName("Boeing 747"); err != nil {
+		tb.Fatal(err)
+	}
+	homes, err := pb.NewHomes(2)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	homes.Set(0, air.Airport_jfk)
+	homes.Set(1, air.Airport_lax)
+	pb.SetRating(100)
+	pb.SetCanFly(true)
+	return msg, root
+}
+
+// readPlaneBase reads every field of the PlaneBase in root without
+// converting text to a string.
+func readPlaneBase(root air.Z) error {
+	if !root.HasPlanebase() {
+		return errors.New("no planebase")
+	}
+	pb, err := root.Planebase()
+	if err != nil {
+		return err
+	}
+	name, err := pb.NameBytes()
+	if err != nil {
+		return err
+	}
+	homes, err := pb.Homes()
+	if err != nil {
+		return err
+	}
+	if len(name) != 10 || homes.Len() != 2 || homes.At(1) != air.Airport_lax || pb.Rating() != 100 || !pb.CanFly() {
+		return errors.New("wrong values")
+	}
+	return nil
+}
+
+// TestGeneratedAccessorsDontAllocate guards against generated accessors
+// allocating.  Structs and lists are returned by value, but they are
+// small (a segment pointer, an address, a size, and some flags), and
+// building with -gcflags=-m shows that none of the accessors used here
+// move their results to the heap: the only escapes reported in
+// generated code are the constant strings passed to panic by union
+// accessors and the string conversion done by text accessors like
+// Name, which is why this test reads text with NameBytes.  Returning
+// pointers instead would force a heap allocation per call.
+func TestGeneratedAccessorsDontAllocate(t *testing.T) {
+	msg, root := newAccessorPlaneBase(t)
+	var err error
+	allocs := testing.AllocsPerRun(100, func() {
+		msg.ReadLimiter().Reset(1 << 62)
+		err = readPlaneBase(root)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allocs > 0 {
+		t.Errorf("reading PlaneBase through generated accessors made %.1f allocations; want 0", allocs)
+	}
+}
+
+func BenchmarkGeneratedAccessors(b *testing.B) {
+	msg, root := newAccessorPlaneBase(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg.ReadLimiter().Reset(1 << 62)
+		if err := readPlaneBase(root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAnyPointerStruct(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provide, err := rpccapnp.NewRootProvide(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := provide.RecipientStruct(capnp.ObjectSize{DataSize: 8}); err != nil {
+		t.Errorf("null RecipientStruct: %v", err)
+	} else if r.IsValid() {
+		t.Error("null RecipientStruct returned a valid struct")
+	}
+
+	// Use a VatId as the application's recipient type.
+	vat, err := rpctwoparty.NewVatId(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vat.SetSide(rpctwoparty.Side_client)
+	if err := provide.SetRecipientPtr(vat.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := provide.RecipientStruct(capnp.ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal("RecipientStruct:", err)
+	}
+	if side := (rpctwoparty.VatId{Struct: r}).Side(); side != rpctwoparty.Side_client {
+		t.Errorf("recipient side = %v; want %v", side, rpctwoparty.Side_client)
+	}
+	if _, err := provide.RecipientStruct(capnp.ObjectSize{DataSize: 16}); err == nil {
+		t.Error("RecipientStruct with larger expected size succeeded")
+	}
+
+	text, err := capnp.NewText(seg, "not a struct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := provide.SetRecipientPtr(text.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provide.RecipientStruct(capnp.ObjectSize{}); err == nil {
+		t.Error("RecipientStruct on text succeeded")
+	}
+}
+
+func TestStructDataBytes(t *testing.T) {
+	t.Parallel()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := capnp.NewRootStruct(seg, capnp.ObjectSize{DataSize: 16, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetUint64(0, 0x0102030405060708)
+	s.SetUint32(8, 42)
+	data := s.DataBytes()
+	if len(data) != 16 || cap(data) != 16 {
+		t.Fatalf("len, cap of DataBytes() = %d, %d; want 16, 16", len(data), cap(data))
+	}
+	if got := binary.LittleEndian.Uint64(data); got != 0x0102030405060708 {
+		t.Errorf("DataBytes()[0:8] = %#x; want 0x0102030405060708", got)
+	}
+	if got := binary.LittleEndian.Uint32(data[8:]); got != 42 {
+		t.Errorf("DataBytes()[8:12] = %d; want 42", got)
+	}
+	binary.LittleEndian.PutUint32(data[12:], 7)
+	if got := s.Uint32(12); got != 7 {
+		t.Errorf("after writing DataBytes, Uint32(12) = %d; want 7", got)
+	}
+	if data := (capnp.Struct{}).DataBytes(); data != nil {
+		t.Errorf("Struct{}.DataBytes() = %v; want nil", data)
+	}
+}
+
+func BenchmarkStructDataBytes(b *testing.B) {
+	const nfields = 8
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	s, err := capnp.NewRootStruct(seg, capnp.ObjectSize{DataSize: nfields * 8})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var want uint64
+	for i := 0; i < nfields; i++ {
+		s.SetUint64(capnp.DataOffset(i*8), uint64(i))
+		want += uint64(i)
+	}
+
+	b.Run("Accessors", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var sum uint64
+			for j := 0; j < nfields; j++ {
+				sum += s.Uint64(capnp.DataOffset(j * 8))
+			}
+			if sum != want {
+				b.Fatal("wrong sum")
+			}
+		}
+	})
+	b.Run("DataBytes", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			data := s.DataBytes()
+			var sum uint64
+			for j := 0; j < nfields; j++ {
+				sum += binary.LittleEndian.Uint64(data[j*8:])
+			}
+			if sum != want {
+				b.Fatal("wrong sum")
+			}
+		}
+	})
+}
+
+func TestCapDescriptorListRange(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := rpccapnp.NewRootPayload(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps, err := payload.NewCapTable(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < caps.Len(); i++ {
+		caps.At(i).SetSenderHosted(uint32(i * 10))
+	}
+
+	var seen []uint32
+	caps.Range(func(i int, d rpccapnp.CapDescriptor) bool {
+		if d.Which() != rpccapnp.CapDescriptor_Which_senderHosted {
+			t.Errorf("caps[%d].Which() = %v; want senderHosted", i, d.Which())
+		}
+		seen = append(seen, d.SenderHosted())
+		return i < 2
+	})
+	if want := []uint32{0, 10, 20}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("Range visited %v; want %v", seen, want)
+	}
+
+	s := caps.ToSlice()
+	if len(s) != caps.Len() {
+		t.Fatalf("len(ToSlice()) = %d; want %d", len(s), caps.Len())
+	}
+	for i, d := range s {
+		if id := d.SenderHosted(); id != uint32(i*10) {
+			t.Errorf("ToSlice()[%d].SenderHosted() = %d; want %d", i, id, i*10)
+		}
+	}
+}
@@ -89,8 +89,33 @@ func NewZdate_List(s *capnp.Segment, sz int32) (Zdate_List, error) {
 
 func (s Zdate_List) At(i int) Zdate { return Zdate{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Zdate_List) AtChecked(i int) (Zdate, error) {
+	p, err := s.List.StructChecked(i)
+	return Zdate{p}, err
+}
+
 func (s Zdate_List) Set(i int, v Zdate) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Zdate_List) ToSlice() []Zdate {
+	v := make([]Zdate, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Zdate_List) Range(f func(i int, v Zdate) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Zdate_List) String() string {
 	str, _ := text.MarshalList(0xde50aebbad57549d, s.List)
 	return str
@@ -136,7 +161,7 @@ func (s Zdata) Data() ([]byte, error) {
 
 func (s Zdata) HasData() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Zdata) SetData(v []byte) error {
@@ -154,8 +179,33 @@ func NewZdata_List(s *capnp.Segment, sz int32) (Zdata_List, error) {
 
 func (s Zdata_List) At(i int) Zdata { return Zdata{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Zdata_List) AtChecked(i int) (Zdata, error) {
+	p, err := s.List.StructChecked(i)
+	return Zdata{p}, err
+}
+
 func (s Zdata_List) Set(i int, v Zdata) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Zdata_List) ToSlice() []Zdata {
+	v := make([]Zdata, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Zdata_List) Range(f func(i int, v Zdata) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Zdata_List) String() string {
 	str, _ := text.MarshalList(0xc7da65f9a2f20ba2, s.List)
 	return str
@@ -281,7 +331,7 @@ func (s PlaneBase) Name() (string, error) {
 
 func (s PlaneBase) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s PlaneBase) NameBytes() ([]byte, error) {
@@ -300,7 +350,7 @@ func (s PlaneBase) Homes() (Airport_List, error) {
 
 func (s PlaneBase) HasHomes() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s PlaneBase) SetHomes(v Airport_List) error {
@@ -361,8 +411,33 @@ func NewPlaneBase_List(s *capnp.Segment, sz int32) (PlaneBase_List, error) {
 
 func (s PlaneBase_List) At(i int) PlaneBase { return PlaneBase{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s PlaneBase_List) AtChecked(i int) (PlaneBase, error) {
+	p, err := s.List.StructChecked(i)
+	return PlaneBase{p}, err
+}
+
 func (s PlaneBase_List) Set(i int, v PlaneBase) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s PlaneBase_List) ToSlice() []PlaneBase {
+	v := make([]PlaneBase, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s PlaneBase_List) Range(f func(i int, v PlaneBase) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s PlaneBase_List) String() string {
 	str, _ := text.MarshalList(0xd8bccf6e60a73791, s.List)
 	return str
@@ -408,7 +483,7 @@ func (s B737) Base() (PlaneBase, error) {
 
 func (s B737) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s B737) SetBase(v PlaneBase) error {
@@ -437,8 +512,33 @@ func NewB737_List(s *capnp.Segment, sz int32) (B737_List, error) {
 
 func (s B737_List) At(i int) B737 { return B737{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s B737_List) AtChecked(i int) (B737, error) {
+	p, err := s.List.StructChecked(i)
+	return B737{p}, err
+}
+
 func (s B737_List) Set(i int, v B737) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s B737_List) ToSlice() []B737 {
+	v := make([]B737, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s B737_List) Range(f func(i int, v B737) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s B737_List) String() string {
 	str, _ := text.MarshalList(0xccb3b2e3603826e0, s.List)
 	return str
@@ -488,7 +588,7 @@ func (s A320) Base() (PlaneBase, error) {
 
 func (s A320) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s A320) SetBase(v PlaneBase) error {
@@ -517,8 +617,33 @@ func NewA320_List(s *capnp.Segment, sz int32) (A320_List, error) {
 
 func (s A320_List) At(i int) A320 { return A320{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s A320_List) AtChecked(i int) (A320, error) {
+	p, err := s.List.StructChecked(i)
+	return A320{p}, err
+}
+
 func (s A320_List) Set(i int, v A320) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s A320_List) ToSlice() []A320 {
+	v := make([]A320, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s A320_List) Range(f func(i int, v A320) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s A320_List) String() string {
 	str, _ := text.MarshalList(0xd98c608877d9cb8d, s.List)
 	return str
@@ -568,7 +693,7 @@ func (s F16) Base() (PlaneBase, error) {
 
 func (s F16) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s F16) SetBase(v PlaneBase) error {
@@ -597,8 +722,33 @@ func NewF16_List(s *capnp.Segment, sz int32) (F16_List, error) {
 
 func (s F16_List) At(i int) F16 { return F16{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s F16_List) AtChecked(i int) (F16, error) {
+	p, err := s.List.StructChecked(i)
+	return F16{p}, err
+}
+
 func (s F16_List) Set(i int, v F16) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s F16_List) ToSlice() []F16 {
+	v := make([]F16, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s F16_List) Range(f func(i int, v F16) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s F16_List) String() string {
 	str, _ := text.MarshalList(0xe1c9eac512335361, s.List)
 	return str
@@ -648,7 +798,7 @@ func (s Regression) Base() (PlaneBase, error) {
 
 func (s Regression) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Regression) SetBase(v PlaneBase) error {
@@ -681,7 +831,7 @@ func (s Regression) Beta() (capnp.Float64List, error) {
 
 func (s Regression) HasBeta() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Regression) SetBeta(v capnp.Float64List) error {
@@ -706,7 +856,7 @@ func (s Regression) Planes() (Aircraft_List, error) {
 
 func (s Regression) HasPlanes() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Regression) SetPlanes(v Aircraft_List) error {
@@ -751,8 +901,33 @@ func NewRegression_List(s *capnp.Segment, sz int32) (Regression_List, error) {
 
 func (s Regression_List) At(i int) Regression { return Regression{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Regression_List) AtChecked(i int) (Regression, error) {
+	p, err := s.List.StructChecked(i)
+	return Regression{p}, err
+}
+
 func (s Regression_List) Set(i int, v Regression) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Regression_List) ToSlice() []Regression {
+	v := make([]Regression, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Regression_List) Range(f func(i int, v Regression) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Regression_List) String() string {
 	str, _ := text.MarshalList(0xb1f0385d845e367f, s.List)
 	return str
@@ -840,7 +1015,7 @@ func (s Aircraft) HasB737() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Aircraft) SetB737(v B737) error {
@@ -873,7 +1048,7 @@ func (s Aircraft) HasA320() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Aircraft) SetA320(v A320) error {
@@ -906,7 +1081,7 @@ func (s Aircraft) HasF16() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Aircraft) SetF16(v F16) error {
@@ -937,8 +1112,33 @@ func NewAircraft_List(s *capnp.Segment, sz int32) (Aircraft_List, error) {
 
 func (s Aircraft_List) At(i int) Aircraft { return Aircraft{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Aircraft_List) AtChecked(i int) (Aircraft, error) {
+	p, err := s.List.StructChecked(i)
+	return Aircraft{p}, err
+}
+
 func (s Aircraft_List) Set(i int, v Aircraft) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Aircraft_List) ToSlice() []Aircraft {
+	v := make([]Aircraft, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Aircraft_List) Range(f func(i int, v Aircraft) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Aircraft_List) String() string {
 	str, _ := text.MarshalList(0xe54e10aede55c7b1, s.List)
 	return str
@@ -1158,7 +1358,7 @@ func (s Z) HasZz() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZz(v Z) error {
@@ -1323,7 +1523,7 @@ func (s Z) HasText() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) TextBytes() ([]byte, error) {
@@ -1349,7 +1549,7 @@ func (s Z) HasBlob() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetBlob(v []byte) error {
@@ -1370,7 +1570,7 @@ func (s Z) HasF64vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetF64vec(v capnp.Float64List) error {
@@ -1403,7 +1603,7 @@ func (s Z) HasF32vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetF32vec(v capnp.Float32List) error {
@@ -1436,7 +1636,7 @@ func (s Z) HasI64vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetI64vec(v capnp.Int64List) error {
@@ -1469,7 +1669,7 @@ func (s Z) HasI32vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetI32vec(v capnp.Int32List) error {
@@ -1502,7 +1702,7 @@ func (s Z) HasI16vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetI16vec(v capnp.Int16List) error {
@@ -1535,7 +1735,7 @@ func (s Z) HasI8vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetI8vec(v capnp.Int8List) error {
@@ -1568,7 +1768,7 @@ func (s Z) HasU64vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetU64vec(v capnp.UInt64List) error {
@@ -1601,7 +1801,7 @@ func (s Z) HasU32vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetU32vec(v capnp.UInt32List) error {
@@ -1634,7 +1834,7 @@ func (s Z) HasU16vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetU16vec(v capnp.UInt16List) error {
@@ -1667,7 +1867,7 @@ func (s Z) HasU8vec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetU8vec(v capnp.UInt8List) error {
@@ -1700,7 +1900,7 @@ func (s Z) HasBoolvec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetBoolvec(v capnp.BitList) error {
@@ -1733,7 +1933,7 @@ func (s Z) HasDatavec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetDatavec(v capnp.DataList) error {
@@ -1766,7 +1966,7 @@ func (s Z) HasTextvec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetTextvec(v capnp.TextList) error {
@@ -1799,7 +1999,7 @@ func (s Z) HasZvec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZvec(v Z_List) error {
@@ -1832,7 +2032,7 @@ func (s Z) HasZvecvec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZvecvec(v capnp.PointerList) error {
@@ -1865,7 +2065,7 @@ func (s Z) HasZdate() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZdate(v Zdate) error {
@@ -1898,7 +2098,7 @@ func (s Z) HasZdata() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZdata(v Zdata) error {
@@ -1931,7 +2131,7 @@ func (s Z) HasAircraftvec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetAircraftvec(v Aircraft_List) error {
@@ -1964,7 +2164,7 @@ func (s Z) HasAircraft() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetAircraft(v Aircraft) error {
@@ -1997,7 +2197,7 @@ func (s Z) HasRegression() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetRegression(v Regression) error {
@@ -2030,7 +2230,7 @@ func (s Z) HasPlanebase() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetPlanebase(v PlaneBase) error {
@@ -2075,7 +2275,7 @@ func (s Z) HasB737() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetB737(v B737) error {
@@ -2108,7 +2308,7 @@ func (s Z) HasA320() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetA320(v A320) error {
@@ -2141,7 +2341,7 @@ func (s Z) HasF16() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetF16(v F16) error {
@@ -2174,7 +2374,7 @@ func (s Z) HasZdatevec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZdatevec(v Zdate_List) error {
@@ -2207,7 +2407,7 @@ func (s Z) HasZdatavec() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetZdatavec(v Zdata_List) error {
@@ -2262,7 +2462,7 @@ func (s Z) HasEcho() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetEcho(v Echo) error {
@@ -2288,7 +2488,7 @@ func (s Z) HasEchoBases() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Z) SetEchoBases(v EchoBases) error {
@@ -2319,8 +2519,33 @@ func NewZ_List(s *capnp.Segment, sz int32) (Z_List, error) {
 
 func (s Z_List) At(i int) Z { return Z{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Z_List) AtChecked(i int) (Z, error) {
+	p, err := s.List.StructChecked(i)
+	return Z{p}, err
+}
+
 func (s Z_List) Set(i int, v Z) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Z_List) ToSlice() []Z {
+	v := make([]Z, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Z_List) Range(f func(i int, v Z) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Z_List) String() string {
 	str, _ := text.MarshalList(0xea26e9973bd6a0d9, s.List)
 	return str
@@ -2428,7 +2653,7 @@ func (s Counter) Words() (string, error) {
 
 func (s Counter) HasWords() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Counter) WordsBytes() ([]byte, error) {
@@ -2447,7 +2672,7 @@ func (s Counter) Wordlist() (capnp.TextList, error) {
 
 func (s Counter) HasWordlist() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Counter) SetWordlist(v capnp.TextList) error {
@@ -2472,7 +2697,7 @@ func (s Counter) Bitlist() (capnp.BitList, error) {
 
 func (s Counter) HasBitlist() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Counter) SetBitlist(v capnp.BitList) error {
@@ -2501,8 +2726,33 @@ func NewCounter_List(s *capnp.Segment, sz int32) (Counter_List, error) {
 
 func (s Counter_List) At(i int) Counter { return Counter{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Counter_List) AtChecked(i int) (Counter, error) {
+	p, err := s.List.StructChecked(i)
+	return Counter{p}, err
+}
+
 func (s Counter_List) Set(i int, v Counter) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Counter_List) ToSlice() []Counter {
+	v := make([]Counter, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Counter_List) Range(f func(i int, v Counter) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Counter_List) String() string {
 	str, _ := text.MarshalList(0x8748bc095e10cb5d, s.List)
 	return str
@@ -2548,7 +2798,7 @@ func (s Bag) Counter() (Counter, error) {
 
 func (s Bag) HasCounter() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Bag) SetCounter(v Counter) error {
@@ -2577,8 +2827,33 @@ func NewBag_List(s *capnp.Segment, sz int32) (Bag_List, error) {
 
 func (s Bag_List) At(i int) Bag { return Bag{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Bag_List) AtChecked(i int) (Bag, error) {
+	p, err := s.List.StructChecked(i)
+	return Bag{p}, err
+}
+
 func (s Bag_List) Set(i int, v Bag) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Bag_List) ToSlice() []Bag {
+	v := make([]Bag, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Bag_List) Range(f func(i int, v Bag) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Bag_List) String() string {
 	str, _ := text.MarshalList(0xd636fba4f188dabe, s.List)
 	return str
@@ -2628,7 +2903,7 @@ func (s Zserver) Waitingjobs() (Zjob_List, error) {
 
 func (s Zserver) HasWaitingjobs() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Zserver) SetWaitingjobs(v Zjob_List) error {
@@ -2657,8 +2932,33 @@ func NewZserver_List(s *capnp.Segment, sz int32) (Zserver_List, error) {
 
 func (s Zserver_List) At(i int) Zserver { return Zserver{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Zserver_List) AtChecked(i int) (Zserver, error) {
+	p, err := s.List.StructChecked(i)
+	return Zserver{p}, err
+}
+
 func (s Zserver_List) Set(i int, v Zserver) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Zserver_List) ToSlice() []Zserver {
+	v := make([]Zserver, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Zserver_List) Range(f func(i int, v Zserver) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Zserver_List) String() string {
 	str, _ := text.MarshalList(0xcc4411e60ba9c498, s.List)
 	return str
@@ -2704,7 +3004,7 @@ func (s Zjob) Cmd() (string, error) {
 
 func (s Zjob) HasCmd() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Zjob) CmdBytes() ([]byte, error) {
@@ -2723,7 +3023,7 @@ func (s Zjob) Args() (capnp.TextList, error) {
 
 func (s Zjob) HasArgs() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Zjob) SetArgs(v capnp.TextList) error {
@@ -2752,8 +3052,33 @@ func NewZjob_List(s *capnp.Segment, sz int32) (Zjob_List, error) {
 
 func (s Zjob_List) At(i int) Zjob { return Zjob{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Zjob_List) AtChecked(i int) (Zjob, error) {
+	p, err := s.List.StructChecked(i)
+	return Zjob{p}, err
+}
+
 func (s Zjob_List) Set(i int, v Zjob) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Zjob_List) ToSlice() []Zjob {
+	v := make([]Zjob, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Zjob_List) Range(f func(i int, v Zjob) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Zjob_List) String() string {
 	str, _ := text.MarshalList(0xddd1416669fb7613, s.List)
 	return str
@@ -2803,8 +3128,33 @@ func NewVerEmpty_List(s *capnp.Segment, sz int32) (VerEmpty_List, error) {
 
 func (s VerEmpty_List) At(i int) VerEmpty { return VerEmpty{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerEmpty_List) AtChecked(i int) (VerEmpty, error) {
+	p, err := s.List.StructChecked(i)
+	return VerEmpty{p}, err
+}
+
 func (s VerEmpty_List) Set(i int, v VerEmpty) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerEmpty_List) ToSlice() []VerEmpty {
+	v := make([]VerEmpty, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerEmpty_List) Range(f func(i int, v VerEmpty) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerEmpty_List) String() string {
 	str, _ := text.MarshalList(0x93c99951eacc72ff, s.List)
 	return str
@@ -2862,8 +3212,33 @@ func NewVerOneData_List(s *capnp.Segment, sz int32) (VerOneData_List, error) {
 
 func (s VerOneData_List) At(i int) VerOneData { return VerOneData{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerOneData_List) AtChecked(i int) (VerOneData, error) {
+	p, err := s.List.StructChecked(i)
+	return VerOneData{p}, err
+}
+
 func (s VerOneData_List) Set(i int, v VerOneData) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerOneData_List) ToSlice() []VerOneData {
+	v := make([]VerOneData, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerOneData_List) Range(f func(i int, v VerOneData) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerOneData_List) String() string {
 	str, _ := text.MarshalList(0xfca3742893be4cde, s.List)
 	return str
@@ -2929,8 +3304,33 @@ func NewVerTwoData_List(s *capnp.Segment, sz int32) (VerTwoData_List, error) {
 
 func (s VerTwoData_List) At(i int) VerTwoData { return VerTwoData{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerTwoData_List) AtChecked(i int) (VerTwoData, error) {
+	p, err := s.List.StructChecked(i)
+	return VerTwoData{p}, err
+}
+
 func (s VerTwoData_List) Set(i int, v VerTwoData) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerTwoData_List) ToSlice() []VerTwoData {
+	v := make([]VerTwoData, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerTwoData_List) Range(f func(i int, v VerTwoData) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerTwoData_List) String() string {
 	str, _ := text.MarshalList(0xf705dc45c94766fd, s.List)
 	return str
@@ -2976,7 +3376,7 @@ func (s VerOnePtr) Ptr() (VerOneData, error) {
 
 func (s VerOnePtr) HasPtr() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerOnePtr) SetPtr(v VerOneData) error {
@@ -3005,8 +3405,33 @@ func NewVerOnePtr_List(s *capnp.Segment, sz int32) (VerOnePtr_List, error) {
 
 func (s VerOnePtr_List) At(i int) VerOnePtr { return VerOnePtr{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerOnePtr_List) AtChecked(i int) (VerOnePtr, error) {
+	p, err := s.List.StructChecked(i)
+	return VerOnePtr{p}, err
+}
+
 func (s VerOnePtr_List) Set(i int, v VerOnePtr) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerOnePtr_List) ToSlice() []VerOnePtr {
+	v := make([]VerOnePtr, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerOnePtr_List) Range(f func(i int, v VerOnePtr) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerOnePtr_List) String() string {
 	str, _ := text.MarshalList(0x94bf7df83408218d, s.List)
 	return str
@@ -3056,7 +3481,7 @@ func (s VerTwoPtr) Ptr1() (VerOneData, error) {
 
 func (s VerTwoPtr) HasPtr1() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoPtr) SetPtr1(v VerOneData) error {
@@ -3081,7 +3506,7 @@ func (s VerTwoPtr) Ptr2() (VerOneData, error) {
 
 func (s VerTwoPtr) HasPtr2() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoPtr) SetPtr2(v VerOneData) error {
@@ -3110,8 +3535,33 @@ func NewVerTwoPtr_List(s *capnp.Segment, sz int32) (VerTwoPtr_List, error) {
 
 func (s VerTwoPtr_List) At(i int) VerTwoPtr { return VerTwoPtr{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerTwoPtr_List) AtChecked(i int) (VerTwoPtr, error) {
+	p, err := s.List.StructChecked(i)
+	return VerTwoPtr{p}, err
+}
+
 func (s VerTwoPtr_List) Set(i int, v VerTwoPtr) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerTwoPtr_List) ToSlice() []VerTwoPtr {
+	v := make([]VerTwoPtr, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerTwoPtr_List) Range(f func(i int, v VerTwoPtr) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerTwoPtr_List) String() string {
 	str, _ := text.MarshalList(0xc95babe3bd394d2d, s.List)
 	return str
@@ -3181,7 +3631,7 @@ func (s VerTwoDataTwoPtr) Ptr1() (VerOneData, error) {
 
 func (s VerTwoDataTwoPtr) HasPtr1() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoDataTwoPtr) SetPtr1(v VerOneData) error {
@@ -3206,7 +3656,7 @@ func (s VerTwoDataTwoPtr) Ptr2() (VerOneData, error) {
 
 func (s VerTwoDataTwoPtr) HasPtr2() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoDataTwoPtr) SetPtr2(v VerOneData) error {
@@ -3235,10 +3685,35 @@ func NewVerTwoDataTwoPtr_List(s *capnp.Segment, sz int32) (VerTwoDataTwoPtr_List
 
 func (s VerTwoDataTwoPtr_List) At(i int) VerTwoDataTwoPtr { return VerTwoDataTwoPtr{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerTwoDataTwoPtr_List) AtChecked(i int) (VerTwoDataTwoPtr, error) {
+	p, err := s.List.StructChecked(i)
+	return VerTwoDataTwoPtr{p}, err
+}
+
 func (s VerTwoDataTwoPtr_List) Set(i int, v VerTwoDataTwoPtr) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerTwoDataTwoPtr_List) ToSlice() []VerTwoDataTwoPtr {
+	v := make([]VerTwoDataTwoPtr, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerTwoDataTwoPtr_List) Range(f func(i int, v VerTwoDataTwoPtr) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerTwoDataTwoPtr_List) String() string {
 	str, _ := text.MarshalList(0xb61ee2ecff34ca73, s.List)
 	return str
@@ -3292,7 +3767,7 @@ func (s HoldsVerEmptyList) Mylist() (VerEmpty_List, error) {
 
 func (s HoldsVerEmptyList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerEmptyList) SetMylist(v VerEmpty_List) error {
@@ -3323,10 +3798,35 @@ func (s HoldsVerEmptyList_List) At(i int) HoldsVerEmptyList {
 	return HoldsVerEmptyList{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerEmptyList_List) AtChecked(i int) (HoldsVerEmptyList, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerEmptyList{p}, err
+}
+
 func (s HoldsVerEmptyList_List) Set(i int, v HoldsVerEmptyList) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerEmptyList_List) ToSlice() []HoldsVerEmptyList {
+	v := make([]HoldsVerEmptyList, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerEmptyList_List) Range(f func(i int, v HoldsVerEmptyList) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerEmptyList_List) String() string {
 	str, _ := text.MarshalList(0xde9ed43cfaa83093, s.List)
 	return str
@@ -3372,7 +3872,7 @@ func (s HoldsVerOneDataList) Mylist() (VerOneData_List, error) {
 
 func (s HoldsVerOneDataList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerOneDataList) SetMylist(v VerOneData_List) error {
@@ -3403,10 +3903,35 @@ func (s HoldsVerOneDataList_List) At(i int) HoldsVerOneDataList {
 	return HoldsVerOneDataList{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerOneDataList_List) AtChecked(i int) (HoldsVerOneDataList, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerOneDataList{p}, err
+}
+
 func (s HoldsVerOneDataList_List) Set(i int, v HoldsVerOneDataList) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerOneDataList_List) ToSlice() []HoldsVerOneDataList {
+	v := make([]HoldsVerOneDataList, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerOneDataList_List) Range(f func(i int, v HoldsVerOneDataList) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerOneDataList_List) String() string {
 	str, _ := text.MarshalList(0xabd055422a4d7df1, s.List)
 	return str
@@ -3452,7 +3977,7 @@ func (s HoldsVerTwoDataList) Mylist() (VerTwoData_List, error) {
 
 func (s HoldsVerTwoDataList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerTwoDataList) SetMylist(v VerTwoData_List) error {
@@ -3483,10 +4008,35 @@ func (s HoldsVerTwoDataList_List) At(i int) HoldsVerTwoDataList {
 	return HoldsVerTwoDataList{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerTwoDataList_List) AtChecked(i int) (HoldsVerTwoDataList, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerTwoDataList{p}, err
+}
+
 func (s HoldsVerTwoDataList_List) Set(i int, v HoldsVerTwoDataList) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerTwoDataList_List) ToSlice() []HoldsVerTwoDataList {
+	v := make([]HoldsVerTwoDataList, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerTwoDataList_List) Range(f func(i int, v HoldsVerTwoDataList) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerTwoDataList_List) String() string {
 	str, _ := text.MarshalList(0xcbdc765fd5dff7ba, s.List)
 	return str
@@ -3532,7 +4082,7 @@ func (s HoldsVerOnePtrList) Mylist() (VerOnePtr_List, error) {
 
 func (s HoldsVerOnePtrList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerOnePtrList) SetMylist(v VerOnePtr_List) error {
@@ -3563,10 +4113,35 @@ func (s HoldsVerOnePtrList_List) At(i int) HoldsVerOnePtrList {
 	return HoldsVerOnePtrList{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerOnePtrList_List) AtChecked(i int) (HoldsVerOnePtrList, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerOnePtrList{p}, err
+}
+
 func (s HoldsVerOnePtrList_List) Set(i int, v HoldsVerOnePtrList) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerOnePtrList_List) ToSlice() []HoldsVerOnePtrList {
+	v := make([]HoldsVerOnePtrList, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerOnePtrList_List) Range(f func(i int, v HoldsVerOnePtrList) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerOnePtrList_List) String() string {
 	str, _ := text.MarshalList(0xe508a29c83a059f8, s.List)
 	return str
@@ -3612,7 +4187,7 @@ func (s HoldsVerTwoPtrList) Mylist() (VerTwoPtr_List, error) {
 
 func (s HoldsVerTwoPtrList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerTwoPtrList) SetMylist(v VerTwoPtr_List) error {
@@ -3643,10 +4218,35 @@ func (s HoldsVerTwoPtrList_List) At(i int) HoldsVerTwoPtrList {
 	return HoldsVerTwoPtrList{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerTwoPtrList_List) AtChecked(i int) (HoldsVerTwoPtrList, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerTwoPtrList{p}, err
+}
+
 func (s HoldsVerTwoPtrList_List) Set(i int, v HoldsVerTwoPtrList) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerTwoPtrList_List) ToSlice() []HoldsVerTwoPtrList {
+	v := make([]HoldsVerTwoPtrList, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerTwoPtrList_List) Range(f func(i int, v HoldsVerTwoPtrList) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerTwoPtrList_List) String() string {
 	str, _ := text.MarshalList(0xcf9beaca1cc180c8, s.List)
 	return str
@@ -3692,7 +4292,7 @@ func (s HoldsVerTwoTwoList) Mylist() (VerTwoDataTwoPtr_List, error) {
 
 func (s HoldsVerTwoTwoList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerTwoTwoList) SetMylist(v VerTwoDataTwoPtr_List) error {
@@ -3723,10 +4323,35 @@ func (s HoldsVerTwoTwoList_List) At(i int) HoldsVerTwoTwoList {
 	return HoldsVerTwoTwoList{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerTwoTwoList_List) AtChecked(i int) (HoldsVerTwoTwoList, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerTwoTwoList{p}, err
+}
+
 func (s HoldsVerTwoTwoList_List) Set(i int, v HoldsVerTwoTwoList) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerTwoTwoList_List) ToSlice() []HoldsVerTwoTwoList {
+	v := make([]HoldsVerTwoTwoList, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerTwoTwoList_List) Range(f func(i int, v HoldsVerTwoTwoList) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerTwoTwoList_List) String() string {
 	str, _ := text.MarshalList(0x95befe3f14606e6b, s.List)
 	return str
@@ -3772,7 +4397,7 @@ func (s HoldsVerTwoTwoPlus) Mylist() (VerTwoTwoPlus_List, error) {
 
 func (s HoldsVerTwoTwoPlus) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsVerTwoTwoPlus) SetMylist(v VerTwoTwoPlus_List) error {
@@ -3803,10 +4428,35 @@ func (s HoldsVerTwoTwoPlus_List) At(i int) HoldsVerTwoTwoPlus {
 	return HoldsVerTwoTwoPlus{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsVerTwoTwoPlus_List) AtChecked(i int) (HoldsVerTwoTwoPlus, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsVerTwoTwoPlus{p}, err
+}
+
 func (s HoldsVerTwoTwoPlus_List) Set(i int, v HoldsVerTwoTwoPlus) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsVerTwoTwoPlus_List) ToSlice() []HoldsVerTwoTwoPlus {
+	v := make([]HoldsVerTwoTwoPlus, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsVerTwoTwoPlus_List) Range(f func(i int, v HoldsVerTwoTwoPlus) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsVerTwoTwoPlus_List) String() string {
 	str, _ := text.MarshalList(0x87c33f2330feb3d8, s.List)
 	return str
@@ -3868,7 +4518,7 @@ func (s VerTwoTwoPlus) Ptr1() (VerTwoDataTwoPtr, error) {
 
 func (s VerTwoTwoPlus) HasPtr1() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoTwoPlus) SetPtr1(v VerTwoDataTwoPtr) error {
@@ -3893,7 +4543,7 @@ func (s VerTwoTwoPlus) Ptr2() (VerTwoDataTwoPtr, error) {
 
 func (s VerTwoTwoPlus) HasPtr2() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoTwoPlus) SetPtr2(v VerTwoDataTwoPtr) error {
@@ -3926,7 +4576,7 @@ func (s VerTwoTwoPlus) Lst3() (capnp.Int64List, error) {
 
 func (s VerTwoTwoPlus) HasLst3() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s VerTwoTwoPlus) SetLst3(v capnp.Int64List) error {
@@ -3955,8 +4605,33 @@ func NewVerTwoTwoPlus_List(s *capnp.Segment, sz int32) (VerTwoTwoPlus_List, erro
 
 func (s VerTwoTwoPlus_List) At(i int) VerTwoTwoPlus { return VerTwoTwoPlus{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VerTwoTwoPlus_List) AtChecked(i int) (VerTwoTwoPlus, error) {
+	p, err := s.List.StructChecked(i)
+	return VerTwoTwoPlus{p}, err
+}
+
 func (s VerTwoTwoPlus_List) Set(i int, v VerTwoTwoPlus) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VerTwoTwoPlus_List) ToSlice() []VerTwoTwoPlus {
+	v := make([]VerTwoTwoPlus, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VerTwoTwoPlus_List) Range(f func(i int, v VerTwoTwoPlus) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VerTwoTwoPlus_List) String() string {
 	str, _ := text.MarshalList(0xce44aee2d9e25049, s.List)
 	return str
@@ -4010,7 +4685,7 @@ func (s HoldsText) Txt() (string, error) {
 
 func (s HoldsText) HasTxt() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsText) TxtBytes() ([]byte, error) {
@@ -4029,7 +4704,7 @@ func (s HoldsText) Lst() (capnp.TextList, error) {
 
 func (s HoldsText) HasLst() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsText) SetLst(v capnp.TextList) error {
@@ -4054,7 +4729,7 @@ func (s HoldsText) Lstlst() (capnp.PointerList, error) {
 
 func (s HoldsText) HasLstlst() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HoldsText) SetLstlst(v capnp.PointerList) error {
@@ -4083,8 +4758,33 @@ func NewHoldsText_List(s *capnp.Segment, sz int32) (HoldsText_List, error) {
 
 func (s HoldsText_List) At(i int) HoldsText { return HoldsText{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HoldsText_List) AtChecked(i int) (HoldsText, error) {
+	p, err := s.List.StructChecked(i)
+	return HoldsText{p}, err
+}
+
 func (s HoldsText_List) Set(i int, v HoldsText) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HoldsText_List) ToSlice() []HoldsText {
+	v := make([]HoldsText, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HoldsText_List) Range(f func(i int, v HoldsText) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HoldsText_List) String() string {
 	str, _ := text.MarshalList(0xe5817f849ff906dc, s.List)
 	return str
@@ -4130,7 +4830,7 @@ func (s WrapEmpty) MightNotBeReallyEmpty() (VerEmpty, error) {
 
 func (s WrapEmpty) HasMightNotBeReallyEmpty() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s WrapEmpty) SetMightNotBeReallyEmpty(v VerEmpty) error {
@@ -4159,8 +4859,33 @@ func NewWrapEmpty_List(s *capnp.Segment, sz int32) (WrapEmpty_List, error) {
 
 func (s WrapEmpty_List) At(i int) WrapEmpty { return WrapEmpty{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s WrapEmpty_List) AtChecked(i int) (WrapEmpty, error) {
+	p, err := s.List.StructChecked(i)
+	return WrapEmpty{p}, err
+}
+
 func (s WrapEmpty_List) Set(i int, v WrapEmpty) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s WrapEmpty_List) ToSlice() []WrapEmpty {
+	v := make([]WrapEmpty, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s WrapEmpty_List) Range(f func(i int, v WrapEmpty) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s WrapEmpty_List) String() string {
 	str, _ := text.MarshalList(0x9ab599979b02ac59, s.List)
 	return str
@@ -4210,7 +4935,7 @@ func (s Wrap2x2) MightNotBeReallyEmpty() (VerTwoDataTwoPtr, error) {
 
 func (s Wrap2x2) HasMightNotBeReallyEmpty() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Wrap2x2) SetMightNotBeReallyEmpty(v VerTwoDataTwoPtr) error {
@@ -4239,8 +4964,33 @@ func NewWrap2x2_List(s *capnp.Segment, sz int32) (Wrap2x2_List, error) {
 
 func (s Wrap2x2_List) At(i int) Wrap2x2 { return Wrap2x2{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Wrap2x2_List) AtChecked(i int) (Wrap2x2, error) {
+	p, err := s.List.StructChecked(i)
+	return Wrap2x2{p}, err
+}
+
 func (s Wrap2x2_List) Set(i int, v Wrap2x2) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Wrap2x2_List) ToSlice() []Wrap2x2 {
+	v := make([]Wrap2x2, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Wrap2x2_List) Range(f func(i int, v Wrap2x2) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Wrap2x2_List) String() string {
 	str, _ := text.MarshalList(0xe1a2d1d51107bead, s.List)
 	return str
@@ -4290,7 +5040,7 @@ func (s Wrap2x2plus) MightNotBeReallyEmpty() (VerTwoTwoPlus, error) {
 
 func (s Wrap2x2plus) HasMightNotBeReallyEmpty() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Wrap2x2plus) SetMightNotBeReallyEmpty(v VerTwoTwoPlus) error {
@@ -4319,8 +5069,33 @@ func NewWrap2x2plus_List(s *capnp.Segment, sz int32) (Wrap2x2plus_List, error) {
 
 func (s Wrap2x2plus_List) At(i int) Wrap2x2plus { return Wrap2x2plus{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Wrap2x2plus_List) AtChecked(i int) (Wrap2x2plus, error) {
+	p, err := s.List.StructChecked(i)
+	return Wrap2x2plus{p}, err
+}
+
 func (s Wrap2x2plus_List) Set(i int, v Wrap2x2plus) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Wrap2x2plus_List) ToSlice() []Wrap2x2plus {
+	v := make([]Wrap2x2plus, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Wrap2x2plus_List) Range(f func(i int, v Wrap2x2plus) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Wrap2x2plus_List) String() string {
 	str, _ := text.MarshalList(0xe684eb3aef1a6859, s.List)
 	return str
@@ -4405,8 +5180,33 @@ func NewVoidUnion_List(s *capnp.Segment, sz int32) (VoidUnion_List, error) {
 
 func (s VoidUnion_List) At(i int) VoidUnion { return VoidUnion{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VoidUnion_List) AtChecked(i int) (VoidUnion, error) {
+	p, err := s.List.StructChecked(i)
+	return VoidUnion{p}, err
+}
+
 func (s VoidUnion_List) Set(i int, v VoidUnion) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VoidUnion_List) ToSlice() []VoidUnion {
+	v := make([]VoidUnion, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VoidUnion_List) Range(f func(i int, v VoidUnion) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VoidUnion_List) String() string {
 	str, _ := text.MarshalList(0x8821cdb23640783a, s.List)
 	return str
@@ -4452,7 +5252,7 @@ func (s Nester1Capn) Strs() (capnp.TextList, error) {
 
 func (s Nester1Capn) HasStrs() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Nester1Capn) SetStrs(v capnp.TextList) error {
@@ -4481,8 +5281,33 @@ func NewNester1Capn_List(s *capnp.Segment, sz int32) (Nester1Capn_List, error) {
 
 func (s Nester1Capn_List) At(i int) Nester1Capn { return Nester1Capn{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Nester1Capn_List) AtChecked(i int) (Nester1Capn, error) {
+	p, err := s.List.StructChecked(i)
+	return Nester1Capn{p}, err
+}
+
 func (s Nester1Capn_List) Set(i int, v Nester1Capn) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Nester1Capn_List) ToSlice() []Nester1Capn {
+	v := make([]Nester1Capn, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Nester1Capn_List) Range(f func(i int, v Nester1Capn) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Nester1Capn_List) String() string {
 	str, _ := text.MarshalList(0xf14fad09425d081c, s.List)
 	return str
@@ -4528,7 +5353,7 @@ func (s RWTestCapn) NestMatrix() (capnp.PointerList, error) {
 
 func (s RWTestCapn) HasNestMatrix() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s RWTestCapn) SetNestMatrix(v capnp.PointerList) error {
@@ -4557,8 +5382,33 @@ func NewRWTestCapn_List(s *capnp.Segment, sz int32) (RWTestCapn_List, error) {
 
 func (s RWTestCapn_List) At(i int) RWTestCapn { return RWTestCapn{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s RWTestCapn_List) AtChecked(i int) (RWTestCapn, error) {
+	p, err := s.List.StructChecked(i)
+	return RWTestCapn{p}, err
+}
+
 func (s RWTestCapn_List) Set(i int, v RWTestCapn) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s RWTestCapn_List) ToSlice() []RWTestCapn {
+	v := make([]RWTestCapn, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s RWTestCapn_List) Range(f func(i int, v RWTestCapn) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s RWTestCapn_List) String() string {
 	str, _ := text.MarshalList(0xf7ff4414476c186a, s.List)
 	return str
@@ -4604,7 +5454,7 @@ func (s ListStructCapn) Vec() (Nester1Capn_List, error) {
 
 func (s ListStructCapn) HasVec() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s ListStructCapn) SetVec(v Nester1Capn_List) error {
@@ -4633,8 +5483,33 @@ func NewListStructCapn_List(s *capnp.Segment, sz int32) (ListStructCapn_List, er
 
 func (s ListStructCapn_List) At(i int) ListStructCapn { return ListStructCapn{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s ListStructCapn_List) AtChecked(i int) (ListStructCapn, error) {
+	p, err := s.List.StructChecked(i)
+	return ListStructCapn{p}, err
+}
+
 func (s ListStructCapn_List) Set(i int, v ListStructCapn) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s ListStructCapn_List) ToSlice() []ListStructCapn {
+	v := make([]ListStructCapn, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s ListStructCapn_List) Range(f func(i int, v ListStructCapn) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s ListStructCapn_List) String() string {
 	str, _ := text.MarshalList(0xb1ac056ed7647011, s.List)
 	return str
@@ -4745,7 +5620,7 @@ func (s Echo_echo_Params) In() (string, error) {
 
 func (s Echo_echo_Params) HasIn() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Echo_echo_Params) InBytes() ([]byte, error) {
@@ -4768,10 +5643,35 @@ func NewEcho_echo_Params_List(s *capnp.Segment, sz int32) (Echo_echo_Params_List
 
 func (s Echo_echo_Params_List) At(i int) Echo_echo_Params { return Echo_echo_Params{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Echo_echo_Params_List) AtChecked(i int) (Echo_echo_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return Echo_echo_Params{p}, err
+}
+
 func (s Echo_echo_Params_List) Set(i int, v Echo_echo_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Echo_echo_Params_List) ToSlice() []Echo_echo_Params {
+	v := make([]Echo_echo_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Echo_echo_Params_List) Range(f func(i int, v Echo_echo_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Echo_echo_Params_List) String() string {
 	str, _ := text.MarshalList(0x8a165fb4d71bf3a2, s.List)
 	return str
@@ -4817,7 +5717,7 @@ func (s Echo_echo_Results) Out() (string, error) {
 
 func (s Echo_echo_Results) HasOut() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Echo_echo_Results) OutBytes() ([]byte, error) {
@@ -4842,10 +5742,35 @@ func (s Echo_echo_Results_List) At(i int) Echo_echo_Results {
 	return Echo_echo_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Echo_echo_Results_List) AtChecked(i int) (Echo_echo_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return Echo_echo_Results{p}, err
+}
+
 func (s Echo_echo_Results_List) Set(i int, v Echo_echo_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Echo_echo_Results_List) ToSlice() []Echo_echo_Results {
+	v := make([]Echo_echo_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Echo_echo_Results_List) Range(f func(i int, v Echo_echo_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Echo_echo_Results_List) String() string {
 	str, _ := text.MarshalList(0x9b37d729b9dd7b9d, s.List)
 	return str
@@ -4891,7 +5816,7 @@ func (s Hoth) Base() (EchoBase, error) {
 
 func (s Hoth) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Hoth) SetBase(v EchoBase) error {
@@ -4920,8 +5845,33 @@ func NewHoth_List(s *capnp.Segment, sz int32) (Hoth_List, error) {
 
 func (s Hoth_List) At(i int) Hoth { return Hoth{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hoth_List) AtChecked(i int) (Hoth, error) {
+	p, err := s.List.StructChecked(i)
+	return Hoth{p}, err
+}
+
 func (s Hoth_List) Set(i int, v Hoth) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hoth_List) ToSlice() []Hoth {
+	v := make([]Hoth, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hoth_List) Range(f func(i int, v Hoth) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hoth_List) String() string {
 	str, _ := text.MarshalList(0xad87da456fb0ebb9, s.List)
 	return str
@@ -4971,7 +5921,7 @@ func (s EchoBase) Echo() Echo {
 
 func (s EchoBase) HasEcho() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s EchoBase) SetEcho(v Echo) error {
@@ -4994,8 +5944,33 @@ func NewEchoBase_List(s *capnp.Segment, sz int32) (EchoBase_List, error) {
 
 func (s EchoBase_List) At(i int) EchoBase { return EchoBase{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s EchoBase_List) AtChecked(i int) (EchoBase, error) {
+	p, err := s.List.StructChecked(i)
+	return EchoBase{p}, err
+}
+
 func (s EchoBase_List) Set(i int, v EchoBase) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s EchoBase_List) ToSlice() []EchoBase {
+	v := make([]EchoBase, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s EchoBase_List) Range(f func(i int, v EchoBase) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s EchoBase_List) String() string {
 	str, _ := text.MarshalList(0xa8bf13fef2674866, s.List)
 	return str
@@ -5045,7 +6020,7 @@ func (s EchoBases) Bases() (EchoBase_List, error) {
 
 func (s EchoBases) HasBases() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s EchoBases) SetBases(v EchoBase_List) error {
@@ -5074,8 +6049,33 @@ func NewEchoBases_List(s *capnp.Segment, sz int32) (EchoBases_List, error) {
 
 func (s EchoBases_List) At(i int) EchoBases { return EchoBases{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s EchoBases_List) AtChecked(i int) (EchoBases, error) {
+	p, err := s.List.StructChecked(i)
+	return EchoBases{p}, err
+}
+
 func (s EchoBases_List) Set(i int, v EchoBases) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s EchoBases_List) ToSlice() []EchoBases {
+	v := make([]EchoBases, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s EchoBases_List) Range(f func(i int, v EchoBases) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s EchoBases_List) String() string {
 	str, _ := text.MarshalList(0xc02e9d191c6ac0bc, s.List)
 	return str
@@ -5121,7 +6121,7 @@ func (s StackingRoot) A() (StackingA, error) {
 
 func (s StackingRoot) HasA() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s StackingRoot) SetA(v StackingA) error {
@@ -5150,7 +6150,7 @@ func (s StackingRoot) AWithDefault() (StackingA, error) {
 
 func (s StackingRoot) HasAWithDefault() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s StackingRoot) SetAWithDefault(v StackingA) error {
@@ -5179,8 +6179,33 @@ func NewStackingRoot_List(s *capnp.Segment, sz int32) (StackingRoot_List, error)
 
 func (s StackingRoot_List) At(i int) StackingRoot { return StackingRoot{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s StackingRoot_List) AtChecked(i int) (StackingRoot, error) {
+	p, err := s.List.StructChecked(i)
+	return StackingRoot{p}, err
+}
+
 func (s StackingRoot_List) Set(i int, v StackingRoot) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s StackingRoot_List) ToSlice() []StackingRoot {
+	v := make([]StackingRoot, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s StackingRoot_List) Range(f func(i int, v StackingRoot) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s StackingRoot_List) String() string {
 	str, _ := text.MarshalList(0x8fae7b41c61fc890, s.List)
 	return str
@@ -5242,7 +6267,7 @@ func (s StackingA) B() (StackingB, error) {
 
 func (s StackingA) HasB() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s StackingA) SetB(v StackingB) error {
@@ -5271,8 +6296,33 @@ func NewStackingA_List(s *capnp.Segment, sz int32) (StackingA_List, error) {
 
 func (s StackingA_List) At(i int) StackingA { return StackingA{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s StackingA_List) AtChecked(i int) (StackingA, error) {
+	p, err := s.List.StructChecked(i)
+	return StackingA{p}, err
+}
+
 func (s StackingA_List) Set(i int, v StackingA) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s StackingA_List) ToSlice() []StackingA {
+	v := make([]StackingA, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s StackingA_List) Range(f func(i int, v StackingA) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s StackingA_List) String() string {
 	str, _ := text.MarshalList(0x9d3032ff86043b75, s.List)
 	return str
@@ -5334,8 +6384,33 @@ func NewStackingB_List(s *capnp.Segment, sz int32) (StackingB_List, error) {
 
 func (s StackingB_List) At(i int) StackingB { return StackingB{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s StackingB_List) AtChecked(i int) (StackingB, error) {
+	p, err := s.List.StructChecked(i)
+	return StackingB{p}, err
+}
+
 func (s StackingB_List) Set(i int, v StackingB) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s StackingB_List) ToSlice() []StackingB {
+	v := make([]StackingB, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s StackingB_List) Range(f func(i int, v StackingB) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s StackingB_List) String() string {
 	str, _ := text.MarshalList(0x85257b30d6edf8c5, s.List)
 	return str
@@ -5452,10 +6527,35 @@ func (s CallSequence_getNumber_Params_List) At(i int) CallSequence_getNumber_Par
 	return CallSequence_getNumber_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CallSequence_getNumber_Params_List) AtChecked(i int) (CallSequence_getNumber_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return CallSequence_getNumber_Params{p}, err
+}
+
 func (s CallSequence_getNumber_Params_List) Set(i int, v CallSequence_getNumber_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CallSequence_getNumber_Params_List) ToSlice() []CallSequence_getNumber_Params {
+	v := make([]CallSequence_getNumber_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CallSequence_getNumber_Params_List) Range(f func(i int, v CallSequence_getNumber_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CallSequence_getNumber_Params_List) String() string {
 	str, _ := text.MarshalList(0xf58782f48a121998, s.List)
 	return str
@@ -5515,10 +6615,35 @@ func (s CallSequence_getNumber_Results_List) At(i int) CallSequence_getNumber_Re
 	return CallSequence_getNumber_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CallSequence_getNumber_Results_List) AtChecked(i int) (CallSequence_getNumber_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return CallSequence_getNumber_Results{p}, err
+}
+
 func (s CallSequence_getNumber_Results_List) Set(i int, v CallSequence_getNumber_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CallSequence_getNumber_Results_List) ToSlice() []CallSequence_getNumber_Results {
+	v := make([]CallSequence_getNumber_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CallSequence_getNumber_Results_List) Range(f func(i int, v CallSequence_getNumber_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CallSequence_getNumber_Results_List) String() string {
 	str, _ := text.MarshalList(0xa465f9502fd11e97, s.List)
 	return str
@@ -5564,7 +6689,7 @@ func (s Defaults) Text() (string, error) {
 
 func (s Defaults) HasText() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Defaults) TextBytes() ([]byte, error) {
@@ -5583,7 +6708,7 @@ func (s Defaults) Data() ([]byte, error) {
 
 func (s Defaults) HasData() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Defaults) SetData(v []byte) error {
@@ -5628,8 +6753,33 @@ func NewDefaults_List(s *capnp.Segment, sz int32) (Defaults_List, error) {
 
 func (s Defaults_List) At(i int) Defaults { return Defaults{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Defaults_List) AtChecked(i int) (Defaults, error) {
+	p, err := s.List.StructChecked(i)
+	return Defaults{p}, err
+}
+
 func (s Defaults_List) Set(i int, v Defaults) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Defaults_List) ToSlice() []Defaults {
+	v := make([]Defaults, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Defaults_List) Range(f func(i int, v Defaults) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Defaults_List) String() string {
 	str, _ := text.MarshalList(0x97e38948c61f878d, s.List)
 	return str
@@ -5675,7 +6825,7 @@ func (s BenchmarkA) Name() (string, error) {
 
 func (s BenchmarkA) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s BenchmarkA) NameBytes() ([]byte, error) {
@@ -5702,7 +6852,7 @@ func (s BenchmarkA) Phone() (string, error) {
 
 func (s BenchmarkA) HasPhone() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s BenchmarkA) PhoneBytes() ([]byte, error) {
@@ -5749,8 +6899,33 @@ func NewBenchmarkA_List(s *capnp.Segment, sz int32) (BenchmarkA_List, error) {
 
 func (s BenchmarkA_List) At(i int) BenchmarkA { return BenchmarkA{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s BenchmarkA_List) AtChecked(i int) (BenchmarkA, error) {
+	p, err := s.List.StructChecked(i)
+	return BenchmarkA{p}, err
+}
+
 func (s BenchmarkA_List) Set(i int, v BenchmarkA) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s BenchmarkA_List) ToSlice() []BenchmarkA {
+	v := make([]BenchmarkA, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s BenchmarkA_List) Range(f func(i int, v BenchmarkA) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s BenchmarkA_List) String() string {
 	str, _ := text.MarshalList(0xde2a1a960863c11c, s.List)
 	return str
@@ -5796,7 +6971,7 @@ func (s AllocBenchmark) Fields() (AllocBenchmark_Field_List, error) {
 
 func (s AllocBenchmark) HasFields() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s AllocBenchmark) SetFields(v AllocBenchmark_Field_List) error {
@@ -5825,8 +7000,33 @@ func NewAllocBenchmark_List(s *capnp.Segment, sz int32) (AllocBenchmark_List, er
 
 func (s AllocBenchmark_List) At(i int) AllocBenchmark { return AllocBenchmark{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s AllocBenchmark_List) AtChecked(i int) (AllocBenchmark, error) {
+	p, err := s.List.StructChecked(i)
+	return AllocBenchmark{p}, err
+}
+
 func (s AllocBenchmark_List) Set(i int, v AllocBenchmark) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s AllocBenchmark_List) ToSlice() []AllocBenchmark {
+	v := make([]AllocBenchmark, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s AllocBenchmark_List) Range(f func(i int, v AllocBenchmark) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s AllocBenchmark_List) String() string {
 	str, _ := text.MarshalList(0xecea3e9ebcbe5655, s.List)
 	return str
@@ -5872,7 +7072,7 @@ func (s AllocBenchmark_Field) StringValue() (string, error) {
 
 func (s AllocBenchmark_Field) HasStringValue() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s AllocBenchmark_Field) StringValueBytes() ([]byte, error) {
@@ -5897,10 +7097,35 @@ func (s AllocBenchmark_Field_List) At(i int) AllocBenchmark_Field {
 	return AllocBenchmark_Field{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s AllocBenchmark_Field_List) AtChecked(i int) (AllocBenchmark_Field, error) {
+	p, err := s.List.StructChecked(i)
+	return AllocBenchmark_Field{p}, err
+}
+
 func (s AllocBenchmark_Field_List) Set(i int, v AllocBenchmark_Field) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s AllocBenchmark_Field_List) ToSlice() []AllocBenchmark_Field {
+	v := make([]AllocBenchmark_Field, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s AllocBenchmark_Field_List) Range(f func(i int, v AllocBenchmark_Field) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s AllocBenchmark_Field_List) String() string {
 	str, _ := text.MarshalList(0xb8fb64b8ed846ae6, s.List)
 	return str
@@ -40,7 +40,7 @@ func (s Book) Title() (string, error) {
 
 func (s Book) HasTitle() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Book) TitleBytes() ([]byte, error) {
@@ -71,8 +71,33 @@ func NewBook_List(s *capnp.Segment, sz int32) (Book_List, error) {
 
 func (s Book_List) At(i int) Book { return Book{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Book_List) AtChecked(i int) (Book, error) {
+	p, err := s.List.StructChecked(i)
+	return Book{p}, err
+}
+
 func (s Book_List) Set(i int, v Book) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Book_List) ToSlice() []Book {
+	v := make([]Book, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Book_List) Range(f func(i int, v Book) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Book_List) String() string {
 	str, _ := text.MarshalList(0x8100cc88d7d4d47c, s.List)
 	return str
@@ -113,10 +113,35 @@ func (s HashFactory_newSha1_Params_List) At(i int) HashFactory_newSha1_Params {
 	return HashFactory_newSha1_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HashFactory_newSha1_Params_List) AtChecked(i int) (HashFactory_newSha1_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return HashFactory_newSha1_Params{p}, err
+}
+
 func (s HashFactory_newSha1_Params_List) Set(i int, v HashFactory_newSha1_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HashFactory_newSha1_Params_List) ToSlice() []HashFactory_newSha1_Params {
+	v := make([]HashFactory_newSha1_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HashFactory_newSha1_Params_List) Range(f func(i int, v HashFactory_newSha1_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HashFactory_newSha1_Params_List) String() string {
 	str, _ := text.MarshalList(0x92b20ad1a58ca0ca, s.List)
 	return str
@@ -162,7 +187,7 @@ func (s HashFactory_newSha1_Results) Hash() Hash {
 
 func (s HashFactory_newSha1_Results) HasHash() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HashFactory_newSha1_Results) SetHash(v Hash) error {
@@ -187,10 +212,35 @@ func (s HashFactory_newSha1_Results_List) At(i int) HashFactory_newSha1_Results
 	return HashFactory_newSha1_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HashFactory_newSha1_Results_List) AtChecked(i int) (HashFactory_newSha1_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return HashFactory_newSha1_Results{p}, err
+}
+
 func (s HashFactory_newSha1_Results_List) Set(i int, v HashFactory_newSha1_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HashFactory_newSha1_Results_List) ToSlice() []HashFactory_newSha1_Results {
+	v := make([]HashFactory_newSha1_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HashFactory_newSha1_Results_List) Range(f func(i int, v HashFactory_newSha1_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HashFactory_newSha1_Results_List) String() string {
 	str, _ := text.MarshalList(0xea3e50f7663f7bdf, s.List)
 	return str
@@ -349,7 +399,7 @@ func (s Hash_write_Params) Data() ([]byte, error) {
 
 func (s Hash_write_Params) HasData() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Hash_write_Params) SetData(v []byte) error {
@@ -369,10 +419,35 @@ func (s Hash_write_Params_List) At(i int) Hash_write_Params {
 	return Hash_write_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hash_write_Params_List) AtChecked(i int) (Hash_write_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return Hash_write_Params{p}, err
+}
+
 func (s Hash_write_Params_List) Set(i int, v Hash_write_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hash_write_Params_List) ToSlice() []Hash_write_Params {
+	v := make([]Hash_write_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hash_write_Params_List) Range(f func(i int, v Hash_write_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hash_write_Params_List) String() string {
 	str, _ := text.MarshalList(0xdffe94ae546cdee3, s.List)
 	return str
@@ -424,10 +499,35 @@ func (s Hash_write_Results_List) At(i int) Hash_write_Results {
 	return Hash_write_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hash_write_Results_List) AtChecked(i int) (Hash_write_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return Hash_write_Results{p}, err
+}
+
 func (s Hash_write_Results_List) Set(i int, v Hash_write_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hash_write_Results_List) ToSlice() []Hash_write_Results {
+	v := make([]Hash_write_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hash_write_Results_List) Range(f func(i int, v Hash_write_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hash_write_Results_List) String() string {
 	str, _ := text.MarshalList(0x80ac741ec7fb8f65, s.List)
 	return str
@@ -477,10 +577,35 @@ func NewHash_sum_Params_List(s *capnp.Segment, sz int32) (Hash_sum_Params_List,
 
 func (s Hash_sum_Params_List) At(i int) Hash_sum_Params { return Hash_sum_Params{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hash_sum_Params_List) AtChecked(i int) (Hash_sum_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return Hash_sum_Params{p}, err
+}
+
 func (s Hash_sum_Params_List) Set(i int, v Hash_sum_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hash_sum_Params_List) ToSlice() []Hash_sum_Params {
+	v := make([]Hash_sum_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hash_sum_Params_List) Range(f func(i int, v Hash_sum_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hash_sum_Params_List) String() string {
 	str, _ := text.MarshalList(0xe74bb2d0190cf89c, s.List)
 	return str
@@ -526,7 +651,7 @@ func (s Hash_sum_Results) Hash() ([]byte, error) {
 
 func (s Hash_sum_Results) HasHash() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Hash_sum_Results) SetHash(v []byte) error {
@@ -544,10 +669,35 @@ func NewHash_sum_Results_List(s *capnp.Segment, sz int32) (Hash_sum_Results_List
 
 func (s Hash_sum_Results_List) At(i int) Hash_sum_Results { return Hash_sum_Results{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hash_sum_Results_List) AtChecked(i int) (Hash_sum_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return Hash_sum_Results{p}, err
+}
+
 func (s Hash_sum_Results_List) Set(i int, v Hash_sum_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hash_sum_Results_List) ToSlice() []Hash_sum_Results {
+	v := make([]Hash_sum_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hash_sum_Results_List) Range(f func(i int, v Hash_sum_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hash_sum_Results_List) String() string {
 	str, _ := text.MarshalList(0xd093963b95a4e107, s.List)
 	return str
@@ -86,7 +86,7 @@ func (s Node) DisplayName() (string, error) {
 
 func (s Node) HasDisplayName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) DisplayNameBytes() ([]byte, error) {
@@ -121,7 +121,7 @@ func (s Node) Parameters() (Node_Parameter_List, error) {
 
 func (s Node) HasParameters() bool {
 	p, err := s.Struct.Ptr(5)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) SetParameters(v Node_Parameter_List) error {
@@ -154,7 +154,7 @@ func (s Node) NestedNodes() (Node_NestedNode_List, error) {
 
 func (s Node) HasNestedNodes() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) SetNestedNodes(v Node_NestedNode_List) error {
@@ -179,7 +179,7 @@ func (s Node) Annotations() (Annotation_List, error) {
 
 func (s Node) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) SetAnnotations(v Annotation_List) error {
@@ -263,7 +263,7 @@ func (s Node_structNode) Fields() (Field_List, error) {
 
 func (s Node_structNode) HasFields() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_structNode) SetFields(v Field_List) error {
@@ -294,7 +294,7 @@ func (s Node_enum) Enumerants() (Enumerant_List, error) {
 
 func (s Node_enum) HasEnumerants() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_enum) SetEnumerants(v Enumerant_List) error {
@@ -325,7 +325,7 @@ func (s Node_interface) Methods() (Method_List, error) {
 
 func (s Node_interface) HasMethods() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_interface) SetMethods(v Method_List) error {
@@ -350,7 +350,7 @@ func (s Node_interface) Superclasses() (Superclass_List, error) {
 
 func (s Node_interface) HasSuperclasses() bool {
 	p, err := s.Struct.Ptr(4)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_interface) SetSuperclasses(v Superclass_List) error {
@@ -381,7 +381,7 @@ func (s Node_const) Type() (Type, error) {
 
 func (s Node_const) HasType() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_const) SetType(v Type) error {
@@ -406,7 +406,7 @@ func (s Node_const) Value() (Value, error) {
 
 func (s Node_const) HasValue() bool {
 	p, err := s.Struct.Ptr(4)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_const) SetValue(v Value) error {
@@ -437,7 +437,7 @@ func (s Node_annotation) Type() (Type, error) {
 
 func (s Node_annotation) HasType() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_annotation) SetType(v Type) error {
@@ -562,8 +562,33 @@ func NewNode_List(s *capnp.Segment, sz int32) (Node_List, error) {
 
 func (s Node_List) At(i int) Node { return Node{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Node_List) AtChecked(i int) (Node, error) {
+	p, err := s.List.StructChecked(i)
+	return Node{p}, err
+}
+
 func (s Node_List) Set(i int, v Node) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Node_List) ToSlice() []Node {
+	v := make([]Node, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Node_List) Range(f func(i int, v Node) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Node_Promise is a wrapper for a Node promised by a client call.
 type Node_Promise struct{ *capnp.Pipeline }
 
@@ -661,7 +686,7 @@ func (s Node_Parameter) Name() (string, error) {
 
 func (s Node_Parameter) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_Parameter) NameBytes() ([]byte, error) {
@@ -684,8 +709,33 @@ func NewNode_Parameter_List(s *capnp.Segment, sz int32) (Node_Parameter_List, er
 
 func (s Node_Parameter_List) At(i int) Node_Parameter { return Node_Parameter{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Node_Parameter_List) AtChecked(i int) (Node_Parameter, error) {
+	p, err := s.List.StructChecked(i)
+	return Node_Parameter{p}, err
+}
+
 func (s Node_Parameter_List) Set(i int, v Node_Parameter) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Node_Parameter_List) ToSlice() []Node_Parameter {
+	v := make([]Node_Parameter, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Node_Parameter_List) Range(f func(i int, v Node_Parameter) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Node_Parameter_Promise is a wrapper for a Node_Parameter promised by a client call.
 type Node_Parameter_Promise struct{ *capnp.Pipeline }
 
@@ -721,7 +771,7 @@ func (s Node_NestedNode) Name() (string, error) {
 
 func (s Node_NestedNode) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_NestedNode) NameBytes() ([]byte, error) {
@@ -752,10 +802,35 @@ func NewNode_NestedNode_List(s *capnp.Segment, sz int32) (Node_NestedNode_List,
 
 func (s Node_NestedNode_List) At(i int) Node_NestedNode { return Node_NestedNode{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Node_NestedNode_List) AtChecked(i int) (Node_NestedNode, error) {
+	p, err := s.List.StructChecked(i)
+	return Node_NestedNode{p}, err
+}
+
 func (s Node_NestedNode_List) Set(i int, v Node_NestedNode) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Node_NestedNode_List) ToSlice() []Node_NestedNode {
+	v := make([]Node_NestedNode, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Node_NestedNode_List) Range(f func(i int, v Node_NestedNode) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Node_NestedNode_Promise is a wrapper for a Node_NestedNode promised by a client call.
 type Node_NestedNode_Promise struct{ *capnp.Pipeline }
 
@@ -834,7 +909,7 @@ func (s Field) Name() (string, error) {
 
 func (s Field) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field) NameBytes() ([]byte, error) {
@@ -861,7 +936,7 @@ func (s Field) Annotations() (Annotation_List, error) {
 
 func (s Field) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field) SetAnnotations(v Annotation_List) error {
@@ -908,7 +983,7 @@ func (s Field_slot) Type() (Type, error) {
 
 func (s Field_slot) HasType() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field_slot) SetType(v Type) error {
@@ -933,7 +1008,7 @@ func (s Field_slot) DefaultValue() (Value, error) {
 
 func (s Field_slot) HasDefaultValue() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field_slot) SetDefaultValue(v Value) error {
@@ -1003,8 +1078,33 @@ func NewField_List(s *capnp.Segment, sz int32) (Field_List, error) {
 
 func (s Field_List) At(i int) Field { return Field{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Field_List) AtChecked(i int) (Field, error) {
+	p, err := s.List.StructChecked(i)
+	return Field{p}, err
+}
+
 func (s Field_List) Set(i int, v Field) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Field_List) ToSlice() []Field {
+	v := make([]Field, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Field_List) Range(f func(i int, v Field) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Field_Promise is a wrapper for a Field promised by a client call.
 type Field_Promise struct{ *capnp.Pipeline }
 
@@ -1078,7 +1178,7 @@ func (s Enumerant) Name() (string, error) {
 
 func (s Enumerant) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Enumerant) NameBytes() ([]byte, error) {
@@ -1105,7 +1205,7 @@ func (s Enumerant) Annotations() (Annotation_List, error) {
 
 func (s Enumerant) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Enumerant) SetAnnotations(v Annotation_List) error {
@@ -1134,8 +1234,33 @@ func NewEnumerant_List(s *capnp.Segment, sz int32) (Enumerant_List, error) {
 
 func (s Enumerant_List) At(i int) Enumerant { return Enumerant{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Enumerant_List) AtChecked(i int) (Enumerant, error) {
+	p, err := s.List.StructChecked(i)
+	return Enumerant{p}, err
+}
+
 func (s Enumerant_List) Set(i int, v Enumerant) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Enumerant_List) ToSlice() []Enumerant {
+	v := make([]Enumerant, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Enumerant_List) Range(f func(i int, v Enumerant) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Enumerant_Promise is a wrapper for a Enumerant promised by a client call.
 type Enumerant_Promise struct{ *capnp.Pipeline }
 
@@ -1179,7 +1304,7 @@ func (s Superclass) Brand() (Brand, error) {
 
 func (s Superclass) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Superclass) SetBrand(v Brand) error {
@@ -1208,8 +1333,33 @@ func NewSuperclass_List(s *capnp.Segment, sz int32) (Superclass_List, error) {
 
 func (s Superclass_List) At(i int) Superclass { return Superclass{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Superclass_List) AtChecked(i int) (Superclass, error) {
+	p, err := s.List.StructChecked(i)
+	return Superclass{p}, err
+}
+
 func (s Superclass_List) Set(i int, v Superclass) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Superclass_List) ToSlice() []Superclass {
+	v := make([]Superclass, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Superclass_List) Range(f func(i int, v Superclass) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Superclass_Promise is a wrapper for a Superclass promised by a client call.
 type Superclass_Promise struct{ *capnp.Pipeline }
 
@@ -1249,7 +1399,7 @@ func (s Method) Name() (string, error) {
 
 func (s Method) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) NameBytes() ([]byte, error) {
@@ -1276,7 +1426,7 @@ func (s Method) ImplicitParameters() (Node_Parameter_List, error) {
 
 func (s Method) HasImplicitParameters() bool {
 	p, err := s.Struct.Ptr(4)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetImplicitParameters(v Node_Parameter_List) error {
@@ -1309,7 +1459,7 @@ func (s Method) ParamBrand() (Brand, error) {
 
 func (s Method) HasParamBrand() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetParamBrand(v Brand) error {
@@ -1342,7 +1492,7 @@ func (s Method) ResultBrand() (Brand, error) {
 
 func (s Method) HasResultBrand() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetResultBrand(v Brand) error {
@@ -1367,7 +1517,7 @@ func (s Method) Annotations() (Annotation_List, error) {
 
 func (s Method) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetAnnotations(v Annotation_List) error {
@@ -1396,8 +1546,33 @@ func NewMethod_List(s *capnp.Segment, sz int32) (Method_List, error) {
 
 func (s Method_List) At(i int) Method { return Method{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Method_List) AtChecked(i int) (Method, error) {
+	p, err := s.List.StructChecked(i)
+	return Method{p}, err
+}
+
 func (s Method_List) Set(i int, v Method) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Method_List) ToSlice() []Method {
+	v := make([]Method, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Method_List) Range(f func(i int, v Method) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Method_Promise is a wrapper for a Method promised by a client call.
 type Method_Promise struct{ *capnp.Pipeline }
 
@@ -1644,7 +1819,7 @@ func (s Type_list) ElementType() (Type, error) {
 
 func (s Type_list) HasElementType() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_list) SetElementType(v Type) error {
@@ -1683,7 +1858,7 @@ func (s Type_enum) Brand() (Brand, error) {
 
 func (s Type_enum) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_enum) SetBrand(v Brand) error {
@@ -1722,7 +1897,7 @@ func (s Type_structType) Brand() (Brand, error) {
 
 func (s Type_structType) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_structType) SetBrand(v Brand) error {
@@ -1761,7 +1936,7 @@ func (s Type_interface) Brand() (Brand, error) {
 
 func (s Type_interface) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_interface) SetBrand(v Brand) error {
@@ -1868,8 +2043,33 @@ func NewType_List(s *capnp.Segment, sz int32) (Type_List, error) {
 
 func (s Type_List) At(i int) Type { return Type{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Type_List) AtChecked(i int) (Type, error) {
+	p, err := s.List.StructChecked(i)
+	return Type{p}, err
+}
+
 func (s Type_List) Set(i int, v Type) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Type_List) ToSlice() []Type {
+	v := make([]Type, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Type_List) Range(f func(i int, v Type) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Type_Promise is a wrapper for a Type promised by a client call.
 type Type_Promise struct{ *capnp.Pipeline }
 
@@ -2007,7 +2207,7 @@ func (s Brand) Scopes() (Brand_Scope_List, error) {
 
 func (s Brand) HasScopes() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Brand) SetScopes(v Brand_Scope_List) error {
@@ -2036,8 +2236,33 @@ func NewBrand_List(s *capnp.Segment, sz int32) (Brand_List, error) {
 
 func (s Brand_List) At(i int) Brand { return Brand{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Brand_List) AtChecked(i int) (Brand, error) {
+	p, err := s.List.StructChecked(i)
+	return Brand{p}, err
+}
+
 func (s Brand_List) Set(i int, v Brand) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Brand_List) ToSlice() []Brand {
+	v := make([]Brand, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Brand_List) Range(f func(i int, v Brand) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Brand_Promise is a wrapper for a Brand promised by a client call.
 type Brand_Promise struct{ *capnp.Pipeline }
 
@@ -2105,7 +2330,7 @@ func (s Brand_Scope) HasBind() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Brand_Scope) SetBind(v Brand_Binding_List) error {
@@ -2141,8 +2366,33 @@ func NewBrand_Scope_List(s *capnp.Segment, sz int32) (Brand_Scope_List, error) {
 
 func (s Brand_Scope_List) At(i int) Brand_Scope { return Brand_Scope{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Brand_Scope_List) AtChecked(i int) (Brand_Scope, error) {
+	p, err := s.List.StructChecked(i)
+	return Brand_Scope{p}, err
+}
+
 func (s Brand_Scope_List) Set(i int, v Brand_Scope) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Brand_Scope_List) ToSlice() []Brand_Scope {
+	v := make([]Brand_Scope, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Brand_Scope_List) Range(f func(i int, v Brand_Scope) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Brand_Scope_Promise is a wrapper for a Brand_Scope promised by a client call.
 type Brand_Scope_Promise struct{ *capnp.Pipeline }
 
@@ -2207,7 +2457,7 @@ func (s Brand_Binding) HasType() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Brand_Binding) SetType(v Type) error {
@@ -2238,8 +2488,33 @@ func NewBrand_Binding_List(s *capnp.Segment, sz int32) (Brand_Binding_List, erro
 
 func (s Brand_Binding_List) At(i int) Brand_Binding { return Brand_Binding{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Brand_Binding_List) AtChecked(i int) (Brand_Binding, error) {
+	p, err := s.List.StructChecked(i)
+	return Brand_Binding{p}, err
+}
+
 func (s Brand_Binding_List) Set(i int, v Brand_Binding) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Brand_Binding_List) ToSlice() []Brand_Binding {
+	v := make([]Brand_Binding, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Brand_Binding_List) Range(f func(i int, v Brand_Binding) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Brand_Binding_Promise is a wrapper for a Brand_Binding promised by a client call.
 type Brand_Binding_Promise struct{ *capnp.Pipeline }
 
@@ -2458,7 +2733,7 @@ func (s Value) HasText() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) TextBytes() ([]byte, error) {
@@ -2481,7 +2756,7 @@ func (s Value) HasData() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) SetData(v []byte) error {
@@ -2498,13 +2773,23 @@ func (s Value) HasList() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) ListPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// ListStruct returns the list field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Value) ListStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ListPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Value) SetList(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 14)
 	return s.Struct.SetPointer(0, v)
@@ -2533,13 +2818,23 @@ func (s Value) HasStructValue() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) StructValuePtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// StructValueStruct returns the structValue field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Value) StructValueStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.StructValuePtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Value) SetStructValue(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 16)
 	return s.Struct.SetPointer(0, v)
@@ -2564,13 +2859,23 @@ func (s Value) HasAnyPointer() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) AnyPointerPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// AnyPointerStruct returns the anyPointer field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Value) AnyPointerStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.AnyPointerPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Value) SetAnyPointer(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 18)
 	return s.Struct.SetPointer(0, v)
@@ -2592,8 +2897,33 @@ func NewValue_List(s *capnp.Segment, sz int32) (Value_List, error) {
 
 func (s Value_List) At(i int) Value { return Value{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Value_List) AtChecked(i int) (Value, error) {
+	p, err := s.List.StructChecked(i)
+	return Value{p}, err
+}
+
 func (s Value_List) Set(i int, v Value) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Value_List) ToSlice() []Value {
+	v := make([]Value, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Value_List) Range(f func(i int, v Value) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Value_Promise is a wrapper for a Value promised by a client call.
 type Value_Promise struct{ *capnp.Pipeline }
 
@@ -2649,7 +2979,7 @@ func (s Annotation) Brand() (Brand, error) {
 
 func (s Annotation) HasBrand() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Annotation) SetBrand(v Brand) error {
@@ -2674,7 +3004,7 @@ func (s Annotation) Value() (Value, error) {
 
 func (s Annotation) HasValue() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Annotation) SetValue(v Value) error {
@@ -2703,8 +3033,33 @@ func NewAnnotation_List(s *capnp.Segment, sz int32) (Annotation_List, error) {
 
 func (s Annotation_List) At(i int) Annotation { return Annotation{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Annotation_List) AtChecked(i int) (Annotation, error) {
+	p, err := s.List.StructChecked(i)
+	return Annotation{p}, err
+}
+
 func (s Annotation_List) Set(i int, v Annotation) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Annotation_List) ToSlice() []Annotation {
+	v := make([]Annotation, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Annotation_List) Range(f func(i int, v Annotation) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // Annotation_Promise is a wrapper for a Annotation promised by a client call.
 type Annotation_Promise struct{ *capnp.Pipeline }
 
@@ -2833,7 +3188,7 @@ func (s CodeGeneratorRequest) Nodes() (Node_List, error) {
 
 func (s CodeGeneratorRequest) HasNodes() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest) SetNodes(v Node_List) error {
@@ -2858,7 +3213,7 @@ func (s CodeGeneratorRequest) RequestedFiles() (CodeGeneratorRequest_RequestedFi
 
 func (s CodeGeneratorRequest) HasRequestedFiles() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest) SetRequestedFiles(v CodeGeneratorRequest_RequestedFile_List) error {
@@ -2889,10 +3244,35 @@ func (s CodeGeneratorRequest_List) At(i int) CodeGeneratorRequest {
 	return CodeGeneratorRequest{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CodeGeneratorRequest_List) AtChecked(i int) (CodeGeneratorRequest, error) {
+	p, err := s.List.StructChecked(i)
+	return CodeGeneratorRequest{p}, err
+}
+
 func (s CodeGeneratorRequest_List) Set(i int, v CodeGeneratorRequest) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CodeGeneratorRequest_List) ToSlice() []CodeGeneratorRequest {
+	v := make([]CodeGeneratorRequest, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CodeGeneratorRequest_List) Range(f func(i int, v CodeGeneratorRequest) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // CodeGeneratorRequest_Promise is a wrapper for a CodeGeneratorRequest promised by a client call.
 type CodeGeneratorRequest_Promise struct{ *capnp.Pipeline }
 
@@ -2936,7 +3316,7 @@ func (s CodeGeneratorRequest_RequestedFile) Filename() (string, error) {
 
 func (s CodeGeneratorRequest_RequestedFile) HasFilename() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest_RequestedFile) FilenameBytes() ([]byte, error) {
@@ -2955,7 +3335,7 @@ func (s CodeGeneratorRequest_RequestedFile) Imports() (CodeGeneratorRequest_Requ
 
 func (s CodeGeneratorRequest_RequestedFile) HasImports() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest_RequestedFile) SetImports(v CodeGeneratorRequest_RequestedFile_Import_List) error {
@@ -2986,10 +3366,35 @@ func (s CodeGeneratorRequest_RequestedFile_List) At(i int) CodeGeneratorRequest_
 	return CodeGeneratorRequest_RequestedFile{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CodeGeneratorRequest_RequestedFile_List) AtChecked(i int) (CodeGeneratorRequest_RequestedFile, error) {
+	p, err := s.List.StructChecked(i)
+	return CodeGeneratorRequest_RequestedFile{p}, err
+}
+
 func (s CodeGeneratorRequest_RequestedFile_List) Set(i int, v CodeGeneratorRequest_RequestedFile) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CodeGeneratorRequest_RequestedFile_List) ToSlice() []CodeGeneratorRequest_RequestedFile {
+	v := make([]CodeGeneratorRequest_RequestedFile, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CodeGeneratorRequest_RequestedFile_List) Range(f func(i int, v CodeGeneratorRequest_RequestedFile) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // CodeGeneratorRequest_RequestedFile_Promise is a wrapper for a CodeGeneratorRequest_RequestedFile promised by a client call.
 type CodeGeneratorRequest_RequestedFile_Promise struct{ *capnp.Pipeline }
 
@@ -3033,7 +3438,7 @@ func (s CodeGeneratorRequest_RequestedFile_Import) Name() (string, error) {
 
 func (s CodeGeneratorRequest_RequestedFile_Import) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest_RequestedFile_Import) NameBytes() ([]byte, error) {
@@ -3058,10 +3463,35 @@ func (s CodeGeneratorRequest_RequestedFile_Import_List) At(i int) CodeGeneratorR
 	return CodeGeneratorRequest_RequestedFile_Import{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CodeGeneratorRequest_RequestedFile_Import_List) AtChecked(i int) (CodeGeneratorRequest_RequestedFile_Import, error) {
+	p, err := s.List.StructChecked(i)
+	return CodeGeneratorRequest_RequestedFile_Import{p}, err
+}
+
 func (s CodeGeneratorRequest_RequestedFile_Import_List) Set(i int, v CodeGeneratorRequest_RequestedFile_Import) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CodeGeneratorRequest_RequestedFile_Import_List) ToSlice() []CodeGeneratorRequest_RequestedFile_Import {
+	v := make([]CodeGeneratorRequest_RequestedFile_Import, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CodeGeneratorRequest_RequestedFile_Import_List) Range(f func(i int, v CodeGeneratorRequest_RequestedFile_Import) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 // CodeGeneratorRequest_RequestedFile_Import_Promise is a wrapper for a CodeGeneratorRequest_RequestedFile_Import promised by a client call.
 type CodeGeneratorRequest_RequestedFile_Import_Promise struct{ *capnp.Pipeline }
 
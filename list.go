@@ -2,7 +2,9 @@ package capnp
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"sort"
 	"strconv"
 
 	"zombiezen.com/go/capnproto2/internal/strquote"
@@ -43,6 +45,9 @@ func NewCompositeList(s *Segment, sz ObjectSize, n int32) (List, error) {
 	if !sz.isValid() {
 		return List{}, errObjectSize
 	}
+	if err := s.msg.checkPointerCount(sz); err != nil {
+		return List{}, err
+	}
 	sz.DataSize = sz.DataSize.padToWord()
 	total, ok := sz.totalSize().times(n)
 	if !ok || total > maxSize-wordSize {
@@ -201,7 +206,7 @@ func (p List) Len() int {
 func (p List) primitiveElem(i int, expectedSize ObjectSize) (Address, error) {
 	if p.seg == nil || i < 0 || i >= int(p.length) {
 		// This is programmer error, not input error.
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	if p.flags&isBitList != 0 || p.flags&isCompositeList == 0 && p.size != expectedSize || p.flags&isCompositeList != 0 && (p.size.DataSize < expectedSize.DataSize || p.size.PointerCount < expectedSize.PointerCount) {
 		return 0, errElementSize
@@ -217,7 +222,7 @@ func (p List) primitiveElem(i int, expectedSize ObjectSize) (Address, error) {
 func (p List) Struct(i int) Struct {
 	if p.seg == nil || i < 0 || i >= int(p.length) {
 		// This is programmer error, not input error.
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	if p.flags&isBitList != 0 {
 		return Struct{}
@@ -235,6 +240,33 @@ func (p List) Struct(i int) Struct {
 	}
 }
 
+// StructChecked returns the i'th element as a struct, like Struct.
+// Instead of panicking on an out-of-range index or returning a null
+// struct for an element that cannot be read, such as when a bit list
+// is found where a struct list was expected, it returns an error.
+func (p List) StructChecked(i int) (Struct, error) {
+	if p.seg == nil || i < 0 || i >= int(p.length) {
+		return Struct{}, ErrOutOfBounds
+	}
+	if p.flags&isBitList != 0 {
+		return Struct{}, errBitListElement
+	}
+	addr, ok := p.off.element(int32(i), p.size.totalSize())
+	if !ok {
+		return Struct{}, errOverflow
+	}
+	if !p.seg.regionInBounds(addr, p.size.totalSize()) {
+		return Struct{}, ErrOutOfBounds
+	}
+	return Struct{
+		seg:        p.seg,
+		off:        addr,
+		size:       p.size,
+		flags:      isListMember,
+		depthLimit: p.depthLimit - 1,
+	}, nil
+}
+
 // SetStruct set the i'th element to the value in s.
 func (p List) SetStruct(i int, s Struct) error {
 	if p.flags&isBitList != 0 {
@@ -243,6 +275,104 @@ func (p List) SetStruct(i int, s Struct) error {
 	return copyStruct(p.Struct(i), s)
 }
 
+// Swap exchanges the i'th and j'th elements of the list in place.
+// Data is swapped byte for byte, and pointers are rewritten so that
+// they keep referring to the same objects.  Objects are not copied and
+// no memory is allocated.  If Swap returns an error, the list is not
+// changed.
+func (p List) Swap(i, j int) error {
+	if p.seg == nil || i < 0 || i >= int(p.length) || j < 0 || j >= int(p.length) {
+		// This is programmer error, not input error.
+		panic(ErrOutOfBounds)
+	}
+	if i == j {
+		return nil
+	}
+	if err := p.seg.writable(); err != nil {
+		return err
+	}
+	if p.flags&isBitList != 0 {
+		bl := BitList{p}
+		bi, bj := bl.At(i), bl.At(j)
+		bl.Set(i, bj)
+		bl.Set(j, bi)
+		return nil
+	}
+	sz := p.size.totalSize()
+	ai, ok := p.off.element(int32(i), sz)
+	if !ok {
+		return errOverflow
+	}
+	aj, ok := p.off.element(int32(j), sz)
+	if !ok {
+		return errOverflow
+	}
+	// swapPtrs checks every pointer before writing any of them.
+	for _, write := range []bool{false, true} {
+		for k := uint16(0); k < p.size.PointerCount; k++ {
+			off := p.size.DataSize + Size(k)*wordSize
+			pi, _ := ai.addSize(off) // element was already bounds checked
+			pj, _ := aj.addSize(off)
+			if err := swapPtrs(p.seg, pi, pj, write); err != nil {
+				return fmt.Errorf("capnp: swap list elements %d and %d: %v", i, j, err)
+			}
+		}
+	}
+	p.seg.modified()
+	di := p.seg.slice(ai, p.size.DataSize)
+	dj := p.seg.slice(aj, p.size.DataSize)
+	for k := range di {
+		di[k], dj[k] = dj[k], di[k]
+	}
+	return nil
+}
+
+// swapPtrs exchanges the pointer words at a1 and a2 in s, adjusting
+// the offsets of near pointers so that they refer to the same objects.
+// If write is false, it only reports whether the pointers can be
+// swapped.
+func swapPtrs(s *Segment, a1, a2 Address, write bool) error {
+	w1, err := movedPointer(s.readRawPointer(a1), a1, a2)
+	if err != nil {
+		return err
+	}
+	w2, err := movedPointer(s.readRawPointer(a2), a2, a1)
+	if err != nil {
+		return err
+	}
+	if write {
+		s.writeRawPointer(a1, w2)
+		s.writeRawPointer(a2, w1)
+	}
+	return nil
+}
+
+// SortList sorts the elements of l in place using List.Swap.  less
+// reports whether element i should sort before element j, and will
+// typically read the elements from l.  The sort is not stable.  If a
+// swap fails, SortList stops swapping and returns the error, leaving
+// l partially sorted.
+func SortList(l List, less func(i, j int) bool) error {
+	ls := &listSorter{l: l, less: less}
+	sort.Sort(ls)
+	return ls.err
+}
+
+type listSorter struct {
+	l    List
+	less func(i, j int) bool
+	err  error
+}
+
+func (ls *listSorter) Len() int           { return ls.l.Len() }
+func (ls *listSorter) Less(i, j int) bool { return ls.less(i, j) }
+
+func (ls *listSorter) Swap(i, j int) {
+	if ls.err == nil {
+		ls.err = ls.l.Swap(i, j)
+	}
+}
+
 // A BitList is a reference to a list of booleans.
 type BitList struct{ List }
 
@@ -265,7 +395,7 @@ func NewBitList(s *Segment, n int32) (BitList, error) {
 func (p BitList) At(i int) bool {
 	if p.seg == nil || i < 0 || i >= int(p.length) {
 		// This is programmer error, not input error.
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	if p.flags&isBitList == 0 {
 		return false
@@ -279,7 +409,7 @@ func (p BitList) At(i int) bool {
 func (p BitList) Set(i int, v bool) {
 	if p.seg == nil || i < 0 || i >= int(p.length) {
 		// This is programmer error, not input error.
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	if p.flags&isBitList == 0 {
 		// Again, programmer error.  Should have used NewBitList.
@@ -368,6 +498,49 @@ func (p PointerList) SetPtr(i int, v Ptr) error {
 	return p.seg.writePtr(addr, v, false)
 }
 
+// ListAt returns the i'th element in the list as a list.  This is
+// used for lists of lists, like List(List(Int32)).
+func (p PointerList) ListAt(i int) (List, error) {
+	ptr, err := p.PtrAt(i)
+	if err != nil {
+		return List{}, err
+	}
+	return ptr.List(), nil
+}
+
+// SetList sets the i'th pointer in the list to l.
+func (p PointerList) SetList(i int, l List) error {
+	return p.SetPtr(i, l.ToPtr())
+}
+
+// NewListAt allocates a list of n elements, preferring placement in the
+// pointer list's segment, and sets the i'th pointer in the list to it.
+// sz is the size of an element: pointer lists have a size of
+// ObjectSize{PointerCount: 1}, primitive lists have a data size of 0,
+// 1, 2, 4, or 8 bytes and no pointers, and any other size allocates a
+// composite list.  Use NewBitList and SetList for lists of Bool.
+func (p PointerList) NewListAt(i int, sz ObjectSize, n int32) (List, error) {
+	var l List
+	var err error
+	switch {
+	case sz == ObjectSize{PointerCount: 1}:
+		var pl PointerList
+		pl, err = NewPointerList(p.seg, n)
+		l = pl.List
+	case sz.PointerCount == 0 && (sz.DataSize == 0 || sz.DataSize == 1 || sz.DataSize == 2 || sz.DataSize == 4 || sz.DataSize == 8):
+		l, err = newPrimitiveList(p.seg, sz.DataSize, n)
+	default:
+		l, err = NewCompositeList(p.seg, sz, n)
+	}
+	if err != nil {
+		return List{}, err
+	}
+	if err := p.SetList(i, l); err != nil {
+		return List{}, err
+	}
+	return l, nil
+}
+
 // TextList is an array of pointers to strings.
 type TextList struct{ List }
 
@@ -423,6 +596,45 @@ func (l TextList) Set(i int, v string) error {
 	return l.seg.writePtr(addr, p.List.ToPtr(), false)
 }
 
+// FromStringSlice allocates a new list of text pointers in s, preferring
+// placement in s, and sets its elements to the strings in v.  As with
+// Set, empty strings are stored as null pointers, which read back as
+// empty strings.  Embedded NUL bytes are preserved: At and
+// ToStringSlice return every byte up to the terminating NUL.  Cap'n
+// Proto text is not supposed to contain NULs, so other implementations
+// may truncate such strings; use a DataList for arbitrary bytes.
+func FromStringSlice(s *Segment, v []string) (TextList, error) {
+	if int64(len(v)) > math.MaxInt32 {
+		return TextList{}, errOverflow
+	}
+	l, err := NewTextList(s, int32(len(v)))
+	if err != nil {
+		return TextList{}, err
+	}
+	for i, str := range v {
+		if err := l.Set(i, str); err != nil {
+			return TextList{}, err
+		}
+	}
+	return l, nil
+}
+
+// ToStringSlice returns the list's elements as a slice of strings.  An
+// empty or null list returns a nil slice.
+func (l TextList) ToStringSlice() ([]string, error) {
+	if l.Len() == 0 {
+		return nil, nil
+	}
+	v := make([]string, l.Len())
+	for i := range v {
+		var err error
+		if v[i], err = l.At(i); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
 // String returns the list in Cap'n Proto schema format (e.g. `["foo", "bar"]`).
 func (l TextList) String() string {
 	var buf []byte
@@ -548,6 +760,7 @@ func NewText(s *Segment, v string) (UInt8List, error) {
 	if err != nil {
 		return UInt8List{}, err
 	}
+	l.seg.modified()
 	copy(l.seg.slice(l.off, Size(len(v))), v)
 	return l, nil
 }
@@ -559,6 +772,7 @@ func NewTextFromBytes(s *Segment, v []byte) (UInt8List, error) {
 	if err != nil {
 		return UInt8List{}, err
 	}
+	l.seg.modified()
 	copy(l.seg.slice(l.off, Size(len(v))), v)
 	return l, nil
 }
@@ -570,6 +784,7 @@ func NewData(s *Segment, v []byte) (UInt8List, error) {
 	if err != nil {
 		return UInt8List{}, err
 	}
+	l.seg.modified()
 	copy(l.seg.slice(l.off, Size(len(v))), v)
 	return l, nil
 }
@@ -638,6 +853,33 @@ func (l UInt8List) String() string {
 	return string(buf)
 }
 
+// ToUint64Slice returns the elements of l as a newly allocated slice.
+func (l UInt8List) ToUint64Slice() []uint64 {
+	v := make([]uint64, l.Len())
+	for i := range v {
+		v[i] = uint64(l.At(i))
+	}
+	return v
+}
+
+// FromUint64Slice sets the elements of l to the values in v.  It returns an
+// error without modifying l if len(v) != l.Len() or if any value in v
+// does not fit in uint8.
+func (l UInt8List) FromUint64Slice(v []uint64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for _, x := range v {
+		if x > math.MaxUint8 {
+			return errListValueRange
+		}
+	}
+	for i, x := range v {
+		l.Set(i, uint8(x))
+	}
+	return nil
+}
+
 // Int8List is an array of Int8 values.
 type Int8List struct{ List }
 
@@ -682,6 +924,33 @@ func (l Int8List) String() string {
 	return string(buf)
 }
 
+// ToInt64Slice returns the elements of l as a newly allocated slice.
+func (l Int8List) ToInt64Slice() []int64 {
+	v := make([]int64, l.Len())
+	for i := range v {
+		v[i] = int64(l.At(i))
+	}
+	return v
+}
+
+// FromInt64Slice sets the elements of l to the values in v.  It returns an
+// error without modifying l if len(v) != l.Len() or if any value in v
+// does not fit in int8.
+func (l Int8List) FromInt64Slice(v []int64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for _, x := range v {
+		if x < math.MinInt8 || x > math.MaxInt8 {
+			return errListValueRange
+		}
+	}
+	for i, x := range v {
+		l.Set(i, int8(x))
+	}
+	return nil
+}
+
 // A UInt16List is an array of UInt16 values.
 type UInt16List struct{ List }
 
@@ -726,6 +995,33 @@ func (l UInt16List) String() string {
 	return string(buf)
 }
 
+// ToUint64Slice returns the elements of l as a newly allocated slice.
+func (l UInt16List) ToUint64Slice() []uint64 {
+	v := make([]uint64, l.Len())
+	for i := range v {
+		v[i] = uint64(l.At(i))
+	}
+	return v
+}
+
+// FromUint64Slice sets the elements of l to the values in v.  It returns an
+// error without modifying l if len(v) != l.Len() or if any value in v
+// does not fit in uint16.
+func (l UInt16List) FromUint64Slice(v []uint64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for _, x := range v {
+		if x > math.MaxUint16 {
+			return errListValueRange
+		}
+	}
+	for i, x := range v {
+		l.Set(i, uint16(x))
+	}
+	return nil
+}
+
 // Int16List is an array of Int16 values.
 type Int16List struct{ List }
 
@@ -770,6 +1066,33 @@ func (l Int16List) String() string {
 	return string(buf)
 }
 
+// ToInt64Slice returns the elements of l as a newly allocated slice.
+func (l Int16List) ToInt64Slice() []int64 {
+	v := make([]int64, l.Len())
+	for i := range v {
+		v[i] = int64(l.At(i))
+	}
+	return v
+}
+
+// FromInt64Slice sets the elements of l to the values in v.  It returns an
+// error without modifying l if len(v) != l.Len() or if any value in v
+// does not fit in int16.
+func (l Int16List) FromInt64Slice(v []int64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for _, x := range v {
+		if x < math.MinInt16 || x > math.MaxInt16 {
+			return errListValueRange
+		}
+	}
+	for i, x := range v {
+		l.Set(i, int16(x))
+	}
+	return nil
+}
+
 // UInt32List is an array of UInt32 values.
 type UInt32List struct{ List }
 
@@ -814,6 +1137,33 @@ func (l UInt32List) String() string {
 	return string(buf)
 }
 
+// ToUint64Slice returns the elements of l as a newly allocated slice.
+func (l UInt32List) ToUint64Slice() []uint64 {
+	v := make([]uint64, l.Len())
+	for i := range v {
+		v[i] = uint64(l.At(i))
+	}
+	return v
+}
+
+// FromUint64Slice sets the elements of l to the values in v.  It returns an
+// error without modifying l if len(v) != l.Len() or if any value in v
+// does not fit in uint32.
+func (l UInt32List) FromUint64Slice(v []uint64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for _, x := range v {
+		if x > math.MaxUint32 {
+			return errListValueRange
+		}
+	}
+	for i, x := range v {
+		l.Set(i, uint32(x))
+	}
+	return nil
+}
+
 // Int32List is an array of Int32 values.
 type Int32List struct{ List }
 
@@ -858,6 +1208,33 @@ func (l Int32List) String() string {
 	return string(buf)
 }
 
+// ToInt64Slice returns the elements of l as a newly allocated slice.
+func (l Int32List) ToInt64Slice() []int64 {
+	v := make([]int64, l.Len())
+	for i := range v {
+		v[i] = int64(l.At(i))
+	}
+	return v
+}
+
+// FromInt64Slice sets the elements of l to the values in v.  It returns an
+// error without modifying l if len(v) != l.Len() or if any value in v
+// does not fit in int32.
+func (l Int32List) FromInt64Slice(v []int64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for _, x := range v {
+		if x < math.MinInt32 || x > math.MaxInt32 {
+			return errListValueRange
+		}
+	}
+	for i, x := range v {
+		l.Set(i, int32(x))
+	}
+	return nil
+}
+
 // UInt64List is an array of UInt64 values.
 type UInt64List struct{ List }
 
@@ -902,6 +1279,27 @@ func (l UInt64List) String() string {
 	return string(buf)
 }
 
+// ToUint64Slice returns the elements of l as a newly allocated slice.
+func (l UInt64List) ToUint64Slice() []uint64 {
+	v := make([]uint64, l.Len())
+	for i := range v {
+		v[i] = l.At(i)
+	}
+	return v
+}
+
+// FromUint64Slice sets the elements of l to the values in v.  It returns an
+// error if len(v) != l.Len().
+func (l UInt64List) FromUint64Slice(v []uint64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for i, x := range v {
+		l.Set(i, x)
+	}
+	return nil
+}
+
 // Int64List is an array of Int64 values.
 type Int64List struct{ List }
 
@@ -946,6 +1344,27 @@ func (l Int64List) String() string {
 	return string(buf)
 }
 
+// ToInt64Slice returns the elements of l as a newly allocated slice.
+func (l Int64List) ToInt64Slice() []int64 {
+	v := make([]int64, l.Len())
+	for i := range v {
+		v[i] = l.At(i)
+	}
+	return v
+}
+
+// FromInt64Slice sets the elements of l to the values in v.  It returns an
+// error if len(v) != l.Len().
+func (l Int64List) FromInt64Slice(v []int64) error {
+	if len(v) != l.Len() {
+		return errListLength
+	}
+	for i, x := range v {
+		l.Set(i, x)
+	}
+	return nil
+}
+
 // Float32List is an array of Float32 values.
 type Float32List struct{ List }
 
@@ -1034,6 +1453,150 @@ func (l Float64List) String() string {
 	return string(buf)
 }
 
+// SplitList copies l into a sequence of new messages, each of whose
+// root is a list holding the next n or fewer elements of l.  This lets
+// a list that is too large for a single frame be sent as several
+// independent messages; JoinLists reverses the operation.  An empty
+// list produces a single message with an empty list as its root.
+func SplitList(l List, n int) ([]*Message, error) {
+	if n <= 0 {
+		return nil, errors.New("capnp: split list into chunks of non-positive size")
+	}
+	var msgs []*Message
+	for start := 0; start < l.Len() || start == 0; start += n {
+		end := start + n
+		if end > l.Len() {
+			end = l.Len()
+		}
+		msg, seg, err := NewMessage(SingleSegment(nil))
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := newListLike(seg, l, l.size, int32(end-start))
+		if err != nil {
+			return nil, err
+		}
+		if err := copyListElems(chunk, 0, l, start, end-start); err != nil {
+			return nil, err
+		}
+		if err := msg.SetRootPtr(chunk.ToPtr()); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// JoinLists returns a new message whose root is the concatenation of
+// the root lists of msgs, which must all have the same kind of
+// elements.  It reassembles the output of SplitList.
+func JoinLists(msgs []*Message) (*Message, error) {
+	if len(msgs) == 0 {
+		return nil, errors.New("capnp: join lists: no messages")
+	}
+	lists := make([]List, len(msgs))
+	var total int64
+	var sz ObjectSize
+	for i, m := range msgs {
+		p, err := m.RootPtr()
+		if err != nil {
+			return nil, fmt.Errorf("capnp: join lists: message %d: %v", i, err)
+		}
+		l := p.List()
+		if !l.IsValid() {
+			return nil, fmt.Errorf("capnp: join lists: message %d: root is not a list", i)
+		}
+		if i > 0 && (l.flags != lists[0].flags || l.flags&isCompositeList == 0 && l.size != sz) {
+			return nil, fmt.Errorf("capnp: join lists: message %d: %v", i, errElementSize)
+		}
+		if l.size.DataSize > sz.DataSize {
+			sz.DataSize = l.size.DataSize
+		}
+		if l.size.PointerCount > sz.PointerCount {
+			sz.PointerCount = l.size.PointerCount
+		}
+		lists[i] = l
+		total += int64(l.Len())
+	}
+	if total > maxInt32 {
+		return nil, errOverflow
+	}
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	joined, err := newListLike(seg, lists[0], sz, int32(total))
+	if err != nil {
+		return nil, err
+	}
+	off := 0
+	for i, l := range lists {
+		if err := copyListElems(joined, off, l, 0, l.Len()); err != nil {
+			return nil, fmt.Errorf("capnp: join lists: message %d: %v", i, err)
+		}
+		off += l.Len()
+	}
+	if err := msg.SetRootPtr(joined.ToPtr()); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// newListLike allocates a list of n elements with the same encoding as
+// l, using sz as the element size.
+func newListLike(s *Segment, l List, sz ObjectSize, n int32) (List, error) {
+	switch {
+	case l.flags&isBitList != 0:
+		bl, err := NewBitList(s, n)
+		return bl.List, err
+	case l.flags&isCompositeList != 0:
+		return NewCompositeList(s, sz, n)
+	case sz.PointerCount > 0:
+		pl, err := NewPointerList(s, n)
+		return pl.List, err
+	default:
+		return newPrimitiveList(s, sz.DataSize, n)
+	}
+}
+
+// copyListElems copies n elements of src starting at index si into dst
+// starting at index di.  dst must have been created by newListLike.
+func copyListElems(dst List, di int, src List, si, n int) error {
+	switch {
+	case src.flags&isBitList != 0:
+		for i := 0; i < n; i++ {
+			BitList{dst}.Set(di+i, BitList{src}.At(si+i))
+		}
+	case src.flags&isCompositeList != 0:
+		for i := 0; i < n; i++ {
+			if err := dst.SetStruct(di+i, src.Struct(si+i)); err != nil {
+				return fmt.Errorf("element %d: %v", si+i, err)
+			}
+		}
+	case src.size.PointerCount > 0:
+		for i := 0; i < n; i++ {
+			p, err := PointerList{src}.PtrAt(si + i)
+			if err != nil {
+				return fmt.Errorf("element %d: %v", si+i, err)
+			}
+			if err := (PointerList{dst}).SetPtr(di+i, p); err != nil {
+				return fmt.Errorf("element %d: %v", si+i, err)
+			}
+		}
+	default:
+		if n == 0 || src.size.DataSize == 0 {
+			return nil
+		}
+		sz := src.size.DataSize
+		from, _ := src.off.element(int32(si), sz) // list was already validated
+		to, _ := dst.off.element(int32(di), sz)
+		total, _ := sz.times(int32(n))
+		dst.seg.modified()
+		copy(dst.seg.slice(to, total), src.seg.slice(from, total))
+	}
+	return nil
+}
+
 type listFlags uint8
 
 const (
@@ -1041,4 +1604,9 @@ const (
 	isBitList
 )
 
-var errBitListStruct = errors.New("capnp: SetStruct called on bit list")
+var (
+	errBitListStruct  = errors.New("capnp: SetStruct called on bit list")
+	errBitListElement = errors.New("capnp: struct element of bit list")
+	errListLength     = errors.New("capnp: slice length does not match list length")
+	errListValueRange = errors.New("capnp: value out of range for list element type")
+)
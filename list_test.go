@@ -2,6 +2,9 @@ package capnp
 
 import (
 	"bytes"
+	"fmt"
+	"math"
+	"strconv"
 	"testing"
 )
 
@@ -79,6 +82,56 @@ func TestTextListBytesAt(t *testing.T) {
 	}
 }
 
+func TestTextListStringSlice(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tags", "", "a/b/c", "nul\x00inside", "\x00"}
+	l, err := FromStringSlice(seg, want)
+	if err != nil {
+		t.Fatal("FromStringSlice:", err)
+	}
+	if err := msg.SetRootList(l.List); err != nil {
+		t.Fatal("SetRootList:", err)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg, err = Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	root, err := msg.RootList()
+	if err != nil {
+		t.Fatal("RootList:", err)
+	}
+	got, err := TextList{root}.ToStringSlice()
+	if err != nil {
+		t.Fatal("ToStringSlice:", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ToStringSlice() = %q; want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ToStringSlice()[%d] = %q; want %q", i, got[i], want[i])
+		}
+	}
+
+	empty, err := FromStringSlice(seg, nil)
+	if err != nil {
+		t.Fatal("FromStringSlice(nil):", err)
+	}
+	if got, err := empty.ToStringSlice(); err != nil || got != nil {
+		t.Errorf("empty.ToStringSlice() = %q, %v; want [], <nil>", got, err)
+	}
+	if got, err := (TextList{}).ToStringSlice(); err != nil || got != nil {
+		t.Errorf("TextList{}.ToStringSlice() = %q, %v; want [], <nil>", got, err)
+	}
+}
+
 func TestListRaw(t *testing.T) {
 	_, seg, err := NewMessage(SingleSegment(nil))
 	if err != nil {
@@ -128,3 +181,426 @@ func TestListRaw(t *testing.T) {
 		}
 	}
 }
+
+func TestInt32ListInt64Slice(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewInt32List(seg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{math.MinInt32, -1, math.MaxInt32}
+	if err := l.FromInt64Slice(want); err != nil {
+		t.Fatalf("FromInt64Slice(%v): %v", want, err)
+	}
+	if got := l.ToInt64Slice(); !int64sEqual(got, want) {
+		t.Errorf("ToInt64Slice() = %v; want %v", got, want)
+	}
+
+	bad := [][]int64{
+		{0, 0, math.MaxInt32 + 1},
+		{math.MinInt32 - 1, 0, 0},
+		{0, 0},
+		{0, 0, 0, 0},
+	}
+	for _, v := range bad {
+		if err := l.FromInt64Slice(v); err == nil {
+			t.Errorf("FromInt64Slice(%v) = nil; want error", v)
+		}
+		if got := l.ToInt64Slice(); !int64sEqual(got, want) {
+			t.Errorf("after FromInt64Slice(%v), ToInt64Slice() = %v; want %v (unchanged)", v, got, want)
+		}
+	}
+}
+
+func TestUIntListUint64Slice(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l8, err := NewUInt8List(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l8.FromUint64Slice([]uint64{0, math.MaxUint8}); err != nil {
+		t.Errorf("UInt8List.FromUint64Slice([0 %d]): %v", math.MaxUint8, err)
+	}
+	if err := l8.FromUint64Slice([]uint64{0, math.MaxUint8 + 1}); err == nil {
+		t.Errorf("UInt8List.FromUint64Slice([0 %d]) = nil; want error", math.MaxUint8+1)
+	}
+	if got, want := l8.ToUint64Slice(), []uint64{0, math.MaxUint8}; !uint64sEqual(got, want) {
+		t.Errorf("UInt8List.ToUint64Slice() = %v; want %v", got, want)
+	}
+
+	l32, err := NewUInt32List(seg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l32.FromUint64Slice([]uint64{math.MaxUint32}); err != nil {
+		t.Errorf("UInt32List.FromUint64Slice([%d]): %v", uint64(math.MaxUint32), err)
+	}
+	if err := l32.FromUint64Slice([]uint64{math.MaxUint32 + 1}); err == nil {
+		t.Errorf("UInt32List.FromUint64Slice([%d]) = nil; want error", uint64(math.MaxUint32+1))
+	}
+	if got, want := l32.ToUint64Slice(), []uint64{math.MaxUint32}; !uint64sEqual(got, want) {
+		t.Errorf("UInt32List.ToUint64Slice() = %v; want %v", got, want)
+	}
+}
+
+func TestPointerListOfLists(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err := NewPointerList(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.SetRootPtr(outer.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	// Row 0 is allocated by NewListAt, row 1 by hand and set with SetList.
+	l, err := outer.NewListAt(0, ObjectSize{DataSize: 4}, 3)
+	if err != nil {
+		t.Fatal("NewListAt(0, ...):", err)
+	}
+	row0 := Int32List{l}
+	row1, err := NewInt32List(outer.Segment(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outer.SetList(1, row1.List); err != nil {
+		t.Fatal("SetList(1, ...):", err)
+	}
+	for j := 0; j < 3; j++ {
+		row0.Set(j, int32(j))
+		row1.Set(j, int32(10+j))
+	}
+
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg, err = Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	root, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	outer = PointerList{root.List()}
+	if outer.Len() != 2 {
+		t.Fatalf("outer.Len() = %d; want 2", outer.Len())
+	}
+	for i := 0; i < 2; i++ {
+		l, err := outer.ListAt(i)
+		if err != nil {
+			t.Fatalf("outer.ListAt(%d): %v", i, err)
+		}
+		row := Int32List{l}
+		if row.Len() != 3 {
+			t.Errorf("len(list[%d]) = %d; want 3", i, row.Len())
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			if got, want := row.At(j), int32(10*i+j); got != want {
+				t.Errorf("list[%d][%d] = %d; want %d", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestSplitJoinLists(t *testing.T) {
+	const n = 10000
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ints, err := NewInt32List(seg, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		ints.Set(i, int32(i*7))
+		structs.Struct(i).SetUint64(0, uint64(i))
+		if err := structs.Struct(i).SetText(0, strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		list  List
+		check func(l List, i int) error
+	}{
+		{"Int32", ints.List, func(l List, i int) error {
+			if got := (Int32List{l}).At(i); got != int32(i*7) {
+				return fmt.Errorf("= %d; want %d", got, i*7)
+			}
+			return nil
+		}},
+		{"struct", structs, func(l List, i int) error {
+			s := l.Struct(i)
+			txt, err := s.Ptr(0)
+			if err != nil {
+				return err
+			}
+			if s.Uint64(0) != uint64(i) || txt.Text() != strconv.Itoa(i) {
+				return fmt.Errorf("= (%d, %q); want (%d, %q)", s.Uint64(0), txt.Text(), i, strconv.Itoa(i))
+			}
+			return nil
+		}},
+	}
+	for _, test := range tests {
+		const chunkSize = 777
+		chunks, err := SplitList(test.list, chunkSize)
+		if err != nil {
+			t.Errorf("%s: SplitList: %v", test.name, err)
+			continue
+		}
+		if want := (n + chunkSize - 1) / chunkSize; len(chunks) != want {
+			t.Errorf("%s: SplitList returned %d messages; want %d", test.name, len(chunks), want)
+		}
+		// Send each chunk through its own frame.
+		for i, msg := range chunks {
+			data, err := msg.Marshal()
+			if err != nil {
+				t.Fatalf("%s: chunk %d: Marshal: %v", test.name, i, err)
+			}
+			if chunks[i], err = Unmarshal(data); err != nil {
+				t.Fatalf("%s: chunk %d: Unmarshal: %v", test.name, i, err)
+			}
+		}
+		msg, err := JoinLists(chunks)
+		if err != nil {
+			t.Errorf("%s: JoinLists: %v", test.name, err)
+			continue
+		}
+		root, err := msg.RootPtr()
+		if err != nil {
+			t.Errorf("%s: RootPtr: %v", test.name, err)
+			continue
+		}
+		l := root.List()
+		if l.Len() != n {
+			t.Errorf("%s: joined list has %d elements; want %d", test.name, l.Len(), n)
+			continue
+		}
+		for i := 0; i < n; i++ {
+			if err := test.check(l, i); err != nil {
+				t.Errorf("%s: joined list[%d] %v", test.name, i, err)
+				break
+			}
+		}
+	}
+}
+
+func int64sEqual(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func uint64sEqual(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListSwap(t *testing.T) {
+	// Use small segments so that some element pointers are far pointers.
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(96)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 5
+	l, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		s := l.Struct(i)
+		s.SetUint64(0, uint64(i))
+		if err := s.SetText(0, "element "+strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if msg.NumSegments() < 2 {
+		t.Fatal("list text is not in another segment")
+	}
+
+	for i := 0; i < n/2; i++ {
+		if err := l.Swap(i, n-1-i); err != nil {
+			t.Fatalf("Swap(%d, %d): %v", i, n-1-i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		want := n - 1 - i
+		s := l.Struct(i)
+		if got := s.Uint64(0); got != uint64(want) {
+			t.Errorf("element %d: Uint64(0) = %d; want %d", i, got, want)
+		}
+		p, err := s.Ptr(0)
+		if err != nil {
+			t.Errorf("element %d: Ptr(0): %v", i, err)
+			continue
+		}
+		if got, wantText := p.Text(), "element "+strconv.Itoa(want); got != wantText {
+			t.Errorf("element %d: text = %q; want %q", i, got, wantText)
+		}
+	}
+}
+
+func TestListSwap_NoAllocation(t *testing.T) {
+	// Use small segments so that some element pointers are far pointers.
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(96)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 5
+	l, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := l.Struct(i).SetText(0, "element "+strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size := func() (nsegs int64, nbytes int) {
+		nsegs = msg.NumSegments()
+		for id := SegmentID(0); int64(id) < nsegs; id++ {
+			s, err := msg.Segment(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nbytes += len(s.Data())
+		}
+		return nsegs, nbytes
+	}
+	segs0, bytes0 := size()
+	for k := 0; k < 100; k++ {
+		i, j := k%n, (k*3+1)%n
+		if err := l.Swap(i, j); err != nil {
+			t.Fatalf("swap #%d (%d, %d): %v", k, i, j, err)
+		}
+	}
+	if segs, nbytes := size(); segs != segs0 || nbytes != bytes0 {
+		t.Errorf("after 100 swaps, message has %d segments and %d bytes; want %d and %d", segs, nbytes, segs0, bytes0)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		p, err := l.Struct(i).Ptr(0)
+		if err != nil {
+			t.Fatalf("element %d: Ptr(0): %v", i, err)
+		}
+		seen[p.Text()] = true
+	}
+	if len(seen) != n {
+		t.Errorf("after swaps, elements have texts %v; want %d distinct texts", seen, n)
+	}
+}
+
+func TestListSwap_InvalidPointer(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 2}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		l.Struct(i).SetUint64(0, uint64(i+1))
+	}
+	// Element 1's second pointer points before the start of the segment.
+	paddr := l.Struct(1).pointerAddress(1)
+	seg.writeRawPointer(paddr, rawStructPointer(-1<<20, ObjectSize{DataSize: 8}))
+	before := append([]byte(nil), seg.Data()...)
+	if err := l.Swap(0, 1); err == nil {
+		t.Error("Swap with invalid pointer succeeded")
+	}
+	if !bytes.Equal(seg.Data(), before) {
+		t.Error("failed Swap changed the list")
+	}
+}
+
+func TestSortList(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := []int64{5, -3, 42, 0, 7, 7, -100}
+	l, err := NewInt64List(seg, int32(len(vals)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vals {
+		l.Set(i, v)
+	}
+	if err := SortList(l.List, func(i, j int) bool { return l.At(i) < l.At(j) }); err != nil {
+		t.Fatal("SortList:", err)
+	}
+	want := []int64{-100, -3, 0, 5, 7, 7, 42}
+	for i := range want {
+		if got := l.At(i); got != want[i] {
+			t.Errorf("sorted[%d] = %d; want %d", i, got, want[i])
+		}
+	}
+
+	tl, err := NewTextList(seg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range []string{"charlie", "alpha", "bravo"} {
+		tl.Set(i, s)
+	}
+	less := func(i, j int) bool {
+		a, _ := tl.At(i)
+		b, _ := tl.At(j)
+		return a < b
+	}
+	if err := SortList(tl.List, less); err != nil {
+		t.Fatal("SortList:", err)
+	}
+	for i, want := range []string{"alpha", "bravo", "charlie"} {
+		if got, _ := tl.At(i); got != want {
+			t.Errorf("sorted text[%d] = %q; want %q", i, got, want)
+		}
+	}
+
+	bl, err := NewBitList(seg, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bl.Set(0, true)
+	bl.Set(2, true)
+	if err := SortList(bl.List, func(i, j int) bool { return !bl.At(i) && bl.At(j) }); err != nil {
+		t.Fatal("SortList:", err)
+	}
+	for i, want := range []bool{false, false, true, true} {
+		if got := bl.At(i); got != want {
+			t.Errorf("sorted bits[%d] = %t; want %t", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package capnp
+
+import (
+	"errors"
+	"math"
+)
+
+// A ListBuilder builds a composite list whose length is not known up
+// front.  It allocates room for more elements than it has used and,
+// like Go's append, doubles the room whenever it runs out, so appending
+// n elements reallocates the list O(log n) times.  Finalize sets the
+// list's length to the number of elements appended.
+//
+// Reallocating moves the elements' words into the new list, like
+// realloc.  The objects that the elements point to are not copied:
+// pointers are re-encoded to refer to them from the new position, which
+// takes a landing pad if the new list is in another segment.  The old
+// list's space is not reclaimed: the message keeps it as unreachable
+// data.  Passing a capacity to NewListBuilder that is close to the
+// final length avoids this.
+type ListBuilder struct {
+	l     List  // allocated list; l.length is the capacity
+	n     int32 // number of elements appended
+	grows int   // number of reallocations, for testing
+	done  bool
+}
+
+// minListBuilderCap is the capacity that an empty ListBuilder grows to.
+const minListBuilderCap = 4
+
+// NewListBuilder returns a builder for a list of structs of size sz,
+// with room for capacity elements, preferring placement in s.
+func NewListBuilder(s *Segment, sz ObjectSize, capacity int32) (*ListBuilder, error) {
+	if capacity < 0 {
+		return nil, errors.New("capnp: new list builder: negative capacity")
+	}
+	l, err := NewCompositeList(s, sz, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &ListBuilder{l: l}, nil
+}
+
+// Len returns the number of elements that have been appended.
+func (b *ListBuilder) Len() int {
+	return int(b.n)
+}
+
+// Cap returns the number of elements that can be appended before the
+// list is reallocated.
+func (b *ListBuilder) Cap() int {
+	return int(b.l.length)
+}
+
+// At returns the i'th element that has been appended.  Like the struct
+// returned by Append, it is only valid until the next call to Append.
+func (b *ListBuilder) At(i int) Struct {
+	if i < 0 || i >= int(b.n) {
+		panic(ErrOutOfBounds)
+	}
+	return b.l.Struct(i)
+}
+
+// Append adds a zeroed element to the end of the list and returns it.
+// The returned struct is only valid until the next call to Append,
+// since the list may move to make room.  Use At to get an element
+// again after appending more.
+func (b *ListBuilder) Append() (Struct, error) {
+	if b.done {
+		return Struct{}, errListBuilderDone
+	}
+	if b.n == b.l.length {
+		if err := b.grow(); err != nil {
+			return Struct{}, err
+		}
+	}
+	b.n++
+	return b.l.Struct(int(b.n - 1)), nil
+}
+
+// grow reallocates the list with double the capacity.
+func (b *ListBuilder) grow() error {
+	c := int64(b.l.length) * 2
+	if c < minListBuilderCap {
+		c = minListBuilderCap
+	}
+	if c > math.MaxInt32 {
+		c = math.MaxInt32
+	}
+	if c == int64(b.l.length) {
+		return errOverflow
+	}
+	nl, err := NewCompositeList(b.l.seg, b.l.size, int32(c))
+	if err != nil {
+		return err
+	}
+	// Move the elements' words, then fix up their pointers.  The
+	// lists' sizes were checked when they were allocated.
+	sz := b.l.size.totalSize()
+	used, _ := sz.times(b.n)
+	nl.seg.modified()
+	copy(nl.seg.slice(nl.off, used), b.l.seg.slice(b.l.off, used))
+	for i := int32(0); i < b.n; i++ {
+		src, _ := b.l.off.element(i, sz)
+		dst, _ := nl.off.element(i, sz)
+		for k := uint16(0); k < b.l.size.PointerCount; k++ {
+			off := b.l.size.DataSize + Size(k)*wordSize
+			from, _ := src.addSize(off)
+			to, _ := dst.addSize(off)
+			if err := movePtr(nl.seg, to, b.l.seg, from); err != nil {
+				return err
+			}
+		}
+	}
+	b.l = nl
+	b.grows++
+	return nil
+}
+
+// movePtr moves the pointer stored at from in src to to in dst without
+// copying its object.  Far and capability pointers are copied as is.
+// A near pointer is re-encoded for its new position, or if dst is a
+// different segment, replaced with a far pointer to the object.
+func movePtr(dst *Segment, to Address, src *Segment, from Address) error {
+	raw := src.readRawPointer(from)
+	if dst == src {
+		moved, err := movedPointer(raw, from, to)
+		if err != nil {
+			return err
+		}
+		dst.writeRawPointer(to, moved)
+		return nil
+	}
+	if !raw.isNear() {
+		dst.writeRawPointer(to, raw)
+		return nil
+	}
+	p, err := src.readPtr(from, maxDepth)
+	if err != nil {
+		return err
+	}
+	return dst.writePtr(to, p, false)
+}
+
+// Finalize returns the list of the elements that have been appended.
+// If the unused room is at the end of its segment, it is given back to
+// the segment for later allocations.  The builder cannot be appended to
+// after calling Finalize.
+func (b *ListBuilder) Finalize() (List, error) {
+	if b.done {
+		return List{}, errListBuilderDone
+	}
+	b.done = true
+	l := b.l
+	l.seg.writeRawPointer(l.off-Address(wordSize), rawStructPointer(pointerOffset(b.n), l.size))
+	// The sizes were checked when the list was allocated.
+	used, _ := l.size.totalSize().times(b.n)
+	capacity, _ := l.size.totalSize().times(l.length)
+	usedEnd, _ := l.off.addSize(used)
+	if end, _ := l.off.addSize(capacity); end == Address(len(l.seg.data)) {
+		l.seg.data = l.seg.data[:usedEnd]
+	}
+	l.length = b.n
+	return l, nil
+}
+
+var errListBuilderDone = errors.New("capnp: list builder already finalized")
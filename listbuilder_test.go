@@ -0,0 +1,180 @@
+package capnp
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestListBuilder(t *testing.T) {
+	const n = 1000
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sz := ObjectSize{DataSize: 8, PointerCount: 1}
+	b, err := NewListBuilder(seg, sz, 0)
+	if err != nil {
+		t.Fatal("NewListBuilder:", err)
+	}
+	for i := 0; i < n; i++ {
+		s, err := b.Append()
+		if err != nil {
+			t.Fatalf("Append #%d: %v", i, err)
+		}
+		s.SetUint64(0, uint64(i))
+		if err := s.SetText(0, fmt.Sprint("elem", i)); err != nil {
+			t.Fatalf("SetText #%d: %v", i, err)
+		}
+	}
+	if b.Len() != n {
+		t.Errorf("b.Len() = %d; want %d", b.Len(), n)
+	}
+	if limit := math.Log2(n); float64(b.grows) >= limit {
+		t.Errorf("list was reallocated %d times; want < %.1f", b.grows, limit)
+	}
+	if b.Cap() < n {
+		t.Errorf("b.Cap() = %d; want >= %d", b.Cap(), n)
+	}
+	l, err := b.Finalize()
+	if err != nil {
+		t.Fatal("Finalize:", err)
+	}
+	if l.Len() != n {
+		t.Errorf("Finalize().Len() = %d; want %d", l.Len(), n)
+	}
+	if _, err := b.Append(); err == nil {
+		t.Error("Append after Finalize succeeded")
+	}
+	if err := msg.SetRootPtr(l.ToPtr()); err != nil {
+		t.Fatal("SetRootPtr:", err)
+	}
+
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg2, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	p, err := msg2.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	l2 := p.List()
+	if l2.Len() != n {
+		t.Fatalf("decoded list length = %d; want %d", l2.Len(), n)
+	}
+	for i := 0; i < n; i++ {
+		s := l2.Struct(i)
+		if v := s.Uint64(0); v != uint64(i) {
+			t.Errorf("element %d data = %d; want %d", i, v, i)
+		}
+		txt, err := s.Ptr(0)
+		if err != nil {
+			t.Errorf("element %d text: %v", i, err)
+			continue
+		}
+		if got, want := txt.Text(), fmt.Sprint("elem", i); got != want {
+			t.Errorf("element %d text = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestListBuilder_FinalizeTrims(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sz := ObjectSize{DataSize: 8}
+	b, err := NewListBuilder(seg, sz, 10)
+	if err != nil {
+		t.Fatal("NewListBuilder:", err)
+	}
+	start := len(seg.Data())
+	for i := 0; i < 3; i++ {
+		s, err := b.Append()
+		if err != nil {
+			t.Fatal("Append:", err)
+		}
+		s.SetUint64(0, uint64(i+1))
+	}
+	if b.grows != 0 {
+		t.Errorf("list was reallocated %d times within its capacity", b.grows)
+	}
+	l, err := b.Finalize()
+	if err != nil {
+		t.Fatal("Finalize:", err)
+	}
+	if got, want := len(seg.Data()), start-7*8; got != want {
+		t.Errorf("segment length after Finalize = %d; want %d", got, want)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Finalize().Len() = %d; want 3", l.Len())
+	}
+	for i := 0; i < 3; i++ {
+		if v := l.Struct(i).Uint64(0); v != uint64(i+1) {
+			t.Errorf("element %d = %d; want %d", i, v, i+1)
+		}
+	}
+}
+
+func TestListBuilder_GrowMovesPointers(t *testing.T) {
+	tests := []struct {
+		name     string
+		arena    Arena
+		otherSeg bool // whether the list grows into another segment
+	}{
+		{"single segment", SingleSegment(nil), false},
+		{"multiple segments", MultiSegment(nil, MaxSegmentSize(80)), true},
+	}
+	for _, test := range tests {
+		_, seg, err := NewMessage(test.arena)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		sz := ObjectSize{DataSize: 8, PointerCount: 1}
+		b, err := NewListBuilder(seg, sz, 1)
+		if err != nil {
+			t.Fatalf("%s: NewListBuilder: %v", test.name, err)
+		}
+		s, err := b.Append()
+		if err != nil {
+			t.Fatalf("%s: Append: %v", test.name, err)
+		}
+		s.SetUint64(0, 42)
+		if err := s.SetText(0, "hello"); err != nil {
+			t.Fatalf("%s: SetText: %v", test.name, err)
+		}
+		before, err := s.Ptr(0)
+		if err != nil {
+			t.Fatalf("%s: Ptr: %v", test.name, err)
+		}
+		if _, err := b.Append(); err != nil {
+			t.Fatalf("%s: Append: %v", test.name, err)
+		}
+		if b.grows != 1 {
+			t.Fatalf("%s: list was reallocated %d times; want 1", test.name, b.grows)
+		}
+		if otherSeg := b.l.seg != seg; otherSeg != test.otherSeg {
+			t.Fatalf("%s: list grew into another segment = %t; want %t", test.name, otherSeg, test.otherSeg)
+		}
+
+		s = b.At(0)
+		if v := s.Uint64(0); v != 42 {
+			t.Errorf("%s: element 0 data = %d; want 42", test.name, v)
+		}
+		after, err := s.Ptr(0)
+		if err != nil {
+			t.Fatalf("%s: Ptr after grow: %v", test.name, err)
+		}
+		if after.Text() != "hello" {
+			t.Errorf("%s: element 0 text = %q; want \"hello\"", test.name, after.Text())
+		}
+		if after.seg != before.seg || after.off != before.off {
+			t.Errorf("%s: text moved from segment %d offset %d to segment %d offset %d; want unchanged",
+				test.name, before.seg.ID(), before.off, after.seg.ID(), after.off)
+		}
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"sync"
+	"sync/atomic"
+	"unsafe"
 
 	"zombiezen.com/go/capnproto2/internal/packed"
 )
@@ -15,6 +18,7 @@ import (
 const (
 	defaultTraverseLimit = 64 << 20 // 64 MiB
 	defaultDepthLimit    = 64
+	defaultCopyDepth     = 512
 
 	maxStreamSegments = 512
 
@@ -25,7 +29,11 @@ const maxDepth = ^uint(0)
 
 // A Message is a tree of Cap'n Proto objects, split into one or more
 // segments of contiguous memory.  The only required field is Arena.
-// A Message is safe to read from multiple goroutines.
+//
+// A Message is safe to read from multiple goroutines, as long as no
+// goroutine modifies the message while it is being read.  Readers
+// share the message's traversal limit (see ReadLimiter), which is
+// updated atomically.
 type Message struct {
 	// rlimit must be first so that it is 64-bit aligned.
 	// See sync/atomic docs.
@@ -58,6 +66,44 @@ type Message struct {
 	// If not set, this defaults to 64.
 	DepthLimit uint
 
+	// CopyDepthLimit limits how deeply nested the objects can be that
+	// are copied into the message, and that Canonicalize, Validate,
+	// Capabilities, and Struct.RawBytes traverse in it.  These recurse
+	// once per level of nesting, so the limit keeps a pathological
+	// message from overflowing the stack.  Objects built in a message
+	// are not subject to DepthLimit, which only applies to reading.
+	// Exceeding the limit returns ErrDepthLimit.  If not set, this
+	// defaults to 512.
+	CopyDepthLimit uint
+
+	// CheckPtrOwnership makes setting a pointer to an object in a
+	// different message an error.  Normally such an object is copied
+	// into this message, which can hide bugs where a pointer from the
+	// wrong message is used by accident.  Ptr.Message reports which
+	// message a pointer belongs to.
+	CheckPtrOwnership bool
+
+	// CheckPtrOverlap makes setting a struct's pointer field to an
+	// object that overlaps the struct itself an error.  Such pointers
+	// can only arise from hand-built or corrupt messages, and writing
+	// through them later corrupts the struct.  The check costs a few
+	// comparisons per Struct.SetPtr call.
+	CheckPtrOverlap bool
+
+	// MaxPointerCount limits the number of pointers in each struct that
+	// NewStruct and NewCompositeList allocate in the message.  Exceeding
+	// it returns ErrTooManyPointers instead of allocating.  Generic
+	// builders that take struct sizes from untrusted input should set
+	// it, since the encoding allows up to 2^16-1 pointers per struct.
+	// If not set, any pointer count is allowed.
+	MaxPointerCount uint16
+
+	validated uint32 // atomic; see Validated
+
+	// readOnly is set for messages whose segments alias memory owned
+	// by someone else.  Writing to them returns errReadOnly.
+	readOnly bool
+
 	// mu protects the following fields:
 	mu       sync.Mutex
 	segs     map[SegmentID]*Segment
@@ -107,7 +153,9 @@ func (m *Message) Reset(arena Arena) {
 	m.CapTable = nil
 	m.segs = nil
 	m.firstSeg = Segment{}
+	m.readOnly = false
 	m.mu.Unlock()
+	m.clearValidated()
 	if m.TraverseLimit == 0 {
 		m.ReadLimiter().Reset(defaultTraverseLimit)
 	} else {
@@ -115,6 +163,44 @@ func (m *Message) Reset(arena Arena) {
 	}
 }
 
+// SetArena replaces the message's arena, keeping its capability table
+// and traversal limit.  It returns an error unless the message is
+// empty: its current arena is nil, has no segments, or has a single
+// empty segment.  Call Reset first to discard a message's contents.
+//
+// Swapping the arena out from under a message that holds data would
+// leave existing Structs, Lists, and Segments pointing into the old
+// arena's memory, so SetArena refuses to do so.
+func (m *Message) SetArena(arena Arena) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Arena != nil {
+		switch n := m.Arena.NumSegments(); {
+		case n > 1:
+			return errSetArenaHasData
+		case n == 1:
+			// Prefer the loaded segment, since its length reflects
+			// allocations that the arena may not know about.
+			var data []byte
+			if seg := m.segment(0); seg != nil {
+				data = seg.data
+			} else {
+				var err error
+				if data, err = m.Arena.Data(0); err != nil {
+					return err
+				}
+			}
+			if len(data) > 0 {
+				return errSetArenaHasData
+			}
+		}
+	}
+	m.Arena = arena
+	m.segs = nil
+	m.firstSeg = Segment{}
+	return nil
+}
+
 // Root returns the pointer to the message's root object.
 //
 // Deprecated: Use RootPtr.
@@ -129,7 +215,20 @@ func (m *Message) RootPtr() (Ptr, error) {
 	if err != nil {
 		return Ptr{}, err
 	}
-	return s.root().PtrAt(0)
+	root := s.root()
+	if root.Len() == 0 {
+		return Ptr{}, errNoRoot
+	}
+	p, err := root.PtrAt(0)
+	if err != nil {
+		return Ptr{}, err
+	}
+	// A malformed root pointer may point back at itself.  The object
+	// would then overwrite its own pointer when written to.
+	if start, end, ok := p.region(); ok && p.seg == s && start < end && start < Address(wordSize) {
+		return Ptr{}, errRootOverlap
+	}
+	return p, nil
 }
 
 // SetRoot sets the message's root object to p.
@@ -145,7 +244,38 @@ func (m *Message) SetRootPtr(p Ptr) error {
 	if err != nil {
 		return err
 	}
-	return s.root().SetPtr(0, p)
+	root := s.root()
+	if root.Len() == 0 {
+		return errNoRoot
+	}
+	return root.SetPtr(0, p)
+}
+
+// RootList returns the message's root object as a list.  Most messages
+// have a struct root, but a list is also a legal root, such as for a
+// file of records serialized as a single List(Foo).  RootList returns
+// an error if the root is a struct or an interface.  A null root yields
+// the zero List, like reading a null list field.
+func (m *Message) RootList() (List, error) {
+	p, err := m.RootPtr()
+	if err != nil {
+		return List{}, err
+	}
+	if p.IsValid() && p.flags.ptrType() != listPtrType {
+		return List{}, errNotList
+	}
+	return p.List(), nil
+}
+
+// SetRootList sets the message's root object to l.  It returns an error
+// if l is the zero List, since a null root can't be told apart from a
+// message that was never given one.  Like SetRootPtr, l is copied if it
+// belongs to a different message.
+func (m *Message) SetRootList(l List) error {
+	if !l.IsValid() {
+		return errNullRootList
+	}
+	return m.SetRootPtr(l.ToPtr())
 }
 
 // AddCap appends a capability to the message's capability table and
@@ -156,6 +286,228 @@ func (m *Message) AddCap(c Client) CapabilityID {
 	return n
 }
 
+// ResolveCap replaces the client at index id in the message's
+// capability table with resolved.  This is used when a promised
+// capability resolves: interface pointers in the message that refer to
+// id will return resolved from Interface.Client from then on.  The
+// previous client is not closed; it is up to the caller to release it.
+// Like AddCap, ResolveCap must not be called while the message is
+// being read from another goroutine.
+func (m *Message) ResolveCap(id CapabilityID, resolved Client) error {
+	if int64(id) >= int64(len(m.CapTable)) {
+		return errCapOutOfBounds
+	}
+	m.CapTable[id] = resolved
+	return nil
+}
+
+// Import appends copies of src's segments to m and returns a pointer to
+// src's root object inside m.  The pointer can then be set as a field
+// in m without a deep copy: storing it creates a far pointer to the
+// imported data.  Import adjusts the segment IDs of far pointers and
+// the indices of capability pointers in the imported segments, and
+// appends src's capability table to m's.
+//
+// Import copies segment data in bulk rather than object by object, so
+// it is much cheaper than a deep copy for large messages.  However,
+// objects in src that are not reachable from its root are carried along
+// as well.  m must use a MultiSegment arena.
+//
+// The objects reachable from src's root are checked as their pointers
+// are rewritten, counting against src's read limit and m's
+// CopyDepthLimit.  If src is invalid, Import returns an error and
+// leaves m unchanged.
+func (m *Message) Import(src *Message) (Ptr, error) {
+	if src == m {
+		return Ptr{}, errors.New("capnp: import message into itself")
+	}
+	nsrc := src.NumSegments()
+	if nsrc == 0 {
+		return Ptr{}, errMessageEmpty
+	}
+	m.mu.Lock()
+	sa, ok := m.Arena.(segmentAppender)
+	if !ok {
+		m.mu.Unlock()
+		return Ptr{}, errors.New("capnp: import requires a MultiSegment arena")
+	}
+	base := m.Arena.NumSegments()
+	if base+nsrc > 1<<32 {
+		m.mu.Unlock()
+		return Ptr{}, errors.New("capnp: import: too many segments")
+	}
+	m.mu.Unlock()
+
+	// Relocate copies of the segments, so that m is only changed once
+	// the whole import has been checked.
+	r := &relocator{
+		segs:    make([]*Segment, nsrc),
+		segBase: SegmentID(base),
+		capBase: CapabilityID(len(m.CapTable)),
+		rlimit:  src.ReadLimiter(),
+		seen:    make(map[relocKey]bool),
+	}
+	for i := range r.segs {
+		s, err := src.Segment(SegmentID(i))
+		if err != nil {
+			return Ptr{}, fmt.Errorf("capnp: import: %v", err)
+		}
+		r.segs[i] = &Segment{id: SegmentID(i), data: append([]byte(nil), s.data...)}
+	}
+	if len(r.segs[0].data) > 0 {
+		if err := r.relocatePtr(r.segs[0], 0, m.copyDepthLimit()); err != nil {
+			return Ptr{}, fmt.Errorf("capnp: import: %v", err)
+		}
+	}
+
+	m.mu.Lock()
+	for _, s := range r.segs {
+		sa.appendSegment(s.data)
+	}
+	m.mu.Unlock()
+	m.CapTable = append(m.CapTable, src.CapTable...)
+	root, err := m.Segment(SegmentID(base))
+	if err != nil {
+		return Ptr{}, fmt.Errorf("capnp: import: %v", err)
+	}
+	if len(root.data) == 0 {
+		return Ptr{}, nil
+	}
+	return root.root().PtrAt(0)
+}
+
+// A segmentAppender is an Arena that can add existing data as a new
+// segment.  The caller must be holding the message's mu.
+type segmentAppender interface {
+	appendSegment(data []byte)
+}
+
+// CapCount returns the number of entries in the message's capability
+// table.  This may include capabilities that are no longer referenced
+// by any pointer in the message; use Capabilities to find the ones
+// that are reachable from the root.
+func (m *Message) CapCount() int {
+	return len(m.CapTable)
+}
+
+// Capabilities traverses the message starting at the root and returns
+// the clients referenced by interface pointers, in the order they are
+// first encountered.  Each capability is returned at most once.  The
+// traversal does not count against the message's read limit.
+func (m *Message) Capabilities() ([]Client, error) {
+	var ids []CapabilityID
+	seen := make(map[CapabilityID]bool)
+	err := m.uncounted(func() error {
+		root, err := m.RootPtr()
+		if err != nil {
+			return err
+		}
+		return walkPtr(root, m.copyDepthLimit(), func(p Ptr) error {
+			if p.flags.ptrType() != interfacePtrType {
+				return nil
+			}
+			id := p.Interface().Capability()
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	clients := make([]Client, len(ids))
+	for i, id := range ids {
+		if int64(id) < int64(len(m.CapTable)) {
+			clients[i] = m.CapTable[id]
+		}
+	}
+	return clients, nil
+}
+
+// WalkPointers calls visit for every non-null pointer reachable from
+// root, without needing root's schema.  The walk is depth first: each
+// pointer in root's pointer section is visited, followed by the objects
+// reachable from it, before moving on to the next.  A struct's pointers
+// and the elements of a list of structs or pointers are walked in
+// order.  root itself is not visited.  If visit returns an error, the
+// walk stops and WalkPointers returns the error.
+//
+// visit may rewrite the data that a pointer refers to in place, such
+// as overwriting the bytes of a text to redact it, but it must not
+// change the sizes of objects.  Since the message is read as it is
+// walked, the walk counts against the message's read limit.  Objects
+// nested more deeply than the message's CopyDepthLimit stop the walk
+// with ErrDepthLimit.
+func WalkPointers(root Struct, visit func(Ptr) error) error {
+	if !root.IsValid() {
+		return nil
+	}
+	return walkStruct(root, root.seg.msg.copyDepthLimit(), visit)
+}
+
+// walkPtr calls visit with p and every valid pointer reachable from
+// it, depth first.  It returns ErrDepthLimit if objects are nested
+// more than depth levels below p.
+func walkPtr(p Ptr, depth uint, visit func(Ptr) error) error {
+	if !p.IsValid() {
+		return nil
+	}
+	if depth == 0 {
+		return ErrDepthLimit
+	}
+	if err := visit(p); err != nil {
+		return err
+	}
+	switch p.flags.ptrType() {
+	case structPtrType:
+		return walkStruct(p.Struct(), depth-1, visit)
+	case listPtrType:
+		l := p.List()
+		switch {
+		case l.flags&isCompositeList != 0:
+			for i := 0; i < l.Len(); i++ {
+				if err := walkStruct(l.Struct(i), depth-1, visit); err != nil {
+					return err
+				}
+			}
+		case l.flags&isBitList == 0 && l.size == ObjectSize{PointerCount: 1}:
+			pl := PointerList{l}
+			for i := 0; i < l.Len(); i++ {
+				elem, err := pl.PtrAt(i)
+				if err != nil {
+					return err
+				}
+				if err := walkPtr(elem, depth-1, visit); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case interfacePtrType:
+		return nil
+	default:
+		panic("unreachable")
+	}
+}
+
+func walkStruct(s Struct, depth uint, visit func(Ptr) error) error {
+	for i := uint16(0); i < s.size.PointerCount; i++ {
+		p, err := s.Ptr(i)
+		if err != nil {
+			return err
+		}
+		if err := walkPtr(p, depth, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadLimiter returns the message's read limiter.  Useful if you want
 // to reset the traversal limit while reading.
 func (m *Message) ReadLimiter() *ReadLimiter {
@@ -169,6 +521,15 @@ func (m *Message) ReadLimiter() *ReadLimiter {
 	return &m.rlimit
 }
 
+// checkPointerCount returns ErrTooManyPointers if a struct of size sz
+// has more pointers than m.MaxPointerCount allows.
+func (m *Message) checkPointerCount(sz ObjectSize) error {
+	if m.MaxPointerCount != 0 && sz.PointerCount > m.MaxPointerCount {
+		return ErrTooManyPointers
+	}
+	return nil
+}
+
 func (m *Message) depthLimit() uint {
 	if m.DepthLimit != 0 {
 		return m.DepthLimit
@@ -176,12 +537,102 @@ func (m *Message) depthLimit() uint {
 	return defaultDepthLimit
 }
 
-// NumSegments returns the number of segments in the message.
+func (m *Message) copyDepthLimit() uint {
+	if m.CopyDepthLimit != 0 {
+		return m.CopyDepthLimit
+	}
+	return defaultCopyDepth
+}
+
+// Zero overwrites every byte of the message's segments with zeroes,
+// including any capacity past the end of the segments' data.  It is
+// meant to scrub sensitive data before the arena's buffers are reused
+// or returned to a pool.  Zero does not clear the capability table.
+//
+// Zero only reaches the message's current buffers.  Copies made
+// earlier, such as by the garbage collector moving memory or by an
+// arena growing a segment into a new buffer, are left untouched, so
+// Zero narrows the window of exposure rather than closing it.  Zero
+// returns an error for read-only messages, whose memory belongs to
+// someone else.
+func (m *Message) Zero() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.readOnly {
+		return errReadOnly
+	}
+	m.clearValidated()
+	if m.Arena == nil {
+		return nil
+	}
+	n := m.Arena.NumSegments()
+	for i := int64(0); i < n; i++ {
+		id := SegmentID(i)
+		data, err := m.Arena.Data(id)
+		if err != nil {
+			return err
+		}
+		zeroBytes(data[:cap(data)])
+		if seg := m.segment(id); seg != nil {
+			zeroBytes(seg.data[:cap(seg.data)])
+		}
+	}
+	return nil
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Validate reads every pointer reachable from the message's root and
+// returns the first error encountered, as a decoder does with
+// StrictPointers.  If the message is valid, Validate marks it as
+// validated: see Validated.  The traversal does not count against the
+// message's read limit.
+func (m *Message) Validate() error {
+	if err := walkMessage(m); err == ErrDepthLimit {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("capnp: validate: %v", err)
+	}
+	atomic.StoreUint32(&m.validated, 1)
+	return nil
+}
+
+// Validated reports whether the message passed Validate, or was decoded
+// with StrictPointers, and has not been written to since.  Writes
+// through Struct, List, and Message methods clear the flag, as do Reset
+// and Zero.  So do Struct.DataBytes, Ptr.TextBytes, and Ptr.Data, which
+// return slices that alias the message.  The flag is advisory: writes
+// made directly to a segment's Data or to the arena's buffers are not
+// tracked, so code that accepts messages from untrusted callers should
+// not rely on it.  It is meant to let a pipeline that validates
+// messages at its boundary skip checking them again.
+func (m *Message) Validated() bool {
+	return atomic.LoadUint32(&m.validated) != 0
+}
+
+func (m *Message) clearValidated() {
+	// Load first so that writes to an unvalidated message don't
+	// contend on the cache line.
+	if atomic.LoadUint32(&m.validated) != 0 {
+		atomic.StoreUint32(&m.validated, 0)
+	}
+}
+
+// NumSegments returns the number of segments in the message.  Segment
+// IDs range from 0 to NumSegments()-1.
 func (m *Message) NumSegments() int64 {
 	return int64(m.Arena.NumSegments())
 }
 
-// Segment returns the segment with the given ID.
+// Segment returns the segment with the given ID, loading it from the
+// arena if needed.  It returns an error if id is not less than
+// NumSegments.  The segment's Data is the portion of the segment in
+// use, which can be forwarded as-is to reconstruct the message
+// elsewhere with NewMessageFromSegments.
 func (m *Message) Segment(id SegmentID) (*Segment, error) {
 	if isInt32Bit && id > maxInt32 {
 		return nil, errSegment32Bit
@@ -267,6 +718,41 @@ func (m *Message) allocSegment(sz Size) (*Segment, error) {
 	return seg, nil
 }
 
+// NewSegment adds an empty segment with room for at least minsz bytes to
+// the message and returns it.  Objects created in the segment, such as
+// by NewStruct or a generated NewFoo function, are placed in it as long
+// as it has room.  Pointers to them from other segments are stored as
+// far pointers.  This lets a builder choose where objects live, for
+// example to keep a large table apart from the content that refers to
+// it.  The message must use a MultiSegment arena.
+func (m *Message) NewSegment(minsz Size) (*Segment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sa, ok := m.Arena.(segmentAdder)
+	if !ok {
+		return nil, errors.New("capnp: new segment requires a MultiSegment arena")
+	}
+	if m.segs == nil && m.firstSeg.msg != nil {
+		m.segs = make(map[SegmentID]*Segment)
+		m.segs[0] = &m.firstSeg
+	}
+	id, data, err := sa.addSegment(minsz)
+	if err != nil {
+		return nil, err
+	}
+	if isInt32Bit && id > maxInt32 {
+		return nil, errSegment32Bit
+	}
+	return m.setSegment(id, data), nil
+}
+
+// A segmentAdder is an Arena that can add an empty segment with a
+// capacity of at least minsz bytes.  The caller must be holding the
+// message's mu.
+type segmentAdder interface {
+	addSegment(minsz Size) (SegmentID, []byte, error)
+}
+
 // alloc allocates sz zero-filled bytes.  It prefers using s, but may
 // use a different segment in the same message if there's not sufficient
 // capacity.
@@ -384,7 +870,26 @@ func (ss roSingleSegment) Data(id SegmentID) ([]byte, error) {
 }
 
 func (ss roSingleSegment) Allocate(sz Size, segs map[SegmentID]*Segment) (SegmentID, []byte, error) {
-	return 0, nil, errors.New("capnp: segment is read-only")
+	return 0, nil, errReadOnly
+}
+
+// roMultiSegment is an arena of segments that alias memory owned by
+// someone else, so it refuses to allocate.
+type roMultiSegment [][]byte
+
+func (msa roMultiSegment) NumSegments() int64 {
+	return int64(len(msa))
+}
+
+func (msa roMultiSegment) Data(id SegmentID) ([]byte, error) {
+	if int64(id) >= int64(len(msa)) {
+		return nil, errSegmentOutOfBounds
+	}
+	return msa[id], nil
+}
+
+func (msa roMultiSegment) Allocate(sz Size, segs map[SegmentID]*Segment) (SegmentID, []byte, error) {
+	return 0, nil, errReadOnly
 }
 
 type multiSegmentArena [][]byte
@@ -392,12 +897,43 @@ type multiSegmentArena [][]byte
 // MultiSegment returns a new arena that allocates new segments when
 // they are full.  b can be used to populate the buffer for reading or
 // to reserve memory of a specific size.
-func MultiSegment(b [][]byte) Arena {
+func MultiSegment(b [][]byte, opts ...MultiSegmentOption) Arena {
+	var p multiSegmentParams
+	for _, o := range opts {
+		o.f(&p)
+	}
+	if p.maxSize > 0 {
+		return &limitedMultiSegmentArena{
+			multiSegmentArena: multiSegmentArena(b),
+			max:               p.maxSize,
+		}
+	}
 	msa := new(multiSegmentArena)
 	*msa = b
 	return msa
 }
 
+// A MultiSegmentOption is an option for creating a MultiSegment arena.
+type MultiSegmentOption struct {
+	f func(*multiSegmentParams)
+}
+
+type multiSegmentParams struct {
+	maxSize Size
+}
+
+// MaxSegmentSize limits the size of segments that the arena allocates
+// to sz bytes, rounded down to a multiple of the word size.  Once a
+// segment is full, objects are placed in a new segment.  Allocating an
+// object larger than sz is an error.  Segments passed to MultiSegment
+// are not checked against the limit.  A size smaller than a word means
+// no limit.
+func MaxSegmentSize(sz Size) MultiSegmentOption {
+	return MultiSegmentOption{func(p *multiSegmentParams) {
+		p.maxSize = sz &^ (wordSize - 1)
+	}}
+}
+
 // demuxArena slices b into a multi-segment arena.
 func demuxArena(hdr streamHeader, data []byte) (Arena, error) {
 	segs := make([][]byte, int(hdr.maxSegment())+1)
@@ -422,7 +958,20 @@ func (msa *multiSegmentArena) Data(id SegmentID) ([]byte, error) {
 	return (*msa)[id], nil
 }
 
+func (msa *multiSegmentArena) appendSegment(data []byte) {
+	*msa = append(*msa, data[:len(data):len(data)])
+}
+
 func (msa *multiSegmentArena) Allocate(sz Size, segs map[SegmentID]*Segment) (SegmentID, []byte, error) {
+	return msa.allocate(sz, segs, 0)
+}
+
+// allocate implements Allocate.  If max is not zero, new segments will
+// have a capacity of at most max bytes.
+func (msa *multiSegmentArena) allocate(sz Size, segs map[SegmentID]*Segment, max Size) (SegmentID, []byte, error) {
+	if max > 0 && sz.padToWord() > max {
+		return 0, nil, fmt.Errorf("capnp: alloc %d bytes: larger than maximum segment size (%d bytes)", sz, max)
+	}
 	var total int64
 	for i, data := range *msa {
 		id := SegmentID(i)
@@ -442,12 +991,158 @@ func (msa *multiSegmentArena) Allocate(sz Size, segs map[SegmentID]*Segment) (Se
 	if err != nil {
 		return 0, nil, fmt.Errorf("capnp: alloc %d bytes: %v", sz, err)
 	}
+	if max > 0 && n > int(max) {
+		n = int(max)
+	}
 	buf := make([]byte, 0, n)
 	id := SegmentID(len(*msa))
 	*msa = append(*msa, buf)
 	return id, buf, nil
 }
 
+func (msa *multiSegmentArena) addSegment(minsz Size) (SegmentID, []byte, error) {
+	if int64(len(*msa)) >= 1<<32 {
+		return 0, nil, errors.New("capnp: new segment: too many segments")
+	}
+	buf := make([]byte, 0, int(minsz.padToWord()))
+	id := SegmentID(len(*msa))
+	*msa = append(*msa, buf)
+	return id, buf, nil
+}
+
+// limitedMultiSegmentArena is a multiSegmentArena with a maximum
+// segment size.
+type limitedMultiSegmentArena struct {
+	multiSegmentArena
+	max Size
+}
+
+func (lmsa *limitedMultiSegmentArena) Allocate(sz Size, segs map[SegmentID]*Segment) (SegmentID, []byte, error) {
+	return lmsa.multiSegmentArena.allocate(sz, segs, lmsa.max)
+}
+
+func (lmsa *limitedMultiSegmentArena) addSegment(minsz Size) (SegmentID, []byte, error) {
+	if minsz.padToWord() > lmsa.max {
+		return 0, nil, fmt.Errorf("capnp: new segment of %d bytes: larger than maximum segment size (%d bytes)", minsz, lmsa.max)
+	}
+	return lmsa.multiSegmentArena.addSegment(minsz)
+}
+
+// A Ring is a buffer divided into equal-sized regions, each of which
+// can back the single segment of one message.  Regions are handed out
+// in order and reused once released, so a steady stream of messages
+// that are consumed before the ring wraps around does not allocate.
+// A Ring is safe to use from multiple goroutines, such as a producer
+// that builds messages and a consumer that releases them.
+type Ring struct {
+	mu     sync.Mutex
+	arenas []ringArena
+	next   int
+}
+
+// RingArena returns a Ring that divides buf into regions of msgSize
+// bytes, rounded down to a multiple of the word size.  Messages built
+// in a region may not grow larger than the region.  RingArena panics if
+// buf is too small to hold a single region.
+func RingArena(buf []byte, msgSize int) *Ring {
+	msgSize &^= int(wordSize) - 1
+	if msgSize <= 0 || len(buf) < msgSize {
+		panic("capnp: ring buffer smaller than one message")
+	}
+	r := &Ring{arenas: make([]ringArena, len(buf)/msgSize)}
+	for i := range r.arenas {
+		r.arenas[i] = ringArena{
+			ring: r,
+			data: buf[i*msgSize : i*msgSize : (i+1)*msgSize],
+		}
+	}
+	return r
+}
+
+// Arena returns an arena backed by the next region of the ring.  If
+// that region has not been released since it was last handed out,
+// Arena returns ErrRingFull: the consumer has fallen a full ring
+// behind the producer.
+func (r *Ring) Arena() (Arena, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := &r.arenas[r.next]
+	if a.busy {
+		return nil, ErrRingFull
+	}
+	a.busy = true
+	r.next = (r.next + 1) % len(r.arenas)
+	return a, nil
+}
+
+// Reset resets msg to use the arena returned by Arena and allocates
+// its root pointer.  It is like calling NewMessage with the arena, but
+// reuses msg instead of allocating a new Message.
+func (r *Ring) Reset(msg *Message) (first *Segment, err error) {
+	a, err := r.Arena()
+	if err != nil {
+		return nil, err
+	}
+	msg.Reset(a)
+	first, err = msg.Segment(0)
+	if err != nil {
+		r.Release(a)
+		return nil, err
+	}
+	if _, _, err := alloc(first, wordSize); err != nil {
+		r.Release(a)
+		return nil, err
+	}
+	return first, nil
+}
+
+// Release returns the region backing a, an arena returned by Arena, to
+// the ring.  Messages using the arena must not be accessed afterward,
+// since the region will be overwritten by a later message.  Release
+// panics if a did not come from r.
+func (r *Ring) Release(a Arena) {
+	ra, ok := a.(*ringArena)
+	if !ok || ra.ring != r {
+		panic("capnp: Release called with arena from a different ring")
+	}
+	r.mu.Lock()
+	ra.busy = false
+	r.mu.Unlock()
+}
+
+// ringArena is a single-segment arena backed by a region of a Ring.
+type ringArena struct {
+	ring *Ring
+	data []byte // zero length, capacity is the region size
+	busy bool
+}
+
+func (ra *ringArena) NumSegments() int64 {
+	return 1
+}
+
+func (ra *ringArena) Data(id SegmentID) ([]byte, error) {
+	if id != 0 {
+		return nil, errSegmentOutOfBounds
+	}
+	return ra.data, nil
+}
+
+func (ra *ringArena) Allocate(sz Size, segs map[SegmentID]*Segment) (SegmentID, []byte, error) {
+	data := ra.data
+	if segs[0] != nil {
+		data = segs[0].data
+	}
+	if !hasCapacity(data, sz) {
+		return 0, nil, fmt.Errorf("capnp: alloc %d bytes: message larger than ring region (%d bytes)", sz, cap(ra.data))
+	}
+	return 0, data, nil
+}
+
+// ErrRingFull is returned by a Ring when its next region is still in
+// use.
+var ErrRingFull = errors.New("capnp: ring buffer full")
+
 // nextAlloc computes how much more space to allocate given the number
 // of bytes allocated in the entire message and the requested number of
 // bytes.  It will always return a multiple of wordSize.  max must be a
@@ -503,14 +1198,37 @@ type Decoder struct {
 	segbuf [msgHeaderSize]byte
 	hdrbuf []byte
 
+	// peeked is true if hdr and total describe a message whose
+	// header has been read but whose segments have not.
+	peeked bool
+	hdr    streamHeader
+	total  uint64
+
 	reuse bool
+	alias bool
 	buf   []byte
 	msg   Message
 	arena roSingleSegment
 
+	// borrowBuf is the buffer lent by DecodeBorrowed, along with the
+	// message and arena that read from it.  lent is the generation of
+	// the message that holds it, or zero if it is free, and is accessed
+	// atomically.
+	borrowBuf   []byte
+	borrowMsg   Message
+	borrowArena roSingleSegment
+	lent        uint32
+	borrowGen   uint32
+
 	// Maximum number of bytes that can be read per call to Decode.
 	// If not set, a reasonable default is used.
 	MaxMessageSize uint64
+
+	maxTotal uint64 // see SetMaxTotalBytes
+	strict   bool   // see StrictPointers
+
+	trailing bool // see AllowTrailingData
+	decoded  bool // a message has been returned
 }
 
 // NewDecoder creates a new Cap'n Proto framer that reads from r.
@@ -524,45 +1242,162 @@ func NewPackedDecoder(r io.Reader) *Decoder {
 	return NewDecoder(packed.NewReader(bufio.NewReader(r)))
 }
 
-// Decode reads a message from the decoder stream.
-func (d *Decoder) Decode() (*Message, error) {
-	maxSize := d.MaxMessageSize
-	if maxSize == 0 {
-		maxSize = defaultDecodeLimit
+// SetMaxTotalBytes limits the memory that each call to Decode may
+// allocate to n bytes, counting the stream header, the table that maps
+// segment IDs to their data, and the segment data itself.  Decode
+// returns an error before allocating if a message would exceed the
+// limit.  Zero means no limit beyond MaxMessageSize.
+func (d *Decoder) SetMaxTotalBytes(n uint64) {
+	d.maxTotal = n
+}
+
+// PeekHeader reads the framing header of the next message in the
+// stream and reports its number of segments and the total size of its
+// segments in words.  The message's segments are not read: the header
+// is buffered so that the next call to Decode returns the message.
+// Calling PeekHeader again before Decode returns the same values.
+func (d *Decoder) PeekHeader() (segmentCount int, totalWords uint64, err error) {
+	if err := d.readHeader(); err != nil {
+		return 0, 0, err
 	}
+	return int(d.hdr.maxSegment()) + 1, d.total / uint64(wordSize), nil
+}
+
+// readHeader reads the next stream header into d.hdr if it has not
+// already been read by PeekHeader.
+func (d *Decoder) readHeader() error {
+	if d.peeked {
+		return nil
+	}
+	if d.trailing && d.decoded {
+		if _, err := io.Copy(ioutil.Discard, d.r); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	maxSize := d.maxSize()
 	if _, err := io.ReadFull(d.r, d.segbuf[:]); err != nil {
-		return nil, err
+		return err
 	}
 	maxSeg := binary.LittleEndian.Uint32(d.segbuf[:])
 	if maxSeg > maxStreamSegments {
-		return nil, errTooManySegments
+		return errTooManySegments
 	}
 	hdrSize := streamHeaderSize(maxSeg)
 	if hdrSize > maxSize || hdrSize > (1<<31-1) {
-		return nil, errDecodeLimit
+		return errDecodeLimit
+	}
+	if d.maxTotal > 0 && hdrSize > d.maxTotal {
+		return errDecodeLimit
 	}
 	d.hdrbuf = resizeSlice(d.hdrbuf, int(hdrSize))
 	copy(d.hdrbuf, d.segbuf[:])
 	if _, err := io.ReadFull(d.r, d.hdrbuf[msgHeaderSize:]); err != nil {
-		return nil, err
+		return unexpectedEOF(err)
 	}
 	hdr, _, err := parseStreamHeader(d.hdrbuf)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	total, err := hdr.totalSize()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	// TODO(someday): if total size is greater than can fit in one buffer,
 	// attempt to allocate buffer per segment.
 	if total > maxSize-hdrSize || total > (1<<31-1) {
-		return nil, errDecodeLimit
+		return errDecodeLimit
+	}
+	if d.maxTotal > 0 {
+		if err := d.checkTotalBytes(hdr, hdrSize); err != nil {
+			return err
+		}
+	}
+	d.hdr, d.total, d.peeked = hdr, total, true
+	return nil
+}
+
+// checkTotalBytes adds up the memory needed to decode a message with
+// the given header and returns an error as soon as it exceeds the limit
+// set by SetMaxTotalBytes.
+func (d *Decoder) checkTotalBytes(hdr streamHeader, hdrSize uint64) error {
+	nsegs := uint64(hdr.maxSegment()) + 1
+	running := hdrSize + nsegs*uint64(unsafe.Sizeof([]byte(nil)))
+	if running > d.maxTotal {
+		return errDecodeLimit
+	}
+	for i := uint64(0); i < nsegs; i++ {
+		sz, err := hdr.segmentSize(uint32(i))
+		if err != nil {
+			return err
+		}
+		running += uint64(sz)
+		if running > d.maxTotal {
+			return errDecodeLimit
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) maxSize() uint64 {
+	if d.MaxMessageSize == 0 {
+		return defaultDecodeLimit
+	}
+	return d.MaxMessageSize
+}
+
+// Decode reads a message from the decoder stream.
+func (d *Decoder) Decode() (*Message, error) {
+	msg, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.strict {
+		if err := checkPointers(msg); err != nil {
+			return nil, err
+		}
+	}
+	d.decoded = true
+	return msg, nil
+}
+
+// demuxReadOnly returns a read-only arena whose segments alias buf.
+func demuxReadOnly(hdr streamHeader, buf []byte) (Arena, error) {
+	if hdr.maxSegment() == 0 {
+		return roSingleSegment(buf[:len(buf):len(buf)]), nil
+	}
+	segs := make(roMultiSegment, int(hdr.maxSegment())+1)
+	for i := range segs {
+		sz, err := hdr.segmentSize(uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		segs[i], buf = buf[:sz:sz], buf[sz:]
+	}
+	return segs, nil
+}
+
+func (d *Decoder) decode() (*Message, error) {
+	if err := d.readHeader(); err != nil {
+		return nil, err
+	}
+	d.peeked = false
+	hdr, total := d.hdr, d.total
+	if nr, ok := d.r.(nexter); ok && d.alias {
+		buf := nr.Next(int(total))
+		if uint64(len(buf)) < total {
+			return nil, io.ErrUnexpectedEOF
+		}
+		arena, err := demuxReadOnly(hdr, buf)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Arena: arena, readOnly: true}, nil
 	}
 	if !d.reuse {
 		buf := make([]byte, int(total))
 		if _, err := io.ReadFull(d.r, buf); err != nil {
-			return nil, err
+			return nil, unexpectedEOF(err)
 		}
 		arena, err := demuxArena(hdr, buf)
 		if err != nil {
@@ -572,7 +1407,7 @@ func (d *Decoder) Decode() (*Message, error) {
 	}
 	d.buf = resizeSlice(d.buf, int(total))
 	if _, err := io.ReadFull(d.r, d.buf); err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 	var arena Arena
 	if hdr.maxSegment() == 0 {
@@ -589,6 +1424,16 @@ func (d *Decoder) Decode() (*Message, error) {
 	return &d.msg, nil
 }
 
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF.  It is used
+// for reads in the middle of a message, where the stream ending is an
+// error rather than a clean end of input.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
 func resizeSlice(b []byte, size int) []byte {
 	if cap(b) < size {
 		return make([]byte, size)
@@ -596,12 +1441,297 @@ func resizeSlice(b []byte, size int) []byte {
 	return b[:size]
 }
 
+// DecodeInto reads a message from the decoder stream like Decode, but
+// reads the message's segments into buf if it has enough capacity.  If
+// it does not, DecodeInto allocates a new buffer.  The returned
+// message's segments alias the buffer, so buf must not be reused until
+// the caller is done with the message.  PeekHeader reports the size of
+// buffer needed.
+func (d *Decoder) DecodeInto(buf []byte) (*Message, error) {
+	if err := d.readHeader(); err != nil {
+		return nil, err
+	}
+	d.peeked = false
+	hdr, total := d.hdr, d.total
+	if uint64(cap(buf)) >= total {
+		buf = buf[:total]
+	} else {
+		buf = make([]byte, int(total))
+	}
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	arena, err := demuxArena(hdr, buf)
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{Arena: arena}
+	if d.strict {
+		if err := checkPointers(msg); err != nil {
+			return nil, err
+		}
+	}
+	d.decoded = true
+	return msg, nil
+}
+
+// DecodeBorrowed reads a message from the decoder stream like Decode,
+// but into a buffer that the decoder lends to the caller instead of a
+// new one.  This is the fastest way to read messages that are not
+// modified: once the buffer is large enough, decoding does not allocate
+// memory for segments.
+//
+// The message is read-only, since writing to it would change the
+// decoder's buffer.  Allocating in it, including setting a text, data,
+// struct, or list field to a new object, returns an error, as does
+// setting a pointer field in place.  Setters that do not return an
+// error, such as Struct.SetUint32, panic.
+//
+// The caller must call release when it is done with the message.  After
+// release, the decoder reuses the buffer and the *Message for the next
+// message, so the message and everything read from it, including
+// Structs, Lists, and the byte slices returned for text and data
+// fields, must no longer be used.  Copy anything that needs to be
+// retained, or use Decode instead.  If DecodeBorrowed is called before
+// the previous message was released, the new message is read into a
+// new buffer, so holding on to messages is safe but loses the benefit.
+// release may be called from any goroutine, and calling it more than
+// once has no effect.
+func (d *Decoder) DecodeBorrowed() (msg *Message, release func(), err error) {
+	if err := d.readHeader(); err != nil {
+		return nil, nil, err
+	}
+	d.peeked = false
+	hdr, total := d.hdr, d.total
+	lend := atomic.LoadUint32(&d.lent) == 0
+	var buf []byte
+	if lend {
+		d.borrowBuf = resizeSlice(d.borrowBuf, int(total))
+		buf = d.borrowBuf
+	} else {
+		buf = make([]byte, int(total))
+	}
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return nil, nil, unexpectedEOF(err)
+	}
+	var arena Arena
+	if lend && hdr.maxSegment() == 0 {
+		d.borrowArena = roSingleSegment(buf[:len(buf):len(buf)])
+		arena = &d.borrowArena
+	} else if arena, err = demuxReadOnly(hdr, buf); err != nil {
+		return nil, nil, err
+	}
+	if lend {
+		d.borrowMsg.Reset(arena)
+		d.borrowMsg.readOnly = true
+		msg = &d.borrowMsg
+	} else {
+		msg = &Message{Arena: arena, readOnly: true}
+	}
+	if d.strict {
+		if err := checkPointers(msg); err != nil {
+			return nil, nil, err
+		}
+	}
+	d.decoded = true
+	if !lend {
+		return msg, func() {}, nil
+	}
+	d.borrowGen++
+	if d.borrowGen == 0 {
+		d.borrowGen++
+	}
+	gen := d.borrowGen
+	atomic.StoreUint32(&d.lent, gen)
+	return msg, func() { atomic.CompareAndSwapUint32(&d.lent, gen, 0) }, nil
+}
+
 // ReuseBuffer causes the decoder to reuse its buffer on subsequent decodes.
 // The decoder may return messages that cannot handle allocations.
 func (d *Decoder) ReuseBuffer() {
 	d.reuse = true
 }
 
+// StrictPointers controls whether Decode and DecodeInto check every
+// pointer reachable from a message's root before returning the message.
+// Normally, a pointer is only checked when it is read, so a message with
+// a reserved pointer encoding, such as an "other" pointer that is not a
+// capability, or an invalid far pointer is only reported by the
+// accessor that reaches it.  With strict checking, such messages are
+// rejected up front, which catches corruption and version skew early.
+// Messages that exceed the traversal or depth limits are also rejected.
+// The check's reads do not count against the message's read limit.
+func (d *Decoder) StrictPointers(strict bool) {
+	d.strict = strict
+}
+
+// AllowTrailingData controls whether the decoder ignores bytes that
+// follow the first message in the stream.  Some producers pad a
+// serialized message or append extra bytes after it, which a decoder
+// would otherwise try to read as the header of another message.  When
+// trailing data is allowed, the decoder reads at most one message:
+// after a message has been decoded, the next call to Decode, DecodeInto,
+// or PeekHeader reads the rest of the stream, discards it, and returns
+// io.EOF.  This is only useful for streams that hold a single message,
+// and it blocks until the underlying reader reaches EOF.  By default,
+// trailing data is not allowed and is decoded as further messages.
+func (d *Decoder) AllowTrailingData(allow bool) {
+	d.trailing = allow
+}
+
+// checkPointers reads every pointer reachable from msg's root and
+// returns the first error encountered.
+func checkPointers(msg *Message) error {
+	if err := walkMessage(msg); err != nil {
+		return fmt.Errorf("capnp: decode: %v", err)
+	}
+	atomic.StoreUint32(&msg.validated, 1)
+	return nil
+}
+
+// walkMessage reads every pointer reachable from msg's root.  The
+// reads do not count against the message's read limit.
+func walkMessage(msg *Message) error {
+	return msg.uncounted(func() error {
+		root, err := msg.RootPtr()
+		if err != nil {
+			return err
+		}
+		return walkPtr(root, msg.copyDepthLimit(), func(Ptr) error { return nil })
+	})
+}
+
+// uncounted calls f with the read limiter reset to the full traversal
+// limit, then restores the limiter to what it was before, so that the
+// reads that f makes do not use up the caller's budget.
+func (m *Message) uncounted(f func() error) error {
+	rl := m.ReadLimiter()
+	remaining := atomic.LoadUint64(&rl.limit)
+	limit := m.TraverseLimit
+	if limit == 0 {
+		limit = defaultTraverseLimit
+	}
+	rl.Reset(limit)
+	err := f()
+	rl.Reset(remaining)
+	return err
+}
+
+// AliasBuffer controls whether the decoder returns messages whose
+// segments are views into the source's memory instead of copies.  This
+// only takes effect if the decoder's reader has a Next(n int) []byte
+// method that returns the next n bytes without copying them, such as
+// *bytes.Buffer; otherwise, segments are copied as usual.
+//
+// The returned messages are read-only, since writing to them would
+// change the source's memory.  Allocating in them, including setting a
+// text, data, struct, or list field to a new object, returns an error,
+// as does setting a pointer field in place.  Setters that do not return
+// an error, such as Struct.SetUint32, panic.  The source must not be
+// written to while the messages are in use, since writing to a
+// bytes.Buffer may reuse the memory that has already been read.
+func (d *Decoder) AliasBuffer(alias bool) {
+	d.alias = alias
+}
+
+// nexter is implemented by in-memory readers like *bytes.Buffer that
+// can return the next bytes of the stream without copying.
+type nexter interface {
+	Next(n int) []byte
+}
+
+// An AutoDecoder deserializes a stream of messages that may each be
+// packed or unpacked, for reading from peers that don't agree on a
+// framing.  Before each message, the decoder peeks at the first four
+// bytes, which in an unpacked message are the segment count minus one.
+// A message is taken to be unpacked if those bytes are at most the
+// maximum number of segments that Decoder accepts, and packed
+// otherwise.
+//
+// The first byte of a packed message is a tag byte and the next is a
+// nonzero byte of the segment table, so as a little-endian number the
+// first four bytes are too large to be a segment count unless the
+// third and fourth bytes are zero.  That only happens for a packed
+// message whose root pointer is null, which is misread as unpacked.
+type AutoDecoder struct {
+	rd   *bufio.Reader
+	dec  *Decoder
+	pdec *Decoder
+
+	// Maximum number of bytes that can be read per call to Decode.
+	// If not set, a reasonable default is used.
+	MaxMessageSize uint64
+}
+
+// NewAutoDecoder creates a new Cap'n Proto framer that reads packed or
+// unpacked messages from r.
+func NewAutoDecoder(r io.Reader) *AutoDecoder {
+	rd := bufio.NewReader(r)
+	return &AutoDecoder{
+		rd:   rd,
+		dec:  NewDecoder(rd),
+		pdec: NewDecoder(packed.NewReader(rd)),
+	}
+}
+
+// Decode reads a message from the decoder stream.
+func (d *AutoDecoder) Decode() (*Message, error) {
+	msg, _, err := d.DecodeFraming()
+	return msg, err
+}
+
+// DecodeFraming reads a message from the decoder stream and reports
+// whether it was packed.  A caller can use this to reply to a peer in
+// the framing that the peer uses.
+func (d *AutoDecoder) DecodeFraming() (msg *Message, isPacked bool, err error) {
+	b, err := d.rd.Peek(msgHeaderSize)
+	if err != nil {
+		if err == io.EOF && len(b) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, false, err
+	}
+	dec := d.dec
+	if binary.LittleEndian.Uint32(b) > maxStreamSegments {
+		dec, isPacked = d.pdec, true
+	}
+	dec.MaxMessageSize = d.MaxMessageSize
+	msg, err = dec.Decode()
+	return msg, isPacked, err
+}
+
+// A FileStream reads a sequence of messages that have been written one
+// after another, such as a log file of concatenated Cap'n Proto frames.
+type FileStream struct {
+	dec *Decoder
+	err error
+}
+
+// NewFileStream returns a stream that reads messages from r.  The
+// stream reuses its buffers between messages, so a message returned by
+// Next is only valid until the following call to Next.
+func NewFileStream(r io.Reader) *FileStream {
+	dec := NewDecoder(r)
+	dec.ReuseBuffer()
+	return &FileStream{dec: dec}
+}
+
+// Next reads the next message in the stream.  Next returns io.EOF if
+// the stream ends between messages and io.ErrUnexpectedEOF if it ends
+// partway through one.  Once Next returns an error, it returns the
+// same error on every subsequent call.
+func (fs *FileStream) Next() (*Message, error) {
+	if fs.err != nil {
+		return nil, fs.err
+	}
+	msg, err := fs.dec.Decode()
+	if err != nil {
+		fs.err = err
+		return nil, err
+	}
+	return msg, nil
+}
+
 // Unmarshal reads an unpacked serialized stream into a message.  No
 // copying is performed, so the objects in the returned message read
 // directly from data.
@@ -625,6 +1755,51 @@ func Unmarshal(data []byte) (*Message, error) {
 	return &Message{Arena: arena}, nil
 }
 
+// Bytes marshals the message containing s into a single byte slice,
+// suitable for storing in a key-value store.  It operates on the whole
+// message: if s is not the message's root, the result still holds the
+// root and every other object in the message.  Use FromBytes to read
+// the message back.
+func Bytes(s Struct) ([]byte, error) {
+	if !s.IsValid() {
+		return nil, errors.New("capnp: Bytes called on invalid struct")
+	}
+	return s.Segment().Message().Marshal()
+}
+
+// FromBytes reads a message produced by Bytes.  Unlike Unmarshal, it
+// copies data, so the caller may reuse data afterward, as many
+// key-value stores require.  It returns an error if data is truncated.
+func FromBytes(data []byte) (*Message, error) {
+	return Unmarshal(append([]byte(nil), data...))
+}
+
+// NewMessageFromSegments returns a message that reads from segs, with
+// segs[i] as the data for segment i.  Each segment's length must be a
+// multiple of the word size.
+//
+// The message aliases segs instead of copying them, so the slices must
+// not be modified while the message is in use.  Setting fields on the
+// message will modify the slices' contents, but the message will never
+// write past the length of a slice: new objects are placed in newly
+// allocated segments.
+func NewMessageFromSegments(segs [][]byte) (*Message, error) {
+	if len(segs) == 0 {
+		return nil, errNoSegments
+	}
+	arena := make([][]byte, len(segs))
+	for i, data := range segs {
+		if len(data)%int(wordSize) != 0 {
+			return nil, fmt.Errorf("capnp: segment %d size is not a multiple of word size", i)
+		}
+		if Size(len(data)) > maxSegmentSize() {
+			return nil, errSegmentTooLarge
+		}
+		arena[i] = data[:len(data):len(data)]
+	}
+	return &Message{Arena: MultiSegment(arena)}, nil
+}
+
 // UnmarshalPacked reads a packed serialized stream into a message.
 func UnmarshalPacked(data []byte) (*Message, error) {
 	if len(data) == 0 {
@@ -751,6 +1926,23 @@ func (m *Message) segmentSizes() ([]Size, error) {
 	return sizes, nil
 }
 
+// SegmentSizes returns the number of words used in each of the
+// message's segments, in segment ID order.  These are the sizes that
+// Encode and Marshal write in the stream header, so a transport can
+// compute the framing and the total encoded size before serializing
+// the message.
+func (m *Message) SegmentSizes() ([]uint32, error) {
+	sizes, err := m.segmentSizes()
+	if err != nil {
+		return nil, err
+	}
+	words := make([]uint32, len(sizes))
+	for i, sz := range sizes {
+		words[i] = uint32(sz / wordSize)
+	}
+	return words, nil
+}
+
 // Marshal concatenates the segments in the message into a single byte
 // slice including framing.
 func (m *Message) Marshal() ([]byte, error) {
@@ -794,6 +1986,121 @@ func (m *Message) MarshalPacked() ([]byte, error) {
 	return buf, nil
 }
 
+// A CapPolicy specifies what MarshalWithoutCaps does with capability
+// pointers.
+type CapPolicy int
+
+// Capability policies.
+const (
+	// CapPolicyError makes MarshalWithoutCaps return an error if the
+	// message contains a capability pointer.
+	CapPolicyError CapPolicy = iota
+
+	// CapPolicyNull makes MarshalWithoutCaps write null pointers in
+	// place of capability pointers.
+	CapPolicyNull
+)
+
+// MarshalWithoutCaps serializes the message like Marshal, for storage
+// or other uses outside of RPC.  A capability pointer is only an index
+// into the message's CapTable, which is not serialized, so a stored
+// message would otherwise carry indices that refer to nothing.
+// MarshalWithoutCaps traverses the objects reachable from the root and
+// handles each capability pointer according to policy.  The traversal
+// does not count against the message's read limit.  The message itself
+// is not modified.  Objects that are not reachable from the root are
+// copied as is.
+func (m *Message) MarshalWithoutCaps(policy CapPolicy) ([]byte, error) {
+	root, err := m.Segment(0)
+	if err != nil {
+		return nil, err
+	}
+	var caps []capLocation
+	if root.regionInBounds(0, wordSize) {
+		err = m.uncounted(func() error {
+			var err error
+			caps, err = appendCapLocations(caps, root, 0, 1, m.depthLimit())
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("capnp: marshal: %v", err)
+		}
+	}
+	if len(caps) > 0 && policy != CapPolicyNull {
+		return nil, errMarshalCap
+	}
+	data, err := m.Marshal()
+	if err != nil || len(caps) == 0 {
+		return data, err
+	}
+	sizes, err := m.segmentSizes()
+	if err != nil {
+		return nil, err
+	}
+	starts := make([]uint64, len(sizes))
+	off := streamHeaderSize(uint32(len(sizes) - 1))
+	for i, sz := range sizes {
+		starts[i] = off
+		off += uint64(sz)
+	}
+	for _, c := range caps {
+		start := starts[c.seg] + uint64(c.addr)
+		binary.LittleEndian.PutUint64(data[start:], 0)
+	}
+	return data, nil
+}
+
+// capLocation is the location of a capability pointer in a message.
+type capLocation struct {
+	seg  SegmentID
+	addr Address
+}
+
+// appendCapLocations appends the locations of the capability pointers
+// reachable from the n pointers starting at addr in s.
+func appendCapLocations(caps []capLocation, s *Segment, addr Address, n int32, depthLimit uint) ([]capLocation, error) {
+	for i := int32(0); i < n; i++ {
+		paddr, ok := addr.element(i, wordSize)
+		if !ok {
+			return caps, errOverflow
+		}
+		if raw := s.readRawPointer(paddr); raw.pointerType() == otherPointer && raw.otherPointerType() == 0 {
+			caps = append(caps, capLocation{s.id, paddr})
+			continue
+		}
+		p, err := s.readPtr(paddr, depthLimit)
+		if err != nil {
+			return caps, err
+		}
+		if !p.IsValid() {
+			continue
+		}
+		switch p.flags.ptrType() {
+		case structPtrType:
+			st := p.Struct()
+			if st.size.PointerCount == 0 {
+				continue
+			}
+			caps, err = appendCapLocations(caps, st.seg, st.pointerAddress(0), int32(st.size.PointerCount), st.depthLimit)
+		case listPtrType:
+			l := p.List()
+			switch {
+			case l.flags&isCompositeList != 0 && l.size.PointerCount > 0:
+				for j := 0; j < l.Len() && err == nil; j++ {
+					st := l.Struct(j)
+					caps, err = appendCapLocations(caps, st.seg, st.pointerAddress(0), int32(st.size.PointerCount), l.depthLimit)
+				}
+			case l.flags&isCompositeList == 0 && l.size.PointerCount == 1:
+				caps, err = appendCapLocations(caps, l.seg, l.off, l.length, l.depthLimit)
+			}
+		}
+		if err != nil {
+			return caps, err
+		}
+	}
+	return caps, nil
+}
+
 // Stream header sizes.
 const (
 	msgHeaderSize = 4
@@ -836,6 +2143,10 @@ func parseStreamHeader(data []byte) (h streamHeader, tail []byte, err error) {
 		return streamHeader{}, nil, io.ErrUnexpectedEOF
 	}
 	maxSeg := binary.LittleEndian.Uint32(data)
+	if maxSeg == 1<<32-1 {
+		// The segment count would overflow to zero.
+		return streamHeader{}, nil, errNoSegments
+	}
 	// TODO(light): check int
 	hdrSize := streamHeaderSize(maxSeg)
 	if uint64(len(data)) < hdrSize {
@@ -910,4 +2221,12 @@ var (
 	errSegmentTooLarge    = errors.New("capnp: segment too large")
 	errTooManySegments    = errors.New("capnp: too many segments to decode")
 	errDecodeLimit        = errors.New("capnp: message too large")
+	errCapOutOfBounds     = errors.New("capnp: capability ID out of bounds")
+	errSetArenaHasData    = errors.New("capnp: SetArena called on message with data")
+	errReadOnly           = errors.New("capnp: segment is read-only")
+	errNoSegments         = errors.New("capnp: message has no segments")
+	errNoRoot             = errors.New("capnp: message has no root pointer")
+	errRootOverlap        = errors.New("capnp: root object overlaps the root pointer")
+	errNotList            = errors.New("capnp: root is not a list")
+	errNullRootList       = errors.New("capnp: root list is null")
 )
@@ -2,10 +2,14 @@ package capnp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"unsafe"
 )
 
 func TestNewMessage(t *testing.T) {
@@ -376,6 +380,122 @@ func TestMultiSegmentAllocate(t *testing.T) {
 	}
 }
 
+func TestMessageNewSegment(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg2, err := msg.NewSegment(20)
+	if err != nil {
+		t.Fatal("NewSegment:", err)
+	}
+	if seg2.ID() == seg.ID() {
+		t.Errorf("NewSegment returned existing segment %d", seg.ID())
+	}
+	if n := cap(seg2.Data()); n < 24 {
+		t.Errorf("cap(NewSegment(20).Data()) = %d; want >= 24", n)
+	}
+	sub, err := NewStruct(seg2, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Segment() != seg2 {
+		t.Errorf("struct allocated in segment %d; want %d", sub.Segment().ID(), seg2.ID())
+	}
+	sub.SetUint64(0, 42)
+	if err := root.SetPtr(0, sub.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	p, err := root.Ptr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := p.Struct().Uint64(0); v != 42 {
+		t.Errorf("root.Ptr(0).Struct().Uint64(0) = %d; want 42", v)
+	}
+
+	msg, _, err = NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := msg.NewSegment(8); err == nil {
+		t.Error("NewSegment on a SingleSegment message succeeded")
+	}
+	msg, _, err = NewMessage(MultiSegment(nil, MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := msg.NewSegment(72); err == nil {
+		t.Error("NewSegment larger than MaxSegmentSize succeeded")
+	}
+}
+
+func TestMultiSegmentMaxSegmentSize(t *testing.T) {
+	const max = 64
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(max)))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 8})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	for i := uint16(0); i < 8; i++ {
+		sub, err := NewStruct(seg, ObjectSize{DataSize: 24})
+		if err != nil {
+			t.Fatalf("NewStruct #%d: %v", i, err)
+		}
+		sub.SetUint64(0, uint64(i))
+		if err := root.SetPtr(i, sub.ToPtr()); err != nil {
+			t.Fatalf("root.SetPtr(%d, ...): %v", i, err)
+		}
+	}
+	if n := msg.NumSegments(); n < 3 {
+		t.Errorf("msg.NumSegments() = %d; want >= 3", n)
+	}
+	for i := int64(0); i < msg.NumSegments(); i++ {
+		s, err := msg.Segment(SegmentID(i))
+		if err != nil {
+			t.Fatalf("msg.Segment(%d): %v", i, err)
+		}
+		if n := len(s.Data()); n > max {
+			t.Errorf("len(msg.Segment(%d).Data()) = %d; want <= %d", i, n, max)
+		}
+	}
+
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg2, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	p, err := msg2.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	for i := uint16(0); i < 8; i++ {
+		sub, err := p.Struct().Ptr(i)
+		if err != nil {
+			t.Errorf("root.Ptr(%d): %v", i, err)
+			continue
+		}
+		if got := sub.Struct().Uint64(0); got != uint64(i) {
+			t.Errorf("root.Ptr(%d).Struct().Uint64(0) = %d; want %d", i, got, i)
+		}
+	}
+
+	if _, err := NewStruct(seg, ObjectSize{DataSize: max + 8}); err == nil {
+		t.Errorf("NewStruct of %d bytes succeeded; want error", max+8)
+	}
+}
+
 type serializeTest struct {
 	name        string
 	segs        [][]byte
@@ -509,6 +629,80 @@ var serializeTests = []serializeTest{
 	},
 }
 
+func TestRingArena(t *testing.T) {
+	ring := RingArena(make([]byte, 100), 48) // two regions of 48 bytes
+	var msgs [2]Message
+	for i := range msgs {
+		seg, err := ring.Reset(&msgs[i])
+		if err != nil {
+			t.Fatalf("ring.Reset #%d: %v", i, err)
+		}
+		root, err := NewRootStruct(seg, ObjectSize{DataSize: 8})
+		if err != nil {
+			t.Fatalf("NewRootStruct #%d: %v", i, err)
+		}
+		root.SetUint64(0, uint64(i+1))
+	}
+	if _, err := ring.Arena(); err != ErrRingFull {
+		t.Errorf("ring.Arena() with all regions in use error = %v; want %v", err, ErrRingFull)
+	}
+
+	// Messages built in the ring don't overlap.
+	for i := range msgs {
+		p, err := msgs[i].RootPtr()
+		if err != nil {
+			t.Fatalf("msgs[%d].RootPtr(): %v", i, err)
+		}
+		if x := p.Struct().Uint64(0); x != uint64(i+1) {
+			t.Errorf("msgs[%d] root field = %d; want %d", i, x, i+1)
+		}
+	}
+
+	// Releasing the oldest message frees its region for the next one,
+	// which starts out zeroed.
+	ring.Release(msgs[0].Arena)
+	seg, err := ring.Reset(&msgs[0])
+	if err != nil {
+		t.Fatal("ring.Reset after release:", err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal("NewRootStruct after release:", err)
+	}
+	if x := root.Uint64(0); x != 0 {
+		t.Errorf("reused region root field = %d; want 0", x)
+	}
+	if _, err := NewStruct(seg, ObjectSize{DataSize: 48}); err == nil {
+		t.Error("NewStruct larger than ring region succeeded")
+	}
+}
+
+// BenchmarkRingArena emits a million small messages per iteration from
+// a ring that the consumer keeps up with.  It should report zero
+// allocations.
+func BenchmarkRingArena(b *testing.B) {
+	const nmsgs = 1000000
+	ring := RingArena(make([]byte, 64*1024), 64)
+	var msg Message
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < nmsgs; j++ {
+			seg, err := ring.Reset(&msg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			root, err := NewRootStruct(seg, ObjectSize{DataSize: 16, PointerCount: 1})
+			if err != nil {
+				b.Fatal(err)
+			}
+			root.SetUint64(0, uint64(j))
+			root.SetUint64(8, uint64(i))
+			ring.Release(msg.Arena)
+		}
+	}
+}
+
 func TestMarshal(t *testing.T) {
 	for i, test := range serializeTests {
 		if test.decodeFails {
@@ -532,6 +726,86 @@ func TestMarshal(t *testing.T) {
 	}
 }
 
+func TestMarshalWithoutCaps(t *testing.T) {
+	// Use small segments so that some objects are reached through far
+	// pointers and lie in later segments.
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(0, NewInterface(seg, 0).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(1, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	elems, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elems.Struct(0).SetUint64(0, 42)
+	if err := elems.Struct(1).SetPtr(0, NewInterface(elems.Segment(), 1).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(2, elems.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if msg.NumSegments() < 2 {
+		t.Fatal("message has one segment; want several")
+	}
+
+	if _, err := msg.MarshalWithoutCaps(CapPolicyError); err != errMarshalCap {
+		t.Errorf("MarshalWithoutCaps(CapPolicyError) error = %v; want %v", err, errMarshalCap)
+	}
+
+	before := atomic.LoadUint64(&msg.ReadLimiter().limit)
+	data, err := msg.MarshalWithoutCaps(CapPolicyNull)
+	if err != nil {
+		t.Fatal("MarshalWithoutCaps(CapPolicyNull):", err)
+	}
+	if after := atomic.LoadUint64(&msg.ReadLimiter().limit); after != before {
+		t.Errorf("read limit after MarshalWithoutCaps = %d; want %d", after, before)
+	}
+	stored, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	rp, err := stored.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	sroot := rp.Struct()
+	if p, err := sroot.Ptr(0); err != nil || p.IsValid() {
+		t.Errorf("stored root.Ptr(0) = %v, %v; want null", p, err)
+	}
+	if p, err := sroot.Ptr(1); err != nil || p.Text() != "hello" {
+		t.Errorf("stored root.Ptr(1).Text() = %q, %v; want \"hello\"", p.Text(), err)
+	}
+	p, err := sroot.Ptr(2)
+	if err != nil {
+		t.Fatal("stored root.Ptr(2):", err)
+	}
+	slist := p.List()
+	if slist.Len() != 2 || slist.Struct(0).Uint64(0) != 42 {
+		t.Error("stored list elements differ")
+	}
+	if p, err := slist.Struct(1).Ptr(0); err != nil || p.IsValid() {
+		t.Errorf("stored list[1].Ptr(0) = %v, %v; want null", p, err)
+	}
+	if _, err := stored.MarshalWithoutCaps(CapPolicyError); err != nil {
+		t.Error("MarshalWithoutCaps(CapPolicyError) of stored message:", err)
+	}
+
+	// The original message still has its capability pointers.
+	if p, err := root.Ptr(0); err != nil || !p.Interface().IsValid() {
+		t.Errorf("after MarshalWithoutCaps, root.Ptr(0) = %v, %v; want interface", p, err)
+	}
+}
+
 func TestUnmarshal(t *testing.T) {
 	for i, test := range serializeTests {
 		if test.encodeFails {
@@ -568,6 +842,281 @@ func TestUnmarshal(t *testing.T) {
 	}
 }
 
+func TestNewMessageFromSegments(t *testing.T) {
+	seg0 := []byte{
+		// Far pointer: segment 1, offset 0
+		0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+	}
+	seg1 := []byte{
+		// Landing pad: struct pointer, offset 0, 1 data word, 0 pointers
+		0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		// Struct data section
+		0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	msg, err := NewMessageFromSegments([][]byte{seg0, seg1})
+	if err != nil {
+		t.Fatal("NewMessageFromSegments:", err)
+	}
+	if n := msg.NumSegments(); n != 2 {
+		t.Errorf("msg.NumSegments() = %d; want 2", n)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	s := p.Struct()
+	if got := s.Uint64(0); got != 42 {
+		t.Errorf("root.Uint64(0) = %d; want 42", got)
+	}
+	if id := s.Segment().ID(); id != 1 {
+		t.Errorf("root struct is in segment %d; want 1", id)
+	}
+
+	// Writes go to the caller's slices.
+	s.SetUint64(0, 7)
+	if seg1[8] != 7 {
+		t.Errorf("after root.SetUint64(0, 7), seg1[8] = %d; want 7", seg1[8])
+	}
+}
+
+func TestNewMessageFromSegments_Invalid(t *testing.T) {
+	tests := [][][]byte{
+		nil,
+		{make([]byte, 8), make([]byte, 12)},
+	}
+	for _, segs := range tests {
+		if _, err := NewMessageFromSegments(segs); err == nil {
+			t.Errorf("NewMessageFromSegments(%v) succeeded; want error", segs)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint64(0, 42)
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Bytes(root)
+	if err != nil {
+		t.Fatal("Bytes:", err)
+	}
+	msg, err := FromBytes(data)
+	if err != nil {
+		t.Fatal("FromBytes:", err)
+	}
+	// FromBytes must not alias its argument.
+	for i := range data {
+		data[i] = 0xff
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if got := p.Struct().Uint64(0); got != 42 {
+		t.Errorf("root.Uint64(0) = %d; want 42", got)
+	}
+	if p, err := p.Struct().Ptr(0); err != nil {
+		t.Errorf("root.Ptr(0): %v", err)
+	} else if got := p.Text(); got != "hello" {
+		t.Errorf("root.Ptr(0).Text() = %q; want \"hello\"", got)
+	}
+	if _, err := Bytes(Struct{}); err == nil {
+		t.Error("Bytes(Struct{}) succeeded; want error")
+	}
+}
+
+func TestUnmarshal_ZeroSegments(t *testing.T) {
+	// A segment count of 2^32 - 1 + 1 overflows to zero.
+	data := []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+	if _, err := Unmarshal(data); err != errNoSegments {
+		t.Errorf("Unmarshal(zero segments) error = %v; want %v", err, errNoSegments)
+	}
+	if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err == nil {
+		t.Error("Decode(zero segments) succeeded")
+	}
+	if _, err := NewMessageFromSegments(nil); err != errNoSegments {
+		t.Errorf("NewMessageFromSegments(nil) error = %v; want %v", err, errNoSegments)
+	}
+}
+
+func TestUnmarshal_EmptyFirstSegment(t *testing.T) {
+	// An empty first segment is well-framed, but has no root pointer.
+	msg, err := Unmarshal([]byte{0, 0, 0, 0, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	if _, err := msg.RootPtr(); err != errNoRoot {
+		t.Errorf("RootPtr() error = %v; want %v", err, errNoRoot)
+	}
+	if err := msg.SetRootPtr(Ptr{}); err != errNoRoot {
+		t.Errorf("SetRootPtr() error = %v; want %v", err, errNoRoot)
+	}
+
+	// Empty segments after the first are legal.
+	msg, err = Unmarshal([]byte{
+		1, 0, 0, 0, 1, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	})
+	if err != nil {
+		t.Fatal("Unmarshal with empty second segment:", err)
+	}
+	if msg.NumSegments() != 2 {
+		t.Errorf("NumSegments() = %d; want 2", msg.NumSegments())
+	}
+	if p, err := msg.RootPtr(); err != nil || p.IsValid() {
+		t.Errorf("RootPtr() = %v, %v; want null pointer, <nil>", p, err)
+	}
+}
+
+func TestRootPtr_SelfOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"struct data", []byte{0xfc, 0xff, 0xff, 0xff, 1, 0, 0, 0}},
+		{"struct pointers", []byte{0xfc, 0xff, 0xff, 0xff, 0, 0, 1, 0}},
+		{"struct spanning root", []byte{
+			0xfc, 0xff, 0xff, 0xff, 1, 0, 1, 0,
+			0, 0, 0, 0, 0, 0, 0, 0,
+		}},
+		{"byte list", []byte{0xfd, 0xff, 0xff, 0xff, 0x42, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		msg := &Message{Arena: SingleSegment(test.data)}
+		if _, err := msg.RootPtr(); err != errRootOverlap {
+			t.Errorf("%s: RootPtr() error = %v; want %v", test.name, err, errRootOverlap)
+		}
+		d := NewDecoder(bytes.NewReader(mustMarshalSegment(t, test.data)))
+		d.StrictPointers(true)
+		if _, err := d.Decode(); err == nil {
+			t.Errorf("%s: strict Decode succeeded", test.name)
+		}
+	}
+
+	// A zero-sized struct is conventionally encoded with an offset of -1,
+	// which is not an overlap.
+	msg := &Message{Arena: SingleSegment([]byte{0xfc, 0xff, 0xff, 0xff, 0, 0, 0, 0})}
+	if p, err := msg.RootPtr(); err != nil || !p.IsValid() {
+		t.Errorf("RootPtr() of empty struct = %v, %v; want valid struct, <nil>", p, err)
+	}
+}
+
+// mustMarshalSegment frames data as a single-segment message.
+func mustMarshalSegment(t *testing.T, data []byte) []byte {
+	msg := &Message{Arena: SingleSegment(data)}
+	b, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRootList(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < records.Len(); i++ {
+		r := records.Struct(i)
+		r.SetUint64(0, uint64(i+1)*100)
+		if err := r.SetText(0, fmt.Sprintf("record %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := msg.SetRootList(records); err != nil {
+		t.Fatal("SetRootList:", err)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+
+	msg, err = Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	l, err := msg.RootList()
+	if err != nil {
+		t.Fatal("RootList:", err)
+	}
+	if l.Len() != 3 {
+		t.Fatalf("RootList().Len() = %d; want 3", l.Len())
+	}
+	for i := 0; i < l.Len(); i++ {
+		r := l.Struct(i)
+		if got, want := r.Uint64(0), uint64(i+1)*100; got != want {
+			t.Errorf("record %d number = %d; want %d", i, got, want)
+		}
+		p, err := r.Ptr(0)
+		if err != nil {
+			t.Errorf("record %d text: %v", i, err)
+			continue
+		}
+		if got, want := p.Text(), fmt.Sprintf("record %d", i); got != want {
+			t.Errorf("record %d text = %q; want %q", i, got, want)
+		}
+	}
+
+	if err := msg.SetRootList(List{}); err == nil {
+		t.Error("SetRootList(List{}) succeeded")
+	}
+
+	// A struct root is not a list.
+	_, seg, err = NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRootStruct(seg, ObjectSize{DataSize: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seg.Message().RootList(); err != errNotList {
+		t.Errorf("RootList() of struct root error = %v; want %v", err, errNotList)
+	}
+
+	// A null root reads as the zero List.
+	msg, _, err = NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, err := msg.RootList(); err != nil || l.IsValid() {
+		t.Errorf("RootList() of null root = %v, %v; want zero List, <nil>", l, err)
+	}
+}
+
+func TestFromBytes_Truncated(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Bytes(root)
+	if err != nil {
+		t.Fatal("Bytes:", err)
+	}
+	for n := 0; n < len(data); n += 4 {
+		if _, err := FromBytes(data[:n]); err == nil {
+			t.Errorf("FromBytes(data[:%d]) of %d bytes succeeded; want error", n, len(data))
+		}
+	}
+}
+
 func TestEncoder(t *testing.T) {
 	for i, test := range serializeTests {
 		if test.decodeFails {
@@ -630,7 +1179,132 @@ func TestDecoder(t *testing.T) {
 	}
 }
 
-func TestDecoder_MaxMessageSize(t *testing.T) {
+func TestDecoder_AliasBuffer(t *testing.T) {
+	const bigSize = 1 << 20
+	msgs := []*Message{
+		{Arena: MultiSegment([][]byte{
+			incrementingData(16),
+			incrementingData(8),
+		})},
+		{Arena: SingleSegment(make([]byte, bigSize))},
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatalf("Encode message %d: %v", i, err)
+		}
+	}
+	src := buf.Bytes()
+	inSrc := func(b []byte) bool {
+		start := uintptr(unsafe.Pointer(&src[0]))
+		p := uintptr(unsafe.Pointer(&b[0]))
+		return start <= p && p+uintptr(len(b)) <= start+uintptr(len(src))
+	}
+
+	dec := NewDecoder(&buf)
+	dec.AliasBuffer(true)
+	msg, err := dec.Decode()
+	if err != nil {
+		t.Fatal("Decode #1:", err)
+	}
+	if msg.NumSegments() != 2 {
+		t.Fatalf("Decode #1: NumSegments() = %d; want 2", msg.NumSegments())
+	}
+	for i := int64(0); i < msg.NumSegments(); i++ {
+		seg, err := msg.Segment(SegmentID(i))
+		if err != nil {
+			t.Fatalf("Decode #1: Segment(%d): %v", i, err)
+		}
+		want, _ := msgs[0].Segment(SegmentID(i))
+		if !bytes.Equal(seg.Data(), want.Data()) {
+			t.Errorf("Decode #1: Segment(%d) = % 02x; want % 02x", i, seg.Data(), want.Data())
+		}
+		if !inSrc(seg.Data()) {
+			t.Errorf("Decode #1: Segment(%d) is not a view into the buffer", i)
+		}
+	}
+
+	// Check that the segment data isn't copied.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	msg, err = dec.Decode()
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal("Decode #2:", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n >= bigSize {
+		t.Errorf("Decode #2 allocated %d bytes; want < %d", n, bigSize)
+	}
+	seg, err := msg.Segment(0)
+	if err != nil {
+		t.Fatal("Decode #2: Segment(0):", err)
+	}
+	if !inSrc(seg.Data()) {
+		t.Error("Decode #2: Segment(0) is not a view into the buffer")
+	}
+
+	// Allocating in an aliased message fails.
+	if _, err := NewStruct(seg, ObjectSize{DataSize: 8}); err != errReadOnly {
+		t.Errorf("NewStruct in aliased message error = %v; want %v", err, errReadOnly)
+	}
+	if _, err := NewText(seg, "hello"); err != errReadOnly {
+		t.Errorf("NewText in aliased message error = %v; want %v", err, errReadOnly)
+	}
+	// So does writing in place.
+	if err := msg.SetRootPtr(Ptr{}); err != errReadOnly {
+		t.Errorf("SetRootPtr in aliased message error = %v; want %v", err, errReadOnly)
+	}
+	s := Struct{seg: seg, off: 0, size: ObjectSize{DataSize: 8}, depthLimit: maxDepth}
+	if err := catchPanic(func() { s.SetUint64(0, 42) }); err != errReadOnly {
+		t.Errorf("SetUint64 in aliased message panic = %v; want %v", err, errReadOnly)
+	}
+	if s.Uint64(0) != 0 {
+		t.Error("SetUint64 changed the source buffer")
+	}
+	if err := msg.Zero(); err != errReadOnly {
+		t.Errorf("Zero on aliased message error = %v; want %v", err, errReadOnly)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Decode #3: %v; want %v", err, io.EOF)
+	}
+}
+
+func TestDecoder_DecodeInto(t *testing.T) {
+	want := &Message{Arena: MultiSegment([][]byte{
+		incrementingData(16),
+		incrementingData(8),
+	})}
+	data, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 16, 24, 64} {
+		dec := NewDecoder(bytes.NewReader(data))
+		buf := make([]byte, 0, size)
+		msg, err := dec.DecodeInto(buf)
+		if err != nil {
+			t.Errorf("DecodeInto(cap=%d): %v", size, err)
+			continue
+		}
+		for i := int64(0); i < want.NumSegments(); i++ {
+			seg, err := msg.Segment(SegmentID(i))
+			if err != nil {
+				t.Fatalf("DecodeInto(cap=%d): Segment(%d): %v", size, i, err)
+			}
+			wantSeg, _ := want.Segment(SegmentID(i))
+			if !bytes.Equal(seg.Data(), wantSeg.Data()) {
+				t.Errorf("DecodeInto(cap=%d): Segment(%d) = % 02x; want % 02x", size, i, seg.Data(), wantSeg.Data())
+			}
+		}
+		seg0, _ := msg.Segment(0)
+		if aliased := size >= 24 && &seg0.Data()[0] == &buf[:1][0]; aliased != (size >= 24) {
+			t.Errorf("DecodeInto(cap=%d): first segment aliases buffer = %t; want %t", size, aliased, size >= 24)
+		}
+	}
+}
+
+func TestDecoder_MaxMessageSize(t *testing.T) {
 	t.Parallel()
 	zeroWord := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
 	tests := []struct {
@@ -708,6 +1382,428 @@ func TestDecoder_MaxMessageSize(t *testing.T) {
 	}
 }
 
+func TestDecoder_PeekHeader(t *testing.T) {
+	t.Parallel()
+	msgs := []*Message{
+		{Arena: MultiSegment([][]byte{
+			incrementingData(8),
+			incrementingData(16),
+			incrementingData(24),
+		})},
+		{Arena: SingleSegment(incrementingData(32))},
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatalf("Encode message %d: %v", i, err)
+		}
+	}
+
+	d := NewDecoder(&buf)
+	tests := []struct {
+		segmentCount int
+		totalWords   uint64
+	}{
+		{3, 6},
+		{1, 4},
+	}
+	for i, test := range tests {
+		for j := 0; j < 2; j++ {
+			n, total, err := d.PeekHeader()
+			if err != nil {
+				t.Fatalf("message %d: PeekHeader #%d: %v", i, j+1, err)
+			}
+			if n != test.segmentCount || total != test.totalWords {
+				t.Errorf("message %d: PeekHeader #%d = %d, %d; want %d, %d", i, j+1, n, total, test.segmentCount, test.totalWords)
+			}
+		}
+		msg, err := d.Decode()
+		if err != nil {
+			t.Fatalf("message %d: Decode: %v", i, err)
+		}
+		if msg.NumSegments() != int64(test.segmentCount) {
+			t.Fatalf("message %d: Decode NumSegments() = %d; want %d", i, msg.NumSegments(), test.segmentCount)
+		}
+		for k := int64(0); k < msg.NumSegments(); k++ {
+			got, err := msg.Segment(SegmentID(k))
+			if err != nil {
+				t.Fatalf("message %d: Segment(%d): %v", i, k, err)
+			}
+			want, _ := msgs[i].Segment(SegmentID(k))
+			if !bytes.Equal(got.Data(), want.Data()) {
+				t.Errorf("message %d: Segment(%d) = % 02x; want % 02x", i, k, got.Data(), want.Data())
+			}
+		}
+	}
+	if _, _, err := d.PeekHeader(); err != io.EOF {
+		t.Errorf("PeekHeader at end of stream: %v; want %v", err, io.EOF)
+	}
+}
+
+func TestDecoder_SetMaxTotalBytes(t *testing.T) {
+	t.Parallel()
+	const (
+		nsegs   = 100
+		segSize = 1024
+	)
+	segs := make([][]byte, nsegs)
+	for i := range segs {
+		segs[i] = make([]byte, segSize)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(&Message{Arena: MultiSegment(segs)}); err != nil {
+		t.Fatal("Encode:", err)
+	}
+	data := buf.Bytes()
+	hdrSize := len(data) - nsegs*segSize
+
+	d := NewDecoder(bytes.NewReader(data))
+	d.SetMaxTotalBytes(nsegs * segSize)
+	if _, err := d.Decode(); err != errDecodeLimit {
+		t.Errorf("Decode with limit of segment data only: %v; want %v", err, errDecodeLimit)
+	}
+	// The limit must be enforced before reading segment data.
+	d = NewDecoder(bytes.NewReader(data[:hdrSize]))
+	d.SetMaxTotalBytes(64 << 10)
+	if _, err := d.Decode(); err != errDecodeLimit {
+		t.Errorf("Decode of header only with 64 KiB limit: %v; want %v", err, errDecodeLimit)
+	}
+	d = NewDecoder(bytes.NewReader(data))
+	d.SetMaxTotalBytes(2 * nsegs * segSize)
+	msg, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decode with generous limit: %v", err)
+	}
+	if msg.NumSegments() != nsegs {
+		t.Errorf("NumSegments() = %d; want %d", msg.NumSegments(), nsegs)
+	}
+}
+
+func TestDecoder_StrictPointers(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	good, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An "other" pointer with a non-zero type is reserved.
+	seg.writeRawPointer(root.pointerAddress(1), rawPointer(otherPointer)|1<<2)
+	bad, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(bytes.NewReader(bad))
+	m, err := d.Decode()
+	if err != nil {
+		t.Fatal("non-strict Decode:", err)
+	}
+	rp, err := m.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if _, err := rp.Struct().Ptr(1); err == nil {
+		t.Error("reading reserved pointer succeeded")
+	}
+
+	d = NewDecoder(bytes.NewReader(bad))
+	d.StrictPointers(true)
+	if _, err := d.Decode(); err == nil {
+		t.Error("strict Decode of message with reserved pointer succeeded")
+	}
+	d = NewDecoder(bytes.NewReader(bad))
+	d.StrictPointers(true)
+	if _, err := d.DecodeInto(nil); err == nil {
+		t.Error("strict DecodeInto of message with reserved pointer succeeded")
+	}
+
+	d = NewDecoder(bytes.NewReader(good))
+	d.StrictPointers(true)
+	m, err = d.Decode()
+	if err != nil {
+		t.Fatal("strict Decode of valid message:", err)
+	}
+	if limit := m.ReadLimiter().limit; limit != defaultTraverseLimit {
+		t.Errorf("read limit after strict Decode = %d; want %d", limit, defaultTraverseLimit)
+	}
+	rp, err = m.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if p, err := rp.Struct().Ptr(0); err != nil || p.Text() != "hello" {
+		t.Errorf("root.Ptr(0) = %q, %v; want \"hello\", <nil>", p.Text(), err)
+	}
+}
+
+func TestDecoder_DecodeBorrowed(t *testing.T) {
+	t.Parallel()
+	var stream bytes.Buffer
+	enc := NewEncoder(&stream)
+	for i := 0; i < 4; i++ {
+		msg, seg, err := NewMessage(SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := NewRootStruct(seg, ObjectSize{DataSize: 8})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root.SetUint64(0, uint64(i))
+		if err := enc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := NewDecoder(&stream)
+	next := func(want uint64) (*Message, func()) {
+		msg, release, err := dec.DecodeBorrowed()
+		if err != nil {
+			t.Fatal("DecodeBorrowed:", err)
+		}
+		root, err := msg.RootPtr()
+		if err != nil {
+			t.Fatal("RootPtr:", err)
+		}
+		if v := root.Struct().Uint64(0); v != want {
+			t.Errorf("message %d root = %d", want, v)
+		}
+		return msg, release
+	}
+	seg0 := func(msg *Message) *byte {
+		s, err := msg.Segment(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &s.Data()[0]
+	}
+
+	m0, release0 := next(0)
+	s0, err := m0.Segment(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStruct(s0, ObjectSize{DataSize: 8}); err == nil {
+		t.Error("allocating in borrowed message succeeded")
+	}
+	root0, err := m0.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if err := catchPanic(func() { root0.Struct().SetUint64(0, 42) }); err != errReadOnly {
+		t.Errorf("SetUint64 in borrowed message panic = %v; want %v", err, errReadOnly)
+	}
+	if err := m0.SetRootPtr(Ptr{}); err != errReadOnly {
+		t.Errorf("SetRootPtr in borrowed message error = %v; want %v", err, errReadOnly)
+	}
+	// Message 0 is still held, so message 1 must not overwrite it.
+	m1, release1 := next(1)
+	if seg0(m1) == seg0(m0) {
+		t.Error("message 1 reuses the buffer of unreleased message 0")
+	}
+	if root, _ := m0.RootPtr(); root.Struct().Uint64(0) != 0 {
+		t.Error("message 0 changed after decoding message 1")
+	}
+	release1()
+	release0()
+	release0()
+	m2, release2 := next(2)
+	if seg0(m2) != seg0(m0) {
+		t.Error("message 2 does not reuse the released buffer")
+	}
+	release2()
+	next(3)
+	if _, _, err := dec.DecodeBorrowed(); err != io.EOF {
+		t.Errorf("DecodeBorrowed at end of stream error = %v; want io.EOF", err)
+	}
+}
+
+func TestDecoder_AllowTrailingData(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, 0xde, 0xad, 0xbe, 0xef, 0x00, 0x01, 0x02)
+
+	checkHello := func(m *Message) {
+		rp, err := m.RootPtr()
+		if err != nil {
+			t.Fatal("RootPtr:", err)
+		}
+		p, err := rp.Struct().Ptr(0)
+		if err != nil {
+			t.Fatal("Ptr(0):", err)
+		}
+		if s := p.Text(); s != "hello" {
+			t.Errorf("root text = %q; want \"hello\"", s)
+		}
+	}
+
+	d := NewDecoder(bytes.NewReader(data))
+	m, err := d.Decode()
+	if err != nil {
+		t.Fatal("strict first Decode:", err)
+	}
+	checkHello(m)
+	if _, err := d.Decode(); err == nil || err == io.EOF {
+		t.Errorf("strict Decode of trailing junk = %v; want malformed frame error", err)
+	}
+
+	r := bytes.NewReader(data)
+	d = NewDecoder(r)
+	d.AllowTrailingData(true)
+	m, err = d.Decode()
+	if err != nil {
+		t.Fatal("lenient first Decode:", err)
+	}
+	checkHello(m)
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("lenient Decode of trailing junk = %v; want io.EOF", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("%d bytes left unread after lenient Decode; want 0", r.Len())
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("lenient Decode after EOF = %v; want io.EOF", err)
+	}
+}
+
+func TestFileStream(t *testing.T) {
+	t.Parallel()
+	msgs := []*Message{
+		{Arena: SingleSegment(incrementingData(8))},
+		{Arena: MultiSegment([][]byte{
+			incrementingData(16),
+			incrementingData(8),
+		})},
+		{Arena: SingleSegment(incrementingData(24))},
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatalf("Encode message %d: %v", i, err)
+		}
+	}
+	frames := buf.Bytes()
+	partial, err := msgs[2].Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+
+	tests := []struct {
+		name    string
+		trailer []byte
+		err     error
+	}{
+		{"clean end", nil, io.EOF},
+		{"partial header", partial[:4], io.ErrUnexpectedEOF},
+		{"header only", partial[:8], io.ErrUnexpectedEOF},
+		{"partial segment", partial[:len(partial)-8], io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		data := append(append([]byte(nil), frames...), test.trailer...)
+		fs := NewFileStream(bytes.NewReader(data))
+		for i, want := range msgs {
+			msg, err := fs.Next()
+			if err != nil {
+				t.Fatalf("%s: message %d: Next: %v", test.name, i, err)
+			}
+			if msg.NumSegments() != want.NumSegments() {
+				t.Fatalf("%s: message %d: NumSegments() = %d; want %d", test.name, i, msg.NumSegments(), want.NumSegments())
+			}
+			for k := int64(0); k < msg.NumSegments(); k++ {
+				got, err := msg.Segment(SegmentID(k))
+				if err != nil {
+					t.Fatalf("%s: message %d: Segment(%d): %v", test.name, i, k, err)
+				}
+				wantSeg, _ := want.Segment(SegmentID(k))
+				if !bytes.Equal(got.Data(), wantSeg.Data()) {
+					t.Errorf("%s: message %d: Segment(%d) = % 02x; want % 02x", test.name, i, k, got.Data(), wantSeg.Data())
+				}
+			}
+		}
+		for j := 0; j < 2; j++ {
+			if _, err := fs.Next(); err != test.err {
+				t.Errorf("%s: Next #%d after last message: %v; want %v", test.name, j+1, err, test.err)
+			}
+		}
+	}
+}
+
+func TestAutoDecoder(t *testing.T) {
+	t.Parallel()
+	msgs := []*Message{
+		{Arena: SingleSegment(incrementingData(8))},
+		{Arena: MultiSegment([][]byte{
+			incrementingData(16),
+			incrementingData(8),
+		})},
+		{Arena: SingleSegment(incrementingData(24))},
+		{Arena: SingleSegment(incrementingData(8))},
+	}
+	packed := []bool{true, false, true, false}
+	var buf bytes.Buffer
+	enc, penc := NewEncoder(&buf), NewPackedEncoder(&buf)
+	for i, msg := range msgs {
+		e := enc
+		if packed[i] {
+			e = penc
+		}
+		if err := e.Encode(msg); err != nil {
+			t.Fatalf("Encode message %d: %v", i, err)
+		}
+	}
+
+	dec := NewAutoDecoder(&buf)
+	for i, want := range msgs {
+		msg, isPacked, err := dec.DecodeFraming()
+		if err != nil {
+			t.Fatalf("message %d: DecodeFraming: %v", i, err)
+		}
+		if isPacked != packed[i] {
+			t.Errorf("message %d: isPacked = %t; want %t", i, isPacked, packed[i])
+		}
+		if msg.NumSegments() != want.NumSegments() {
+			t.Fatalf("message %d: NumSegments() = %d; want %d", i, msg.NumSegments(), want.NumSegments())
+		}
+		for k := int64(0); k < msg.NumSegments(); k++ {
+			got, err := msg.Segment(SegmentID(k))
+			if err != nil {
+				t.Fatalf("message %d: Segment(%d): %v", i, k, err)
+			}
+			wantSeg, _ := want.Segment(SegmentID(k))
+			if !bytes.Equal(got.Data(), wantSeg.Data()) {
+				t.Errorf("message %d: Segment(%d) = % 02x; want % 02x", i, k, got.Data(), wantSeg.Data())
+			}
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Decode after last message: %v; want %v", err, io.EOF)
+	}
+}
+
 // TestStreamHeaderPadding is a regression test for
 // stream header padding.
 //
@@ -798,6 +1894,732 @@ func TestFirstSegmentMessage_MultiSegment(t *testing.T) {
 	}
 }
 
+func TestMessageCapabilities(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := []Client{
+		ErrorClient(errors.New("cap 0")),
+		ErrorClient(errors.New("cap 1")),
+		ErrorClient(errors.New("cap 2")),
+		ErrorClient(errors.New("cap 3 (unreferenced)")),
+	}
+	msg.CapTable = clients
+
+	// payload {
+	//   content @0 = { a @0 = { cap @0 = cap 1 }, b @1 = [{cap 2}, {cap 1}] },
+	//   caps @1 = [cap 0, null],
+	// }
+	payload, err := NewRootStruct(seg, ObjectSize{PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := NewStruct(seg, ObjectSize{PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := payload.SetPtr(0, content.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetPtr(0, NewInterface(seg, 1).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := content.SetPtr(0, a.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewCompositeList(seg, ObjectSize{PointerCount: 1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Struct(0).SetPtr(0, NewInterface(seg, 2).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Struct(1).SetPtr(0, NewInterface(seg, 1).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := content.SetPtr(1, b.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	caps, err := NewPointerList(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := caps.SetPtr(0, NewInterface(seg, 0).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := payload.SetPtr(1, caps.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := msg.CapCount(); n != 4 {
+		t.Errorf("msg.CapCount() = %d; want 4", n)
+	}
+	got, err := msg.Capabilities()
+	if err != nil {
+		t.Fatal("msg.Capabilities():", err)
+	}
+	want := []Client{clients[1], clients[2], clients[0]}
+	if len(got) != len(want) {
+		t.Fatalf("msg.Capabilities() returned %d clients; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("msg.Capabilities()[%d] = %v; want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMessageCapabilities_NoCaps(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	before := atomic.LoadUint64(&msg.ReadLimiter().limit)
+	caps, err := msg.Capabilities()
+	if err != nil {
+		t.Fatal("msg.Capabilities():", err)
+	}
+	if len(caps) != 0 {
+		t.Errorf("msg.Capabilities() = %v; want empty", caps)
+	}
+	if after := atomic.LoadUint64(&msg.ReadLimiter().limit); after != before {
+		t.Errorf("read limit after Capabilities = %d; want %d", after, before)
+	}
+}
+
+func TestMessageResolveCap(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	promise := ErrorClient(errors.New("promise"))
+	id := msg.AddCap(promise)
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(0, NewInterface(seg, id).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := ErrorClient(errors.New("resolved"))
+	if err := msg.ResolveCap(id, resolved); err != nil {
+		t.Fatalf("msg.ResolveCap(%d, ...): %v", id, err)
+	}
+	p, err := root.Ptr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.Interface().Client()
+	if c != resolved {
+		t.Fatalf("after ResolveCap, Interface.Client() = %v; want %v", c, resolved)
+	}
+	_, err = c.Call(&Call{Method: Method{InterfaceID: 0x1234, MethodID: 0}}).Struct()
+	if err == nil || err.Error() != "resolved" {
+		t.Errorf("call after ResolveCap returned error %v; want resolved", err)
+	}
+	if err := msg.ResolveCap(id+1, resolved); err == nil {
+		t.Errorf("msg.ResolveCap(%d, ...) on table of size 1 = <nil>; want error", id+1)
+	}
+}
+
+func TestMessageSetArena(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint64(0, 0xdeadbeef)
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.SetArena(SingleSegment(nil)); err == nil {
+		t.Error("SetArena on message with data = <nil>; want error")
+	}
+
+	// Copy the message into a pre-sized buffer and swap to it.
+	old := seg.Data()
+	buf := make([]byte, len(old), 4096)
+	copy(buf, old)
+	msg.Reset(nil)
+	if err := msg.SetArena(SingleSegment(buf)); err != nil {
+		t.Fatal("SetArena after Reset:", err)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	root = p.Struct()
+	if got := root.Uint64(0); got != 0xdeadbeef {
+		t.Errorf("root.Uint64(0) = %#x; want 0xdeadbeef", got)
+	}
+	if p, err := root.Ptr(0); err != nil {
+		t.Errorf("root.Ptr(0): %v", err)
+	} else if got := p.Text(); got != "hello" {
+		t.Errorf("root.Ptr(0).Text() = %q; want \"hello\"", got)
+	}
+	seg, err = msg.Segment(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(seg.Data(), old) {
+		t.Errorf("segment 0 after SetArena = % 02x; want % 02x", seg.Data(), old)
+	}
+	if _, err := NewStruct(seg, ObjectSize{DataSize: 64}); err != nil {
+		t.Fatal("NewStruct after SetArena:", err)
+	}
+	if &seg.Data()[0] != &buf[0] {
+		t.Error("allocation after SetArena did not use the pre-sized buffer")
+	}
+}
+
+func TestMessageImport(t *testing.T) {
+	src, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcRoot, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcRoot.SetUint64(0, 0xdeadbeef)
+	if err := srcRoot.SetText(0, "the quick brown fox jumps over the lazy dog"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < list.Len(); i++ {
+		elem := list.Struct(i)
+		elem.SetUint64(0, uint64(i))
+		if err := elem.SetText(0, fmt.Sprintf("element %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srcRoot.SetPtr(1, list.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	srcCap := ErrorClient(errors.New("src cap"))
+	if err := srcRoot.SetPtr(2, NewInterface(seg, src.AddCap(srcCap)).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if n := src.NumSegments(); n < 3 {
+		t.Fatalf("source message has %d segments; want at least 3 for test", n)
+	}
+
+	dst, dseg, err := NewMessage(MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.AddCap(ErrorClient(errors.New("dst cap")))
+	dstRoot, err := NewRootStruct(dseg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := dst.Import(src)
+	if err != nil {
+		t.Fatal("dst.Import(src):", err)
+	}
+	if err := dstRoot.SetPtr(0, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.CapTable) != 2 || dst.CapTable[1] != srcCap {
+		t.Errorf("dst.CapTable = %v; want [dst cap, src cap]", dst.CapTable)
+	}
+
+	// Round-trip through the wire format to check that far pointers
+	// were rewritten and not just cached in memory.
+	data, err := dst.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	msg.CapTable = dst.CapTable
+	rp, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	p, err = rp.Struct().Ptr(0)
+	if err != nil {
+		t.Fatal("root.Ptr(0):", err)
+	}
+	s := p.Struct()
+	if got := s.Uint64(0); got != 0xdeadbeef {
+		t.Errorf("imported.Uint64(0) = %#x; want 0xdeadbeef", got)
+	}
+	if tp, err := s.Ptr(0); err != nil {
+		t.Errorf("imported.Ptr(0): %v", err)
+	} else if got, want := tp.Text(), "the quick brown fox jumps over the lazy dog"; got != want {
+		t.Errorf("imported.Ptr(0).Text() = %q; want %q", got, want)
+	}
+	lp, err := s.Ptr(1)
+	if err != nil {
+		t.Fatal("imported.Ptr(1):", err)
+	}
+	l := lp.List()
+	if l.Len() != 3 {
+		t.Fatalf("imported list length = %d; want 3", l.Len())
+	}
+	for i := 0; i < l.Len(); i++ {
+		elem := l.Struct(i)
+		if got := elem.Uint64(0); got != uint64(i) {
+			t.Errorf("list[%d].Uint64(0) = %d; want %d", i, got, i)
+		}
+		tp, err := elem.Ptr(0)
+		if err != nil {
+			t.Errorf("list[%d].Ptr(0): %v", i, err)
+			continue
+		}
+		if got, want := tp.Text(), fmt.Sprintf("element %d", i); got != want {
+			t.Errorf("list[%d].Ptr(0).Text() = %q; want %q", i, got, want)
+		}
+	}
+	ip, err := s.Ptr(2)
+	if err != nil {
+		t.Fatal("imported.Ptr(2):", err)
+	}
+	if c := ip.Interface().Client(); c != srcCap {
+		t.Errorf("imported.Ptr(2).Interface().Client() = %v; want src cap", c)
+	}
+
+	if _, err := dst.Import(dst); err == nil {
+		t.Error("dst.Import(dst) = <nil>; want error")
+	}
+	single, _, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := single.Import(src); err == nil {
+		t.Error("Import into single segment message = <nil>; want error")
+	}
+}
+
+func TestMessageImport_Invalid(t *testing.T) {
+	words := func(ws ...rawPointer) []byte {
+		data := make([]byte, len(ws)*8)
+		for i, w := range ws {
+			binary.LittleEndian.PutUint64(data[i*8:], uint64(w))
+		}
+		return data
+	}
+	tests := []struct {
+		name string
+		src  *Message
+	}{
+		{
+			name: "far pointer to missing segment",
+			src:  &Message{Arena: SingleSegment(words(rawFarPointer(5, 0)))},
+		},
+		{
+			// Adding the segment base would wrap around to the
+			// destination's first segment.
+			name: "far pointer to segment 2^32-1",
+			src:  &Message{Arena: SingleSegment(words(rawFarPointer(^SegmentID(0), 0)))},
+		},
+		{
+			name: "double-far pointer to missing segment",
+			src: &Message{Arena: SingleSegment(words(
+				rawDoubleFarPointer(0, 8),
+				rawFarPointer(7, 0),
+				rawStructPointer(0, ObjectSize{DataSize: 8}),
+			))},
+		},
+		{
+			name: "huge list of empty structs",
+			src: &Message{
+				Arena: SingleSegment(words(
+					rawListPointer(0, compositeList, 0),
+					rawStructPointer(1<<28, ObjectSize{}),
+				)),
+				TraverseLimit: 1 << 20,
+			},
+		},
+		{
+			name: "nesting deeper than CopyDepthLimit",
+			src:  deepMessage(t, 1000),
+		},
+	}
+	for _, test := range tests {
+		dst, dseg, err := NewMessage(MultiSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := NewRootStruct(dseg, ObjectSize{DataSize: 8, PointerCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root.SetUint64(0, 42)
+		want := append([]byte(nil), dseg.Data()...)
+
+		_, err = dst.Import(test.src)
+		if err == nil {
+			t.Errorf("%s: Import = <nil>; want error", test.name)
+		}
+		if n := dst.NumSegments(); n != 1 {
+			t.Errorf("%s: after failed Import, NumSegments() = %d; want 1", test.name, n)
+		}
+		if !bytes.Equal(dseg.Data(), want) {
+			t.Errorf("%s: failed Import changed destination segment:\n% x\nwant:\n% x", test.name, dseg.Data(), want)
+		}
+		if len(dst.CapTable) != 0 {
+			t.Errorf("%s: failed Import added %d capabilities", test.name, len(dst.CapTable))
+		}
+	}
+}
+
+func TestMessageImport_RemapsCaps(t *testing.T) {
+	// Source: root is a list of two interface pointers, both to cap 0,
+	// which exercises rewriting pointers inside a pointer list.
+	src, seg, err := NewMessage(MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcCap := ErrorClient(errors.New("src cap"))
+	id := src.AddCap(srcCap)
+	if id != 0 {
+		t.Fatalf("src.AddCap = %d; want 0", id)
+	}
+	list, err := NewPointerList(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < list.Len(); i++ {
+		if err := list.SetPtr(i, NewInterface(seg, id).ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.SetRootPtr(list.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, _, err := NewMessage(MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.AddCap(ErrorClient(errors.New("dst cap 0")))
+	dst.AddCap(ErrorClient(errors.New("dst cap 1")))
+	p, err := dst.Import(src)
+	if err != nil {
+		t.Fatal("dst.Import(src):", err)
+	}
+	if len(dst.CapTable) != 3 || dst.CapTable[2] != srcCap {
+		t.Fatalf("dst.CapTable = %v; want [dst cap 0, dst cap 1, src cap]", dst.CapTable)
+	}
+	l := p.List()
+	if l.Len() != 2 {
+		t.Fatalf("imported list length = %d; want 2", l.Len())
+	}
+	for i := 0; i < l.Len(); i++ {
+		ip, err := PointerList{l}.PtrAt(i)
+		if err != nil {
+			t.Fatalf("imported[%d]: %v", i, err)
+		}
+		iface := ip.Interface()
+		if got := iface.Capability(); got != 2 {
+			t.Errorf("imported[%d].Capability() = %d; want 2", i, got)
+		}
+		if c := iface.Client(); c != srcCap {
+			t.Errorf("imported[%d].Client() = %v; want src cap", i, c)
+		}
+	}
+	// The source message is left alone.
+	sp, err := list.PtrAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sp.Interface().Capability(); got != 0 {
+		t.Errorf("source[0].Capability() after import = %d; want 0", got)
+	}
+}
+
+func TestMessageZero(t *testing.T) {
+	tests := []struct {
+		name  string
+		arena Arena
+	}{
+		{"SingleSegment", SingleSegment(nil)},
+		{"MultiSegment", MultiSegment(nil, MaxSegmentSize(64))},
+	}
+	for _, test := range tests {
+		msg, seg, err := NewMessage(test.arena)
+		if err != nil {
+			t.Fatalf("%s: NewMessage: %v", test.name, err)
+		}
+		root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+		if err != nil {
+			t.Fatalf("%s: NewRootStruct: %v", test.name, err)
+		}
+		root.SetUint64(0, 0xdeadbeef)
+		if err := root.SetText(0, "correct horse battery staple"); err != nil {
+			t.Fatalf("%s: SetText: %v", test.name, err)
+		}
+		if err := root.SetData(1, []byte("super secret key material")); err != nil {
+			t.Fatalf("%s: SetData: %v", test.name, err)
+		}
+
+		if err := msg.Zero(); err != nil {
+			t.Fatalf("%s: Zero: %v", test.name, err)
+		}
+		for i := int64(0); i < msg.NumSegments(); i++ {
+			s, err := msg.Segment(SegmentID(i))
+			if err != nil {
+				t.Fatalf("%s: Segment(%d): %v", test.name, i, err)
+			}
+			data := s.Data()
+			for j, b := range data[:cap(data)] {
+				if b != 0 {
+					t.Errorf("%s: segment %d byte %d = %#02x after Zero; want 0", test.name, i, j, b)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestMessageValidateDepthLimit(t *testing.T) {
+	t.Parallel()
+	msg := deepMessage(t, 1000)
+	if err := msg.Validate(); err != ErrDepthLimit {
+		t.Errorf("Validate() on 1000-deep message = %v; want %v", err, ErrDepthLimit)
+	}
+	if msg.Validated() {
+		t.Error("message is validated after Validate failed")
+	}
+	msg.CopyDepthLimit = 1000
+	if err := msg.Validate(); err != nil {
+		t.Errorf("Validate() on 1000-deep message with CopyDepthLimit = 1000: %v", err)
+	}
+}
+
+func TestMessageValidated(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Validated() {
+		t.Error("new message is validated before calling Validate")
+	}
+
+	if err := msg.Validate(); err != nil {
+		t.Fatal("Validate:", err)
+	}
+	if !msg.Validated() {
+		t.Error("message is not validated after Validate")
+	}
+	if _, err := root.Ptr(0); err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Validated() {
+		t.Error("reading a pointer cleared the validated flag")
+	}
+	root.SetUint32(0, 42)
+	if msg.Validated() {
+		t.Error("message is still validated after SetUint32")
+	}
+
+	if err := msg.Validate(); err != nil {
+		t.Fatal("Validate:", err)
+	}
+	if err := root.SetPtr(0, Ptr{}); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Validated() {
+		t.Error("message is still validated after SetPtr")
+	}
+
+	if err := msg.Validate(); err != nil {
+		t.Fatal("Validate:", err)
+	}
+	root.DataBytes()
+	if msg.Validated() {
+		t.Error("message is still validated after DataBytes")
+	}
+
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.Validate(); err != nil {
+		t.Fatal("Validate:", err)
+	}
+	text, err := root.Ptr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := text.Text(); got != "hello" {
+		t.Errorf("Text() = %q; want \"hello\"", got)
+	}
+	if !msg.Validated() {
+		t.Error("Text cleared the validated flag")
+	}
+	text.TextBytes()
+	if msg.Validated() {
+		t.Error("message is still validated after TextBytes")
+	}
+
+	if err := msg.Validate(); err != nil {
+		t.Fatal("Validate:", err)
+	}
+	text.Data()
+	if msg.Validated() {
+		t.Error("message is still validated after Data")
+	}
+
+	// Validate leaves the read limit where it was.
+	if _, err := msg.RootPtr(); err != nil {
+		t.Fatal(err)
+	}
+	before := atomic.LoadUint64(&msg.ReadLimiter().limit)
+	if err := msg.Validate(); err != nil {
+		t.Fatal("Validate:", err)
+	}
+	if after := atomic.LoadUint64(&msg.ReadLimiter().limit); after != before {
+		t.Errorf("read limit after Validate = %d; want %d", after, before)
+	}
+
+	// A corrupt message fails validation and is not marked.
+	bad := &Message{Arena: SingleSegment([]byte{
+		0, 0, 0, 0, 0, 0, 1, 0,
+		0xfc, 0xff, 0xff, 0x7f, 0, 0, 1, 0,
+	})}
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate of message with out-of-bounds pointer succeeded")
+	}
+	if bad.Validated() {
+		t.Error("message is validated after failing Validate")
+	}
+}
+
+func TestSegmentSizes(t *testing.T) {
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "the quick brown fox jumps over the lazy dog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetData(1, make([]byte, 40)); err != nil {
+		t.Fatal(err)
+	}
+	sizes, err := msg.SegmentSizes()
+	if err != nil {
+		t.Fatal("SegmentSizes:", err)
+	}
+	if int64(len(sizes)) != msg.NumSegments() || len(sizes) < 2 {
+		t.Fatalf("len(SegmentSizes()) = %d; want %d (at least 2)", len(sizes), msg.NumSegments())
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(msg); err != nil {
+		t.Fatal("Encode:", err)
+	}
+	out := buf.Bytes()
+	if n := binary.LittleEndian.Uint32(out); int(n) != len(sizes)-1 {
+		t.Errorf("header segment count - 1 = %d; want %d", n, len(sizes)-1)
+	}
+	total := 0
+	for i, sz := range sizes {
+		if hdr := binary.LittleEndian.Uint32(out[4+4*i:]); hdr != sz {
+			t.Errorf("header size of segment %d = %d; SegmentSizes reports %d", i, hdr, sz)
+		}
+		total += int(sz) * 8
+	}
+	hdrSize := (4 + 4*len(sizes) + 7) &^ 7
+	if want := hdrSize + total; len(out) != want {
+		t.Errorf("len(Encode output) = %d; want %d from SegmentSizes", len(out), want)
+	}
+}
+
+func TestMessageSegments(t *testing.T) {
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint64(0, 0xdeadbeef)
+	if err := root.SetText(0, "the quick brown fox jumps over the lazy dog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetData(1, []byte("pack my box with five dozen liquor jugs")); err != nil {
+		t.Fatal(err)
+	}
+
+	n := msg.NumSegments()
+	if n < 2 {
+		t.Fatalf("NumSegments() = %d; want at least 2", n)
+	}
+	segs := make([][]byte, n)
+	for i := int64(0); i < n; i++ {
+		s, err := msg.Segment(SegmentID(i))
+		if err != nil {
+			t.Fatalf("Segment(%d): %v", i, err)
+		}
+		if s.ID() != SegmentID(i) {
+			t.Errorf("Segment(%d).ID() = %d", i, s.ID())
+		}
+		if len(s.Data()) == 0 {
+			t.Errorf("Segment(%d).Data() is empty", i)
+		}
+		segs[i] = append([]byte(nil), s.Data()...)
+	}
+	if _, err := msg.Segment(SegmentID(n)); err == nil {
+		t.Errorf("Segment(%d) succeeded past NumSegments", n)
+	}
+
+	// Forward the segments without reparsing.
+	fwd, err := NewMessageFromSegments(segs)
+	if err != nil {
+		t.Fatal("NewMessageFromSegments:", err)
+	}
+	p, err := fwd.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if got := p.Struct().Uint64(0); got != 0xdeadbeef {
+		t.Errorf("forwarded root.Uint64(0) = %#x; want 0xdeadbeef", got)
+	}
+	tp, err := p.Struct().Ptr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tp.Text(); got != "the quick brown fox jumps over the lazy dog" {
+		t.Errorf("forwarded root text = %q", got)
+	}
+}
+
 func TestNextAlloc(t *testing.T) {
 	const max32 = 1<<31 - 8
 	const max64 = 1<<63 - 8
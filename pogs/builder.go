@@ -0,0 +1,171 @@
+package pogs
+
+import (
+	"fmt"
+	"strings"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// A Builder fills in a struct one field at a time and checks that the
+// required fields have been set before the struct is used.  This is
+// useful for wide structs whose fields arrive from a stream: the struct
+// can be allocated up front and only linked into the message (for
+// example, with SetRootPtr) once Finalize succeeds.
+//
+// The struct's schema must be registered in the default registry.
+type Builder struct {
+	typeID uint64
+	s      capnp.Struct
+	ins    inserter
+	set    map[string]bool
+}
+
+// NewBuilder allocates a struct of the given type in seg and returns a
+// Builder for it.  The struct is not referenced by any pointer.
+func NewBuilder(typeID uint64, seg *capnp.Segment) (*Builder, error) {
+	b := &Builder{typeID: typeID, set: make(map[string]bool)}
+	sz, err := b.ins.structSize(typeID)
+	if err != nil {
+		return nil, fmt.Errorf("pogs: new builder for @%#x: %v", typeID, err)
+	}
+	b.s, err = capnp.NewStruct(seg, sz)
+	if err != nil {
+		return nil, fmt.Errorf("pogs: new builder for @%#x: %v", typeID, err)
+	}
+	return b, nil
+}
+
+// Struct returns the struct being built.  Pointer fields set directly
+// on the struct, such as with generated accessors, are seen by
+// Finalize, but data fields must be set with Set to be counted.
+func (b *Builder) Struct() capnp.Struct {
+	return b.s
+}
+
+// Set sets the field with the given name to val and records that the
+// field has been set.  val may be any value accepted by FieldMapper.
+// Setting a union member also sets the union's discriminant.
+func (b *Builder) Set(name string, val interface{}) error {
+	n, err := b.node()
+	if err != nil {
+		return fmt.Errorf("pogs: set field %s of @%#x: %v", name, b.typeID, err)
+	}
+	fields, err := n.StructNode().Fields()
+	if err != nil {
+		return fmt.Errorf("pogs: set field %s of @%#x: %v", name, b.typeID, err)
+	}
+	if fieldIndex(fields, name) == -1 {
+		return fmt.Errorf("pogs: set field %s of @%#x: %s has no field %s", name, b.typeID, shortDisplayName(n), name)
+	}
+	if err := b.ins.insertMapped(b.typeID, b.s, singleField{name, val}); err != nil {
+		return fmt.Errorf("pogs: set field %s of @%#x: %v", name, b.typeID, err)
+	}
+	b.set[name] = true
+	return nil
+}
+
+// Finalize returns the struct if all of the required fields are
+// present.  A field is present if it was set with Set or, for pointer
+// fields, if it is not null.  A union member is only present if it is
+// the union's current member.  Otherwise, Finalize returns an error
+// that lists the missing fields.
+func (b *Builder) Finalize(required ...string) (capnp.Struct, error) {
+	n, err := b.node()
+	if err != nil {
+		return capnp.Struct{}, fmt.Errorf("pogs: finalize @%#x: %v", b.typeID, err)
+	}
+	fields, err := n.StructNode().Fields()
+	if err != nil {
+		return capnp.Struct{}, fmt.Errorf("pogs: finalize @%#x: %v", b.typeID, err)
+	}
+	var missing []string
+	for _, name := range required {
+		i := fieldIndex(fields, name)
+		if i == -1 {
+			return capnp.Struct{}, fmt.Errorf("pogs: finalize @%#x: %s has no field %s", b.typeID, shortDisplayName(n), name)
+		}
+		ok, err := b.isPresent(n, fields.At(i), name)
+		if err != nil {
+			return capnp.Struct{}, fmt.Errorf("pogs: finalize @%#x: field %s: %v", b.typeID, name, err)
+		}
+		if !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return capnp.Struct{}, fmt.Errorf("pogs: finalize %s: missing required fields %s", shortDisplayName(n), strings.Join(missing, ", "))
+	}
+	return b.s, nil
+}
+
+func (b *Builder) isPresent(n schema.Node, f schema.Field, name string) (bool, error) {
+	if dv := f.DiscriminantValue(); dv != schema.Field_noDiscriminant {
+		off := capnp.DataOffset(n.StructNode().DiscriminantOffset() * 2)
+		if b.s.Uint16(off) != dv {
+			return false, nil
+		}
+	}
+	if b.set[name] || f.Which() != schema.Field_Which_slot {
+		return b.set[name], nil
+	}
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return false, err
+	}
+	if !isPointerType(typ) {
+		return false, nil
+	}
+	p, err := b.s.Ptr(uint16(f.Slot().Offset()))
+	if err != nil {
+		return false, err
+	}
+	return p.IsValid(), nil
+}
+
+func (b *Builder) node() (schema.Node, error) {
+	n, err := b.ins.nodes.Find(b.typeID)
+	if err != nil {
+		return schema.Node{}, err
+	}
+	if !n.IsValid() || n.Which() != schema.Node_Which_structNode {
+		return schema.Node{}, fmt.Errorf("cannot find struct type %#x", b.typeID)
+	}
+	return n, nil
+}
+
+// NewEmptyMessage allocates a single-segment message whose root is a
+// struct of the given type, sized according to the type's schema, with
+// all fields set to their defaults.  The schema must be registered in
+// the default registry.  This is a shortcut for tests and default
+// values that don't want to spell out the generated NewRoot function.
+func NewEmptyMessage(typeID uint64) (*capnp.Message, capnp.Struct, error) {
+	var ins inserter
+	sz, err := ins.structSize(typeID)
+	if err != nil {
+		return nil, capnp.Struct{}, fmt.Errorf("pogs: new message for @%#x: %v", typeID, err)
+	}
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, capnp.Struct{}, fmt.Errorf("pogs: new message for @%#x: %v", typeID, err)
+	}
+	root, err := capnp.NewRootStruct(seg, sz)
+	if err != nil {
+		return nil, capnp.Struct{}, fmt.Errorf("pogs: new message for @%#x: %v", typeID, err)
+	}
+	return msg, root, nil
+}
+
+// singleField is a FieldMapper that supplies a single field.
+type singleField struct {
+	name string
+	val  interface{}
+}
+
+func (sf singleField) Field(name string) (interface{}, bool) {
+	if name != sf.name {
+		return nil, false
+	}
+	return sf.val, true
+}
@@ -0,0 +1,127 @@
+package pogs
+
+import (
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestBuilder(t *testing.T) {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBuilder(rpccapnp.Call_TypeID, seg)
+	if err != nil {
+		t.Fatal("NewBuilder:", err)
+	}
+	sets := []struct {
+		name string
+		val  interface{}
+	}{
+		{"questionId", uint32(5)},
+		{"interfaceId", uint64(0xdeadbeef)},
+		{"methodId", uint16(2)},
+		{"params", mapFields{}},
+	}
+	for _, set := range sets {
+		if err := b.Set(set.name, set.val); err != nil {
+			t.Fatalf("Set(%q, %v): %v", set.name, set.val, err)
+		}
+	}
+	if err := b.Set("noSuchField", 1); err == nil {
+		t.Error("Set(\"noSuchField\", 1) = nil; want error")
+	}
+	if err := b.Set("methodId", "wrong type"); err == nil {
+		t.Error("Set(\"methodId\", \"wrong type\") = nil; want error")
+	}
+
+	required := []string{"questionId", "target", "interfaceId", "methodId", "params"}
+	_, err = b.Finalize(required...)
+	if err == nil {
+		t.Fatal("Finalize with missing target = nil; want error")
+	}
+	if !strings.Contains(err.Error(), "target") || strings.Contains(err.Error(), "params") {
+		t.Errorf("Finalize with missing target error = %q; want mention of only target", err)
+	}
+
+	// Set the target with the generated accessors.
+	target, err := rpccapnp.Call{Struct: b.Struct()}.NewTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.SetImportedCap(1)
+	s, err := b.Finalize(required...)
+	if err != nil {
+		t.Fatal("Finalize:", err)
+	}
+	if err := msg.SetRootPtr(s.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	call, err := rpccapnp.ReadRootCall(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.QuestionId() != 5 || call.InterfaceId() != 0xdeadbeef || call.MethodId() != 2 {
+		t.Errorf("call = (%d, %#x, %d); want (5, 0xdeadbeef, 2)", call.QuestionId(), call.InterfaceId(), call.MethodId())
+	}
+
+	if _, err := b.Finalize("noSuchField"); err == nil {
+		t.Error("Finalize(\"noSuchField\") = nil; want error")
+	}
+}
+
+func TestBuilder_Union(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBuilder(rpccapnp.Message_TypeID, seg)
+	if err != nil {
+		t.Fatal("NewBuilder:", err)
+	}
+	if err := b.Set("finish", mapFields{"questionId": uint32(1)}); err != nil {
+		t.Fatal("Set(\"finish\"):", err)
+	}
+	if _, err := b.Finalize("finish"); err != nil {
+		t.Error("Finalize(\"finish\"):", err)
+	}
+	if err := b.Set("release", mapFields{"id": uint32(1)}); err != nil {
+		t.Fatal("Set(\"release\"):", err)
+	}
+	if _, err := b.Finalize("finish"); err == nil {
+		t.Error("Finalize(\"finish\") after setting another union member = nil; want error")
+	}
+}
+
+func TestNewEmptyMessage(t *testing.T) {
+	msg, s, err := NewEmptyMessage(rpccapnp.Message_TypeID)
+	if err != nil {
+		t.Fatal("NewEmptyMessage(Message):", err)
+	}
+	// Allocate a message the way generated code does to find the size.
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := rpccapnp.NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != want.Size() {
+		t.Errorf("root size = %v; want %v", s.Size(), want.Size())
+	}
+	m, err := rpccapnp.ReadRootMessage(msg)
+	if err != nil {
+		t.Fatal("ReadRootMessage:", err)
+	}
+	if m.Which() != rpccapnp.Message_Which_unimplemented {
+		t.Errorf("root Which() = %v; want %v", m.Which(), rpccapnp.Message_Which_unimplemented)
+	}
+
+	if _, _, err := NewEmptyMessage(0xdeadbeef); err == nil {
+		t.Error("NewEmptyMessage(unknown type) = nil error; want error")
+	}
+}
@@ -0,0 +1,94 @@
+package pogs
+
+import (
+	"fmt"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// SetEnumByName sets the enum field named fieldName in s, a struct of
+// the given type, to the enumerant named enumerantName.  If the field
+// is a union member, the union discriminant is set as well.  The
+// schemas of the struct and enum types must be registered in the
+// default registry.
+func SetEnumByName(typeID uint64, s capnp.Struct, fieldName, enumerantName string) error {
+	var nodes nodemap.Map
+	if err := setEnumByName(&nodes, typeID, s, fieldName, enumerantName); err != nil {
+		return fmt.Errorf("pogs: set enum field %s of @%#x: %v", fieldName, typeID, err)
+	}
+	return nil
+}
+
+func setEnumByName(nodes *nodemap.Map, typeID uint64, s capnp.Struct, fieldName, enumerantName string) error {
+	n, err := nodes.Find(typeID)
+	if err != nil {
+		return err
+	}
+	if !n.IsValid() || n.Which() != schema.Node_Which_structNode {
+		return fmt.Errorf("cannot find struct type %#x", typeID)
+	}
+	fields, err := n.StructNode().Fields()
+	if err != nil {
+		return err
+	}
+	i := fieldIndex(fields, fieldName)
+	if i == -1 {
+		return fmt.Errorf("%s has no field %s", shortDisplayName(n), fieldName)
+	}
+	f := fields.At(i)
+	if f.Which() != schema.Field_Which_slot {
+		return fmt.Errorf("%s.%s is a group", shortDisplayName(n), fieldName)
+	}
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return err
+	}
+	if typ.Which() != schema.Type_Which_enum {
+		return fmt.Errorf("%s.%s is a %v, not an enum", shortDisplayName(n), fieldName, typ.Which())
+	}
+	v, err := enumerantValue(nodes, typ.Enum().TypeId(), enumerantName)
+	if err != nil {
+		return err
+	}
+	dv, err := f.Slot().DefaultValue()
+	if err != nil {
+		return err
+	}
+	if !isFieldInBounds(s.Size(), f.Slot().Offset(), typ) {
+		return fmt.Errorf("can't set %s.%s: allocated struct is too small", shortDisplayName(n), fieldName)
+	}
+	if d := f.DiscriminantValue(); d != schema.Field_noDiscriminant {
+		off := capnp.DataOffset(n.StructNode().DiscriminantOffset() * 2)
+		if s.Size().DataSize < capnp.Size(off+2) {
+			return fmt.Errorf("can't set discriminant for %s: allocated struct is too small", shortDisplayName(n))
+		}
+		s.SetUint16(off, d)
+	}
+	s.SetEnumField(capnp.DataOffset(f.Slot().Offset()*2), v^dv.Enum())
+	return nil
+}
+
+// enumerantValue returns the ordinal of the enumerant named name in the
+// enum type with the given ID.
+func enumerantValue(nodes *nodemap.Map, typeID uint64, name string) (uint16, error) {
+	n, err := nodes.Find(typeID)
+	if err != nil {
+		return 0, err
+	}
+	if n.Which() != schema.Node_Which_enum {
+		return 0, fmt.Errorf("cannot find enum type %#x", typeID)
+	}
+	enums, err := n.Enum().Enumerants()
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < enums.Len(); i++ {
+		b, _ := enums.At(i).NameBytes()
+		if bytesStrEqual(b, name) {
+			return uint16(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%s has no enumerant %s", shortDisplayName(n), name)
+}
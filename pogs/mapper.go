@@ -0,0 +1,231 @@
+package pogs
+
+import (
+	"fmt"
+	"reflect"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// A FieldMapper supplies field values to InsertFrom.  Implementing
+// FieldMapper allows copying from sources that aren't laid out as Go
+// structs the way Insert expects, such as protobuf messages or other
+// generated types.
+type FieldMapper interface {
+	// Field returns the value of the Cap'n Proto field with the given
+	// name.  ok is false if the source does not have the field, in
+	// which case it is left unset.
+	//
+	// The value must be of a Go type that Insert accepts for the
+	// field (see the package documentation), a FieldMapper for a
+	// struct or group field, or nil to clear a pointer field.
+	Field(name string) (val interface{}, ok bool)
+}
+
+// InsertFrom copies the fields supplied by src into s.  Each field in
+// the struct's schema is looked up in src by name.  Setting a union
+// member also sets the union's discriminant; it is an error for src to
+// supply more than one member of the same union.
+func InsertFrom(typeID uint64, s capnp.Struct, src FieldMapper) error {
+	ins := new(inserter)
+	err := ins.insertMapped(typeID, s, src)
+	if err != nil {
+		return fmt.Errorf("pogs: insert @%#x: %v", typeID, err)
+	}
+	return nil
+}
+
+func (ins *inserter) insertMapped(typeID uint64, s capnp.Struct, src FieldMapper) error {
+	n, err := ins.nodes.Find(typeID)
+	if err != nil {
+		return err
+	}
+	if !n.IsValid() || n.Which() != schema.Node_Which_structNode {
+		return fmt.Errorf("cannot find struct type %#x", typeID)
+	}
+	fields, err := n.StructNode().Fields()
+	if err != nil {
+		return err
+	}
+	var member string // union member that has been set
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.At(i)
+		name, err := f.Name()
+		if err != nil {
+			return err
+		}
+		dv := f.DiscriminantValue()
+		if um, ok := src.(unionMapper); ok && dv != schema.Field_noDiscriminant && !um.isActive(name, dv) {
+			continue
+		}
+		val, ok := src.Field(name)
+		if !ok {
+			continue
+		}
+		if dv != schema.Field_noDiscriminant {
+			if member != "" {
+				return fmt.Errorf("can't insert %s: union members %s and %s are both set", shortDisplayName(n), member, name)
+			}
+			off := capnp.DataOffset(n.StructNode().DiscriminantOffset() * 2)
+			if s.Size().DataSize < capnp.Size(off+2) {
+				return fmt.Errorf("can't set discriminant for %s: allocated struct is too small", shortDisplayName(n))
+			}
+			s.SetUint16(off, dv)
+			member = name
+		}
+		switch f.Which() {
+		case schema.Field_Which_slot:
+			if err := ins.insertMappedField(s, f, name, val); err != nil {
+				return err
+			}
+		case schema.Field_Which_group:
+			if gm, ok := val.(FieldMapper); ok {
+				err = ins.insertMapped(f.Group().TypeId(), s, gm)
+			} else {
+				err = ins.insertStruct(f.Group().TypeId(), s, reflect.ValueOf(val))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ins *inserter) insertMappedField(s capnp.Struct, f schema.Field, name string, val interface{}) error {
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return err
+	}
+	if val == nil {
+		if !isPointerType(typ) {
+			return fmt.Errorf("can't insert field %s of type %v from nil", name, typ.Which())
+		}
+		return s.SetPtr(uint16(f.Slot().Offset()), capnp.Ptr{})
+	}
+	sub, ok := val.(FieldMapper)
+	if !ok {
+		return ins.insertField(s, f, reflect.ValueOf(val))
+	}
+	if typ.Which() != schema.Type_Which_structType {
+		return fmt.Errorf("can't insert field %s of type %v from a FieldMapper", name, typ.Which())
+	}
+	id := typ.StructType().TypeId()
+	sz, err := ins.structSize(id)
+	if err != nil {
+		return err
+	}
+	ss, err := capnp.NewStruct(s.Segment(), sz)
+	if err != nil {
+		return err
+	}
+	if err := s.SetPtr(uint16(f.Slot().Offset()), ss.ToPtr()); err != nil {
+		return err
+	}
+	return ins.insertMapped(id, ss, sub)
+}
+
+func isPointerType(t schema.Type) bool {
+	switch t.Which() {
+	case schema.Type_Which_text, schema.Type_Which_data, schema.Type_Which_list,
+		schema.Type_Which_structType, schema.Type_Which_interface, schema.Type_Which_anyPointer:
+		return true
+	default:
+		return false
+	}
+}
+
+// unionMapper is implemented by FieldMappers that know which union
+// member is active, so that InsertFrom skips the inactive members
+// instead of reporting them as set.
+type unionMapper interface {
+	isActive(name string, discriminant uint16) bool
+}
+
+// FieldsOf returns a FieldMapper that reads the exported fields of val,
+// a Go struct or pointer to a struct.  Fields are named the same way
+// as for Insert: by a capnp tag if present, otherwise by the field's
+// name with its first letter lowercased.  Fields tagged "-" and fields
+// of embedded structs whose names are shadowed are skipped.  Unlike
+// Insert, FieldsOf does not need to know the schema, so a source struct
+// may have fields that the Cap'n Proto struct does not.
+//
+// If the struct has a Which field, it selects the union member as it
+// does for Insert, and the other union members are not reported.
+func FieldsOf(val interface{}) FieldMapper {
+	v := reflect.ValueOf(val)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		panic("pogs: FieldsOf called with non-struct " + v.Kind().String())
+	}
+	m := &reflectMapper{val: v, index: make(map[string][]int)}
+	m.visit(v.Type(), nil)
+	return m
+}
+
+type reflectMapper struct {
+	val   reflect.Value
+	index map[string][]int
+
+	which      []int  // index of the Which field, nil if none
+	fixedWhich string // member named by a Which field's tag
+}
+
+func (m *reflectMapper) visit(t reflect.Type, base []int) {
+	var embeds []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			// unexported field
+			continue
+		}
+		p := parseField(f, true)
+		switch {
+		case p.typ == embedField && f.Type.Kind() == reflect.Struct:
+			embeds = append(embeds, i)
+		case p.typ == mappedField && p.schemaName != "":
+			if _, dup := m.index[p.schemaName]; !dup {
+				m.index[p.schemaName] = appendIndex(base, i)
+			}
+		case p.typ == whichField && m.which == nil:
+			if p.fixedWhich == "" && f.Type.Kind() != reflect.Uint16 {
+				continue
+			}
+			m.which = appendIndex(base, i)
+			m.fixedWhich = p.fixedWhich
+		}
+	}
+	// Visit embedded structs last so that outer fields take precedence.
+	for _, i := range embeds {
+		m.visit(t.Field(i).Type, appendIndex(base, i))
+	}
+}
+
+func (m *reflectMapper) Field(name string) (interface{}, bool) {
+	idx, ok := m.index[name]
+	if !ok {
+		return nil, false
+	}
+	return m.val.FieldByIndex(idx).Interface(), true
+}
+
+func (m *reflectMapper) isActive(name string, discriminant uint16) bool {
+	switch {
+	case m.which == nil:
+		return true
+	case m.fixedWhich != "":
+		return name == m.fixedWhich
+	default:
+		return uint16(m.val.FieldByIndex(m.which).Uint()) == discriminant
+	}
+}
+
+func appendIndex(base []int, i int) []int {
+	idx := make([]int, len(base)+1)
+	copy(idx, base)
+	idx[len(base)] = i
+	return idx
+}
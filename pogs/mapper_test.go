@@ -0,0 +1,135 @@
+package pogs
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// mapFields is a FieldMapper backed by a map, standing in for an
+// adapter over another IDL's message type.
+type mapFields map[string]interface{}
+
+func (m mapFields) Field(name string) (interface{}, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+func TestInsertFrom(t *testing.T) {
+	type exceptionBase struct {
+		Reason string `capnp:"reason"`
+		Code   int    `capnp:"-"`
+	}
+	type exception struct {
+		exceptionBase
+		Kind  uint16 `capnp:"type"`
+		Extra string // not in the schema
+	}
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exc, err := rpccapnp.NewRootException(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &exception{
+		exceptionBase: exceptionBase{Reason: "out of cheese", Code: 42},
+		Kind:          uint16(rpccapnp.Exception_Type_overloaded),
+		Extra:         "ignored",
+	}
+	if err := InsertFrom(rpccapnp.Exception_TypeID, exc.Struct, FieldsOf(src)); err != nil {
+		t.Fatal("InsertFrom(Exception, FieldsOf(...)):", err)
+	}
+	if reason, err := exc.Reason(); err != nil {
+		t.Error("Reason:", err)
+	} else if reason != "out of cheese" {
+		t.Errorf("Reason() = %q; want \"out of cheese\"", reason)
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_overloaded {
+		t.Errorf("Type() = %v; want %v", typ, rpccapnp.Exception_Type_overloaded)
+	}
+	if exc.ObsoleteDurability() != 0 || exc.ObsoleteIsCallersFault() {
+		t.Error("fields missing from source were set")
+	}
+}
+
+func TestInsertFrom_Union(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := rpccapnp.NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := mapFields{"finish": mapFields{"questionId": uint32(7), "releaseResultCaps": true}}
+	if err := InsertFrom(rpccapnp.Message_TypeID, msg.Struct, src); err != nil {
+		t.Fatal("InsertFrom(Message, finish):", err)
+	}
+	if msg.Which() != rpccapnp.Message_Which_finish {
+		t.Fatalf("Which() = %v; want %v", msg.Which(), rpccapnp.Message_Which_finish)
+	}
+	fin, err := msg.Finish()
+	if err != nil {
+		t.Fatal("Finish:", err)
+	}
+	if id := fin.QuestionId(); id != 7 {
+		t.Errorf("Finish().QuestionId() = %d; want 7", id)
+	}
+	if !fin.ReleaseResultCaps() {
+		t.Error("Finish().ReleaseResultCaps() = false; want true")
+	}
+
+	tests := []struct {
+		name string
+		src  mapFields
+	}{
+		{"two union members", mapFields{"finish": mapFields{}, "abort": mapFields{}}},
+		{"wrong type", mapFields{"finish": mapFields{"questionId": "7"}}},
+		{"nil for data field", mapFields{"finish": mapFields{"questionId": nil}}},
+	}
+	for _, test := range tests {
+		msg, err := rpccapnp.NewMessage(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := InsertFrom(rpccapnp.Message_TypeID, msg.Struct, test.src); err == nil {
+			t.Errorf("%s: InsertFrom = nil; want error", test.name)
+		}
+	}
+}
+
+func TestInsertFrom_FieldsOfUnion(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := air.NewZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InsertFrom(air.Z_TypeID, z.Struct, FieldsOf(&Z{Which: air.Z_Which_f64, F64: 3.5})); err != nil {
+		t.Fatal("InsertFrom(Z, FieldsOf(f64)):", err)
+	}
+	if z.Which() != air.Z_Which_f64 {
+		t.Errorf("Which() = %v; want %v", z.Which(), air.Z_Which_f64)
+	} else if z.F64() != 3.5 {
+		t.Errorf("F64() = %g; want 3.5", z.F64())
+	}
+
+	z, err = air.NewZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InsertFrom(air.Z_TypeID, z.Struct, FieldsOf(&ZBool{Bool: true})); err != nil {
+		t.Fatal("InsertFrom(Z, FieldsOf(ZBool)):", err)
+	}
+	if z.Which() != air.Z_Which_bool {
+		t.Errorf("Which() = %v; want %v", z.Which(), air.Z_Which_bool)
+	} else if !z.Bool() {
+		t.Error("Bool() = false; want true")
+	}
+}
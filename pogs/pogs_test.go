@@ -8,6 +8,7 @@ import (
 	"github.com/kylelemons/godebug/pretty"
 	"zombiezen.com/go/capnproto2"
 	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
 type Z struct {
@@ -905,6 +906,100 @@ type ZBoolWithExtra struct {
 	ExtraField uint16
 }
 
+func TestSetVoidUnionMember(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vu, err := air.NewRootVoidUnion(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetVoidUnionMember(air.VoidUnion_TypeID, vu.Struct, "b"); err != nil {
+		t.Fatal("SetVoidUnionMember(VoidUnion, \"b\"):", err)
+	}
+	if w := vu.Which(); w != air.VoidUnion_Which_b {
+		t.Errorf("after SetVoidUnionMember(VoidUnion, \"b\"), Which() = %v; want %v", w, air.VoidUnion_Which_b)
+	}
+	if err := SetVoidUnionMember(air.VoidUnion_TypeID, vu.Struct, "a"); err != nil {
+		t.Fatal("SetVoidUnionMember(VoidUnion, \"a\"):", err)
+	}
+	if w := vu.Which(); w != air.VoidUnion_Which_a {
+		t.Errorf("after SetVoidUnionMember(VoidUnion, \"a\"), Which() = %v; want %v", w, air.VoidUnion_Which_a)
+	}
+
+	z, err := air.NewZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.SetF64(3.5)
+	if err := SetVoidUnionMember(air.Z_TypeID, z.Struct, "void"); err != nil {
+		t.Fatal("SetVoidUnionMember(Z, \"void\"):", err)
+	}
+	if w := z.Which(); w != air.Z_Which_void {
+		t.Errorf("after SetVoidUnionMember(Z, \"void\"), Which() = %v; want %v", w, air.Z_Which_void)
+	}
+	for _, name := range []string{"f64", "planebase", "noSuchField"} {
+		if err := SetVoidUnionMember(air.Z_TypeID, z.Struct, name); err == nil {
+			t.Errorf("SetVoidUnionMember(Z, %q) = nil; want error", name)
+		}
+	}
+	if w := z.Which(); w != air.Z_Which_void {
+		t.Errorf("after failed SetVoidUnionMember calls, Which() = %v; want %v", w, air.Z_Which_void)
+	}
+
+	ret, err := rpccapnp.NewReturn(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret.SetTakeFromOtherQuestion(42)
+	if err := SetVoidUnionMember(rpccapnp.Return_TypeID, ret.Struct, "canceled"); err != nil {
+		t.Fatal("SetVoidUnionMember(Return, \"canceled\"):", err)
+	}
+	if w := ret.Which(); w != rpccapnp.Return_Which_canceled {
+		t.Errorf("after SetVoidUnionMember(Return, \"canceled\"), Which() = %v; want %v", w, rpccapnp.Return_Which_canceled)
+	}
+}
+
+func TestSetEnumByName(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exc, err := rpccapnp.NewRootException(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEnumByName(rpccapnp.Exception_TypeID, exc.Struct, "type", "unimplemented"); err != nil {
+		t.Fatal("SetEnumByName(Exception, \"type\", \"unimplemented\"):", err)
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_unimplemented {
+		t.Errorf("after SetEnumByName, Type() = %v; want %v", typ, rpccapnp.Exception_Type_unimplemented)
+	}
+	if err := SetEnumByName(rpccapnp.Exception_TypeID, exc.Struct, "type", "failed"); err != nil {
+		t.Fatal("SetEnumByName(Exception, \"type\", \"failed\"):", err)
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_failed {
+		t.Errorf("after SetEnumByName, Type() = %v; want %v", typ, rpccapnp.Exception_Type_failed)
+	}
+
+	tests := []struct {
+		field, enumerant string
+	}{
+		{"type", "noSuchEnumerant"},
+		{"reason", "failed"},
+		{"noSuchField", "failed"},
+	}
+	for _, test := range tests {
+		if err := SetEnumByName(rpccapnp.Exception_TypeID, exc.Struct, test.field, test.enumerant); err == nil {
+			t.Errorf("SetEnumByName(Exception, %q, %q) = nil; want error", test.field, test.enumerant)
+		}
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_failed {
+		t.Errorf("after failed SetEnumByName calls, Type() = %v; want %v", typ, rpccapnp.Exception_Type_failed)
+	}
+}
+
 func TestExtraFields(t *testing.T) {
 	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
 	if err != nil {
@@ -0,0 +1,84 @@
+package pogs
+
+import (
+	"fmt"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// SetVoidUnionMember sets the union discriminant of s, a struct of the
+// given type, to the member named fieldName.  The member must be a Void
+// field or a group with no fields.  The type's schema must be registered
+// in the default registry.
+func SetVoidUnionMember(typeID uint64, s capnp.Struct, fieldName string) error {
+	var nodes nodemap.Map
+	if err := setVoidUnionMember(&nodes, typeID, s, fieldName); err != nil {
+		return fmt.Errorf("pogs: set union member %s of @%#x: %v", fieldName, typeID, err)
+	}
+	return nil
+}
+
+func setVoidUnionMember(nodes *nodemap.Map, typeID uint64, s capnp.Struct, fieldName string) error {
+	n, err := nodes.Find(typeID)
+	if err != nil {
+		return err
+	}
+	if !n.IsValid() || n.Which() != schema.Node_Which_structNode {
+		return fmt.Errorf("cannot find struct type %#x", typeID)
+	}
+	if !hasDiscriminant(n) {
+		return fmt.Errorf("%s has no union", shortDisplayName(n))
+	}
+	fields, err := n.StructNode().Fields()
+	if err != nil {
+		return err
+	}
+	i := fieldIndex(fields, fieldName)
+	if i == -1 {
+		return fmt.Errorf("%s has no field %s", shortDisplayName(n), fieldName)
+	}
+	f := fields.At(i)
+	dv := f.DiscriminantValue()
+	if dv == schema.Field_noDiscriminant {
+		return fmt.Errorf("%s.%s is not a union member", shortDisplayName(n), fieldName)
+	}
+	isVoid, err := isVoidField(nodes, f)
+	if err != nil {
+		return err
+	}
+	if !isVoid {
+		return fmt.Errorf("%s.%s is not void", shortDisplayName(n), fieldName)
+	}
+	off := capnp.DataOffset(n.StructNode().DiscriminantOffset() * 2)
+	if s.Size().DataSize < capnp.Size(off+2) {
+		return fmt.Errorf("can't set discriminant for %s: allocated struct is too small", shortDisplayName(n))
+	}
+	s.SetUint16(off, dv)
+	return nil
+}
+
+// isVoidField reports whether f is a Void slot or a group with no fields.
+func isVoidField(nodes *nodemap.Map, f schema.Field) (bool, error) {
+	switch f.Which() {
+	case schema.Field_Which_slot:
+		t, err := f.Slot().Type()
+		if err != nil {
+			return false, err
+		}
+		return t.Which() == schema.Type_Which_void, nil
+	case schema.Field_Which_group:
+		g, err := nodes.Find(f.Group().TypeId())
+		if err != nil {
+			return false, err
+		}
+		gf, err := g.StructNode().Fields()
+		if err != nil {
+			return false, err
+		}
+		return gf.Len() == 0, nil
+	default:
+		return false, nil
+	}
+}
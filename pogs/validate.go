@@ -0,0 +1,291 @@
+package pogs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// Rules maps field paths to the constraints that the fields' values
+// must satisfy.  A path is a dot-separated list of field names, like
+// "target.promisedAnswer.questionId".  Each name after the first
+// refers to a field of the struct or group named before it.  Fields of
+// a null struct pointer have their default values, as when reading
+// them with generated accessors.
+type Rules map[string][]Constraint
+
+// A Constraint checks the value of a field, returning an error that
+// describes the problem if the value is invalid.  The value passed to
+// a Constraint depends on the field's type:
+//
+//	Bool                          -> bool
+//	Int8, Int16, Int32, Int64     -> int64
+//	UInt8, UInt16, UInt32, UInt64 -> uint64
+//	Float32, Float64              -> float64
+//	Text                          -> string
+//	Data                          -> []byte
+//	enum                          -> EnumValue
+//	struct, List, interface,
+//	AnyPointer                    -> capnp.Ptr
+type Constraint func(val interface{}) error
+
+// EnumValue is the value passed to a Constraint for an enum field.
+type EnumValue struct {
+	Value uint16
+
+	// Known is true if Value is one of the enumerants declared in the
+	// schema that the program was compiled with.
+	Known bool
+}
+
+// A Violation is a field that failed a constraint.
+type Violation struct {
+	Path string
+	Err  error
+}
+
+func (v Violation) Error() string {
+	return v.Path + ": " + v.Err.Error()
+}
+
+// A ValidationError is returned by Validate and lists every violation
+// found, ordered by path.
+type ValidationError []Violation
+
+func (e ValidationError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString("pogs: validation failed: ")
+	for i, v := range e {
+		if i > 0 {
+			buf.WriteString("; ")
+		}
+		buf.WriteString(v.Error())
+	}
+	return buf.String()
+}
+
+// Validate checks the fields of s, a struct of the given type, against
+// rules.  The schema of the struct type and the types on each path must
+// be registered in the default registry.  Cap'n Proto schemas don't
+// have a standard way of declaring constraints, so the rules are
+// supplied by the caller.  If any field fails a constraint, Validate
+// returns a ValidationError.  Validate returns other errors if a path
+// does not name a field or if the struct can't be read.
+func Validate(typeID uint64, s capnp.Struct, rules Rules) error {
+	paths := make([]string, 0, len(rules))
+	for path := range rules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var v validator
+	var violations ValidationError
+	for _, path := range paths {
+		val, err := v.fieldValue(typeID, s, path)
+		if err != nil {
+			return fmt.Errorf("pogs: validate @%#x: %s: %v", typeID, path, err)
+		}
+		for _, c := range rules[path] {
+			if err := c(val); err != nil {
+				violations = append(violations, Violation{Path: path, Err: err})
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+type validator struct {
+	extracter
+}
+
+// fieldValue returns the value of the field at path as documented on
+// Constraint.
+func (v *validator) fieldValue(typeID uint64, s capnp.Struct, path string) (interface{}, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		n, err := v.nodes.Find(typeID)
+		if err != nil {
+			return nil, err
+		}
+		if n.Which() != schema.Node_Which_structNode {
+			return nil, fmt.Errorf("cannot find struct type %#x", typeID)
+		}
+		fields, err := n.StructNode().Fields()
+		if err != nil {
+			return nil, err
+		}
+		fi := fieldIndex(fields, name)
+		if fi == -1 {
+			return nil, fmt.Errorf("%s has no field %s", shortDisplayName(n), name)
+		}
+		f := fields.At(fi)
+		last := i == len(names)-1
+		if f.Which() == schema.Field_Which_group {
+			if last {
+				return s.ToPtr(), nil
+			}
+			typeID = f.Group().TypeId()
+			continue
+		}
+		typ, err := f.Slot().Type()
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return v.slotValue(s, f, typ)
+		}
+		if typ.Which() != schema.Type_Which_structType {
+			return nil, fmt.Errorf("%s.%s is a %v, not a struct", shortDisplayName(n), name, typ.Which())
+		}
+		p, err := s.Ptr(uint16(f.Slot().Offset()))
+		if err != nil {
+			return nil, err
+		}
+		typeID, s = typ.StructType().TypeId(), p.Struct()
+	}
+	panic("unreachable")
+}
+
+func (v *validator) slotValue(s capnp.Struct, f schema.Field, typ schema.Type) (interface{}, error) {
+	switch typ.Which() {
+	case schema.Type_Which_structType, schema.Type_Which_list, schema.Type_Which_interface, schema.Type_Which_anyPointer:
+		return s.Ptr(uint16(f.Slot().Offset()))
+	case schema.Type_Which_void:
+		return nil, nil
+	}
+	var goType reflect.Type
+	switch typ.Which() {
+	case schema.Type_Which_text:
+		goType = reflect.TypeOf("")
+	case schema.Type_Which_data:
+		goType = reflect.TypeOf([]byte(nil))
+	default:
+		k, ok := typeMap[typ.Which()]
+		if !ok {
+			return nil, fmt.Errorf("unknown field type %v", typ.Which())
+		}
+		goType = kindTypes[k]
+	}
+	val := reflect.New(goType).Elem()
+	if err := v.extractField(val, s, f); err != nil {
+		return nil, err
+	}
+	switch k := val.Kind(); {
+	case typ.Which() == schema.Type_Which_enum:
+		return v.enumValue(typ.Enum().TypeId(), uint16(val.Uint()))
+	case k >= reflect.Int8 && k <= reflect.Int64:
+		return val.Int(), nil
+	case k >= reflect.Uint8 && k <= reflect.Uint64:
+		return val.Uint(), nil
+	case k == reflect.Float32 || k == reflect.Float64:
+		return val.Float(), nil
+	default:
+		return val.Interface(), nil
+	}
+}
+
+func (v *validator) enumValue(typeID uint64, x uint16) (EnumValue, error) {
+	n, err := v.nodes.Find(typeID)
+	if err != nil {
+		return EnumValue{}, err
+	}
+	if n.Which() != schema.Node_Which_enum {
+		return EnumValue{}, fmt.Errorf("cannot find enum type %#x", typeID)
+	}
+	enums, err := n.Enum().Enumerants()
+	if err != nil {
+		return EnumValue{}, err
+	}
+	return EnumValue{Value: x, Known: int(x) < enums.Len()}, nil
+}
+
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+// NonEmpty returns a Constraint that requires a text, data, or list
+// field to have a non-zero length, or a struct, interface, or
+// AnyPointer field to be non-null.
+func NonEmpty() Constraint {
+	return func(val interface{}) error {
+		switch val := val.(type) {
+		case string:
+			if val == "" {
+				return errEmpty
+			}
+		case []byte:
+			if len(val) == 0 {
+				return errEmpty
+			}
+		case capnp.Ptr:
+			if !val.IsValid() {
+				return errEmpty
+			}
+			if l := val.List(); l.IsValid() && l.Len() == 0 {
+				return errEmpty
+			}
+		default:
+			return fmt.Errorf("NonEmpty can't check a %T", val)
+		}
+		return nil
+	}
+}
+
+// IntRange returns a Constraint that requires an integer or enum field
+// to be between min and max, inclusive.
+func IntRange(min, max int64) Constraint {
+	return func(val interface{}) error {
+		var inRange bool
+		switch v := val.(type) {
+		case int64:
+			inRange = min <= v && v <= max
+		case uint64:
+			inRange = max >= 0 && v <= uint64(max) && (min <= 0 || v >= uint64(min))
+		case EnumValue:
+			val = v.Value
+			inRange = min <= int64(v.Value) && int64(v.Value) <= max
+		default:
+			return fmt.Errorf("IntRange can't check a %T", val)
+		}
+		if !inRange {
+			return fmt.Errorf("%v is not between %d and %d", val, min, max)
+		}
+		return nil
+	}
+}
+
+// KnownEnum returns a Constraint that requires an enum field to be one
+// of the enumerants declared in the schema.  Values added to the enum
+// by newer versions of the schema fail this constraint.
+func KnownEnum() Constraint {
+	return func(val interface{}) error {
+		e, ok := val.(EnumValue)
+		if !ok {
+			return fmt.Errorf("KnownEnum can't check a %T", val)
+		}
+		if !e.Known {
+			return fmt.Errorf("unknown enumerant %d", e.Value)
+		}
+		return nil
+	}
+}
+
+var errEmpty = errors.New("must not be empty")
@@ -0,0 +1,99 @@
+package pogs
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestValidate(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := Rules{
+		"reason": {NonEmpty()},
+		"type":   {KnownEnum()},
+	}
+
+	exc, err := rpccapnp.NewRootException(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Validate(rpccapnp.Exception_TypeID, exc.Struct, rules)
+	verr, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Validate(empty exception) = %v; want ValidationError", err)
+	}
+	if len(verr) != 1 || verr[0].Path != "reason" {
+		t.Errorf("Validate(empty exception) violations = %v; want one for reason", verr)
+	}
+
+	if err := exc.SetReason("out of cheese"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(rpccapnp.Exception_TypeID, exc.Struct, rules); err != nil {
+		t.Errorf("Validate(exception with reason) = %v; want nil", err)
+	}
+
+	// All violations are reported.
+	exc.SetType(rpccapnp.Exception_Type(42))
+	exc.SetReason("")
+	err = Validate(rpccapnp.Exception_TypeID, exc.Struct, rules)
+	if verr, ok := err.(ValidationError); !ok || len(verr) != 2 || verr[0].Path != "reason" || verr[1].Path != "type" {
+		t.Errorf("Validate(bad exception) = %v; want violations for reason and type", err)
+	}
+
+	if err := Validate(rpccapnp.Exception_TypeID, exc.Struct, Rules{"noSuchField": {NonEmpty()}}); err == nil {
+		t.Error("Validate with unknown field = nil; want error")
+	} else if _, ok := err.(ValidationError); ok {
+		t.Errorf("Validate with unknown field = %v; want non-validation error", err)
+	}
+}
+
+func TestValidate_Path(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := rpccapnp.NewRootCall(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call.SetMethodId(7)
+	rules := Rules{
+		"target":                           {NonEmpty()},
+		"target.promisedAnswer.questionId": {IntRange(1, 100)},
+		"methodId":                         {IntRange(0, 5)},
+		"sendResultsTo":                    {NonEmpty()},
+	}
+	err = Validate(rpccapnp.Call_TypeID, call.Struct, rules)
+	verr, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Validate(call) = %v; want ValidationError", err)
+	}
+	want := []string{"methodId", "target", "target.promisedAnswer.questionId"}
+	if len(verr) != len(want) {
+		t.Fatalf("Validate(call) = %v; want violations for %v", err, want)
+	}
+	for i := range want {
+		if verr[i].Path != want[i] {
+			t.Errorf("violation %d path = %q; want %q", i, verr[i].Path, want[i])
+		}
+	}
+
+	target, err := call.NewTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa, err := target.NewPromisedAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa.SetQuestionId(5)
+	call.SetMethodId(3)
+	if err := Validate(rpccapnp.Call_TypeID, call.Struct, rules); err != nil {
+		t.Errorf("Validate(call) after fixes = %v; want nil", err)
+	}
+}
@@ -1,5 +1,7 @@
 package capnp
 
+import "fmt"
+
 // A Ptr is a reference to a Cap'n Proto struct, list, or interface.
 // The zero value is a null pointer.
 type Ptr struct {
@@ -58,6 +60,27 @@ func (p Ptr) StructDefault(def []byte) (Struct, error) {
 	return s, nil
 }
 
+// StructOfSize converts p to a Struct, returning an error if p is not
+// null and does not hold a Struct pointer, or if the struct is smaller
+// than expected in either its data or pointer section.  It is useful
+// for interpreting an AnyPointer field as a struct of a particular
+// type, such as the recipient of a Provide message.  Pass the zero
+// ObjectSize to accept a struct of any size, including one written by
+// an older version of the schema.  A null pointer yields the zero
+// Struct, which reads as all default values.
+func (p Ptr) StructOfSize(expected ObjectSize) (Struct, error) {
+	if !p.IsValid() {
+		return Struct{}, nil
+	}
+	if p.flags.ptrType() != structPtrType {
+		return Struct{}, errNotStruct
+	}
+	if p.size.DataSize < expected.DataSize || p.size.PointerCount < expected.PointerCount {
+		return Struct{}, fmt.Errorf("capnp: struct %v is smaller than expected %v", p.size, expected)
+	}
+	return p.Struct(), nil
+}
+
 // List converts p to a List. If p does not hold a List pointer,
 // the zero value is returned.
 func (p Ptr) List() List {
@@ -125,23 +148,27 @@ func (p Ptr) TextDefault(def string) string {
 
 // TextBytes attempts to convert p into Text, returning nil if p is not
 // a valid 1-byte list pointer.  It returns a slice directly into the
-// segment.
+// segment, so like Struct.DataBytes it clears the message's validated
+// flag.
 func (p Ptr) TextBytes() []byte {
 	b, ok := p.text()
 	if !ok {
 		return nil
 	}
+	p.seg.aliased()
 	return b
 }
 
 // TextBytesDefault attempts to convert p into Text, returning def if p
 // is not a valid 1-byte list pointer.  It returns a slice directly into
-// the segment.
+// the segment, so like Struct.DataBytes it clears the message's
+// validated flag.
 func (p Ptr) TextBytesDefault(def string) []byte {
 	b, ok := p.text()
 	if !ok {
 		return []byte(def)
 	}
+	p.seg.aliased()
 	return b
 }
 
@@ -159,13 +186,16 @@ func (p Ptr) text() (b []byte, ok bool) {
 }
 
 // Data attempts to convert p into Data, returning nil if p is not a
-// valid 1-byte list pointer.
+// valid 1-byte list pointer.  It returns a slice directly into the
+// segment, so like Struct.DataBytes it clears the message's validated
+// flag.
 func (p Ptr) Data() []byte {
 	return p.DataDefault(nil)
 }
 
 // DataDefault attempts to convert p into Data, returning def if p is
-// not a valid 1-byte list pointer.
+// not a valid 1-byte list pointer.  Like Data, it clears the message's
+// validated flag if it returns a slice of the segment.
 func (p Ptr) DataDefault(def []byte) []byte {
 	if !isOneByteList(p) {
 		return def
@@ -175,6 +205,7 @@ func (p Ptr) DataDefault(def []byte) []byte {
 	if b == nil {
 		return def
 	}
+	l.seg.aliased()
 	return b
 }
 
@@ -204,6 +235,40 @@ func (p Ptr) Segment() *Segment {
 	return p.seg
 }
 
+// Message returns the message this pointer points into.
+// If p is invalid, then Message returns nil.
+func (p Ptr) Message() *Message {
+	if p.seg == nil {
+		return nil
+	}
+	return p.seg.msg
+}
+
+// region returns the range of p's segment occupied by the object that
+// p refers to, including a composite list's tag word.  ok is false for
+// null and interface pointers, which don't occupy any of the segment.
+func (p Ptr) region() (start, end Address, ok bool) {
+	var sz Size
+	switch p.flags.ptrType() {
+	case structPtrType:
+		start, sz = p.off, p.size.totalSize()
+	case listPtrType:
+		l := p.List()
+		start, sz = l.off, l.allocSize()
+		if l.flags&isCompositeList != 0 {
+			// allocSize includes the tag word.
+			start -= Address(wordSize)
+		}
+	default:
+		return 0, 0, false
+	}
+	if p.seg == nil {
+		return 0, 0, false
+	}
+	end, _ = start.addSize(sz) // object was already validated
+	return start, end, true
+}
+
 // Default returns p if it is valid, otherwise it unmarshals def.
 func (p Ptr) Default(def []byte) (Ptr, error) {
 	if !p.IsValid() {
@@ -19,6 +19,25 @@ func nearPointerOffset(paddr, addr Address) pointerOffset {
 	return pointerOffset(addr/Address(wordSize) - paddr/Address(wordSize) - 1)
 }
 
+// movedPointer returns the pointer p, stored at paddr, re-encoded to be
+// stored at newAddr in the same segment.  Only near pointers depend on
+// where they are stored: far and capability pointers, null pointers,
+// and pointers to zero-sized structs are returned as is.
+func movedPointer(p rawPointer, paddr, newAddr Address) (rawPointer, error) {
+	if !p.isNear() {
+		return p, nil
+	}
+	base, ok := paddr.addSize(wordSize)
+	if !ok {
+		return 0, errOverflow
+	}
+	addr, ok := p.offset().resolve(base)
+	if !ok {
+		return 0, errPointerAddress
+	}
+	return p.withOffset(nearPointerOffset(newAddr, addr)), nil
+}
+
 // rawPointer is an encoded pointer.
 type rawPointer uint64
 
@@ -83,6 +102,20 @@ func (p rawPointer) pointerType() pointerType {
 	return t
 }
 
+// isNear reports whether p is a struct or list pointer whose offset is
+// relative to where it is stored.  Null pointers and pointers to
+// zero-sized structs don't refer to a location.
+func (p rawPointer) isNear() bool {
+	switch p.pointerType() {
+	case structPointer:
+		return !p.structSize().isZero()
+	case listPointer:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p rawPointer) structSize() ObjectSize {
 	c := uint16(p >> 32)
 	d := uint16(p >> 48)
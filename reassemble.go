@@ -0,0 +1,118 @@
+package capnp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Reassembler collects the segments of messages that may arrive in
+// any order, such as over a datagram transport.  Each segment is tagged
+// with the ID of the message it belongs to, its segment ID, and the
+// total number of segments in the message.  Once every segment of a
+// message has arrived, the Reassembler returns the complete message.
+//
+// It is safe to use a Reassembler from multiple goroutines.
+type Reassembler struct {
+	// Timeout is how long an incomplete message is kept after its first
+	// segment arrives.  Expire discards messages older than this.  If
+	// Timeout is zero, incomplete messages are never discarded.
+	Timeout time.Duration
+
+	// MaxPending limits how many incomplete messages are kept at once.
+	// Once it is reached, Add returns an error for segments of new
+	// messages until a pending message completes or expires, so a peer
+	// cannot use up memory by starting messages it never finishes.  If
+	// MaxPending is zero, it defaults to 1024.
+	MaxPending int
+
+	mu      sync.Mutex
+	pending map[uint64]*partialMessage
+}
+
+type partialMessage struct {
+	segs     [][]byte
+	have     int
+	deadline time.Time
+}
+
+// Add records data as segment id of the message msgID, which has nsegs
+// segments in total.  If this completes the message, Add returns it;
+// otherwise Add returns nil.  The message aliases the segment data, so
+// the caller must not modify data after passing it to Add.  A segment
+// that has already been received is ignored.
+func (r *Reassembler) Add(msgID uint64, id SegmentID, nsegs int, data []byte) (*Message, error) {
+	if nsegs <= 0 || nsegs > maxStreamSegments {
+		return nil, fmt.Errorf("capnp: reassemble message %d: invalid segment count %d", msgID, nsegs)
+	}
+	if int64(id) >= int64(nsegs) {
+		return nil, fmt.Errorf("capnp: reassemble message %d: segment %d out of range [0, %d)", msgID, id, nsegs)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pm := r.pending[msgID]
+	if pm == nil {
+		if max := r.maxPending(); len(r.pending) >= max {
+			return nil, fmt.Errorf("capnp: reassemble message %d: too many incomplete messages (limit %d)", msgID, max)
+		}
+		pm = &partialMessage{segs: make([][]byte, nsegs)}
+		if r.Timeout > 0 {
+			pm.deadline = time.Now().Add(r.Timeout)
+		}
+		if r.pending == nil {
+			r.pending = make(map[uint64]*partialMessage)
+		}
+		r.pending[msgID] = pm
+	} else if len(pm.segs) != nsegs {
+		return nil, fmt.Errorf("capnp: reassemble message %d: segment count %d does not match earlier count %d", msgID, nsegs, len(pm.segs))
+	}
+	if pm.segs[id] != nil {
+		return nil, nil
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	pm.segs[id] = data
+	pm.have++
+	if pm.have < nsegs {
+		return nil, nil
+	}
+	delete(r.pending, msgID)
+	msg, err := NewMessageFromSegments(pm.segs)
+	if err != nil {
+		return nil, fmt.Errorf("capnp: reassemble message %d: %v", msgID, err)
+	}
+	return msg, nil
+}
+
+func (r *Reassembler) maxPending() int {
+	if r.MaxPending > 0 {
+		return r.MaxPending
+	}
+	return defaultMaxPending
+}
+
+const defaultMaxPending = 1024
+
+// Expire discards the incomplete messages whose timeout has passed as
+// of now and returns their IDs.  It should be called periodically.
+// Segments that arrive for a discarded message start a new message.
+func (r *Reassembler) Expire(now time.Time) []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uint64
+	for id, pm := range r.pending {
+		if !pm.deadline.IsZero() && !now.Before(pm.deadline) {
+			delete(r.pending, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Pending returns the number of incomplete messages.
+func (r *Reassembler) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
@@ -0,0 +1,150 @@
+package capnp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReassembler(t *testing.T) {
+	msg, seg, err := NewMessage(MultiSegment(nil, MaxSegmentSize(64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint64(0, 0xdeadbeef)
+	if err := root.SetText(0, "the quick brown fox jumps over the lazy dog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(1, "pack my box with five dozen liquor jugs"); err != nil {
+		t.Fatal(err)
+	}
+	nsegs := int(msg.NumSegments())
+	if nsegs < 3 {
+		t.Fatalf("message has %d segments; want at least 3 for test", nsegs)
+	}
+	segs := make([][]byte, nsegs)
+	for i := range segs {
+		s, err := msg.Segment(SegmentID(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		segs[i] = append([]byte(nil), s.Data()...)
+	}
+
+	r := &Reassembler{Timeout: time.Minute}
+	// Deliver segment 1 last and the rest in reverse order.
+	var order []SegmentID
+	for i := nsegs - 1; i >= 0; i-- {
+		if i != 1 {
+			order = append(order, SegmentID(i))
+		}
+	}
+	for _, id := range order {
+		m, err := r.Add(42, id, nsegs, segs[id])
+		if err != nil {
+			t.Fatalf("Add(42, %d, ...): %v", id, err)
+		}
+		if m != nil {
+			t.Fatalf("Add(42, %d, ...) returned message before all segments arrived", id)
+		}
+	}
+	// Duplicate segments are ignored.
+	if m, err := r.Add(42, 2, nsegs, segs[2]); m != nil || err != nil {
+		t.Fatalf("Add(42, 2, ...) duplicate = %v, %v; want <nil>, <nil>", m, err)
+	}
+	if _, err := r.Add(42, 1, nsegs+1, segs[1]); err == nil {
+		t.Error("Add with mismatched segment count = <nil>; want error")
+	}
+	m, err := r.Add(42, 1, nsegs, segs[1])
+	if err != nil {
+		t.Fatal("Add(42, 1, ...):", err)
+	}
+	if m == nil {
+		t.Fatal("Add(42, 1, ...) = <nil> message after all segments arrived")
+	}
+	if n := r.Pending(); n != 0 {
+		t.Errorf("r.Pending() = %d after reassembly; want 0", n)
+	}
+	p, err := m.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	s := p.Struct()
+	if got := s.Uint64(0); got != 0xdeadbeef {
+		t.Errorf("root.Uint64(0) = %#x; want 0xdeadbeef", got)
+	}
+	for i, want := range []string{"the quick brown fox jumps over the lazy dog", "pack my box with five dozen liquor jugs"} {
+		tp, err := s.Ptr(uint16(i))
+		if err != nil {
+			t.Errorf("root.Ptr(%d): %v", i, err)
+			continue
+		}
+		if got := tp.Text(); got != want {
+			t.Errorf("root.Ptr(%d).Text() = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestReassembler_Timeout(t *testing.T) {
+	r := &Reassembler{Timeout: time.Minute}
+	data := make([]byte, 8)
+	if _, err := r.Add(1, 0, 2, data); err != nil {
+		t.Fatal(err)
+	}
+	if ids := r.Expire(time.Now()); len(ids) != 0 {
+		t.Errorf("Expire(now) = %v; want no messages", ids)
+	}
+	ids := r.Expire(time.Now().Add(2 * time.Minute))
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("Expire(now + 2m) = %v; want [1]", ids)
+	}
+	if n := r.Pending(); n != 0 {
+		t.Errorf("r.Pending() = %d after Expire; want 0", n)
+	}
+
+	// A late segment starts over instead of completing the message.
+	m, err := r.Add(1, 1, 2, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != nil {
+		t.Error("Add after Expire completed a discarded message")
+	}
+}
+
+func TestReassembler_MaxPending(t *testing.T) {
+	r := &Reassembler{MaxPending: 2}
+	data := make([]byte, 8)
+	for id := uint64(1); id <= 2; id++ {
+		if _, err := r.Add(id, 0, 2, data); err != nil {
+			t.Fatalf("Add(%d, 0, 2): %v", id, err)
+		}
+	}
+	if _, err := r.Add(3, 0, 2, data); err == nil {
+		t.Error("Add for a new message beyond MaxPending succeeded")
+	}
+	// Segments of pending messages are still accepted.
+	m, err := r.Add(1, 1, 2, data)
+	if err != nil {
+		t.Fatal("Add(1, 1, 2):", err)
+	}
+	if m == nil {
+		t.Error("Add(1, 1, 2) did not complete message 1")
+	}
+	if _, err := r.Add(3, 0, 2, data); err != nil {
+		t.Errorf("Add(3, 0, 2) after message 1 completed: %v", err)
+	}
+
+	r = new(Reassembler)
+	for id := uint64(0); id < defaultMaxPending; id++ {
+		if _, err := r.Add(id, 0, 2, data); err != nil {
+			t.Fatalf("Add(%d, 0, 2) with default MaxPending: %v", id, err)
+		}
+	}
+	if _, err := r.Add(defaultMaxPending, 0, 2, data); err == nil {
+		t.Errorf("Add for message %d succeeded; want default limit of %d", defaultMaxPending, defaultMaxPending)
+	}
+}
@@ -0,0 +1,101 @@
+package capnp
+
+import "io"
+
+// A RecordWriter writes a stream of records, each a struct framed as
+// its own single-segment message.  This is the common pattern of using
+// Cap'n Proto as a log or export format: records are written as they
+// are produced, so the whole stream never needs to be held in memory.
+// The stream can be read with a RecordReader, a FileStream, or any
+// Cap'n Proto implementation that reads concatenated messages.
+type RecordWriter struct {
+	enc *Encoder
+	msg *Message
+	buf []byte
+}
+
+// NewRecordWriter returns a RecordWriter that writes to w.
+func NewRecordWriter(w io.Writer) *RecordWriter {
+	return &RecordWriter{enc: NewEncoder(w)}
+}
+
+// NewRecord allocates an empty struct of the given size as the root of
+// a new record and returns it.  The writer reuses the record's buffer
+// for the next record, so the struct is only valid until the next call
+// to NewRecord or Write.
+func (rw *RecordWriter) NewRecord(sz ObjectSize) (Struct, error) {
+	if err := rw.reset(); err != nil {
+		return Struct{}, err
+	}
+	seg, err := rw.msg.Segment(0)
+	if err != nil {
+		return Struct{}, err
+	}
+	return NewRootStruct(seg, sz)
+}
+
+// Write writes s as the next record.  If s was allocated in the record
+// started by the last call to NewRecord, s becomes the record's root
+// and the record is written without copying.  Otherwise, s and the
+// objects it refers to are first copied into a new record, so s may
+// belong to any message.
+func (rw *RecordWriter) Write(s Struct) error {
+	if rw.msg == nil || s.seg == nil || s.seg.msg != rw.msg {
+		if err := rw.reset(); err != nil {
+			return err
+		}
+	}
+	if err := rw.msg.SetRootPtr(s.ToPtr()); err != nil {
+		return err
+	}
+	return rw.enc.Encode(rw.msg)
+}
+
+// reset replaces rw.msg with an empty message that reuses the previous
+// record's buffer.
+func (rw *RecordWriter) reset() error {
+	if rw.msg != nil {
+		if seg, err := rw.msg.Segment(0); err == nil {
+			rw.buf = seg.data[:0]
+		}
+	}
+	msg, _, err := NewMessage(SingleSegment(rw.buf))
+	if err != nil {
+		return err
+	}
+	rw.msg = msg
+	return nil
+}
+
+// A RecordReader reads a stream of records written by a RecordWriter.
+type RecordReader struct {
+	fs *FileStream
+}
+
+// NewRecordReader returns a RecordReader that reads from r.  Like a
+// FileStream, it reuses its buffer between records, so a record
+// returned by Next is only valid until the following call to Next.
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{fs: NewFileStream(r)}
+}
+
+// Next reads the next record and returns its root struct.  Next returns
+// io.EOF at the end of the stream.  It returns an error if a record's
+// root is not a struct.  Once Next returns an error, it returns the
+// same error on every subsequent call.
+func (rr *RecordReader) Next() (Struct, error) {
+	msg, err := rr.fs.Next()
+	if err != nil {
+		return Struct{}, err
+	}
+	root, err := msg.RootPtr()
+	if err != nil {
+		rr.fs.err = err
+		return Struct{}, err
+	}
+	if root.IsValid() && root.flags.ptrType() != structPtrType {
+		rr.fs.err = errNotStruct
+		return Struct{}, errNotStruct
+	}
+	return root.Struct(), nil
+}
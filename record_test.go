@@ -0,0 +1,68 @@
+package capnp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestRecordStream(t *testing.T) {
+	const n = 100000
+	var buf bytes.Buffer
+	rw := NewRecordWriter(&buf)
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			// Build directly in the writer's record.
+			rec, err := rw.NewRecord(ObjectSize{DataSize: 8, PointerCount: 1})
+			if err != nil {
+				t.Fatalf("NewRecord #%d: %v", i, err)
+			}
+			rec.SetUint64(0, uint64(i))
+			if err := rec.SetText(0, fmt.Sprint("rec", i)); err != nil {
+				t.Fatalf("SetText #%d: %v", i, err)
+			}
+			if err := rw.Write(rec); err != nil {
+				t.Fatalf("Write #%d: %v", i, err)
+			}
+			continue
+		}
+		// Build elsewhere and let Write copy it.
+		_, seg, err := NewMessage(SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.SetUint64(0, uint64(i))
+		if err := rec.SetText(0, fmt.Sprint("rec", i)); err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.Write(rec); err != nil {
+			t.Fatalf("Write #%d: %v", i, err)
+		}
+	}
+
+	rr := NewRecordReader(&buf)
+	for i := 0; i < n; i++ {
+		rec, err := rr.Next()
+		if err != nil {
+			t.Fatalf("Next #%d: %v", i, err)
+		}
+		if got := rec.Uint64(0); got != uint64(i) {
+			t.Fatalf("record #%d number = %d; want %d", i, got, i)
+		}
+		p, err := rec.Ptr(0)
+		if err != nil {
+			t.Fatalf("record #%d text: %v", i, err)
+		}
+		if got, want := p.Text(), fmt.Sprint("rec", i); got != want {
+			t.Fatalf("record #%d text = %q; want %q", i, got, want)
+		}
+	}
+	if _, err := rr.Next(); err != io.EOF {
+		t.Errorf("Next after last record = %v; want io.EOF", err)
+	}
+}
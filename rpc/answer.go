@@ -50,11 +50,23 @@ type answer struct {
 	conn       *Conn
 	resolved   chan struct{}
 
+	// resultsElsewhere is true if the caller asked for the results to
+	// be kept in this vat (sendResultsTo.yourself).  The results are
+	// still available for pipelining, but the Return message will not
+	// include them.
+	resultsElsewhere bool
+
 	mu    sync.RWMutex
 	obj   capnp.Ptr
 	err   error
 	done  bool
 	queue []pcall
+
+	// forward is the question that this answer's call was forwarded to
+	// with sendResultsTo.yourself, or nil.  Calls pipelined on the
+	// answer are sent to forward, even after the answer is done, since
+	// the results are kept in the remote vat.
+	forward *question
 }
 
 // fulfill is called to resolve an answer successfully.  It returns an
@@ -78,15 +90,22 @@ func (a *answer) fulfill(obj capnp.Ptr) error {
 	} else {
 		retmsg := newReturnMessage(nil, a.id)
 		ret, _ := retmsg.Return()
-		payload, _ := ret.NewResults()
-		payload.SetContentPtr(obj)
-		if payloadTab, err := a.conn.makeCapTable(ret.Segment()); err != nil {
-			firstErr = err
-		} else {
-			payload.SetCapTable(payloadTab)
+		if a.resultsElsewhere {
+			ret.SetResultsSentElsewhere()
 			if err := a.conn.sendMessage(retmsg); err != nil {
 				firstErr = err
 			}
+		} else {
+			payload, _ := ret.NewResults()
+			payload.SetContentPtr(obj)
+			if payloadTab, err := a.conn.makeCapTable(ret.Segment()); err != nil {
+				firstErr = err
+			} else {
+				payload.SetCapTable(payloadTab)
+				if err := a.conn.sendMessage(retmsg); err != nil {
+					firstErr = err
+				}
+			}
 		}
 
 		queues, err := a.emptyQueue(obj)
@@ -134,6 +153,25 @@ func (a *answer) reject(err error) error {
 	return firstErr
 }
 
+// takeFrom resolves an answer whose call was forwarded to q, after the
+// remote vat kept q's results.  The Return tells the caller to take the
+// results from its own answer to q.  The caller must be holding onto
+// a.conn.mu.
+func (a *answer) takeFrom(q *question) error {
+	a.mu.Lock()
+	if a.done {
+		panic("answer.takeFrom called on resolved answer")
+	}
+	a.done = true
+	m := newReturnMessage(nil, a.id)
+	ret, _ := m.Return()
+	ret.SetTakeFromOtherQuestion(uint32(q.id))
+	err := a.conn.sendMessage(m)
+	close(a.resolved)
+	a.mu.Unlock()
+	return err
+}
+
 // emptyQueue splits the queue by which capability it targets
 // and drops any invalid calls.  Once this function returns, a.queue
 // will be nil.
@@ -189,6 +227,11 @@ func (a *answer) queueDisembargo(transform []capnp.PipelineOp, id embargoID, tar
 	if !a.done {
 		return false, errDisembargoOngoingAnswer
 	}
+	if a.forward != nil {
+		// Calls pipelined on the answer were forwarded to the remote vat
+		// in order, so there's nothing to embargo.
+		return false, nil
+	}
 	if a.err != nil {
 		return false, errDisembargoNonImport
 	}
@@ -233,6 +276,26 @@ func joinAnswer(a *answer, ca capnp.Answer) {
 	a.conn.mu.Unlock()
 }
 
+// joinForwarded resolves an RPC answer with the results of the question
+// that its call was forwarded to, unless the remote vat kept them and
+// the answer took them with takeFrom.  The caller must not be holding
+// onto a.conn.mu.
+func joinForwarded(a *answer, q *question) {
+	s, err := q.Struct()
+	a.conn.mu.Lock()
+	a.mu.RLock()
+	done := a.done
+	a.mu.RUnlock()
+	if !done {
+		if err == nil {
+			a.fulfill(s.ToPtr())
+		} else {
+			a.reject(err)
+		}
+	}
+	a.conn.mu.Unlock()
+}
+
 // joinFulfiller resolves a fulfiller by waiting on a generic answer.
 func joinFulfiller(f *fulfiller.Fulfiller, ca capnp.Answer) {
 	s, err := ca.Struct()
@@ -460,6 +523,10 @@ type localAnswerClient struct {
 
 func (lac *localAnswerClient) Call(call *capnp.Call) capnp.Answer {
 	lac.a.mu.Lock()
+	if q := lac.a.forward; q != nil {
+		lac.a.mu.Unlock()
+		return q.PipelineCall(lac.transform, call)
+	}
 	if lac.a.done {
 		obj, err := lac.a.obj, lac.a.err
 		lac.a.mu.Unlock()
@@ -479,9 +546,9 @@ func (lac *localAnswerClient) Call(call *capnp.Call) capnp.Answer {
 
 func (lac *localAnswerClient) Close() error {
 	lac.a.mu.RLock()
-	obj, err, done := lac.a.obj, lac.a.err, lac.a.done
+	obj, err, done, forward := lac.a.obj, lac.a.err, lac.a.done, lac.a.forward
 	lac.a.mu.RUnlock()
-	if !done {
+	if !done || forward != nil {
 		return nil
 	}
 	client := clientFromResolution(lac.transform, obj, err)
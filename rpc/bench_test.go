@@ -1,6 +1,10 @@
 package rpc_test
 
 import (
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -9,6 +13,7 @@ import (
 	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
 func BenchmarkPingPong(b *testing.B) {
@@ -53,3 +58,88 @@ func (pingPongServer) EchoNum(call testcapnp.PingPong_echoNum) error {
 	call.Results.SetN(call.Params.N())
 	return nil
 }
+
+func BenchmarkStreamTransport_SendMixed(b *testing.B) {
+	b.Run("Default", func(b *testing.B) {
+		benchmarkStreamTransportSendMixed(b)
+	})
+	b.Run("PooledSendBuffers", func(b *testing.B) {
+		benchmarkStreamTransportSendMixed(b, rpc.PooledSendBuffers())
+	})
+}
+
+// benchmarkStreamTransportSendMixed sends mostly small messages with an
+// occasional large one, then reports how much heap remains in use
+// after a collection while the transport is still alive.
+func benchmarkStreamTransportSendMixed(b *testing.B, options ...rpc.StreamTransportOption) {
+	small := strings.Repeat("x", 100)
+	large := strings.Repeat("x", 4<<20)
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	t := rpc.StreamTransport(nopCloser{ioutil.Discard}, options...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reason := small
+		if i%100 == 0 {
+			reason = large
+		}
+		err := sendMessage(context.Background(), t, func(msg rpccapnp.Message) error {
+			ab, err := msg.NewAbort()
+			if err != nil {
+				return err
+			}
+			return ab.SetReason(reason)
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	var after runtime.MemStats
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(t)
+	retained := int64(after.HeapInuse) - int64(before.HeapInuse)
+	if retained < 0 {
+		retained = 0
+	}
+	b.ReportMetric(float64(retained), "retained-B")
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Read(p []byte) (int, error) { return 0, io.EOF }
+func (nopCloser) Close() error               { return nil }
+
+func BenchmarkStreamTransport_SendMultiSegment(b *testing.B) {
+	m := newMultiSegmentMessage(b)
+	b.Run("Transport", func(b *testing.B) {
+		conn := new(writeCountConn)
+		tr := rpc.StreamTransport(conn)
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			conn.buf.Reset()
+			if err := tr.SendMessage(ctx, m); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(conn.writes)/float64(b.N), "writes/op")
+	})
+	b.Run("Encoder", func(b *testing.B) {
+		conn := new(writeCountConn)
+		enc := capnp.NewEncoder(conn)
+		for i := 0; i < b.N; i++ {
+			conn.buf.Reset()
+			if err := enc.Encode(m.Segment().Message()); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(conn.writes)/float64(b.N), "writes/op")
+	})
+}
@@ -0,0 +1,132 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// A CallSpec describes a Call message for BuildCall.
+type CallSpec struct {
+	QuestionID uint32
+	Target     rpccapnp.MessageTarget
+	Method     capnp.Method
+
+	// Params is the content of the call's parameter payload and
+	// CapTable describes the capabilities that interface pointers in
+	// Params refer to.
+	Params   capnp.Ptr
+	CapTable []rpccapnp.CapDescriptor
+
+	// SendResultsTo is where the callee should send the results.  The
+	// zero value is the caller.  ThirdParty is only used if
+	// SendResultsTo is thirdParty.
+	SendResultsTo rpccapnp.Call_sendResultsTo_Which
+	ThirdParty    capnp.Ptr
+
+	AllowThirdPartyTailCall bool
+}
+
+// BuildCall allocates a Call in seg with the fields from spec.  The
+// target, parameters, and capability descriptors are copied into the
+// Call if they are in a different message.  The result can be set as
+// the call variant of a Message.
+func BuildCall(seg *capnp.Segment, spec CallSpec) (rpccapnp.Call, error) {
+	call, err := rpccapnp.NewCall(seg)
+	if err != nil {
+		return rpccapnp.Call{}, err
+	}
+	call.SetQuestionId(spec.QuestionID)
+	if err := call.SetTarget(spec.Target); err != nil {
+		return rpccapnp.Call{}, err
+	}
+	call.SetInterfaceId(spec.Method.InterfaceID)
+	call.SetMethodId(spec.Method.MethodID)
+	call.SetAllowThirdPartyTailCall(spec.AllowThirdPartyTailCall)
+	payload, err := call.NewParams()
+	if err != nil {
+		return rpccapnp.Call{}, err
+	}
+	if err := payload.SetContentPtr(spec.Params); err != nil {
+		return rpccapnp.Call{}, err
+	}
+	if len(spec.CapTable) > 0 {
+		ctab, err := payload.NewCapTable(int32(len(spec.CapTable)))
+		if err != nil {
+			return rpccapnp.Call{}, err
+		}
+		for i, desc := range spec.CapTable {
+			if err := ctab.Set(i, desc); err != nil {
+				return rpccapnp.Call{}, err
+			}
+		}
+	}
+	switch spec.SendResultsTo {
+	case rpccapnp.Call_sendResultsTo_Which_caller:
+		call.SendResultsTo().SetCaller()
+	case rpccapnp.Call_sendResultsTo_Which_yourself:
+		if err := SetSendResultsToYourself(call); err != nil {
+			return rpccapnp.Call{}, err
+		}
+	case rpccapnp.Call_sendResultsTo_Which_thirdParty:
+		if err := call.SendResultsTo().SetThirdPartyPtr(spec.ThirdParty); err != nil {
+			return rpccapnp.Call{}, err
+		}
+	default:
+		return rpccapnp.Call{}, fmt.Errorf("rpc: build call: unknown sendResultsTo %v", spec.SendResultsTo)
+	}
+	return call, nil
+}
+
+// SetSendResultsToYourself asks the callee of call to keep the results
+// instead of returning them (sendResultsTo.yourself).  The caller can
+// then send a Return with takeFromOtherQuestion to tell the vat that is
+// waiting on the results to take them from this call.  call must
+// already have a target, since only a call on a capability or promise
+// that the callee hosts can have its results kept there.
+func SetSendResultsToYourself(call rpccapnp.Call) error {
+	if !call.HasTarget() {
+		return errors.New("rpc: send results to yourself: call has no target")
+	}
+	target, err := call.Target()
+	if err != nil {
+		return fmt.Errorf("rpc: send results to yourself: %v", err)
+	}
+	switch target.Which() {
+	case rpccapnp.MessageTarget_Which_importedCap:
+	case rpccapnp.MessageTarget_Which_promisedAnswer:
+		if !target.HasPromisedAnswer() {
+			return errors.New("rpc: send results to yourself: promised answer target is null")
+		}
+	default:
+		return fmt.Errorf("rpc: send results to yourself: unknown target %v", target.Which())
+	}
+	call.SendResultsTo().SetYourself()
+	return nil
+}
+
+// BuildTransform allocates in seg the list of operations for a
+// PromisedAnswer's transform field that follows the pointer fields in
+// order, starting at the answer's results.  Each field becomes a
+// getPointerField op.  An empty path becomes a single noop, which
+// refers to the results themselves.
+func BuildTransform(seg *capnp.Segment, fields []uint16) (rpccapnp.PromisedAnswer_Op_List, error) {
+	if len(fields) == 0 {
+		ops, err := rpccapnp.NewPromisedAnswer_Op_List(seg, 1)
+		if err != nil {
+			return rpccapnp.PromisedAnswer_Op_List{}, err
+		}
+		ops.At(0).SetNoop()
+		return ops, nil
+	}
+	ops, err := rpccapnp.NewPromisedAnswer_Op_List(seg, int32(len(fields)))
+	if err != nil {
+		return rpccapnp.PromisedAnswer_Op_List{}, err
+	}
+	for i, f := range fields {
+		ops.At(i).SetGetPointerField(f)
+	}
+	return ops, nil
+}
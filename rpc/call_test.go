@@ -0,0 +1,209 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestBuildCall(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Inputs come from a different message to check that they're copied.
+	_, in, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := rpccapnp.NewMessageTarget(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.SetImportedCap(9)
+	params, err := capnp.NewStruct(in, capnp.ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.SetUint64(0, 0xfeed)
+	desc, err := rpccapnp.NewCapDescriptor(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc.SetSenderHosted(3)
+	thirdParty, err := capnp.NewText(in, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call, err := rpc.BuildCall(seg, rpc.CallSpec{
+		QuestionID:              17,
+		Target:                  target,
+		Method:                  capnp.Method{InterfaceID: 0xdeadbeef, MethodID: 4},
+		Params:                  params.ToPtr(),
+		CapTable:                []rpccapnp.CapDescriptor{desc},
+		SendResultsTo:           rpccapnp.Call_sendResultsTo_Which_thirdParty,
+		ThirdParty:              thirdParty.ToPtr(),
+		AllowThirdPartyTailCall: true,
+	})
+	if err != nil {
+		t.Fatal("BuildCall:", err)
+	}
+	if id := call.QuestionId(); id != 17 {
+		t.Errorf("QuestionId() = %d; want 17", id)
+	}
+	if id := call.InterfaceId(); id != 0xdeadbeef {
+		t.Errorf("InterfaceId() = %#x; want 0xdeadbeef", id)
+	}
+	if id := call.MethodId(); id != 4 {
+		t.Errorf("MethodId() = %d; want 4", id)
+	}
+	if !call.AllowThirdPartyTailCall() {
+		t.Error("AllowThirdPartyTailCall() = false; want true")
+	}
+	tgt, err := call.Target()
+	if err != nil {
+		t.Fatal("Target():", err)
+	}
+	if tgt.Which() != rpccapnp.MessageTarget_Which_importedCap || tgt.ImportedCap() != 9 {
+		t.Errorf("Target() = %v; want importedCap 9", tgt)
+	}
+	payload, err := call.Params()
+	if err != nil {
+		t.Fatal("Params():", err)
+	}
+	content, err := payload.ContentPtr()
+	if err != nil {
+		t.Fatal("Params().Content():", err)
+	}
+	if content.Segment() != seg {
+		t.Error("params content was not copied into the call's message")
+	}
+	if x := content.Struct().Uint64(0); x != 0xfeed {
+		t.Errorf("params content field = %#x; want 0xfeed", x)
+	}
+	ctab, err := payload.CapTable()
+	if err != nil {
+		t.Fatal("Params().CapTable():", err)
+	}
+	if ctab.Len() != 1 {
+		t.Fatalf("len(Params().CapTable()) = %d; want 1", ctab.Len())
+	}
+	if d := ctab.At(0); d.Which() != rpccapnp.CapDescriptor_Which_senderHosted || d.SenderHosted() != 3 {
+		t.Errorf("Params().CapTable()[0] = %v; want senderHosted 3", d)
+	}
+	rt := call.SendResultsTo()
+	if rt.Which() != rpccapnp.Call_sendResultsTo_Which_thirdParty {
+		t.Fatalf("SendResultsTo().Which() = %v; want thirdParty", rt.Which())
+	}
+	tp, err := rt.ThirdPartyPtr()
+	if err != nil {
+		t.Fatal("SendResultsTo().ThirdParty():", err)
+	}
+	if got := tp.Text(); got != "carol" {
+		t.Errorf("SendResultsTo().ThirdParty() = %q; want \"carol\"", got)
+	}
+}
+
+func TestBuildCall_Defaults(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := rpccapnp.NewMessageTarget(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := rpc.BuildCall(seg, rpc.CallSpec{Target: target})
+	if err != nil {
+		t.Fatal("BuildCall:", err)
+	}
+	if w := call.SendResultsTo().Which(); w != rpccapnp.Call_sendResultsTo_Which_caller {
+		t.Errorf("SendResultsTo().Which() = %v; want caller", w)
+	}
+	payload, err := call.Params()
+	if err != nil {
+		t.Fatal("Params():", err)
+	}
+	if payload.HasCapTable() {
+		t.Error("Params().HasCapTable() = true; want false")
+	}
+
+	if _, err := rpc.BuildCall(seg, rpc.CallSpec{Target: target, SendResultsTo: 99}); err == nil {
+		t.Error("BuildCall with unknown SendResultsTo succeeded")
+	}
+}
+
+func TestSetSendResultsToYourself(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := rpccapnp.NewCall(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rpc.SetSendResultsToYourself(call); err == nil {
+		t.Error("SetSendResultsToYourself on call without target succeeded")
+	}
+	target, err := call.NewTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.SetPromisedAnswer(rpccapnp.PromisedAnswer{})
+	if err := rpc.SetSendResultsToYourself(call); err == nil {
+		t.Error("SetSendResultsToYourself on call with null promised answer succeeded")
+	}
+	target.SetImportedCap(5)
+	if err := rpc.SetSendResultsToYourself(call); err != nil {
+		t.Fatal("SetSendResultsToYourself:", err)
+	}
+	if w := call.SendResultsTo().Which(); w != rpccapnp.Call_sendResultsTo_Which_yourself {
+		t.Errorf("SendResultsTo().Which() = %v; want yourself", w)
+	}
+}
+
+func TestBuildTransform(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa, err := rpccapnp.NewPromisedAnswer(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := rpc.BuildTransform(seg, []uint16{2, 0})
+	if err != nil {
+		t.Fatal("BuildTransform:", err)
+	}
+	if err := pa.SetTransform(ops); err != nil {
+		t.Fatal("SetTransform:", err)
+	}
+	got, err := pa.Transform()
+	if err != nil {
+		t.Fatal("Transform:", err)
+	}
+	if got.Len() != 2 {
+		t.Fatalf("Transform().Len() = %d; want 2", got.Len())
+	}
+	for i, want := range []uint16{2, 0} {
+		op := got.At(i)
+		if op.Which() != rpccapnp.PromisedAnswer_Op_Which_getPointerField {
+			t.Errorf("op %d is %v; want getPointerField", i, op.Which())
+			continue
+		}
+		if f := op.GetPointerField(); f != want {
+			t.Errorf("op %d getPointerField = %d; want %d", i, f, want)
+		}
+	}
+
+	ops, err = rpc.BuildTransform(seg, nil)
+	if err != nil {
+		t.Fatal("BuildTransform(nil):", err)
+	}
+	if ops.Len() != 1 || ops.At(0).Which() != rpccapnp.PromisedAnswer_Op_Which_noop {
+		t.Errorf("BuildTransform(nil) = %v; want a single noop", ops)
+	}
+}
@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// checksumSize is the size of the footer that follows each message.
+const checksumSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumCodec frames messages like StreamTransport, followed by a
+// checksum.
+type checksumCodec struct {
+	r     io.Reader
+	whash hash.Hash32
+
+	rhash hash.Hash32
+	dec   *capnp.Decoder
+	rsum  [checksumSize]byte
+}
+
+// ChecksummedTransport creates a transport that sends and receives
+// unpacked Cap'n Proto messages like StreamTransport, but follows each
+// message with a four byte little-endian checksum of the message's
+// bytes.  Received messages whose checksum does not match are rejected
+// with ErrChecksumMismatch, although corruption of a message's segment
+// table may instead be reported as a decoding error.  This detects
+// silent corruption on byte streams that don't have their own
+// integrity checks, such as serial links.  Because the framing differs
+// from StreamTransport, both ends of the stream must use
+// ChecksummedTransport with the same hash.
+//
+// newHash is called once for each direction of the stream.  If newHash
+// is nil, CRC-32C (Castagnoli) is used.
+//
+// Closing the transport will close the underlying ReadWriteCloser.
+// Like StreamTransport, the transport sets the deadlines of rwc from
+// the Context if rwc has SetWriteDeadline or SetReadDeadline methods,
+// and the returned Transport implements RecvPauser and BatchSender.
+func ChecksummedTransport(rwc io.ReadWriteCloser, newHash func() hash.Hash32) Transport {
+	if newHash == nil {
+		newHash = func() hash.Hash32 { return crc32.New(castagnoli) }
+	}
+	c := &checksumCodec{
+		r:     rwc,
+		whash: newHash(),
+		rhash: newHash(),
+	}
+	// Everything the decoder reads is fed through the hash.
+	c.dec = capnp.NewDecoder(io.TeeReader(rwc, c.rhash))
+	return newFramedTransport(rwc, c)
+}
+
+func (c *checksumCodec) encode(buf *bytes.Buffer, msg *capnp.Message) error {
+	start := buf.Len()
+	if err := capnp.NewEncoder(buf).Encode(msg); err != nil {
+		return err
+	}
+	c.whash.Reset()
+	c.whash.Write(buf.Bytes()[start:])
+	var sum [checksumSize]byte
+	binary.LittleEndian.PutUint32(sum[:], c.whash.Sum32())
+	buf.Write(sum[:])
+	return nil
+}
+
+// decode reads a single message and its checksum from the stream.
+func (c *checksumCodec) decode() (*capnp.Message, error) {
+	c.rhash.Reset()
+	msg, err := c.dec.Decode()
+	if err != nil {
+		return nil, err
+	}
+	want := c.rhash.Sum32()
+	if _, err := io.ReadFull(c.r, c.rsum[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(c.rsum[:]) != want {
+		return nil, ErrChecksumMismatch
+	}
+	return msg, nil
+}
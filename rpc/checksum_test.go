@@ -0,0 +1,101 @@
+package rpc_test
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestChecksummedTransport(t *testing.T) {
+	tests := []struct {
+		name    string
+		newHash func() hash.Hash32
+	}{
+		{"CRC-32C", nil},
+		{"IEEE", func() hash.Hash32 { return crc32.NewIEEE() }},
+	}
+	ctx := context.Background()
+	for _, test := range tests {
+		conn := new(writeCountConn)
+		sender := rpc.ChecksummedTransport(conn, test.newHash)
+		for _, qid := range []uint32{42, 7} {
+			if err := sender.SendMessage(ctx, newFinishMessage(t, qid)); err != nil {
+				t.Fatalf("%s: SendMessage: %v", test.name, err)
+			}
+		}
+
+		receiver := rpc.ChecksummedTransport(readOnlyConn{&conn.buf}, test.newHash)
+		for _, qid := range []uint32{42, 7} {
+			msg, err := receiver.RecvMessage(ctx)
+			if err != nil {
+				t.Fatalf("%s: RecvMessage: %v", test.name, err)
+			}
+			checkFinishMessage(t, msg.Segment().Message(), qid)
+		}
+	}
+}
+
+func TestChecksummedTransport_SendMessages(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	sender := rpc.ChecksummedTransport(conn, nil).(rpc.BatchSender)
+	msgs := []rpccapnp.Message{newFinishMessage(t, 42), newFinishMessage(t, 7)}
+	if err := sender.SendMessages(ctx, msgs); err != nil {
+		t.Fatal("SendMessages:", err)
+	}
+	if conn.writes != 1 {
+		t.Errorf("SendMessages made %d writes; want 1", conn.writes)
+	}
+	receiver := rpc.ChecksummedTransport(readOnlyConn{&conn.buf}, nil)
+	for _, qid := range []uint32{42, 7} {
+		msg, err := receiver.RecvMessage(ctx)
+		if err != nil {
+			t.Fatal("RecvMessage:", err)
+		}
+		checkFinishMessage(t, msg.Segment().Message(), qid)
+	}
+}
+
+func TestChecksummedTransport_Corrupt(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	if err := rpc.ChecksummedTransport(conn, nil).SendMessage(ctx, newFinishMessage(t, 42)); err != nil {
+		t.Fatal("SendMessage:", err)
+	}
+	data := conn.buf.Bytes()
+	// Flip a bit in the message body, just before the checksum footer.
+	data[len(data)-5] ^= 0x01
+
+	receiver := rpc.ChecksummedTransport(readOnlyConn{bytes.NewReader(data)}, nil)
+	if _, err := receiver.RecvMessage(ctx); err != rpc.ErrChecksumMismatch {
+		t.Errorf("RecvMessage of corrupted message error = %v; want %v", err, rpc.ErrChecksumMismatch)
+	}
+}
+
+func TestChecksummedTransport_HashMismatch(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	if err := rpc.ChecksummedTransport(conn, nil).SendMessage(ctx, newFinishMessage(t, 42)); err != nil {
+		t.Fatal("SendMessage:", err)
+	}
+	receiver := rpc.ChecksummedTransport(readOnlyConn{&conn.buf}, func() hash.Hash32 { return crc32.NewIEEE() })
+	if _, err := receiver.RecvMessage(ctx); err != rpc.ErrChecksumMismatch {
+		t.Errorf("RecvMessage with different hash error = %v; want %v", err, rpc.ErrChecksumMismatch)
+	}
+}
+
+// readOnlyConn is a stub connection that reads from an io.Reader and
+// discards writes.
+type readOnlyConn struct {
+	r io.Reader
+}
+
+func (c readOnlyConn) Read(p []byte) (int, error)  { return c.r.Read(p) }
+func (c readOnlyConn) Write(p []byte) (int, error) { return len(p), nil }
+func (c readOnlyConn) Close() error                { return nil }
@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"sync"
+	"sync/atomic"
+
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// messageCounts counts the messages of each type that a Conn has sent
+// and received.  The common types are counted with atomic adds so that
+// counting doesn't contend with the send and receive goroutines.
+type messageCounts struct {
+	known [rpccapnp.Message_Which_disembargo + 1]uint64
+
+	mu    sync.Mutex
+	other map[rpccapnp.Message_Which]uint64
+}
+
+func (mc *messageCounts) add(w rpccapnp.Message_Which) {
+	if int(w) < len(mc.known) {
+		atomic.AddUint64(&mc.known[w], 1)
+		return
+	}
+	// A type added to the protocol after this package was written.
+	mc.mu.Lock()
+	if mc.other == nil {
+		mc.other = make(map[rpccapnp.Message_Which]uint64)
+	}
+	mc.other[w]++
+	mc.mu.Unlock()
+}
+
+func (mc *messageCounts) snapshot() map[rpccapnp.Message_Which]uint64 {
+	m := make(map[rpccapnp.Message_Which]uint64)
+	for i := range mc.known {
+		if n := atomic.LoadUint64(&mc.known[i]); n > 0 {
+			m[rpccapnp.Message_Which(i)] = n
+		}
+	}
+	mc.mu.Lock()
+	for w, n := range mc.other {
+		m[w] = n
+	}
+	mc.mu.Unlock()
+	return m
+}
+
+// MessageCounts returns the number of messages of each type that have
+// been sent and received on the connection, added together.  Types that
+// have not been seen are omitted.  A message is counted once the
+// transport has sent or received it, so messages still waiting in the
+// send queue are not included.  The returned map is a snapshot that the
+// caller may modify.
+func (c *Conn) MessageCounts() map[rpccapnp.Message_Which]uint64 {
+	return c.counts.snapshot()
+}
@@ -0,0 +1,50 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestMessageCounts(t *testing.T) {
+	ctx := context.Background()
+	conn, p := newUnpairedConn(t)
+	defer p.Close()
+
+	if counts := conn.MessageCounts(); len(counts) != 0 {
+		t.Errorf("MessageCounts() before any messages = %v; want empty", counts)
+	}
+	// Sends Bootstrap, receives Return, sends Finish.
+	bootstrapAndFulfill(t, ctx, conn, p, false)
+
+	// Closing the connection sends an Abort and waits for the send
+	// and receive goroutines to stop, so the counts are final.
+	abort := startRecvMessage(p)
+	if err := conn.Close(); err != nil {
+		t.Error("conn.Close:", err)
+	}
+	if r := <-abort; r.err != nil {
+		t.Fatal("reading abort:", r.err)
+	} else if r.msg.Which() != rpccapnp.Message_Which_abort {
+		t.Fatalf("message after Close is %v; want abort", r.msg.Which())
+	}
+
+	counts := conn.MessageCounts()
+	want := map[rpccapnp.Message_Which]uint64{
+		rpccapnp.Message_Which_bootstrap: 1,
+		rpccapnp.Message_Which_return:    1,
+		rpccapnp.Message_Which_finish:    1,
+		rpccapnp.Message_Which_abort:     1,
+	}
+	for w, n := range want {
+		if counts[w] != n {
+			t.Errorf("MessageCounts()[%v] = %d; want %d", w, counts[w], n)
+		}
+	}
+	for w, n := range counts {
+		if _, ok := want[w]; !ok {
+			t.Errorf("MessageCounts()[%v] = %d; want 0", w, n)
+		}
+	}
+}
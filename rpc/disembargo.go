@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// NewSenderLoopbackDisembargo allocates a Disembargo in seg that asks
+// the receiver to reflect embargoID back once every call it has
+// received for target has been delivered.  target is copied into the
+// Disembargo if it is in a different message.  The result can be set
+// as the disembargo variant of a Message.
+func NewSenderLoopbackDisembargo(seg *capnp.Segment, target rpccapnp.MessageTarget, embargoID uint32) (rpccapnp.Disembargo, error) {
+	d, err := newDisembargo(seg, target)
+	if err != nil {
+		return rpccapnp.Disembargo{}, err
+	}
+	d.Context().SetSenderLoopback(embargoID)
+	return d, nil
+}
+
+// NewReceiverLoopbackDisembargo allocates a Disembargo in seg that
+// answers a senderLoopback Disembargo with the given embargoID.  target
+// is copied into the Disembargo if it is in a different message.
+func NewReceiverLoopbackDisembargo(seg *capnp.Segment, target rpccapnp.MessageTarget, embargoID uint32) (rpccapnp.Disembargo, error) {
+	d, err := newDisembargo(seg, target)
+	if err != nil {
+		return rpccapnp.Disembargo{}, err
+	}
+	d.Context().SetReceiverLoopback(embargoID)
+	return d, nil
+}
+
+func newDisembargo(seg *capnp.Segment, target rpccapnp.MessageTarget) (rpccapnp.Disembargo, error) {
+	d, err := rpccapnp.NewDisembargo(seg)
+	if err != nil {
+		return rpccapnp.Disembargo{}, err
+	}
+	if err := d.SetTarget(target); err != nil {
+		return rpccapnp.Disembargo{}, err
+	}
+	return d, nil
+}
@@ -0,0 +1,88 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestNewSenderLoopbackDisembargo(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Build the target in a different message to check that it's copied.
+	_, tseg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := rpccapnp.NewMessageTarget(tseg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa, err := target.NewPromisedAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa.SetQuestionId(5)
+
+	d, err := rpc.NewSenderLoopbackDisembargo(seg, target, 42)
+	if err != nil {
+		t.Fatal("NewSenderLoopbackDisembargo:", err)
+	}
+	if w := d.Context().Which(); w != rpccapnp.Disembargo_context_Which_senderLoopback {
+		t.Fatalf("d.Context().Which() = %v; want senderLoopback", w)
+	}
+	if id := d.Context().SenderLoopback(); id != 42 {
+		t.Errorf("d.Context().SenderLoopback() = %d; want 42", id)
+	}
+	dt, err := d.Target()
+	if err != nil {
+		t.Fatal("d.Target():", err)
+	}
+	if dt.Segment() != seg {
+		t.Error("target was not copied into the disembargo's message")
+	}
+	if dt.Which() != rpccapnp.MessageTarget_Which_promisedAnswer {
+		t.Fatalf("d.Target().Which() = %v; want promisedAnswer", dt.Which())
+	}
+	dpa, err := dt.PromisedAnswer()
+	if err != nil {
+		t.Fatal("d.Target().PromisedAnswer():", err)
+	}
+	if id := dpa.QuestionId(); id != 5 {
+		t.Errorf("target question ID = %d; want 5", id)
+	}
+}
+
+func TestNewReceiverLoopbackDisembargo(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := rpccapnp.NewMessageTarget(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.SetImportedCap(3)
+
+	d, err := rpc.NewReceiverLoopbackDisembargo(seg, target, 7)
+	if err != nil {
+		t.Fatal("NewReceiverLoopbackDisembargo:", err)
+	}
+	if w := d.Context().Which(); w != rpccapnp.Disembargo_context_Which_receiverLoopback {
+		t.Fatalf("d.Context().Which() = %v; want receiverLoopback", w)
+	}
+	if id := d.Context().ReceiverLoopback(); id != 7 {
+		t.Errorf("d.Context().ReceiverLoopback() = %d; want 7", id)
+	}
+	dt, err := d.Target()
+	if err != nil {
+		t.Fatal("d.Target():", err)
+	}
+	if dt.Which() != rpccapnp.MessageTarget_Which_importedCap || dt.ImportedCap() != 3 {
+		t.Errorf("d.Target() = %v; want importedCap 3", dt)
+	}
+}
@@ -1,6 +1,7 @@
 package rpc_test
 
 import (
+	"sync"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -9,6 +10,7 @@ import (
 	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
 func TestEmbargo(t *testing.T) {
@@ -64,6 +66,133 @@ func TestEmbargo(t *testing.T) {
 	check(call5, 5)
 }
 
+func TestReflectedCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	var (
+		mu          sync.Mutex
+		forwarded   bool
+		took        bool
+		echoReturn  = make(chan struct{})
+		echoReturns sync.Once
+	)
+	pt := &watchTransport{Transport: p}
+	qt := &watchTransport{Transport: q, onSend: func(msg rpccapnp.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch msg.Which() {
+		case rpccapnp.Message_Which_call:
+			call, _ := msg.Call()
+			if call.SendResultsTo().Which() == rpccapnp.Call_sendResultsTo_Which_yourself {
+				forwarded = true
+			}
+		case rpccapnp.Message_Which_return:
+			ret, _ := msg.Return()
+			switch ret.Which() {
+			case rpccapnp.Return_Which_takeFromOtherQuestion:
+				took = true
+			case rpccapnp.Return_Which_results:
+				// Only the echo results are a struct with a capability.
+				results, _ := ret.Results()
+				content, _ := results.ContentPtr()
+				ctab, _ := results.CapTable()
+				if content.Struct().IsValid() && ctab.Len() > 0 {
+					echoReturns.Do(func() { close(echoReturn) })
+				}
+			}
+		}
+	}}
+	log := testLogger{t}
+	c := rpc.NewConn(pt, rpc.ConnLog(log))
+	echoSrv := testcapnp.Echoer_ServerToClient(new(Echoer))
+	d := rpc.NewConn(qt, rpc.MainInterface(echoSrv.Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+	if _, err := callseq(ctx, client.Client, 0).Struct(); err != nil {
+		t.Fatal("bootstrap call:", err)
+	}
+	localCap := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+
+	// Hold the echo's Return so that the call on its result is pipelined
+	// to the remote vat after the remote vat has returned it.
+	pt.hold()
+	echo := client.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(localCap)
+	})
+	<-echoReturn
+	call0 := callseq(ctx, echo.Cap().Client, 0)
+	pt.release()
+
+	if r, err := call0.Struct(); err != nil {
+		t.Error("call0 error:", err)
+	} else if r.N() != 0 {
+		t.Errorf("call0 = %d; want 0", r.N())
+	}
+	if r, err := callseq(ctx, localCap.Client, 1).Struct(); err != nil {
+		t.Error("call1 error:", err)
+	} else if r.N() != 1 {
+		t.Errorf("call1 = %d; want 1", r.N())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !forwarded {
+		t.Error("remote vat did not forward the call with sendResultsTo.yourself")
+	}
+	if !took {
+		t.Error("remote vat did not return with takeFromOtherQuestion")
+	}
+}
+
+// A watchTransport reports sent messages to onSend and can hold
+// received messages until released.
+type watchTransport struct {
+	rpc.Transport
+	onSend func(rpccapnp.Message)
+
+	mu   sync.Mutex
+	gate chan struct{}
+}
+
+func (w *watchTransport) SendMessage(ctx context.Context, msg rpccapnp.Message) error {
+	if w.onSend != nil {
+		w.onSend(msg)
+	}
+	return w.Transport.SendMessage(ctx, msg)
+}
+
+func (w *watchTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, error) {
+	msg, err := w.Transport.RecvMessage(ctx)
+	w.mu.Lock()
+	gate := w.gate
+	w.mu.Unlock()
+	if gate != nil {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return rpccapnp.Message{}, ctx.Err()
+		}
+	}
+	return msg, err
+}
+
+func (w *watchTransport) hold() {
+	w.mu.Lock()
+	w.gate = make(chan struct{})
+	w.mu.Unlock()
+}
+
+func (w *watchTransport) release() {
+	w.mu.Lock()
+	close(w.gate)
+	w.gate = nil
+	w.mu.Unlock()
+}
+
 func callseq(c context.Context, client capnp.Client, n uint32) testcapnp.CallOrder_getCallSequence_Results_Promise {
 	return testcapnp.CallOrder{Client: client}.GetCallSequence(c, func(p testcapnp.CallOrder_getCallSequence_Params) error {
 		p.SetExpected(n)
@@ -50,8 +50,51 @@ func (a Abort) Error() string {
 	return "rpc: aborted by remote: " + r
 }
 
+// An ExceptionError is an error with the type and reason of a Cap'n
+// Proto RPC exception.  Unlike Exception, it does not refer to a
+// message, so it remains valid after the message is reused.
+type ExceptionError struct {
+	Type   rpccapnp.Exception_Type
+	Reason string
+}
+
+// Error returns the exception's reason prefixed with "rpc exception: ",
+// the same text that Exception.Error returns for the exception.
+func (e *ExceptionError) Error() string {
+	return "rpc exception: " + e.Reason
+}
+
+// ExceptionToError returns an *ExceptionError with the type and reason
+// of e, or nil if e is a null struct.
+func ExceptionToError(e rpccapnp.Exception) error {
+	if !e.IsValid() {
+		return nil
+	}
+	r, _ := e.Reason()
+	return &ExceptionError{Type: e.Type(), Reason: r}
+}
+
+// NewExceptionMessage allocates an Exception in seg that describes err.
+// An *ExceptionError or Exception keeps its type and reason, so
+// converting an Exception with ExceptionToError and back preserves
+// both.  Any other error becomes a failed exception with the error's
+// message as the reason.
+func NewExceptionMessage(seg *capnp.Segment, err error) (rpccapnp.Exception, error) {
+	exc, nerr := rpccapnp.NewException(seg)
+	if nerr != nil {
+		return rpccapnp.Exception{}, nerr
+	}
+	toException(exc, err)
+	return exc, nil
+}
+
 // toException sets fields on exc to match err.
 func toException(exc rpccapnp.Exception, err error) {
+	if ee, ok := err.(*ExceptionError); ok {
+		exc.SetReason(ee.Reason)
+		exc.SetType(ee.Type)
+		return
+	}
 	if ee, ok := err.(Exception); ok {
 		// TODO(light): copy struct
 		r, err := ee.Reason()
@@ -68,16 +111,25 @@ func toException(exc rpccapnp.Exception, err error) {
 
 // Errors
 var (
-	ErrConnClosed = errors.New("rpc: connection closed")
+	ErrConnClosed       = errors.New("rpc: connection closed")
+	ErrCapsRejected     = errors.New("rpc: received message carries capabilities")
+	ErrHandshakeTimeout = errors.New("rpc: no return received before handshake timeout")
+	ErrChecksumMismatch = errors.New("rpc: message checksum mismatch")
+	ErrBodyReadTimeout  = errors.New("rpc: timed out reading message body")
 )
 
 // Internal errors
 var (
 	errQuestionReused  = errors.New("rpc: question ID reused")
+	errResultsTaken    = errors.New("rpc: results were taken by the remote vat's caller")
 	errNoMainInterface = errors.New("rpc: no bootstrap interface")
 	errBadTarget       = errors.New("rpc: target not found")
 	errShutdown        = errors.New("rpc: shutdown")
 	errUnimplemented   = errors.New("rpc: remote used unimplemented protocol feature")
+	errNoClients       = errors.New("rpc: MultiConnClient has no clients")
+
+	errGRPCFrameTooLarge = errors.New("rpc: gRPC frame too large")
+	errGRPCCompressed    = errors.New("rpc: received compressed gRPC frame without decompressor")
 )
 
 type bootstrapError struct {
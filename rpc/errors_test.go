@@ -0,0 +1,77 @@
+package rpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestExceptionErrorRoundTrip(t *testing.T) {
+	types := []rpccapnp.Exception_Type{
+		rpccapnp.Exception_Type_failed,
+		rpccapnp.Exception_Type_overloaded,
+		rpccapnp.Exception_Type_disconnected,
+		rpccapnp.Exception_Type_unimplemented,
+	}
+	for _, typ := range types {
+		_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		exc, err := rpccapnp.NewException(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exc.SetType(typ)
+		exc.SetReason("something went " + typ.String())
+
+		e := rpc.ExceptionToError(exc)
+		ee, ok := e.(*rpc.ExceptionError)
+		if !ok {
+			t.Errorf("%v: ExceptionToError = %#v; want *rpc.ExceptionError", typ, e)
+			continue
+		}
+		if ee.Type != typ || ee.Reason != "something went "+typ.String() {
+			t.Errorf("%v: ExceptionToError = %+v", typ, ee)
+		}
+
+		_, seg2, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := rpc.NewExceptionMessage(seg2, e)
+		if err != nil {
+			t.Errorf("%v: NewExceptionMessage: %v", typ, err)
+			continue
+		}
+		if back.Type() != typ {
+			t.Errorf("%v: round-tripped type = %v", typ, back.Type())
+		}
+		if r, err := back.Reason(); err != nil || r != "something went "+typ.String() {
+			t.Errorf("%v: round-tripped reason = %q, %v", typ, r, err)
+		}
+	}
+}
+
+func TestNewExceptionMessage_PlainError(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exc, err := rpc.NewExceptionMessage(seg, errors.New("boom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exc.Type() != rpccapnp.Exception_Type_failed {
+		t.Errorf("type = %v; want failed", exc.Type())
+	}
+	if r, _ := exc.Reason(); r != "boom" {
+		t.Errorf("reason = %q; want \"boom\"", r)
+	}
+	if e := rpc.ExceptionToError(rpccapnp.Exception{}); e != nil {
+		t.Errorf("ExceptionToError(null) = %v; want nil", e)
+	}
+}
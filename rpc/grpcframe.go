@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// grpcHeaderSize is the size of a gRPC message frame header: a
+// compressed flag byte followed by a big-endian uint32 payload length.
+const grpcHeaderSize = 5
+
+// maxGRPCFrameSize is the largest payload that a gRPC-framed transport
+// will read, matching the default limit of capnp.Decoder.
+const maxGRPCFrameSize = 64 << 20
+
+// grpcCodec frames messages like gRPC messages.
+type grpcCodec struct {
+	r io.Reader
+
+	compress   func([]byte) ([]byte, error)
+	decompress func([]byte) ([]byte, error)
+
+	hdr [grpcHeaderSize]byte
+}
+
+// NewGRPCFramedTransport creates a transport that sends and receives
+// messages framed like gRPC messages on the wire: a one byte compressed
+// flag, a four byte big-endian payload length, and then the payload.
+// The payload is an unpacked Cap'n Proto message with its segment
+// table, as produced by capnp.Message.Marshal.  This allows tunneling
+// Cap'n Proto RPC over an existing gRPC byte stream.
+//
+// Closing the transport will close the underlying ReadWriteCloser.
+// Like StreamTransport, the transport sets the deadlines of rwc from
+// the Context if rwc has SetWriteDeadline or SetReadDeadline methods,
+// and the returned Transport implements RecvPauser and BatchSender.
+func NewGRPCFramedTransport(rwc io.ReadWriteCloser, options ...GRPCFramedTransportOption) Transport {
+	c := &grpcCodec{r: rwc}
+	for _, o := range options {
+		o.f(c)
+	}
+	return newFramedTransport(rwc, c)
+}
+
+// A GRPCFramedTransportOption is an option for creating a gRPC-framed
+// transport.
+type GRPCFramedTransportOption struct {
+	f func(*grpcCodec)
+}
+
+// GRPCCompression sets the functions used for frames with the
+// compressed flag set.  If compress is not nil, every outgoing payload
+// is passed through it and sent with the compressed flag set.  If
+// decompress is not nil, incoming payloads with the compressed flag set
+// are passed through it before being parsed.  Without decompress,
+// receiving a compressed frame is an error.
+func GRPCCompression(compress, decompress func([]byte) ([]byte, error)) GRPCFramedTransportOption {
+	return GRPCFramedTransportOption{func(c *grpcCodec) {
+		c.compress = compress
+		c.decompress = decompress
+	}}
+}
+
+func (c *grpcCodec) encode(buf *bytes.Buffer, msg *capnp.Message) error {
+	payload, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+	var flag byte
+	if c.compress != nil {
+		if payload, err = c.compress(payload); err != nil {
+			return fmt.Errorf("rpc: compress message: %v", err)
+		}
+		flag = 1
+	}
+	if uint64(len(payload)) > 1<<32-1 {
+		return errGRPCFrameTooLarge
+	}
+	var hdr [grpcHeaderSize]byte
+	hdr[0] = flag
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(payload)))
+	buf.Write(hdr[:])
+	buf.Write(payload)
+	return nil
+}
+
+// decode reads a single frame from the stream and parses its payload.
+func (c *grpcCodec) decode() (*capnp.Message, error) {
+	if _, err := io.ReadFull(c.r, c.hdr[:]); err != nil {
+		return nil, err
+	}
+	flag, n := c.hdr[0], binary.BigEndian.Uint32(c.hdr[1:])
+	if flag > 1 {
+		return nil, fmt.Errorf("rpc: invalid gRPC frame compressed flag %d", flag)
+	}
+	if n > maxGRPCFrameSize {
+		return nil, errGRPCFrameTooLarge
+	}
+	// Read the payload as it arrives instead of trusting the length
+	// with a single allocation.
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, c.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	payload := buf.Bytes()
+	if flag == 1 {
+		if c.decompress == nil {
+			return nil, errGRPCCompressed
+		}
+		var err error
+		if payload, err = c.decompress(payload); err != nil {
+			return nil, fmt.Errorf("rpc: decompress message: %v", err)
+		}
+	}
+	return capnp.Unmarshal(payload)
+}
@@ -0,0 +1,177 @@
+package rpc_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestGRPCFramedTransport(t *testing.T) {
+	ctx := context.Background()
+	c1, c2 := net.Pipe()
+	tr := rpc.NewGRPCFramedTransport(c1)
+	defer tr.Close()
+	defer c2.Close()
+
+	// Send: check the frame header that goes over the wire.
+	sent := newFinishMessage(t, 42)
+	sendDone := make(chan error, 1)
+	go func() {
+		sendDone <- tr.SendMessage(ctx, sent)
+	}()
+	var hdr [5]byte
+	if _, err := io.ReadFull(c2, hdr[:]); err != nil {
+		t.Fatal("reading frame header:", err)
+	}
+	if hdr[0] != 0 {
+		t.Errorf("compressed flag = %d; want 0", hdr[0])
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(hdr[1:]))
+	if _, err := io.ReadFull(c2, payload); err != nil {
+		t.Fatal("reading frame payload:", err)
+	}
+	if err := <-sendDone; err != nil {
+		t.Fatal("SendMessage:", err)
+	}
+	msg, err := capnp.Unmarshal(payload)
+	if err != nil {
+		t.Fatal("Unmarshal payload:", err)
+	}
+	checkFinishMessage(t, msg, 42)
+
+	// Receive: write a frame by hand.
+	want, err := newFinishMessage(t, 7).Segment().Message().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]byte, 5, 5+len(want))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(want)))
+	frame = append(frame, want...)
+	go c2.Write(frame)
+	got, err := tr.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("RecvMessage:", err)
+	}
+	checkFinishMessage(t, got.Segment().Message(), 7)
+}
+
+func TestGRPCFramedTransport_Compression(t *testing.T) {
+	ctx := context.Background()
+	compress := func(b []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	decompress := func(b []byte) ([]byte, error) {
+		return ioutil.ReadAll(flate.NewReader(bytes.NewReader(b)))
+	}
+	c1, c2 := net.Pipe()
+	sender := rpc.NewGRPCFramedTransport(c1, rpc.GRPCCompression(compress, nil))
+	defer sender.Close()
+	receiver := rpc.NewGRPCFramedTransport(c2, rpc.GRPCCompression(nil, decompress))
+	defer receiver.Close()
+
+	sendDone := make(chan error, 1)
+	go func() {
+		sendDone <- sender.SendMessage(ctx, newFinishMessage(t, 42))
+	}()
+	got, err := receiver.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("RecvMessage:", err)
+	}
+	if err := <-sendDone; err != nil {
+		t.Fatal("SendMessage:", err)
+	}
+	checkFinishMessage(t, got.Segment().Message(), 42)
+}
+
+func TestGRPCFramedTransport_CompressedWithoutDecompressor(t *testing.T) {
+	c1, c2 := net.Pipe()
+	tr := rpc.NewGRPCFramedTransport(c1)
+	defer tr.Close()
+	defer c2.Close()
+
+	go c2.Write([]byte{1, 0, 0, 0, 1, 0})
+	if _, err := tr.RecvMessage(context.Background()); err == nil {
+		t.Error("RecvMessage of compressed frame succeeded without a decompressor")
+	}
+}
+
+func TestGRPCFramedTransport_ShortPayload(t *testing.T) {
+	// The header claims a payload much larger than what follows.
+	frame := []byte{0, 0x01, 0, 0, 0, 1, 2, 3}
+	tr := rpc.NewGRPCFramedTransport(readOnlyConn{bytes.NewReader(frame)})
+	if _, err := tr.RecvMessage(context.Background()); err != io.ErrUnexpectedEOF {
+		t.Errorf("RecvMessage of truncated frame error = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestGRPCFramedTransport_SendMessages(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	sender := rpc.NewGRPCFramedTransport(conn).(rpc.BatchSender)
+	msgs := []rpccapnp.Message{newFinishMessage(t, 1), newFinishMessage(t, 2)}
+	if err := sender.SendMessages(ctx, msgs); err != nil {
+		t.Fatal("SendMessages:", err)
+	}
+	if conn.writes != 1 {
+		t.Errorf("SendMessages made %d writes; want 1", conn.writes)
+	}
+	receiver := rpc.NewGRPCFramedTransport(readOnlyConn{&conn.buf})
+	for i := range msgs {
+		got, err := receiver.RecvMessage(ctx)
+		if err != nil {
+			t.Fatalf("RecvMessage #%d: %v", i+1, err)
+		}
+		checkFinishMessage(t, got.Segment().Message(), uint32(i+1))
+	}
+}
+
+func newFinishMessage(t *testing.T, qid uint32) rpccapnp.Message {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := rpccapnp.NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fin, err := m.NewFinish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fin.SetQuestionId(qid)
+	return m
+}
+
+func checkFinishMessage(t *testing.T, msg *capnp.Message, qid uint32) {
+	m, err := rpccapnp.ReadRootMessage(msg)
+	if err != nil {
+		t.Fatal("ReadRootMessage:", err)
+	}
+	if m.Which() != rpccapnp.Message_Which_finish {
+		t.Fatalf("message is %v; want finish", m.Which())
+	}
+	fin, err := m.Finish()
+	if err != nil {
+		t.Fatal("Finish:", err)
+	}
+	if id := fin.QuestionId(); id != qid {
+		t.Errorf("finish question ID = %d; want %d", id, qid)
+	}
+}
@@ -134,10 +134,35 @@ func (s HandleFactory_newHandle_Params_List) At(i int) HandleFactory_newHandle_P
 	return HandleFactory_newHandle_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HandleFactory_newHandle_Params_List) AtChecked(i int) (HandleFactory_newHandle_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return HandleFactory_newHandle_Params{p}, err
+}
+
 func (s HandleFactory_newHandle_Params_List) Set(i int, v HandleFactory_newHandle_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HandleFactory_newHandle_Params_List) ToSlice() []HandleFactory_newHandle_Params {
+	v := make([]HandleFactory_newHandle_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HandleFactory_newHandle_Params_List) Range(f func(i int, v HandleFactory_newHandle_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HandleFactory_newHandle_Params_List) String() string {
 	str, _ := text.MarshalList(0x99821793f0a50b5e, s.List)
 	return str
@@ -183,7 +208,7 @@ func (s HandleFactory_newHandle_Results) Handle() Handle {
 
 func (s HandleFactory_newHandle_Results) HasHandle() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s HandleFactory_newHandle_Results) SetHandle(v Handle) error {
@@ -208,10 +233,35 @@ func (s HandleFactory_newHandle_Results_List) At(i int) HandleFactory_newHandle_
 	return HandleFactory_newHandle_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s HandleFactory_newHandle_Results_List) AtChecked(i int) (HandleFactory_newHandle_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return HandleFactory_newHandle_Results{p}, err
+}
+
 func (s HandleFactory_newHandle_Results_List) Set(i int, v HandleFactory_newHandle_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s HandleFactory_newHandle_Results_List) ToSlice() []HandleFactory_newHandle_Results {
+	v := make([]HandleFactory_newHandle_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s HandleFactory_newHandle_Results_List) Range(f func(i int, v HandleFactory_newHandle_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s HandleFactory_newHandle_Results_List) String() string {
 	str, _ := text.MarshalList(0xd57b5111c59d048c, s.List)
 	return str
@@ -332,10 +382,35 @@ func (s Hanger_hang_Params_List) At(i int) Hanger_hang_Params {
 	return Hanger_hang_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hanger_hang_Params_List) AtChecked(i int) (Hanger_hang_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return Hanger_hang_Params{p}, err
+}
+
 func (s Hanger_hang_Params_List) Set(i int, v Hanger_hang_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hanger_hang_Params_List) ToSlice() []Hanger_hang_Params {
+	v := make([]Hanger_hang_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hanger_hang_Params_List) Range(f func(i int, v Hanger_hang_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hanger_hang_Params_List) String() string {
 	str, _ := text.MarshalList(0xb4512d1c0c85f06f, s.List)
 	return str
@@ -387,10 +462,35 @@ func (s Hanger_hang_Results_List) At(i int) Hanger_hang_Results {
 	return Hanger_hang_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Hanger_hang_Results_List) AtChecked(i int) (Hanger_hang_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return Hanger_hang_Results{p}, err
+}
+
 func (s Hanger_hang_Results_List) Set(i int, v Hanger_hang_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Hanger_hang_Results_List) ToSlice() []Hanger_hang_Results {
+	v := make([]Hanger_hang_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Hanger_hang_Results_List) Range(f func(i int, v Hanger_hang_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Hanger_hang_Results_List) String() string {
 	str, _ := text.MarshalList(0xb9c9455b55ed47b0, s.List)
 	return str
@@ -515,10 +615,35 @@ func (s CallOrder_getCallSequence_Params_List) At(i int) CallOrder_getCallSequen
 	return CallOrder_getCallSequence_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CallOrder_getCallSequence_Params_List) AtChecked(i int) (CallOrder_getCallSequence_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return CallOrder_getCallSequence_Params{p}, err
+}
+
 func (s CallOrder_getCallSequence_Params_List) Set(i int, v CallOrder_getCallSequence_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CallOrder_getCallSequence_Params_List) ToSlice() []CallOrder_getCallSequence_Params {
+	v := make([]CallOrder_getCallSequence_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CallOrder_getCallSequence_Params_List) Range(f func(i int, v CallOrder_getCallSequence_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CallOrder_getCallSequence_Params_List) String() string {
 	str, _ := text.MarshalList(0x993e61d6a54c166f, s.List)
 	return str
@@ -578,10 +703,35 @@ func (s CallOrder_getCallSequence_Results_List) At(i int) CallOrder_getCallSeque
 	return CallOrder_getCallSequence_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CallOrder_getCallSequence_Results_List) AtChecked(i int) (CallOrder_getCallSequence_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return CallOrder_getCallSequence_Results{p}, err
+}
+
 func (s CallOrder_getCallSequence_Results_List) Set(i int, v CallOrder_getCallSequence_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CallOrder_getCallSequence_Results_List) ToSlice() []CallOrder_getCallSequence_Results {
+	v := make([]CallOrder_getCallSequence_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CallOrder_getCallSequence_Results_List) Range(f func(i int, v CallOrder_getCallSequence_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CallOrder_getCallSequence_Results_List) String() string {
 	str, _ := text.MarshalList(0x88f809ef7f873e58, s.List)
 	return str
@@ -728,7 +878,7 @@ func (s Echoer_echo_Params) Cap() CallOrder {
 
 func (s Echoer_echo_Params) HasCap() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Echoer_echo_Params) SetCap(v CallOrder) error {
@@ -753,10 +903,35 @@ func (s Echoer_echo_Params_List) At(i int) Echoer_echo_Params {
 	return Echoer_echo_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Echoer_echo_Params_List) AtChecked(i int) (Echoer_echo_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return Echoer_echo_Params{p}, err
+}
+
 func (s Echoer_echo_Params_List) Set(i int, v Echoer_echo_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Echoer_echo_Params_List) ToSlice() []Echoer_echo_Params {
+	v := make([]Echoer_echo_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Echoer_echo_Params_List) Range(f func(i int, v Echoer_echo_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Echoer_echo_Params_List) String() string {
 	str, _ := text.MarshalList(0xe96a45cad5d1a1d3, s.List)
 	return str
@@ -806,7 +981,7 @@ func (s Echoer_echo_Results) Cap() CallOrder {
 
 func (s Echoer_echo_Results) HasCap() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Echoer_echo_Results) SetCap(v CallOrder) error {
@@ -831,10 +1006,35 @@ func (s Echoer_echo_Results_List) At(i int) Echoer_echo_Results {
 	return Echoer_echo_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Echoer_echo_Results_List) AtChecked(i int) (Echoer_echo_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return Echoer_echo_Results{p}, err
+}
+
 func (s Echoer_echo_Results_List) Set(i int, v Echoer_echo_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Echoer_echo_Results_List) ToSlice() []Echoer_echo_Results {
+	v := make([]Echoer_echo_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Echoer_echo_Results_List) Range(f func(i int, v Echoer_echo_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Echoer_echo_Results_List) String() string {
 	str, _ := text.MarshalList(0x8b45b4847bd839c8, s.List)
 	return str
@@ -963,10 +1163,35 @@ func (s PingPong_echoNum_Params_List) At(i int) PingPong_echoNum_Params {
 	return PingPong_echoNum_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s PingPong_echoNum_Params_List) AtChecked(i int) (PingPong_echoNum_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return PingPong_echoNum_Params{p}, err
+}
+
 func (s PingPong_echoNum_Params_List) Set(i int, v PingPong_echoNum_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s PingPong_echoNum_Params_List) ToSlice() []PingPong_echoNum_Params {
+	v := make([]PingPong_echoNum_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s PingPong_echoNum_Params_List) Range(f func(i int, v PingPong_echoNum_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s PingPong_echoNum_Params_List) String() string {
 	str, _ := text.MarshalList(0xd797e0a99edf0921, s.List)
 	return str
@@ -1026,10 +1251,35 @@ func (s PingPong_echoNum_Results_List) At(i int) PingPong_echoNum_Results {
 	return PingPong_echoNum_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s PingPong_echoNum_Results_List) AtChecked(i int) (PingPong_echoNum_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return PingPong_echoNum_Results{p}, err
+}
+
 func (s PingPong_echoNum_Results_List) Set(i int, v PingPong_echoNum_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s PingPong_echoNum_Results_List) ToSlice() []PingPong_echoNum_Results {
+	v := make([]PingPong_echoNum_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s PingPong_echoNum_Results_List) Range(f func(i int, v PingPong_echoNum_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s PingPong_echoNum_Results_List) String() string {
 	str, _ := text.MarshalList(0x85ddfd96db252600, s.List)
 	return str
@@ -1160,10 +1410,35 @@ func NewAdder_add_Params_List(s *capnp.Segment, sz int32) (Adder_add_Params_List
 
 func (s Adder_add_Params_List) At(i int) Adder_add_Params { return Adder_add_Params{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Adder_add_Params_List) AtChecked(i int) (Adder_add_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return Adder_add_Params{p}, err
+}
+
 func (s Adder_add_Params_List) Set(i int, v Adder_add_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Adder_add_Params_List) ToSlice() []Adder_add_Params {
+	v := make([]Adder_add_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Adder_add_Params_List) Range(f func(i int, v Adder_add_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Adder_add_Params_List) String() string {
 	str, _ := text.MarshalList(0x9ed99eb5024ed6ef, s.List)
 	return str
@@ -1223,10 +1498,35 @@ func (s Adder_add_Results_List) At(i int) Adder_add_Results {
 	return Adder_add_Results{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Adder_add_Results_List) AtChecked(i int) (Adder_add_Results, error) {
+	p, err := s.List.StructChecked(i)
+	return Adder_add_Results{p}, err
+}
+
 func (s Adder_add_Results_List) Set(i int, v Adder_add_Results) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Adder_add_Results_List) ToSlice() []Adder_add_Results {
+	v := make([]Adder_add_Results, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Adder_add_Results_List) Range(f func(i int, v Adder_add_Results) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Adder_add_Results_List) String() string {
 	str, _ := text.MarshalList(0xa74428796527f253, s.List)
 	return str
@@ -44,6 +44,13 @@ dig:
 			client = curr.client
 		case *localAnswerClient:
 			curr.a.mu.Lock()
+			if q := curr.a.forward; q != nil {
+				curr.a.mu.Unlock()
+				if q.conn != c {
+					return q.PipelineCall(curr.transform, cl)
+				}
+				return q.lockedPipelineCall(curr.transform, cl)
+			}
 			if curr.a.done {
 				obj, err := curr.a.obj, curr.a.err
 				curr.a.mu.Unlock()
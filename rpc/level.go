@@ -0,0 +1,316 @@
+package rpc
+
+import (
+	"fmt"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// A Level is a Cap'n Proto RPC protocol level, as described in
+// rpc.capnp.  Each level adds message variants and fields to the ones
+// before it.
+type Level int
+
+// Protocol levels.
+const (
+	// Level0 covers bootstrapping and calling capabilities.
+	Level0 Level = 0
+
+	// Level1 adds promise pipelining of capabilities, resolution,
+	// release, and embargos.
+	Level1 Level = 1
+
+	// Level2 adds persistent capabilities (the obsolete save and
+	// delete messages).
+	Level2 Level = 2
+
+	// Level3 adds three-way introductions.
+	Level3 Level = 3
+
+	// Level4 adds joins.
+	Level4 Level = 4
+)
+
+// A LevelError is returned when a message uses a variant or field that
+// was introduced after the allowed protocol level.
+type LevelError struct {
+	// Feature is the name of the message variant or field.
+	Feature string
+
+	// Need is the level that introduced the feature.
+	Need Level
+
+	// Have is the allowed level.
+	Have Level
+}
+
+func (e *LevelError) Error() string {
+	return fmt.Sprintf("rpc: %s requires protocol level %d (restricted to %d)", e.Feature, e.Need, e.Have)
+}
+
+// require returns a *LevelError if feature, introduced at need, is not
+// allowed at level l.
+func (l Level) require(feature string, need Level) error {
+	if need <= l {
+		return nil
+	}
+	return &LevelError{Feature: feature, Need: need, Have: l}
+}
+
+// Check reports whether m only uses message variants and fields that
+// are allowed at level l.  It is useful for validating a message before
+// sending it to a peer that negotiated an older protocol.
+func (l Level) Check(m rpccapnp.Message) error {
+	switch m.Which() {
+	case rpccapnp.Message_Which_call:
+		call, err := m.Call()
+		if err != nil {
+			return err
+		}
+		if call.SendResultsTo().Which() == rpccapnp.Call_sendResultsTo_Which_thirdParty {
+			if err := l.require("call.sendResultsTo.thirdParty", Level3); err != nil {
+				return err
+			}
+		}
+		params, err := call.Params()
+		if err != nil {
+			return err
+		}
+		return l.checkPayload(params)
+	case rpccapnp.Message_Which_return:
+		ret, err := m.Return()
+		if err != nil {
+			return err
+		}
+		switch ret.Which() {
+		case rpccapnp.Return_Which_results:
+			results, err := ret.Results()
+			if err != nil {
+				return err
+			}
+			return l.checkPayload(results)
+		case rpccapnp.Return_Which_acceptFromThirdParty:
+			return l.require("return.acceptFromThirdParty", Level3)
+		}
+		return nil
+	case rpccapnp.Message_Which_resolve:
+		if err := l.require("resolve", Level1); err != nil {
+			return err
+		}
+		res, err := m.Resolve()
+		if err != nil {
+			return err
+		}
+		if res.Which() != rpccapnp.Resolve_Which_cap {
+			return nil
+		}
+		desc, err := res.Cap()
+		if err != nil {
+			return err
+		}
+		return l.checkCapDescriptor(desc)
+	case rpccapnp.Message_Which_release:
+		return l.require("release", Level1)
+	case rpccapnp.Message_Which_disembargo:
+		if err := l.require("disembargo", Level1); err != nil {
+			return err
+		}
+		d, err := m.Disembargo()
+		if err != nil {
+			return err
+		}
+		switch d.Context().Which() {
+		case rpccapnp.Disembargo_context_Which_accept:
+			return l.require("disembargo.context.accept", Level3)
+		case rpccapnp.Disembargo_context_Which_provide:
+			return l.require("disembargo.context.provide", Level3)
+		}
+		return nil
+	case rpccapnp.Message_Which_obsoleteSave:
+		return l.require("obsoleteSave", Level2)
+	case rpccapnp.Message_Which_obsoleteDelete:
+		return l.require("obsoleteDelete", Level2)
+	case rpccapnp.Message_Which_provide:
+		return l.require("provide", Level3)
+	case rpccapnp.Message_Which_accept:
+		return l.require("accept", Level3)
+	case rpccapnp.Message_Which_join:
+		return l.require("join", Level4)
+	default:
+		return nil
+	}
+}
+
+func (l Level) checkPayload(p rpccapnp.Payload) error {
+	ct, err := p.CapTable()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < ct.Len(); i++ {
+		if err := l.checkCapDescriptor(ct.At(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l Level) checkCapDescriptor(desc rpccapnp.CapDescriptor) error {
+	switch desc.Which() {
+	case rpccapnp.CapDescriptor_Which_senderPromise:
+		return l.require("capDescriptor.senderPromise", Level1)
+	case rpccapnp.CapDescriptor_Which_receiverAnswer:
+		return l.require("capDescriptor.receiverAnswer", Level1)
+	case rpccapnp.CapDescriptor_Which_thirdPartyHosted:
+		return l.require("capDescriptor.thirdPartyHosted", Level3)
+	default:
+		return nil
+	}
+}
+
+// A LevelMessage is an RPC message whose setters return a *LevelError
+// instead of setting a message variant that was introduced after Level.
+// Setters inherited from Message for variants allowed at every level
+// are unchanged.  Fields nested inside a variant are not restricted by
+// LevelMessage; use Level.Check on the finished message to validate
+// those.
+type LevelMessage struct {
+	rpccapnp.Message
+	Level Level
+}
+
+// SetResolve sets the resolve variant, which requires Level1.
+func (m LevelMessage) SetResolve(v rpccapnp.Resolve) error {
+	if err := m.Level.require("resolve", Level1); err != nil {
+		return err
+	}
+	return m.Message.SetResolve(v)
+}
+
+// NewResolve sets the resolve variant to a new struct, which requires
+// Level1.
+func (m LevelMessage) NewResolve() (rpccapnp.Resolve, error) {
+	if err := m.Level.require("resolve", Level1); err != nil {
+		return rpccapnp.Resolve{}, err
+	}
+	return m.Message.NewResolve()
+}
+
+// SetRelease sets the release variant, which requires Level1.
+func (m LevelMessage) SetRelease(v rpccapnp.Release) error {
+	if err := m.Level.require("release", Level1); err != nil {
+		return err
+	}
+	return m.Message.SetRelease(v)
+}
+
+// NewRelease sets the release variant to a new struct, which requires
+// Level1.
+func (m LevelMessage) NewRelease() (rpccapnp.Release, error) {
+	if err := m.Level.require("release", Level1); err != nil {
+		return rpccapnp.Release{}, err
+	}
+	return m.Message.NewRelease()
+}
+
+// SetDisembargo sets the disembargo variant, which requires Level1.
+func (m LevelMessage) SetDisembargo(v rpccapnp.Disembargo) error {
+	if err := m.Level.require("disembargo", Level1); err != nil {
+		return err
+	}
+	return m.Message.SetDisembargo(v)
+}
+
+// NewDisembargo sets the disembargo variant to a new struct, which
+// requires Level1.
+func (m LevelMessage) NewDisembargo() (rpccapnp.Disembargo, error) {
+	if err := m.Level.require("disembargo", Level1); err != nil {
+		return rpccapnp.Disembargo{}, err
+	}
+	return m.Message.NewDisembargo()
+}
+
+// SetObsoleteSave sets the obsoleteSave variant, which requires Level2.
+func (m LevelMessage) SetObsoleteSave(v capnp.Pointer) error {
+	if err := m.Level.require("obsoleteSave", Level2); err != nil {
+		return err
+	}
+	return m.Message.SetObsoleteSave(v)
+}
+
+// SetObsoleteSavePtr sets the obsoleteSave variant, which requires
+// Level2.
+func (m LevelMessage) SetObsoleteSavePtr(v capnp.Ptr) error {
+	if err := m.Level.require("obsoleteSave", Level2); err != nil {
+		return err
+	}
+	return m.Message.SetObsoleteSavePtr(v)
+}
+
+// SetObsoleteDelete sets the obsoleteDelete variant, which requires
+// Level2.
+func (m LevelMessage) SetObsoleteDelete(v capnp.Pointer) error {
+	if err := m.Level.require("obsoleteDelete", Level2); err != nil {
+		return err
+	}
+	return m.Message.SetObsoleteDelete(v)
+}
+
+// SetObsoleteDeletePtr sets the obsoleteDelete variant, which requires
+// Level2.
+func (m LevelMessage) SetObsoleteDeletePtr(v capnp.Ptr) error {
+	if err := m.Level.require("obsoleteDelete", Level2); err != nil {
+		return err
+	}
+	return m.Message.SetObsoleteDeletePtr(v)
+}
+
+// SetProvide sets the provide variant, which requires Level3.
+func (m LevelMessage) SetProvide(v rpccapnp.Provide) error {
+	if err := m.Level.require("provide", Level3); err != nil {
+		return err
+	}
+	return m.Message.SetProvide(v)
+}
+
+// NewProvide sets the provide variant to a new struct, which requires
+// Level3.
+func (m LevelMessage) NewProvide() (rpccapnp.Provide, error) {
+	if err := m.Level.require("provide", Level3); err != nil {
+		return rpccapnp.Provide{}, err
+	}
+	return m.Message.NewProvide()
+}
+
+// SetAccept sets the accept variant, which requires Level3.
+func (m LevelMessage) SetAccept(v rpccapnp.Accept) error {
+	if err := m.Level.require("accept", Level3); err != nil {
+		return err
+	}
+	return m.Message.SetAccept(v)
+}
+
+// NewAccept sets the accept variant to a new struct, which requires
+// Level3.
+func (m LevelMessage) NewAccept() (rpccapnp.Accept, error) {
+	if err := m.Level.require("accept", Level3); err != nil {
+		return rpccapnp.Accept{}, err
+	}
+	return m.Message.NewAccept()
+}
+
+// SetJoin sets the join variant, which requires Level4.
+func (m LevelMessage) SetJoin(v rpccapnp.Join) error {
+	if err := m.Level.require("join", Level4); err != nil {
+		return err
+	}
+	return m.Message.SetJoin(v)
+}
+
+// NewJoin sets the join variant to a new struct, which requires Level4.
+func (m LevelMessage) NewJoin() (rpccapnp.Join, error) {
+	if err := m.Level.require("join", Level4); err != nil {
+		return rpccapnp.Join{}, err
+	}
+	return m.Message.NewJoin()
+}
@@ -0,0 +1,85 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestLevelMessage(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmsg, err := rpccapnp.NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := rpc.LevelMessage{Message: rmsg, Level: rpc.Level1}
+	provide, err := rpccapnp.NewProvide(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.SetProvide(provide)
+	if le, ok := err.(*rpc.LevelError); !ok {
+		t.Errorf("SetProvide error = %v; want *rpc.LevelError", err)
+	} else if le.Feature != "provide" || le.Need != rpc.Level3 || le.Have != rpc.Level1 {
+		t.Errorf("SetProvide error = %+v; want {Feature:provide Need:3 Have:1}", le)
+	}
+	if m.Which() == rpccapnp.Message_Which_provide {
+		t.Error("SetProvide set the provide variant despite error")
+	}
+	if _, err := m.NewJoin(); err == nil {
+		t.Error("NewJoin succeeded at level 1")
+	}
+	if _, err := m.NewDisembargo(); err != nil {
+		t.Errorf("NewDisembargo: %v", err)
+	}
+	if err := rpc.Level1.Check(m.Message); err != nil {
+		t.Errorf("Level1.Check(disembargo) = %v; want nil", err)
+	}
+	if err := rpc.Level0.Check(m.Message); err == nil {
+		t.Error("Level0.Check(disembargo) = nil; want error")
+	}
+}
+
+func TestLevelCheckFields(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := rpccapnp.NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := m.NewCall()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := call.NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := params.NewCapTable(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct.At(0).SetSenderHosted(1)
+	if err := rpc.Level0.Check(m); err != nil {
+		t.Errorf("Level0.Check(call with senderHosted) = %v; want nil", err)
+	}
+	ct.At(0).SetSenderPromise(1)
+	if err := rpc.Level0.Check(m); err == nil {
+		t.Error("Level0.Check(call with senderPromise) = nil; want error")
+	}
+	if err := rpc.Level1.Check(m); err != nil {
+		t.Errorf("Level1.Check(call with senderPromise) = %v; want nil", err)
+	}
+	call.SendResultsTo().SetThirdPartyPtr(capnp.Ptr{})
+	if err := rpc.Level1.Check(m); err == nil {
+		t.Error("Level1.Check(call to third party) = nil; want error")
+	}
+}
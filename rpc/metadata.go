@@ -0,0 +1,153 @@
+package rpc
+
+import (
+	"sort"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// Metadata is a set of opaque key-value pairs sent along with a call,
+// such as a trace ID.
+type Metadata map[string]string
+
+// CallHooks are functions that a connection calls for each call it
+// sends or receives.  Together they propagate per-call metadata, like
+// distributed tracing contexts, without changing any schemas.
+//
+// The hooks are called while the connection is processing messages,
+// so they must not block or make calls on the connection.
+type CallHooks struct {
+	// OnSendCall is called with the context of an outgoing call.  The
+	// metadata it returns is sent with the call.  If it returns an
+	// error, the call fails with that error without being sent.
+	OnSendCall func(ctx context.Context, m capnp.Method) (Metadata, error)
+
+	// OnRecvCall is called for an incoming call with the context the
+	// call will run in and the metadata sent with the call, which is
+	// nil if there was none.  The returned context is passed to the
+	// callee.  If it returns an error, the call is rejected with that
+	// error.
+	OnRecvCall func(ctx context.Context, m capnp.Method, md Metadata) (context.Context, error)
+
+	// OnQuestionSent is called after an outgoing call has been queued
+	// to send, with the question ID it was assigned.  Pairing it with
+	// OnQuestionResolved gives the call's latency.
+	OnQuestionSent func(id uint32, m capnp.Method)
+
+	// OnQuestionResolved is called once for each question passed to
+	// OnQuestionSent, when its return arrives or it is canceled.  err
+	// is nil if the call returned results.  A question ID may be reused
+	// for a new call after it has been resolved.
+	OnQuestionResolved func(id uint32, err error)
+}
+
+// ConnCallHooks sets the hooks that the connection calls for each call.
+//
+// Metadata is carried by the transport, outside of the RPC messages,
+// if it implements MetadataTransport, like a StreamTransport created
+// with the CallMetadata option.  Otherwise, OnSendCall is still called
+// but its metadata is not sent, and OnRecvCall is passed nil metadata.
+func ConnCallHooks(h CallHooks) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.hooks = h
+	}}
+}
+
+// A MetadataTransport is a Transport that can send metadata alongside
+// a message, such as the transport returned by StreamTransport with the
+// CallMetadata option.  A Conn uses it to send the metadata returned
+// by CallHooks.OnSendCall with each call.
+type MetadataTransport interface {
+	// SendMessageMetadata is like SendMessage, but sends md with msg.
+	SendMessageMetadata(ctx context.Context, msg rpccapnp.Message, md Metadata) error
+
+	// RecvMessageMetadata is like RecvMessage, but also returns the
+	// metadata sent with the message, which is nil if there was none.
+	RecvMessageMetadata(ctx context.Context) (rpccapnp.Message, Metadata, error)
+}
+
+// newCallMessage returns a new call message for a call with the given
+// context and method, along with the metadata from the OnSendCall hook
+// to send with it.
+func (c *Conn) newCallMessage(ctx context.Context, m *capnp.Method) (rpccapnp.Message, rpccapnp.Call, Metadata, error) {
+	var md Metadata
+	if c.hooks.OnSendCall != nil {
+		var err error
+		md, err = c.hooks.OnSendCall(ctx, *m)
+		if err != nil {
+			return rpccapnp.Message{}, rpccapnp.Call{}, nil, err
+		}
+		if len(md) == 0 {
+			md = nil
+		}
+	}
+	msg := newMessage(nil)
+	msgCall, _ := msg.NewCall()
+	return msg, msgCall, md, nil
+}
+
+// recvCallContext applies the OnRecvCall hook to an incoming call.
+func (c *Conn) recvCallContext(ctx context.Context, md Metadata, m *capnp.Method) (context.Context, error) {
+	if c.hooks.OnRecvCall == nil {
+		return ctx, nil
+	}
+	return c.hooks.OnRecvCall(ctx, *m, md)
+}
+
+// newMetadataMessage returns a message whose root is a list of
+// alternating keys and values from md, sorted by key.
+func newMetadataMessage(md Metadata) (*capnp.Message, error) {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	l, err := capnp.NewTextList(seg, int32(len(keys)*2))
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		if err := l.Set(i*2, k); err != nil {
+			return nil, err
+		}
+		if err := l.Set(i*2+1, md[k]); err != nil {
+			return nil, err
+		}
+	}
+	if err := msg.SetRootPtr(l.List.ToPtr()); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readMetadata reads a message written by newMetadataMessage.  It
+// returns nil if the message has no metadata.
+func readMetadata(msg *capnp.Message) (Metadata, error) {
+	p, err := msg.RootPtr()
+	if err != nil {
+		return nil, err
+	}
+	l := capnp.TextList{List: p.List()}
+	if l.Len() == 0 {
+		return nil, nil
+	}
+	md := make(Metadata, l.Len()/2)
+	for i := 0; i+1 < l.Len(); i += 2 {
+		k, err := l.At(i)
+		if err != nil {
+			return nil, err
+		}
+		v, err := l.At(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		md[k] = v
+	}
+	return md, nil
+}
@@ -0,0 +1,211 @@
+package rpc_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+type traceKey struct{}
+
+// tracePingPong echoes numbers and records the trace ID that each call
+// ran with.
+type tracePingPong struct {
+	traces chan string
+}
+
+func (pp tracePingPong) EchoNum(call testcapnp.PingPong_echoNum) error {
+	id, _ := call.Ctx.Value(traceKey{}).(string)
+	pp.traces <- id
+	call.Results.SetN(call.Params.N())
+	return nil
+}
+
+func TestCallHooks(t *testing.T) {
+	testCallHooks(t, []rpc.StreamTransportOption{rpc.CallMetadata()}, "4bf92f3577b34da6")
+}
+
+func TestCallHooks_NoMetadataTransport(t *testing.T) {
+	// Without CallMetadata, the hooks are still called but the trace ID
+	// does not reach the other side.
+	testCallHooks(t, nil, "")
+}
+
+func testCallHooks(t *testing.T, opts []rpc.StreamTransportOption, want string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := testLogger{t}
+	c1, c2 := net.Pipe()
+	// logtransport would hide the MetadataTransport methods.
+	p, q := rpc.StreamTransport(c1, opts...), rpc.StreamTransport(c2, opts...)
+	sent := make(chan bool, 2)
+	c := rpc.NewConn(p, rpc.ConnLog(log), rpc.ConnCallHooks(rpc.CallHooks{
+		OnSendCall: func(ctx context.Context, m capnp.Method) (rpc.Metadata, error) {
+			id, ok := ctx.Value(traceKey{}).(string)
+			sent <- ok
+			if !ok {
+				return nil, nil
+			}
+			return rpc.Metadata{"trace-id": id}, nil
+		},
+	}))
+	impl := tracePingPong{traces: make(chan string, 1)}
+	srv := testcapnp.PingPong_ServerToClient(impl)
+	d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(log), rpc.ConnCallHooks(rpc.CallHooks{
+		OnRecvCall: func(ctx context.Context, m capnp.Method, md rpc.Metadata) (context.Context, error) {
+			if id, ok := md["trace-id"]; ok {
+				ctx = context.WithValue(ctx, traceKey{}, id)
+			}
+			return ctx, nil
+		},
+	}))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.PingPong{Client: c.Bootstrap(ctx)}
+
+	tests := []struct {
+		ctx    context.Context
+		traced bool
+		want   string
+	}{
+		{context.WithValue(ctx, traceKey{}, "4bf92f3577b34da6"), true, want},
+		{ctx, false, ""},
+	}
+	for i, test := range tests {
+		n := int32(i + 42)
+		r, err := client.EchoNum(test.ctx, func(p testcapnp.PingPong_echoNum_Params) error {
+			p.SetN(n)
+			return nil
+		}).Struct()
+		if err != nil {
+			t.Errorf("call%d error: %v", i, err)
+			continue
+		}
+		if r.N() != n {
+			t.Errorf("call%d = %d; want %d", i, r.N(), n)
+		}
+		if traced := <-sent; traced != test.traced {
+			t.Errorf("call%d: OnSendCall saw trace ID = %t; want %t", i, traced, test.traced)
+		}
+		if got := <-impl.traces; got != test.want {
+			t.Errorf("call%d ran with trace ID %q; want %q", i, got, test.want)
+		}
+	}
+	if err := client.Client.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+}
+
+func TestStreamTransport_CallMetadata(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	sender := rpc.StreamTransport(conn, rpc.CallMetadata()).(rpc.MetadataTransport)
+	md := rpc.Metadata{"trace-id": "4bf92f3577b34da6", "span-id": "00f067aa0ba902b7"}
+	if err := sender.SendMessageMetadata(ctx, newFinishMessage(t, 42), md); err != nil {
+		t.Fatal("SendMessageMetadata:", err)
+	}
+	if err := sender.(rpc.Transport).SendMessage(ctx, newFinishMessage(t, 7)); err != nil {
+		t.Fatal("SendMessage:", err)
+	}
+
+	receiver := rpc.StreamTransport(readOnlyConn{&conn.buf}, rpc.CallMetadata()).(rpc.MetadataTransport)
+	msg, got, err := receiver.RecvMessageMetadata(ctx)
+	if err != nil {
+		t.Fatal("RecvMessageMetadata #1:", err)
+	}
+	checkFinishMessage(t, msg.Segment().Message(), 42)
+	if !reflect.DeepEqual(got, md) {
+		t.Errorf("RecvMessageMetadata #1 metadata = %v; want %v", got, md)
+	}
+	msg, got, err = receiver.RecvMessageMetadata(ctx)
+	if err != nil {
+		t.Fatal("RecvMessageMetadata #2:", err)
+	}
+	checkFinishMessage(t, msg.Segment().Message(), 7)
+	if got != nil {
+		t.Errorf("RecvMessageMetadata #2 metadata = %v; want <nil>", got)
+	}
+}
+
+func TestCallHooks_Questions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := testLogger{t}
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	type sentEvent struct {
+		id uint32
+		m  capnp.Method
+	}
+	type resolvedEvent struct {
+		id  uint32
+		err error
+	}
+	sent := make(chan sentEvent, 10)
+	resolved := make(chan resolvedEvent, 10)
+	c := rpc.NewConn(p, rpc.ConnLog(log), rpc.ConnCallHooks(rpc.CallHooks{
+		OnQuestionSent: func(id uint32, m capnp.Method) {
+			sent <- sentEvent{id, m}
+		},
+		OnQuestionResolved: func(id uint32, err error) {
+			resolved <- resolvedEvent{id, err}
+		},
+	}))
+	impl := tracePingPong{traces: make(chan string, 1)}
+	srv := testcapnp.PingPong_ServerToClient(impl)
+	d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.PingPong{Client: c.Bootstrap(ctx)}
+
+	_, err := client.EchoNum(ctx, func(p testcapnp.PingPong_echoNum_Params) error {
+		p.SetN(42)
+		return nil
+	}).Struct()
+	if err != nil {
+		t.Fatal("EchoNum:", err)
+	}
+	<-impl.traces
+
+	// The bootstrap question is not a call, so only the EchoNum
+	// question should be reported.
+	var s sentEvent
+	select {
+	case s = <-sent:
+	default:
+		t.Fatal("OnQuestionSent not called")
+	}
+	if s.m.InterfaceID != testcapnp.PingPong_TypeID || s.m.MethodID != 0 {
+		t.Errorf("OnQuestionSent method = %v; want PingPong.echoNum", s.m)
+	}
+	select {
+	case r := <-resolved:
+		if r.id != s.id {
+			t.Errorf("OnQuestionResolved id = %d; want %d (from OnQuestionSent)", r.id, s.id)
+		}
+		if r.err != nil {
+			t.Errorf("OnQuestionResolved error = %v; want <nil>", r.err)
+		}
+	default:
+		t.Fatal("OnQuestionResolved not called")
+	}
+	select {
+	case s := <-sent:
+		t.Errorf("extra OnQuestionSent(%d, %v)", s.id, s.m)
+	case r := <-resolved:
+		t.Errorf("extra OnQuestionResolved(%d, %v)", r.id, r.err)
+	default:
+	}
+	if err := client.Client.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+}
@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"sync/atomic"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// A ConnSelector chooses which of n clients a MultiConnClient sends a
+// call to.  It must return a value in the range [0, n) and be safe to
+// call from multiple goroutines.
+type ConnSelector func(call *capnp.Call, n int) int
+
+// RoundRobin returns a ConnSelector that sends each call to the next
+// client in turn.
+func RoundRobin() ConnSelector {
+	var next uint32
+	return func(_ *capnp.Call, n int) int {
+		return int((atomic.AddUint32(&next, 1) - 1) % uint32(n))
+	}
+}
+
+// A MultiConnClient is a capnp.Client that spreads calls across
+// several clients for the same capability, typically the bootstrap
+// interfaces of several Conns to the same vat.  Each call goes to a
+// single client, so calls pipelined on its answer stay on the same
+// connection.  Calls made directly on a MultiConnClient are not ordered
+// relative to each other unless the selector always picks the same
+// client.
+type MultiConnClient struct {
+	clients []capnp.Client
+	sel     ConnSelector
+}
+
+// NewMultiConnClient returns a client that distributes calls among
+// clients using sel.  If sel is nil, RoundRobin is used.  The
+// MultiConnClient takes ownership of clients: closing it closes them.
+func NewMultiConnClient(clients []capnp.Client, sel ConnSelector) *MultiConnClient {
+	if sel == nil {
+		sel = RoundRobin()
+	}
+	return &MultiConnClient{
+		clients: append([]capnp.Client(nil), clients...),
+		sel:     sel,
+	}
+}
+
+// Call sends the call to the client chosen by the selector.
+func (mc *MultiConnClient) Call(call *capnp.Call) capnp.Answer {
+	if len(mc.clients) == 0 {
+		return capnp.ErrorAnswer(errNoClients)
+	}
+	return mc.clients[mc.sel(call, len(mc.clients))].Call(call)
+}
+
+// Close closes all of the underlying clients and returns the first
+// error encountered.
+func (mc *MultiConnClient) Close() error {
+	var firstErr error
+	for _, c := range mc.clients {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,58 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestMultiConnClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := testLogger{t}
+	// Each connection has its own server, which counts only the calls
+	// sent over that connection.
+	var clients []capnp.Client
+	for i := 0; i < 2; i++ {
+		p, q := pipetransport.New()
+		if *logMessages {
+			p = logtransport.New(nil, p)
+		}
+		c := rpc.NewConn(p, rpc.ConnLog(log))
+		srv := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+		d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(log))
+		defer d.Wait()
+		defer c.Close()
+		clients = append(clients, c.Bootstrap(ctx))
+	}
+	client := rpc.NewMultiConnClient(clients, rpc.RoundRobin())
+
+	// Round-robin alternates connections, so each server sees every
+	// other call.
+	want := []uint32{0, 0, 1, 1, 2, 2}
+	for i, n := range want {
+		r, err := callseq(ctx, client, n).Struct()
+		if err != nil {
+			t.Errorf("call%d error: %v", i, err)
+			continue
+		}
+		if r.N() != n {
+			t.Errorf("call%d = %d; want %d", i, r.N(), n)
+		}
+	}
+	if err := client.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+}
+
+func TestMultiConnClient_NoClients(t *testing.T) {
+	client := rpc.NewMultiConnClient(nil, nil)
+	if _, err := callseq(context.Background(), client, 0).Struct(); err == nil {
+		t.Error("call on MultiConnClient with no clients succeeded; want error")
+	}
+}
@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// OrderedReturns makes the connection complete questions in the order
+// that they were sent.  Normally a question completes as soon as its
+// return message arrives, which over a multiplexed transport may not
+// be the order that the calls were made.  With this option, a return
+// that arrives before the returns of earlier questions is buffered
+// until every earlier question has completed, either by its own return
+// or by being canceled.  Answers, and hooks like OnQuestionResolved,
+// then see results in call order.
+//
+// The tradeoff is head-of-line blocking: one slow call holds up the
+// results of every call made after it, even ones that the remote vat
+// has already answered, and their buffered returns stay in memory.
+// Canceling the slow call's Context releases the calls behind it.
+func OrderedReturns() ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.orderedReturns = true
+	}}
+}
+
+// returnOrder tracks the questions of a connection that uses
+// OrderedReturns.  It is protected by Conn.mu.
+type returnOrder struct {
+	// sent lists questions in the order that they were sent.  Questions
+	// at the front that have completed are removed lazily by trim.
+	sent []*question
+
+	// early holds returns that arrived before earlier questions
+	// completed.  The messages have been copied out of the transport.
+	// A question that is canceled while its return waits here still
+	// needs the return handled to free its ID.
+	early map[*question]rpccapnp.Message
+}
+
+func newReturnOrder() *returnOrder {
+	return &returnOrder{early: make(map[*question]rpccapnp.Message)}
+}
+
+// trim removes completed questions from the front of o.sent.
+func (o *returnOrder) trim() {
+	for len(o.sent) > 0 && !o.sent[0].inProgress() {
+		o.sent[0] = nil
+		o.sent = o.sent[1:]
+	}
+}
+
+// blocked reports whether the return for q must wait for an earlier
+// question to complete.
+func (o *returnOrder) blocked(q *question) bool {
+	o.trim()
+	return len(o.sent) > 0 && o.sent[0] != q && q.inProgress()
+}
+
+// queueReturn buffers m, a return message, if its question must wait
+// for earlier questions to complete.  It reports whether m was
+// buffered.  The caller is holding onto c.mu.
+func (c *Conn) queueReturn(m rpccapnp.Message) bool {
+	if c.order == nil {
+		return false
+	}
+	ret, err := m.Return()
+	if err != nil {
+		return false
+	}
+	q := c.findQuestion(questionID(ret.AnswerId()))
+	if q == nil || !c.order.blocked(q) {
+		return false
+	}
+	c.order.early[q] = m
+	return true
+}
+
+// deliverEarlyReturns handles buffered returns whose earlier questions
+// have all completed, as well as buffered returns whose questions were
+// canceled.  It is called whenever a question completes.  The caller
+// is holding onto c.mu.
+func (c *Conn) deliverEarlyReturns() {
+	o := c.order
+	if o == nil {
+		return
+	}
+	for q, m := range o.early {
+		if q.inProgress() {
+			continue
+		}
+		delete(o.early, q)
+		if err := c.handleReturnMessage(m); err != nil {
+			c.errorf("handle return: %v", err)
+		}
+	}
+	for {
+		o.trim()
+		if len(o.sent) == 0 {
+			return
+		}
+		q := o.sent[0]
+		m, ok := o.early[q]
+		if !ok {
+			return
+		}
+		delete(o.early, q)
+		if err := c.handleReturnMessage(m); err != nil {
+			c.errorf("handle return: %v", err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestOrderedReturns(t *testing.T) {
+	ctx := context.Background()
+	resolved := make(chan uint32, 10)
+	conn, p := newUnpairedConn(t, rpc.OrderedReturns(), rpc.ConnCallHooks(rpc.CallHooks{
+		OnQuestionResolved: func(id uint32, err error) {
+			if err != nil {
+				t.Errorf("question %d resolved with error: %v", id, err)
+			}
+			resolved <- id
+		},
+	}))
+	defer conn.Close()
+	defer p.Close()
+	client := bootstrapAndFulfill(t, ctx, conn, p, false)
+
+	// Make three calls and note the question IDs in call order.
+	answers := make([]capnp.Answer, 3)
+	qids := make([]uint32, 3)
+	for i := range answers {
+		readDone := startRecvMessage(p)
+		n := uint64(i)
+		answers[i] = client.Call(&capnp.Call{
+			Ctx: ctx,
+			Method: capnp.Method{
+				InterfaceID: interfaceID,
+				MethodID:    methodID,
+			},
+			ParamsSize: capnp.ObjectSize{DataSize: 8},
+			ParamsFunc: func(s capnp.Struct) error {
+				s.SetUint64(0, n)
+				return nil
+			},
+		})
+		read := <-readDone
+		if read.err != nil {
+			t.Fatal("reading call:", read.err)
+		}
+		call, err := read.msg.Call()
+		if err != nil {
+			t.Fatal("reading call:", err)
+		}
+		qids[i] = call.QuestionId()
+	}
+
+	// Return out of order: last, first, middle.
+	for _, i := range []int{2, 0, 1} {
+		n := uint64(i)
+		err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+			ret, err := msg.NewReturn()
+			if err != nil {
+				return err
+			}
+			ret.SetAnswerId(qids[i])
+			payload, err := ret.NewResults()
+			if err != nil {
+				return err
+			}
+			content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{DataSize: 8})
+			if err != nil {
+				return err
+			}
+			content.SetUint64(0, n*100)
+			return payload.SetContent(content)
+		})
+		if err != nil {
+			t.Fatalf("sending return for call %d: %v", i, err)
+		}
+	}
+	for i, want := range qids {
+		if got := <-resolved; got != want {
+			t.Errorf("question #%d to resolve = %d; want %d (call %d)", i+1, got, want, i)
+		}
+	}
+	for i, a := range answers {
+		s, err := a.Struct()
+		if err != nil {
+			t.Errorf("call %d: %v", i, err)
+			continue
+		}
+		if x := s.Uint64(0); x != uint64(i)*100 {
+			t.Errorf("call %d result = %d; want %d", i, x, i*100)
+		}
+	}
+	for range qids {
+		if r := <-startRecvMessage(p); r.err != nil {
+			t.Fatal("reading finish:", r.err)
+		} else if r.msg.Which() != rpccapnp.Message_Which_finish {
+			t.Errorf("message after return = %v; want finish", r.msg.Which())
+		}
+	}
+}
+
+func TestOrderedReturns_CancelBuffered(t *testing.T) {
+	ctx := context.Background()
+	conn, p := newUnpairedConn(t, rpc.OrderedReturns())
+	defer conn.Close()
+	defer p.Close()
+	client := bootstrapAndFulfill(t, ctx, conn, p, false)
+
+	call := func(ctx context.Context) (capnp.Answer, uint32) {
+		readDone := startRecvMessage(p)
+		ans := client.Call(&capnp.Call{
+			Ctx: ctx,
+			Method: capnp.Method{
+				InterfaceID: interfaceID,
+				MethodID:    methodID,
+			},
+			ParamsSize: capnp.ObjectSize{DataSize: 8},
+			ParamsFunc: func(s capnp.Struct) error { return nil },
+		})
+		read := <-readDone
+		if read.err != nil {
+			t.Fatal("reading call:", read.err)
+		}
+		c, err := read.msg.Call()
+		if err != nil {
+			t.Fatal("reading call:", err)
+		}
+		return ans, c.QuestionId()
+	}
+	sendReturn := func(qid uint32) {
+		err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+			ret, err := msg.NewReturn()
+			if err != nil {
+				return err
+			}
+			ret.SetAnswerId(qid)
+			_, err = ret.NewResults()
+			return err
+		})
+		if err != nil {
+			t.Fatalf("sending return for question %d: %v", qid, err)
+		}
+	}
+
+	first, firstID := call(ctx)
+	blockedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	blocked, blockedID := call(blockedCtx)
+
+	// The return for the second call waits behind the first.  A
+	// bootstrap round trip ensures the conn has buffered it.
+	sendReturn(blockedID)
+	err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		boot, err := msg.NewBootstrap()
+		if err != nil {
+			return err
+		}
+		boot.SetQuestionId(99)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("sending bootstrap:", err)
+	}
+	if r := <-startRecvMessage(p); r.err != nil {
+		t.Fatal("reading bootstrap return:", r.err)
+	} else if r.msg.Which() != rpccapnp.Message_Which_return {
+		t.Fatalf("message after bootstrap = %v; want return", r.msg.Which())
+	}
+
+	cancel()
+	if r := <-startRecvMessage(p); r.err != nil {
+		t.Fatal("reading finish:", r.err)
+	} else if fin, err := r.msg.Finish(); r.msg.Which() != rpccapnp.Message_Which_finish || err != nil {
+		t.Fatalf("message after cancel = %v; want finish", r.msg.Which())
+	} else if fin.QuestionId() != blockedID {
+		t.Fatalf("finish question ID = %d; want %d", fin.QuestionId(), blockedID)
+	}
+	if _, err := blocked.Struct(); err != context.Canceled {
+		t.Errorf("canceled call error = %v; want %v", err, context.Canceled)
+	}
+
+	// Canceling the call handled its buffered return, which frees the
+	// question ID for the next call.
+	_, nextID := call(ctx)
+	if nextID != blockedID {
+		t.Errorf("question ID after canceled call = %d; want %d (reused)", nextID, blockedID)
+	}
+
+	sendReturn(firstID)
+	if _, err := first.Struct(); err != nil {
+		t.Error("first call:", err)
+	}
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// RecoverToException allocates a failed Exception in seg that reports
+// recovered, a value returned by recover in a method implementation.
+// The reason holds the panic value but not the stack trace, which can
+// reveal details of the server's code to the peer.  If includeStack is
+// true, the stack of the calling goroutine is appended to the reason;
+// call RecoverToException directly from the deferred function so that
+// the stack shows where the panic happened.
+//
+// Methods served by the server package have their panics recovered and
+// rejected with an error that holds the panic value but not the stack.
+// A method can recover itself to send the stack, for example during
+// development:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+//			exc, _ := rpc.RecoverToException(seg, r, true)
+//			err = rpc.Exception{Exception: exc}
+//		}
+//	}()
+func RecoverToException(seg *capnp.Segment, recovered interface{}, includeStack bool) (rpccapnp.Exception, error) {
+	if recovered == nil {
+		return rpccapnp.Exception{}, errNothingRecovered
+	}
+	exc, err := rpccapnp.NewException(seg)
+	if err != nil {
+		return rpccapnp.Exception{}, err
+	}
+	exc.SetType(rpccapnp.Exception_Type_failed)
+	reason := fmt.Sprintf("server panic: %v", recovered)
+	if includeStack {
+		reason += "\n\n" + string(debug.Stack())
+	}
+	if err := exc.SetReason(reason); err != nil {
+		return rpccapnp.Exception{}, err
+	}
+	return exc, nil
+}
+
+var errNothingRecovered = errors.New("rpc: recover to exception: recovered value is nil")
@@ -0,0 +1,115 @@
+package rpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/server"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestPanickingMethodReturnsException(t *testing.T) {
+	const questionID = 999
+	main := server.New([]server.Method{{
+		Method: capnp.Method{InterfaceID: interfaceID, MethodID: methodID},
+		Impl: func(ctx context.Context, opts capnp.CallOptions, params, results capnp.Struct) error {
+			panic("boom")
+		},
+	}}, nil)
+	conn, p := newUnpairedConn(t, rpc.MainInterface(main))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendBootstrapAndFinish(t, p)
+
+	err := sendMessage(context.TODO(), p, func(msg rpccapnp.Message) error {
+		call, err := msg.NewCall()
+		if err != nil {
+			return err
+		}
+		call.SetQuestionId(questionID)
+		call.SetInterfaceId(interfaceID)
+		call.SetMethodId(methodID)
+		target, err := call.NewTarget()
+		if err != nil {
+			return err
+		}
+		target.SetImportedCap(importID)
+		payload, err := call.NewParams()
+		if err != nil {
+			return err
+		}
+		content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{})
+		if err != nil {
+			return err
+		}
+		return payload.SetContent(content)
+	})
+	if err != nil {
+		t.Fatal("Call message failed:", err)
+	}
+	retmsg, err := p.RecvMessage(context.TODO())
+	if err != nil {
+		t.Fatal("Read Call return failed:", err)
+	}
+	if retmsg.Which() != rpccapnp.Message_Which_return {
+		t.Fatalf("Conn sent %v message; want return", retmsg.Which())
+	}
+	ret, err := retmsg.Return()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := ret.AnswerId(); id != questionID {
+		t.Errorf("Return.answerId = %d; want %d", id, questionID)
+	}
+	if ret.Which() != rpccapnp.Return_Which_exception {
+		t.Fatalf("Return.Which() = %v; want exception", ret.Which())
+	}
+	exc, err := ret.Exception()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_failed {
+		t.Errorf("Return.exception.type = %v; want failed", typ)
+	}
+	reason, err := exc.Reason()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reason, "boom") {
+		t.Errorf("Return.exception.reason = %q; want it to mention the panic value", reason)
+	}
+	if strings.Contains(reason, "goroutine") {
+		t.Errorf("Return.exception.reason = %q; includes a stack trace", reason)
+	}
+}
+
+func TestRecoverToException(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exc rpccapnp.Exception
+	func() {
+		defer func() {
+			exc, err = rpc.RecoverToException(seg, recover(), true)
+		}()
+		panic("boom")
+	}()
+	if err != nil {
+		t.Fatal("RecoverToException:", err)
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_failed {
+		t.Errorf("type = %v; want failed", typ)
+	}
+	reason, _ := exc.Reason()
+	if !strings.Contains(reason, "boom") || !strings.Contains(reason, "goroutine") {
+		t.Errorf("reason = %q; want panic value and stack", reason)
+	}
+
+	if _, err := rpc.RecoverToException(seg, nil, false); err == nil {
+		t.Error("RecoverToException(seg, nil, false) succeeded")
+	}
+}
@@ -0,0 +1,26 @@
+package rpc
+
+import (
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// PeekWhich returns the type of the RPC message serialized in frame, an
+// unpacked message with its segment table, as produced by
+// capnp.Message.Marshal.  Proxies can use it to route frames without
+// decoding their contents: only the segment table, the root pointer,
+// and the message's discriminant are read, and frame is not copied.
+//
+// A decoded rpccapnp.Message does not need PeekWhich: its Which method
+// reads only the discriminant as well.
+func PeekWhich(frame []byte) (rpccapnp.Message_Which, error) {
+	msg, err := capnp.Unmarshal(frame)
+	if err != nil {
+		return 0, err
+	}
+	m, err := rpccapnp.ReadRootMessage(msg)
+	if err != nil {
+		return 0, err
+	}
+	return m.Which(), nil
+}
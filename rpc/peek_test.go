@@ -0,0 +1,88 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestPeekWhich(t *testing.T) {
+	tests := []struct {
+		which rpccapnp.Message_Which
+		arena capnp.Arena
+		build func(rpccapnp.Message) error
+	}{
+		{rpccapnp.Message_Which_bootstrap, capnp.SingleSegment(nil), func(m rpccapnp.Message) error {
+			_, err := m.NewBootstrap()
+			return err
+		}},
+		{rpccapnp.Message_Which_call, capnp.SingleSegment(nil), func(m rpccapnp.Message) error {
+			call, err := m.NewCall()
+			if err != nil {
+				return err
+			}
+			_, err = call.NewParams()
+			return err
+		}},
+		{rpccapnp.Message_Which_finish, capnp.SingleSegment(nil), func(m rpccapnp.Message) error {
+			_, err := m.NewFinish()
+			return err
+		}},
+		{rpccapnp.Message_Which_abort, capnp.SingleSegment(nil), func(m rpccapnp.Message) error {
+			exc, err := m.NewAbort()
+			if err != nil {
+				return err
+			}
+			return exc.SetReason("bye")
+		}},
+		// A small maximum segment size puts the return in its own segment.
+		{rpccapnp.Message_Which_return, capnp.MultiSegment(nil, capnp.MaxSegmentSize(24)), func(m rpccapnp.Message) error {
+			ret, err := m.NewReturn()
+			if err != nil {
+				return err
+			}
+			ret.SetCanceled()
+			return nil
+		}},
+	}
+	for _, test := range tests {
+		_, seg, err := capnp.NewMessage(test.arena)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := rpccapnp.NewRootMessage(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := test.build(m); err != nil {
+			t.Fatalf("build %v: %v", test.which, err)
+		}
+		if test.which == rpccapnp.Message_Which_return && seg.Message().NumSegments() < 2 {
+			t.Errorf("return message has %d segments; want >= 2", seg.Message().NumSegments())
+		}
+		frame, err := seg.Message().Marshal()
+		if err != nil {
+			t.Fatalf("Marshal %v: %v", test.which, err)
+		}
+		which, err := rpc.PeekWhich(frame)
+		if err != nil {
+			t.Errorf("PeekWhich(%v frame): %v", test.which, err)
+			continue
+		}
+		if which != test.which {
+			t.Errorf("PeekWhich(%v frame) = %v", test.which, which)
+		}
+	}
+}
+
+func TestPeekWhich_Truncated(t *testing.T) {
+	frame, err := newFinishMessage(t, 1).Segment().Message().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rpc.PeekWhich(frame[:len(frame)-8]); err == nil {
+		t.Error("PeekWhich of truncated frame succeeded")
+	}
+}
@@ -57,6 +57,14 @@ type question struct {
 	// Protected by conn.mu
 	derived [][]capnp.PipelineOp
 
+	// forwardFor is the answer that this question was asked on behalf
+	// of with sendResultsTo.yourself, or nil.  returned is set once the
+	// remote vat has kept the results: the question then stays in the
+	// table for pipelining until forwardFor is finished.  Both are
+	// protected by conn.mu.
+	forwardFor *answer
+	returned   bool
+
 	// Fields below are protected by mu.
 	mu    sync.RWMutex
 	obj   capnp.Ptr
@@ -75,6 +83,12 @@ const (
 
 // start signals that the question has been sent.
 func (q *question) start() {
+	if q.conn.order != nil {
+		q.conn.order.sent = append(q.conn.order.sent, q)
+	}
+	if q.method != nil && q.conn.hooks.OnQuestionSent != nil {
+		q.conn.hooks.OnQuestionSent(uint32(q.id), *q.method)
+	}
 	go func() {
 		select {
 		case <-q.resolved:
@@ -92,6 +106,7 @@ func (q *question) start() {
 				}
 				if q.cancel(q.ctx.Err()) {
 					q.conn.sendMessage(newFinishMessage(nil, q.id, true /* release */))
+					q.conn.deliverEarlyReturns()
 				}
 				q.conn.workers.Done()
 				q.conn.mu.Unlock()
@@ -137,7 +152,7 @@ func (q *question) fulfill(obj capnp.Ptr) {
 		mt.SetPromisedAnswer(pa)
 
 		select {
-		case q.conn.out <- m:
+		case q.conn.out <- outgoingMessage{msg: m}:
 		case <-q.conn.bg.Done():
 			// TODO(soon): perhaps just drop all embargoes in this case?
 		}
@@ -148,6 +163,7 @@ func (q *question) fulfill(obj capnp.Ptr) {
 		panic("question.fulfill called more than once")
 	}
 	q.obj, q.state = obj, questionResolved
+	q.resolvedHook(nil)
 	close(q.resolved)
 	q.mu.Unlock()
 }
@@ -164,6 +180,7 @@ func (q *question) reject(err error) {
 	}
 	q.err = err
 	q.state = questionResolved
+	q.resolvedHook(err)
 	close(q.resolved)
 	q.mu.Unlock()
 }
@@ -179,12 +196,29 @@ func (q *question) cancel(err error) bool {
 	if canceled {
 		q.err = err
 		q.state = questionCanceled
+		q.resolvedHook(err)
 		close(q.resolved)
 	}
 	q.mu.Unlock()
 	return canceled
 }
 
+// inProgress reports whether q has not yet been resolved or canceled.
+func (q *question) inProgress() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.state == questionInProgress
+}
+
+// resolvedHook calls the OnQuestionResolved hook if q is a call.  It is
+// called before q.resolved is closed, so the hook has run by the time
+// the caller sees the result.
+func (q *question) resolvedHook(err error) {
+	if q.method != nil && q.conn.hooks.OnQuestionResolved != nil {
+		q.conn.hooks.OnQuestionResolved(uint32(q.id), err)
+	}
+}
+
 // addPromise records a returned capability as being used for a call.
 // This is needed for determining embargoes upon resolution.  The
 // caller must be holding onto q.conn.mu.
@@ -253,16 +287,18 @@ func (q *question) lockedPipelineCall(transform []capnp.PipelineOp, ccall *capnp
 		return q.conn.lockedCall(client, ccall)
 	}
 
+	msg, msgCall, md, err := q.conn.newCallMessage(ccall.Ctx, &ccall.Method)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
 	pipeq := q.conn.newQuestion(ccall.Ctx, &ccall.Method)
-	msg := newMessage(nil)
-	msgCall, _ := msg.NewCall()
 	msgCall.SetQuestionId(uint32(pipeq.id))
 	msgCall.SetInterfaceId(ccall.Method.InterfaceID)
 	msgCall.SetMethodId(ccall.Method.MethodID)
 	target, _ := msgCall.NewTarget()
 	a, _ := target.NewPromisedAnswer()
 	a.SetQuestionId(uint32(q.id))
-	err := transformToPromisedAnswer(a.Segment(), a, transform)
+	err = transformToPromisedAnswer(a.Segment(), a, transform)
 	if err != nil {
 		q.conn.popQuestion(pipeq.id)
 		return capnp.ErrorAnswer(err)
@@ -274,7 +310,7 @@ func (q *question) lockedPipelineCall(transform []capnp.PipelineOp, ccall *capnp
 	}
 
 	select {
-	case q.conn.out <- msg:
+	case q.conn.out <- outgoingMessage{msg, md}:
 	case <-ccall.Ctx.Done():
 		q.conn.popQuestion(pipeq.id)
 		return capnp.ErrorAnswer(ccall.Ctx.Err())
@@ -11,6 +11,7 @@ import (
 	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
 	"zombiezen.com/go/capnproto2/server"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
 func TestRelease(t *testing.T) {
@@ -143,3 +144,88 @@ func (hf *HandleFactory) numHandles() int {
 	hf.mu.Unlock()
 	return n
 }
+
+func TestReleaseOverCount(t *testing.T) {
+	ctx := context.Background()
+	conn, p := newUnpairedConn(t, rpc.MainInterface(mockClient()))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendOverRelease(t, p)
+
+	// The release was clamped to two references, so the export is gone
+	// and a call to it fails.
+	err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		call, err := msg.NewCall()
+		if err != nil {
+			return err
+		}
+		call.SetQuestionId(77)
+		call.SetInterfaceId(interfaceID)
+		call.SetMethodId(methodID)
+		target, err := call.NewTarget()
+		if err != nil {
+			return err
+		}
+		target.SetImportedCap(importID)
+		payload, err := call.NewParams()
+		if err != nil {
+			return err
+		}
+		content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{})
+		if err != nil {
+			return err
+		}
+		return payload.SetContent(content)
+	})
+	if err != nil {
+		t.Fatal("send call:", err)
+	}
+	retmsg, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("read return:", err)
+	}
+	if retmsg.Which() != rpccapnp.Message_Which_return {
+		t.Fatalf("Conn sent %v message; want return", retmsg.Which())
+	}
+	ret, err := retmsg.Return()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Which() != rpccapnp.Return_Which_exception {
+		t.Errorf("call to released export returned %v; want exception", ret.Which())
+	}
+}
+
+func TestReleaseOverCount_Strict(t *testing.T) {
+	conn, p := newUnpairedConn(t, rpc.MainInterface(mockClient()), rpc.StrictRelease())
+	defer conn.Close()
+	defer p.Close()
+	sendOverRelease(t, p)
+
+	recvAbort(t, context.Background(), p)
+	if err := conn.Wait(); err == nil {
+		t.Error("conn.Wait() = <nil>; want error")
+	}
+}
+
+// sendOverRelease bootstraps twice, so that p holds two references to
+// the bootstrap export, and then releases five references.
+func sendOverRelease(t *testing.T, p rpc.Transport) (importID uint32) {
+	importID = sendBootstrapAndFinish(t, p)
+	if id := sendBootstrapAndFinish(t, p); id != importID {
+		t.Fatalf("second bootstrap import ID = %d; want %d", id, importID)
+	}
+	err := sendMessage(context.Background(), p, func(msg rpccapnp.Message) error {
+		rel, err := msg.NewRelease()
+		if err != nil {
+			return err
+		}
+		rel.SetId(importID)
+		rel.SetReferenceCount(5)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("send release:", err)
+	}
+	return importID
+}
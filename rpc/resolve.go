@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// BuildResolveCap allocates a Resolve in seg that reports that the
+// promise exported as promiseID resolved to the capability described
+// by desc.  desc is copied into the Resolve if it is in a different
+// message.  The result can be set as the resolve variant of a Message.
+func BuildResolveCap(seg *capnp.Segment, promiseID uint32, desc rpccapnp.CapDescriptor) (rpccapnp.Resolve, error) {
+	res, err := rpccapnp.NewResolve(seg)
+	if err != nil {
+		return rpccapnp.Resolve{}, err
+	}
+	res.SetPromiseId(promiseID)
+	if err := res.SetCap(desc); err != nil {
+		return rpccapnp.Resolve{}, err
+	}
+	return res, nil
+}
+
+// BuildResolveException allocates a Resolve in seg that reports that
+// the promise exported as promiseID was broken with exc.  exc is
+// encoded the same way as errors returned from calls: an Exception
+// keeps its type and reason, and any other error becomes a failed
+// exception with the error's message as the reason.
+func BuildResolveException(seg *capnp.Segment, promiseID uint32, exc error) (rpccapnp.Resolve, error) {
+	res, err := rpccapnp.NewResolve(seg)
+	if err != nil {
+		return rpccapnp.Resolve{}, err
+	}
+	res.SetPromiseId(promiseID)
+	e, err := res.NewException()
+	if err != nil {
+		return rpccapnp.Resolve{}, err
+	}
+	toException(e, exc)
+	return res, nil
+}
@@ -0,0 +1,68 @@
+package rpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestBuildResolveCap(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := rpccapnp.NewCapDescriptor(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc.SetSenderHosted(7)
+
+	res, err := rpc.BuildResolveCap(seg, 42, desc)
+	if err != nil {
+		t.Fatal("BuildResolveCap:", err)
+	}
+	if res.Which() != rpccapnp.Resolve_Which_cap {
+		t.Fatalf("res.Which() = %v; want cap", res.Which())
+	}
+	if id := res.PromiseId(); id != 42 {
+		t.Errorf("res.PromiseId() = %d; want 42", id)
+	}
+	d, err := res.Cap()
+	if err != nil {
+		t.Fatal("res.Cap():", err)
+	}
+	if d.Which() != rpccapnp.CapDescriptor_Which_senderHosted || d.SenderHosted() != 7 {
+		t.Errorf("res.Cap() = %v; want senderHosted 7", d)
+	}
+}
+
+func TestBuildResolveException(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := rpc.BuildResolveException(seg, 42, errors.New("promise broken"))
+	if err != nil {
+		t.Fatal("BuildResolveException:", err)
+	}
+	if res.Which() != rpccapnp.Resolve_Which_exception {
+		t.Fatalf("res.Which() = %v; want exception", res.Which())
+	}
+	if id := res.PromiseId(); id != 42 {
+		t.Errorf("res.PromiseId() = %d; want 42", id)
+	}
+	exc, err := res.Exception()
+	if err != nil {
+		t.Fatal("res.Exception():", err)
+	}
+	if typ := exc.Type(); typ != rpccapnp.Exception_Type_failed {
+		t.Errorf("exception type = %v; want failed", typ)
+	}
+	if reason, err := exc.Reason(); err != nil || reason != "promise broken" {
+		t.Errorf("exception reason = %q, %v; want \"promise broken\", <nil>", reason, err)
+	}
+}
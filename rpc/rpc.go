@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
@@ -17,11 +19,21 @@ import (
 type Conn struct {
 	transport  Transport
 	log        Logger
+	hooks      CallHooks
 	mainFunc   func(context.Context) (capnp.Client, error)
 	mainCloser io.Closer
+	maxCaps    int           // zero means no limit
+	strictRel  bool          // see StrictRelease
 	death      chan struct{} // closed after state is connDead
+	counts     *messageCounts
 
-	out chan rpccapnp.Message
+	out chan outgoingMessage
+
+	// handshake is the timer started by HandshakeTimeout, or nil.
+	// handshakeState is one of the handshake* constants, accessed
+	// atomically.
+	handshake      *time.Timer
+	handshakeState int32
 
 	bg       context.Context
 	bgCancel context.CancelFunc
@@ -43,13 +55,20 @@ type Conn struct {
 	embargoID  idgen
 	answers    map[answerID]*answer
 	imports    map[importID]*impent
+	order      *returnOrder // nil unless OrderedReturns is set
 }
 
 type connParams struct {
 	log            Logger
+	hooks          CallHooks
 	mainFunc       func(context.Context) (capnp.Client, error)
 	mainCloser     io.Closer
 	sendBufferSize int
+	maxCaps        int
+
+	handshakeTimeout time.Duration
+	orderedReturns   bool
+	strictRelease    bool
 }
 
 // A ConnOption is an option for opening a connection.
@@ -89,6 +108,45 @@ func SendBufferSize(numMsgs int) ConnOption {
 	}}
 }
 
+// HandshakeTimeout bounds the time from creating the connection to
+// receiving the first return message from the remote vat, usually the
+// answer to Bootstrap.  If no return arrives in time, the connection
+// is aborted with ErrHandshakeTimeout.  This guards against hanging
+// forever on a peer that accepts a connection but never responds.
+//
+// A connection that only serves calls never receives a return, so this
+// option should only be used on connections that make calls.
+func HandshakeTimeout(d time.Duration) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.handshakeTimeout = d
+	}}
+}
+
+// MaxCapsPerMessage limits the number of entries in the capability
+// table of a received call or return.  A message that exceeds the limit
+// aborts the connection before any of its capabilities are added to
+// the connection's import table, which keeps a remote vat from
+// exhausting the tables with a single message.  A limit of zero (the
+// default) means no limit.
+func MaxCapsPerMessage(n int) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.maxCaps = n
+	}}
+}
+
+// StrictRelease makes receiving a release message for more references
+// to an export than the remote vat holds abort the connection.  Such a
+// release is a protocol violation: honoring it could close a
+// capability that the remote vat, or a third party it passed the
+// reference to, still uses.  By default, the release is clamped to the
+// number of references held, so the export is closed, and the
+// violation is logged.
+func StrictRelease() ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.strictRelease = true
+	}}
+}
+
 // NewConn creates a new connection that communicates on c.
 // Closing the connection will cause c to be closed.
 func NewConn(t Transport, options ...ConnOption) *Conn {
@@ -102,20 +160,55 @@ func NewConn(t Transport, options ...ConnOption) *Conn {
 
 	conn := &Conn{
 		transport:  t,
-		out:        make(chan rpccapnp.Message, p.sendBufferSize),
+		out:        make(chan outgoingMessage, p.sendBufferSize),
 		mainFunc:   p.mainFunc,
 		mainCloser: p.mainCloser,
 		log:        p.log,
+		hooks:      p.hooks,
+		maxCaps:    p.maxCaps,
+		strictRel:  p.strictRelease,
 		death:      make(chan struct{}),
+		counts:     new(messageCounts),
 		mu:         newChanMutex(),
 	}
+	if p.orderedReturns {
+		conn.order = newReturnOrder()
+	}
 	conn.bg, conn.bgCancel = context.WithCancel(context.Background())
+	if p.handshakeTimeout > 0 {
+		conn.handshake = time.AfterFunc(p.handshakeTimeout, conn.handshakeExpired)
+	}
 	conn.workers.Add(2)
 	go conn.dispatchRecv()
 	go conn.dispatchSend()
 	return conn
 }
 
+const (
+	handshakePending int32 = iota
+	handshakeDone
+	handshakeExpired
+)
+
+// finishHandshake stops the handshake timer, if any.  It is called for
+// every received return message.
+func (c *Conn) finishHandshake() {
+	if c.handshake == nil {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&c.handshakeState, handshakePending, handshakeDone) {
+		c.handshake.Stop()
+	}
+}
+
+// handshakeExpired is called by the handshake timer.
+func (c *Conn) handshakeExpired() {
+	if atomic.CompareAndSwapInt32(&c.handshakeState, handshakePending, handshakeExpired) {
+		c.errorf("handshake timed out")
+		c.abort(ErrHandshakeTimeout)
+	}
+}
+
 // Wait waits until the connection is closed or aborted by the remote vat.
 // Wait will always return an error, usually ErrConnClosed or of type Abort.
 func (c *Conn) Wait() error {
@@ -133,7 +226,7 @@ func (c *Conn) Done() <-chan struct{} {
 func (c *Conn) Err() error {
 	c.stateMu.RLock()
 	var err error
-	if c.state != connDead {
+	if c.state == connDead {
 		err = c.closeErr
 	}
 	c.stateMu.RUnlock()
@@ -212,6 +305,9 @@ func (c *Conn) startWork() error {
 
 // teardown moves the connection from the dying to the dead state.
 func (c *Conn) teardown(abort rpccapnp.Message) {
+	if c.handshake != nil {
+		c.handshake.Stop()
+	}
 	c.workers.Wait()
 
 	c.mu.Lock()
@@ -253,6 +349,9 @@ func (c *Conn) teardown(abort rpccapnp.Message) {
 	var werr error
 	if abort.IsValid() {
 		werr = c.transport.SendMessage(context.Background(), abort)
+		if werr == nil {
+			c.counts.add(abort.Which())
+		}
 	}
 	cerr := c.transport.Close()
 
@@ -294,7 +393,7 @@ func (c *Conn) Bootstrap(ctx context.Context) capnp.Client {
 	// Worst case, this blocks until a message is sent on the transport.
 	// Common case, this just adds to the channel queue.
 	select {
-	case c.out <- msg:
+	case c.out <- outgoingMessage{msg: msg}:
 		q.start()
 		return capnp.NewPipeline(q).Client()
 	case <-ctx.Done():
@@ -307,12 +406,21 @@ func (c *Conn) Bootstrap(ctx context.Context) capnp.Client {
 }
 
 // handleMessage is run from the receive goroutine to process a single
-// message.  m cannot be held onto past the return of handleMessage, and
-// c.mu is not held at the start of handleMessage.
-func (c *Conn) handleMessage(m rpccapnp.Message) {
+// message and the metadata received with it.  m cannot be held onto
+// past the return of handleMessage, and c.mu is not held at the start
+// of handleMessage.
+func (c *Conn) handleMessage(m rpccapnp.Message, md Metadata) {
 	switch m.Which() {
 	case rpccapnp.Message_Which_unimplemented:
-		// no-op for now to avoid feedback loop
+		// Never reply to an unimplemented message, to avoid a feedback loop.
+		c.mu.Lock()
+		err := c.handleUnimplementedMessage(m)
+		c.deliverEarlyReturns()
+		c.mu.Unlock()
+
+		if err != nil {
+			c.errorf("handle unimplemented: %v", err)
+		}
 	case rpccapnp.Message_Which_abort:
 		a, err := copyAbort(m)
 		if err != nil {
@@ -324,11 +432,17 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 	case rpccapnp.Message_Which_return:
 		m = copyRPCMessage(m)
 		c.mu.Lock()
-		err := c.handleReturnMessage(m)
+		var err error
+		if !c.queueReturn(m) {
+			err = c.handleReturnMessage(m)
+			c.deliverEarlyReturns()
+		}
 		c.mu.Unlock()
 
 		if err != nil {
 			c.errorf("handle return: %v", err)
+		} else {
+			c.finishHandshake()
 		}
 	case rpccapnp.Message_Which_finish:
 		mfin, err := m.Finish()
@@ -342,7 +456,11 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 		a := c.popAnswer(id)
 		if a == nil {
 			c.mu.Unlock()
-			c.errorf("finish called for unknown answer %d", id)
+			// Finishing a question that was never asked is a protocol
+			// violation.
+			err := fmt.Errorf("rpc: received finish for unknown answer id=%d", id)
+			c.errorf("%v", err)
+			c.abort(err)
 			return
 		}
 		a.cancel()
@@ -351,6 +469,18 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 				c.releaseExport(id, 1)
 			}
 		}
+		if q := a.forward; q != nil && q.returned {
+			// The caller took the results that the remote vat kept for
+			// the forwarded question, so that question can be finished.
+			q.mu.RLock()
+			qstate := q.state
+			q.mu.RUnlock()
+			if qstate == questionInProgress {
+				c.popQuestion(q.id)
+				q.reject(errResultsTaken)
+				c.sendMessage(newFinishMessage(nil, q.id, mfin.ReleaseResultCaps()))
+			}
+		}
 		c.mu.Unlock()
 	case rpccapnp.Message_Which_bootstrap:
 		boot, err := m.Bootstrap()
@@ -370,7 +500,7 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 	case rpccapnp.Message_Which_call:
 		m = copyRPCMessage(m)
 		c.mu.Lock()
-		err := c.handleCallMessage(m)
+		err := c.handleCallMessage(m, md)
 		c.mu.Unlock()
 
 		if err != nil {
@@ -386,8 +516,13 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 		refs := int(rel.ReferenceCount())
 
 		c.mu.Lock()
-		c.releaseExport(id, refs)
+		err = c.handleReleaseMessage(id, refs)
 		c.mu.Unlock()
+
+		if err != nil {
+			c.errorf("%v", err)
+			c.abort(err)
+		}
 	case rpccapnp.Message_Which_disembargo:
 		m = copyRPCMessage(m)
 		c.mu.Lock()
@@ -430,15 +565,23 @@ func (c *Conn) fillParams(payload rpccapnp.Payload, cl *capnp.Call) error {
 }
 
 func transformToPromisedAnswer(s *capnp.Segment, answer rpccapnp.PromisedAnswer, transform []capnp.PipelineOp) error {
-	opList, err := rpccapnp.NewPromisedAnswer_Op_List(s, int32(len(transform)))
-	if err != nil {
-		return err
+	if len(transform) == 0 {
+		// Leave the transform as an empty list rather than a noop.
+		opList, err := rpccapnp.NewPromisedAnswer_Op_List(s, 0)
+		if err != nil {
+			return err
+		}
+		return answer.SetTransform(opList)
 	}
+	fields := make([]uint16, len(transform))
 	for i, op := range transform {
-		opList.At(i).SetGetPointerField(uint16(op.Field))
+		fields[i] = op.Field
 	}
-	err = answer.SetTransform(opList)
-	return err
+	opList, err := BuildTransform(s, fields)
+	if err != nil {
+		return err
+	}
+	return answer.SetTransform(opList)
 }
 
 // handleReturnMessage is to handle a received return message.
@@ -449,9 +592,13 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 		return err
 	}
 	id := questionID(ret.AnswerId())
-	q := c.popQuestion(id)
+	q := c.findQuestion(id)
 	if q == nil {
-		return fmt.Errorf("received return for unknown question id=%d", id)
+		// Answering a question that was never asked is a protocol
+		// violation.
+		err := fmt.Errorf("rpc: received return for unknown question id=%d", id)
+		c.abort(err)
+		return err
 	}
 	if ret.ReleaseParamCaps() {
 		for _, id := range q.paramCaps {
@@ -461,6 +608,15 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 	q.mu.RLock()
 	qstate := q.state
 	q.mu.RUnlock()
+	if ret.Which() == rpccapnp.Return_Which_resultsSentElsewhere && q.forwardFor != nil && qstate == questionInProgress {
+		// The remote vat kept the results, so the caller of the answer
+		// that this question was forwarded for takes them from there.
+		// The question stays open for pipelining until that answer is
+		// finished.
+		q.returned = true
+		return q.forwardFor.takeFrom(q)
+	}
+	c.popQuestion(id)
 	if qstate == questionCanceled {
 		// We already sent the finish message.
 		return nil
@@ -510,6 +666,32 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 		c.errorf("%v", err)
 		q.reject(err)
 		return nil
+	case rpccapnp.Return_Which_takeFromOtherQuestion:
+		aid := answerID(ret.TakeFromOtherQuestion())
+		a := c.answers[aid]
+		if a == nil || !a.resultsElsewhere {
+			err := fmt.Errorf("rpc: return for question id=%d takes results from answer id=%d, which did not keep them", id, aid)
+			c.abort(err)
+			return err
+		}
+		a.mu.RLock()
+		obj, aerr, done := a.obj, a.err, a.done
+		a.mu.RUnlock()
+		if !done {
+			err := fmt.Errorf("rpc: return for question id=%d takes results from unreturned answer id=%d", id, aid)
+			c.abort(err)
+			return err
+		}
+		if aerr != nil {
+			q.reject(aerr)
+			break
+		}
+		content, err := copyResults(obj)
+		if err != nil {
+			q.reject(err)
+			break
+		}
+		q.fulfill(content)
 	default:
 		um := newUnimplementedMessage(nil, m)
 		c.sendMessage(um)
@@ -520,6 +702,60 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 	return nil
 }
 
+// handleUnimplementedMessage handles a message that the remote vat
+// echoed back because it does not implement it.  A bootstrap or call
+// that comes back this way fails with capnp.ErrUnimplemented, so that
+// the caller can fall back to another approach.  Other echoed messages
+// are ignored.  The caller holds onto c.mu.
+func (c *Conn) handleUnimplementedMessage(m rpccapnp.Message) error {
+	orig, err := m.Unimplemented()
+	if err != nil {
+		return err
+	}
+	var id questionID
+	switch orig.Which() {
+	case rpccapnp.Message_Which_bootstrap:
+		boot, err := orig.Bootstrap()
+		if err != nil {
+			return err
+		}
+		id = questionID(boot.QuestionId())
+	case rpccapnp.Message_Which_call:
+		call, err := orig.Call()
+		if err != nil {
+			return err
+		}
+		id = questionID(call.QuestionId())
+	default:
+		c.infof("remote does not implement %v messages", orig.Which())
+		return nil
+	}
+	q := c.popQuestion(id)
+	if q == nil {
+		return fmt.Errorf("received unimplemented for unknown question id=%d", id)
+	}
+	// The remote never took ownership of the parameters' capabilities.
+	for _, id := range q.paramCaps {
+		c.releaseExport(id, 1)
+	}
+	q.mu.RLock()
+	qstate := q.state
+	q.mu.RUnlock()
+	if qstate == questionCanceled {
+		return nil
+	}
+	// No answer was created on the remote, so no finish is sent.
+	if q.method != nil {
+		q.reject(&capnp.MethodError{
+			Method: q.method,
+			Err:    capnp.ErrUnimplemented,
+		})
+	} else {
+		q.reject(capnp.ErrUnimplemented)
+	}
+	return nil
+}
+
 func newFinishMessage(buf []byte, questionID questionID, release bool) rpccapnp.Message {
 	m := newMessage(buf)
 	f, _ := m.NewFinish()
@@ -536,6 +772,9 @@ func (c *Conn) populateMessageCapTable(payload rpccapnp.Payload) error {
 	if err != nil {
 		return err
 	}
+	if c.maxCaps > 0 && ctab.Len() > c.maxCaps {
+		return fmt.Errorf("rpc: capability table has %d entries, more than limit of %d", ctab.Len(), c.maxCaps)
+	}
 	for i, n := 0, ctab.Len(); i < n; i++ {
 		desc := ctab.At(i)
 		switch desc.Which() {
@@ -580,7 +819,10 @@ func (c *Conn) populateMessageCapTable(payload rpccapnp.Payload) error {
 			if err != nil {
 				return err
 			}
-			transform := promisedAnswerOpsToTransform(recvTransform)
+			transform, err := promisedAnswerOpsToTransform(recvTransform)
+			if err != nil {
+				return err
+			}
 			msg.AddCap(a.pipelineClient(transform))
 		default:
 			c.errorf("unknown capability type %v", desc.Which())
@@ -616,10 +858,8 @@ func (c *Conn) handleBootstrapMessage(id answerID) error {
 	a := c.insertAnswer(id, cancel)
 	if a == nil {
 		// Question ID reused, error out.
-		retmsg := newReturnMessage(nil, id)
-		r, _ := retmsg.Return()
-		setReturnException(r, errQuestionReused)
-		return c.sendMessage(retmsg)
+		c.abort(errQuestionReused)
+		return errQuestionReused
 	}
 	if c.mainFunc == nil {
 		return a.reject(errNoMainInterface)
@@ -637,9 +877,31 @@ func (c *Conn) handleBootstrapMessage(id answerID) error {
 	return a.fulfill(in.ToPtr())
 }
 
-// handleCallMessage handles a received call message.  It mutates the
-// capability table of its parameter.  The caller holds onto c.mu.
-func (c *Conn) handleCallMessage(m rpccapnp.Message) error {
+// handleReleaseMessage releases refs of the remote vat's references to
+// an export.  A release of more references than the remote vat holds
+// is an error if the connection uses StrictRelease, in which case
+// nothing is released.  Otherwise, it is clamped and logged.  The
+// caller holds onto c.mu.
+func (c *Conn) handleReleaseMessage(id exportID, refs int) error {
+	e := c.findExport(id)
+	if e == nil {
+		return nil
+	}
+	if refs > e.wireRefs {
+		if c.strictRel {
+			return fmt.Errorf("rpc: received release of %d references to export id=%d, which has %d", refs, id, e.wireRefs)
+		}
+		c.errorf("protocol violation: received release of %d references to export id=%d, which has %d", refs, id, e.wireRefs)
+		refs = e.wireRefs
+	}
+	c.releaseExport(id, refs)
+	return nil
+}
+
+// handleCallMessage handles a received call message and its metadata.
+// It mutates the capability table of its parameter.  The caller holds
+// onto c.mu.
+func (c *Conn) handleCallMessage(m rpccapnp.Message, md Metadata) error {
 	mcall, err := m.Call()
 	if err != nil {
 		return err
@@ -652,6 +914,12 @@ func (c *Conn) handleCallMessage(m rpccapnp.Message) error {
 		um := newUnimplementedMessage(nil, m)
 		return c.sendMessage(um)
 	}
+	sendResultsTo := mcall.SendResultsTo().Which()
+	if sendResultsTo != rpccapnp.Call_sendResultsTo_Which_caller && sendResultsTo != rpccapnp.Call_sendResultsTo_Which_yourself {
+		// Sending results to a third party requires level 3 support.
+		um := newUnimplementedMessage(nil, m)
+		return c.sendMessage(um)
+	}
 	mparams, err := mcall.Params()
 	if err != nil {
 		return err
@@ -671,10 +939,15 @@ func (c *Conn) handleCallMessage(m rpccapnp.Message) error {
 		c.abort(errQuestionReused)
 		return errQuestionReused
 	}
+	a.resultsElsewhere = sendResultsTo == rpccapnp.Call_sendResultsTo_Which_yourself
 	meth := capnp.Method{
 		InterfaceID: mcall.InterfaceId(),
 		MethodID:    mcall.MethodId(),
 	}
+	ctx, err = c.recvCallContext(ctx, md, &meth)
+	if err != nil {
+		return a.reject(err)
+	}
 	paramContent, err := mparams.ContentPtr()
 	if err != nil {
 		return err
@@ -698,6 +971,9 @@ func (c *Conn) routeCallMessage(result *answer, mt rpccapnp.MessageTarget, cl *c
 		if e == nil {
 			return errBadTarget
 		}
+		if ic := c.reflectedImport(result, e.client); ic != nil {
+			return c.forwardCall(result, ic, cl)
+		}
 		answer := c.lockedCall(e.client, cl)
 		go joinAnswer(result, answer)
 	case rpccapnp.MessageTarget_Which_promisedAnswer:
@@ -718,12 +994,22 @@ func (c *Conn) routeCallMessage(result *answer, mt rpccapnp.MessageTarget, cl *c
 		if err != nil {
 			return err
 		}
-		transform := promisedAnswerOpsToTransform(mtrans)
+		transform, err := promisedAnswerOpsToTransform(mtrans)
+		if err != nil {
+			return err
+		}
 		pa.mu.Lock()
-		if pa.done {
+		if q := pa.forward; q != nil {
+			pa.mu.Unlock()
+			answer := q.lockedPipelineCall(transform, cl)
+			go joinAnswer(result, answer)
+		} else if pa.done {
 			obj, err := pa.obj, pa.err
 			pa.mu.Unlock()
 			client := clientFromResolution(transform, obj, err)
+			if ic := c.reflectedImport(result, client); ic != nil {
+				return c.forwardCall(result, ic, cl)
+			}
 			answer := c.lockedCall(client, cl)
 			go joinAnswer(result, answer)
 		} else {
@@ -737,6 +1023,41 @@ func (c *Conn) routeCallMessage(result *answer, mt rpccapnp.MessageTarget, cl *c
 	return nil
 }
 
+// reflectedImport returns the import that client refers to if it is
+// hosted by the remote vat, so that a call on it for result can be
+// forwarded back with sendResultsTo.yourself.  The remote vat then
+// keeps the results instead of sending them here only to have them
+// sent back.  It returns nil if the call should be made normally.
+func (c *Conn) reflectedImport(result *answer, client capnp.Client) *importClient {
+	if result.resultsElsewhere {
+		// The caller already asked to keep the results here.
+		return nil
+	}
+	ic := isImport(client)
+	if ic == nil || ic.conn != c {
+		return nil
+	}
+	return ic
+}
+
+// forwardCall sends cl to the remote capability ic with
+// sendResultsTo.yourself on behalf of result.  result is resolved when
+// the question returns, either by taking its results from the remote
+// vat's answer or, if the remote vat returned them anyway, by sending
+// them back.  The caller is holding onto c.mu.
+func (c *Conn) forwardCall(result *answer, ic *importClient, cl *capnp.Call) error {
+	q, err := ic.lockedSend(cl, true)
+	if err != nil {
+		return err
+	}
+	q.forwardFor = result
+	result.mu.Lock()
+	result.forward = q
+	result.mu.Unlock()
+	go joinForwarded(result, q)
+	return nil
+}
+
 func (c *Conn) handleDisembargoMessage(msg rpccapnp.Message) error {
 	d, err := msg.Disembargo()
 	if err != nil {
@@ -765,7 +1086,10 @@ func (c *Conn) handleDisembargoMessage(msg rpccapnp.Message) error {
 		if err != nil {
 			return err
 		}
-		transform := promisedAnswerOpsToTransform(dtrans)
+		transform, err := promisedAnswerOpsToTransform(dtrans)
+		if err != nil {
+			return err
+		}
 		queued, err := a.queueDisembargo(transform, id, dtarget)
 		if err != nil {
 			return err
@@ -809,11 +1133,14 @@ func (c *Conn) newContext() (context.Context, context.CancelFunc) {
 	return context.WithCancel(c.bg)
 }
 
-func promisedAnswerOpsToTransform(list rpccapnp.PromisedAnswer_Op_List) []capnp.PipelineOp {
+func promisedAnswerOpsToTransform(list rpccapnp.PromisedAnswer_Op_List) ([]capnp.PipelineOp, error) {
 	n := list.Len()
 	transform := make([]capnp.PipelineOp, 0, n)
 	for i := 0; i < n; i++ {
-		op := list.At(i)
+		op, err := list.AtChecked(i)
+		if err != nil {
+			return nil, fmt.Errorf("rpc: transform op %d: %v", i, err)
+		}
 		switch op.Which() {
 		case rpccapnp.PromisedAnswer_Op_Which_getPointerField:
 			transform = append(transform, capnp.PipelineOp{
@@ -823,7 +1150,7 @@ func promisedAnswerOpsToTransform(list rpccapnp.PromisedAnswer_Op_List) []capnp.
 			// no-op
 		}
 	}
-	return transform
+	return transform, nil
 }
 
 func newAbortMessage(buf []byte, err error) rpccapnp.Message {
@@ -865,6 +1192,23 @@ func clientFromResolution(transform []capnp.PipelineOp, obj capnp.Ptr, err error
 	return c
 }
 
+// copyResults copies obj and the capabilities it refers to into a new
+// message, so that a question can hold the results of an answer
+// without sharing the answer's capability table.
+func copyResults(obj capnp.Ptr) (capnp.Ptr, error) {
+	if !obj.IsValid() {
+		return obj, nil
+	}
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.Ptr{}, err
+	}
+	if err := msg.SetRootPtr(obj); err != nil {
+		return capnp.Ptr{}, err
+	}
+	return msg.RootPtr()
+}
+
 func newMessage(buf []byte) rpccapnp.Message {
 	_, s, err := capnp.NewMessage(capnp.SingleSegment(buf))
 	if err != nil {
@@ -5,12 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
 	"zombiezen.com/go/capnproto2/rpc"
 	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
 	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
@@ -59,6 +61,82 @@ func TestBootstrap(t *testing.T) {
 	readBootstrap(t, clientCtx, conn, p)
 }
 
+func TestConnErr(t *testing.T) {
+	conn, p := newUnpairedConn(t)
+	defer p.Close()
+	if err := conn.Err(); err != nil {
+		t.Errorf("conn.Err() before Close = %v; want <nil>", err)
+	}
+	abort := startRecvMessage(p)
+	if err := conn.Close(); err != nil {
+		t.Error("conn.Close():", err)
+	}
+	if err := conn.Wait(); err != rpc.ErrConnClosed {
+		t.Errorf("conn.Wait() = %v; want %v", err, rpc.ErrConnClosed)
+	}
+	if err := conn.Err(); err != rpc.ErrConnClosed {
+		t.Errorf("conn.Err() after Close = %v; want %v", err, rpc.ErrConnClosed)
+	}
+	<-abort
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	conn, p := newUnpairedConn(t, rpc.HandshakeTimeout(50*time.Millisecond))
+	defer conn.Close()
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The peer reads the bootstrap message but never answers it.
+	client, _ := readBootstrap(t, ctx, conn, p)
+	msg, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("Read Abort failed:", err)
+	}
+	if msg.Which() != rpccapnp.Message_Which_abort {
+		t.Fatalf("Conn sent %v message after handshake timeout, want Message_Which_abort", msg.Which())
+	}
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed 5s after handshake timeout")
+	}
+	if err := conn.Err(); err != rpc.ErrHandshakeTimeout {
+		t.Errorf("conn.Err() = %v; want %v", err, rpc.ErrHandshakeTimeout)
+	}
+	if _, err := callseq(ctx, client, 0).Struct(); err == nil {
+		t.Error("call on bootstrap client after handshake timeout succeeded; want error")
+	}
+}
+
+func TestHandshakeTimeout_Answered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := testLogger{t}
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	const timeout = 50 * time.Millisecond
+	c := rpc.NewConn(p, rpc.ConnLog(log), rpc.HandshakeTimeout(timeout))
+	srv := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := c.Bootstrap(ctx)
+	if _, err := callseq(ctx, client, 0).Struct(); err != nil {
+		t.Fatal("call0:", err)
+	}
+
+	time.Sleep(2 * timeout)
+	if _, err := callseq(ctx, client, 1).Struct(); err != nil {
+		t.Error("call1 after handshake timeout elapsed:", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+}
+
 func readBootstrap(t *testing.T, ctx context.Context, conn *rpc.Conn, p rpc.Transport) (client capnp.Client, questionID uint32) {
 	clientCh := make(chan capnp.Client, 1)
 	go func() {
@@ -257,6 +335,89 @@ func TestCallOnPromisedAnswer(t *testing.T) {
 	}
 }
 
+func TestCallOnDeepPipeline(t *testing.T) {
+	ctx := context.Background()
+	conn, p := newUnpairedConn(t)
+	defer conn.Close()
+	defer p.Close()
+	client, _ := readBootstrap(t, ctx, conn, p)
+
+	readDone := startRecvMessage(p)
+	ans := client.Call(&capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID: interfaceID,
+			MethodID:    methodID,
+		},
+		ParamsSize: capnp.ObjectSize{},
+	})
+	read := <-readDone
+	if read.err != nil {
+		t.Fatal("Reading first call failed:", read.err)
+	}
+	if read.msg.Which() != rpccapnp.Message_Which_call {
+		t.Fatalf("Conn sent %v message, want Message_Which_call", read.msg.Which())
+	}
+	firstCall, err := read.msg.Call()
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstID := firstCall.QuestionId()
+
+	// Chain three pointer fields off of the answer, then call the result.
+	readDone = startRecvMessage(p)
+	deep := capnp.NewPipeline(ans).GetPipeline(0).GetPipeline(2).GetPipeline(1).Client()
+	deep.Call(&capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID: interfaceID,
+			MethodID:    methodID,
+		},
+		ParamsSize: capnp.ObjectSize{},
+	})
+	read = <-readDone
+	if read.err != nil {
+		t.Fatal("Reading pipelined call failed:", read.err)
+	}
+	if read.msg.Which() != rpccapnp.Message_Which_call {
+		t.Fatalf("Conn sent %v message, want Message_Which_call", read.msg.Which())
+	}
+	call, err := read.msg.Call()
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := call.Target()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Which() != rpccapnp.MessageTarget_Which_promisedAnswer {
+		t.Fatalf("Target is %v, want MessageTarget_Which_promisedAnswer", target.Which())
+	}
+	pa, err := target.PromisedAnswer()
+	if err != nil {
+		t.Fatal("call.target.promisedAnswer error:", err)
+	}
+	if qid := pa.QuestionId(); qid != firstID {
+		t.Errorf("Target question ID = %d; want %d", qid, firstID)
+	}
+	xform, err := pa.Transform()
+	if err != nil {
+		t.Fatal("call.target.promisedAnswer.transform error:", err)
+	}
+	want := []uint16{0, 2, 1}
+	if xform.Len() != len(want) {
+		t.Fatalf("Target transform has %d ops; want %d", xform.Len(), len(want))
+	}
+	for i, field := range want {
+		op := xform.At(i)
+		if op.Which() != rpccapnp.PromisedAnswer_Op_Which_getPointerField {
+			t.Errorf("transform[%d] is %v; want getPointerField", i, op.Which())
+		} else if op.GetPointerField() != field {
+			t.Errorf("transform[%d] = get field %d; want %d", i, op.GetPointerField(), field)
+		}
+	}
+}
+
 func TestCallOnExportId_BootstrapIsPromise(t *testing.T) {
 	testCallOnExportId(t, true)
 }
@@ -326,6 +487,212 @@ func testCallOnExportId(t *testing.T, bootstrapIsPromise bool) {
 	}
 }
 
+func TestUnimplementedCall(t *testing.T) {
+	ctx := context.Background()
+	conn, p := newUnpairedConn(t)
+	defer conn.Close()
+	defer p.Close()
+	client := bootstrapAndFulfill(t, ctx, conn, p, false)
+
+	readDone := startRecvMessage(p)
+	ans := client.Call(&capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID: interfaceID,
+			MethodID:    methodID,
+		},
+		ParamsSize: capnp.ObjectSize{DataSize: 8},
+	})
+	read := <-readDone
+	if read.err != nil {
+		t.Fatal("Reading failed:", read.err)
+	}
+	if read.msg.Which() != rpccapnp.Message_Which_call {
+		t.Fatalf("Conn sent %v message, want Message_Which_call", read.msg.Which())
+	}
+	err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		return msg.SetUnimplemented(read.msg)
+	})
+	if err != nil {
+		t.Fatal("sendMessage:", err)
+	}
+
+	_, err = ans.Struct()
+	if !capnp.IsUnimplemented(err) {
+		t.Errorf("ans.Struct() error = %v; want unimplemented", err)
+	}
+}
+
+func TestReturnForUnknownQuestion(t *testing.T) {
+	ctx := context.Background()
+	conn, p := newUnpairedConn(t)
+	defer conn.Close()
+	defer p.Close()
+
+	err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		ret, err := msg.NewReturn()
+		if err != nil {
+			return err
+		}
+		ret.SetAnswerId(1234)
+		_, err = ret.NewResults()
+		return err
+	})
+	if err != nil {
+		t.Fatal("sendMessage:", err)
+	}
+	recvAbort(t, ctx, p)
+	if err := conn.Wait(); err == nil {
+		t.Error("conn.Wait() = <nil>; want protocol error")
+	}
+}
+
+func TestCallReusesLiveQuestionID(t *testing.T) {
+	const questionID = 999
+	ctx := context.Background()
+	main := stubClient(func(ctx context.Context, params capnp.Struct) (capnp.Struct, error) {
+		_, s, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			return capnp.Struct{}, err
+		}
+		return capnp.NewRootStruct(s, capnp.ObjectSize{})
+	})
+	conn, p := newUnpairedConn(t, rpc.MainInterface(main))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendBootstrapAndFinish(t, p)
+
+	sendCall := func() error {
+		return sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+			call, err := msg.NewCall()
+			if err != nil {
+				return err
+			}
+			call.SetQuestionId(questionID)
+			call.SetInterfaceId(interfaceID)
+			call.SetMethodId(methodID)
+			target, err := call.NewTarget()
+			if err != nil {
+				return err
+			}
+			target.SetImportedCap(importID)
+			payload, err := call.NewParams()
+			if err != nil {
+				return err
+			}
+			content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{})
+			if err != nil {
+				return err
+			}
+			return payload.SetContent(content)
+		})
+	}
+	if err := sendCall(); err != nil {
+		t.Fatal("first call:", err)
+	}
+	retmsg, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("read return:", err)
+	}
+	if retmsg.Which() != rpccapnp.Message_Which_return {
+		t.Fatalf("Conn sent %v message; want return", retmsg.Which())
+	}
+
+	// Reuse the question ID before sending a Finish.
+	if err := sendCall(); err != nil {
+		t.Fatal("second call:", err)
+	}
+	recvAbort(t, ctx, p)
+	if err := conn.Wait(); err == nil {
+		t.Error("conn.Wait() = <nil>; want protocol error")
+	}
+}
+
+func TestMaxCapsPerMessage(t *testing.T) {
+	const maxCaps = 2
+	ctx := context.Background()
+	main := stubClient(func(ctx context.Context, params capnp.Struct) (capnp.Struct, error) {
+		_, s, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			return capnp.Struct{}, err
+		}
+		return capnp.NewRootStruct(s, capnp.ObjectSize{})
+	})
+	conn, p := newUnpairedConn(t, rpc.MainInterface(main), rpc.MaxCapsPerMessage(maxCaps))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendBootstrapAndFinish(t, p)
+
+	sendCall := func(questionID uint32, ncaps int32) error {
+		return sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+			call, err := msg.NewCall()
+			if err != nil {
+				return err
+			}
+			call.SetQuestionId(questionID)
+			call.SetInterfaceId(interfaceID)
+			call.SetMethodId(methodID)
+			target, err := call.NewTarget()
+			if err != nil {
+				return err
+			}
+			target.SetImportedCap(importID)
+			payload, err := call.NewParams()
+			if err != nil {
+				return err
+			}
+			content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{})
+			if err != nil {
+				return err
+			}
+			if err := payload.SetContent(content); err != nil {
+				return err
+			}
+			ctab, err := payload.NewCapTable(ncaps)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < ctab.Len(); i++ {
+				ctab.At(i).SetSenderHosted(uint32(100 + i))
+			}
+			return nil
+		})
+	}
+	if err := sendCall(1, maxCaps); err != nil {
+		t.Fatal("call at limit:", err)
+	}
+	retmsg, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("read return:", err)
+	}
+	if retmsg.Which() != rpccapnp.Message_Which_return {
+		t.Fatalf("Conn sent %v message for call at limit; want return", retmsg.Which())
+	}
+
+	if err := sendCall(2, maxCaps+1); err != nil {
+		t.Fatal("call over limit:", err)
+	}
+	recvAbort(t, ctx, p)
+	if err := conn.Wait(); err == nil {
+		t.Error("conn.Wait() = <nil>; want error")
+	}
+}
+
+// recvAbort reads messages from p until it receives an abort.
+func recvAbort(t *testing.T, ctx context.Context, p rpc.Transport) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	for {
+		msg, err := p.RecvMessage(ctx)
+		if err != nil {
+			t.Fatal("waiting for abort:", err)
+		}
+		if msg.Which() == rpccapnp.Message_Which_abort {
+			return
+		}
+	}
+}
+
 func TestMainInterface(t *testing.T) {
 	main := mockClient()
 	conn, p := newUnpairedConn(t, rpc.MainInterface(main))
@@ -555,6 +922,129 @@ func TestReceiveCallOnExport(t *testing.T) {
 	}
 }
 
+func TestReceiveCallSendResultsToYourself(t *testing.T) {
+	const questionID = 999
+	called := false
+	main := stubClient(func(ctx context.Context, params capnp.Struct) (capnp.Struct, error) {
+		msg, s, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			return capnp.Struct{}, err
+		}
+		result, err := capnp.NewStruct(s, capnp.ObjectSize{})
+		if err != nil {
+			return capnp.Struct{}, err
+		}
+		called = true
+		if err := msg.SetRoot(result); err != nil {
+			return capnp.Struct{}, err
+		}
+		return result, nil
+	})
+	conn, p := newUnpairedConn(t, rpc.MainInterface(main))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendBootstrapAndFinish(t, p)
+
+	err := sendMessage(context.TODO(), p, func(msg rpccapnp.Message) error {
+		call, err := msg.NewCall()
+		if err != nil {
+			return err
+		}
+		call.SetQuestionId(questionID)
+		call.SetInterfaceId(interfaceID)
+		call.SetMethodId(methodID)
+		call.SendResultsTo().SetYourself()
+		target, err := call.NewTarget()
+		if err != nil {
+			return err
+		}
+		target.SetImportedCap(importID)
+		payload, err := call.NewParams()
+		if err != nil {
+			return err
+		}
+		content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{})
+		if err != nil {
+			return err
+		}
+		payload.SetContent(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Call message failed:", err)
+	}
+	retmsg, err := p.RecvMessage(context.TODO())
+	if err != nil {
+		t.Fatal("Read Call return failed:", err)
+	}
+
+	if !called {
+		t.Error("interface not called")
+	}
+	if retmsg.Which() != rpccapnp.Message_Which_return {
+		t.Fatalf("Return message is %v; want %v", retmsg.Which(), rpccapnp.Message_Which_return)
+	}
+	ret, err := retmsg.Return()
+	if err != nil {
+		t.Fatal("return error:", err)
+	}
+	if id := ret.AnswerId(); id != questionID {
+		t.Errorf("Return.answerId = %d; want %d", id, questionID)
+	}
+	if ret.Which() == rpccapnp.Return_Which_exception {
+		exc, _ := ret.Exception()
+		reason, _ := exc.Reason()
+		t.Error("Return.exception:", reason)
+	} else if ret.Which() != rpccapnp.Return_Which_resultsSentElsewhere {
+		t.Errorf("Return.Which() = %v; want %v", ret.Which(), rpccapnp.Return_Which_resultsSentElsewhere)
+	}
+}
+
+func TestReceiveCallSendResultsToThirdParty(t *testing.T) {
+	const questionID = 999
+	conn, p := newUnpairedConn(t, rpc.MainInterface(mockClient()))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendBootstrapAndFinish(t, p)
+
+	err := sendMessage(context.TODO(), p, func(msg rpccapnp.Message) error {
+		call, err := msg.NewCall()
+		if err != nil {
+			return err
+		}
+		call.SetQuestionId(questionID)
+		call.SetInterfaceId(interfaceID)
+		call.SetMethodId(methodID)
+		if err := call.SendResultsTo().SetThirdPartyPtr(capnp.Ptr{}); err != nil {
+			return err
+		}
+		target, err := call.NewTarget()
+		if err != nil {
+			return err
+		}
+		target.SetImportedCap(importID)
+		_, err = call.NewParams()
+		return err
+	})
+	if err != nil {
+		t.Fatal("Call message failed:", err)
+	}
+	msg, err := p.RecvMessage(context.TODO())
+	if err != nil {
+		t.Fatal("Read Call response failed:", err)
+	}
+	if msg.Which() != rpccapnp.Message_Which_unimplemented {
+		t.Fatalf("Response message is %v; want %v", msg.Which(), rpccapnp.Message_Which_unimplemented)
+	}
+	um, err := msg.Unimplemented()
+	if err != nil {
+		t.Fatal("unimplemented error:", err)
+	}
+	if um.Which() != rpccapnp.Message_Which_call {
+		t.Errorf("Unimplemented message is %v; want %v", um.Which(), rpccapnp.Message_Which_call)
+	}
+}
+
 func sendBootstrapAndFinish(t *testing.T, p rpc.Transport) (importID uint32) {
 	importID, questionID := bootstrapRoundtrip(t, p)
 	err := sendMessage(context.TODO(), p, func(msg rpccapnp.Message) error {
@@ -78,35 +78,55 @@ func (ic *importClient) Call(cl *capnp.Call) capnp.Answer {
 // lockedCall is equivalent to Call but assumes that the caller is
 // already holding onto ic.conn.mu.
 func (ic *importClient) lockedCall(cl *capnp.Call) capnp.Answer {
+	q, err := ic.lockedSend(cl, false)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	return q
+}
+
+// lockedSend sends cl to the remote capability as a new question.  If
+// yourself is true, the remote vat is asked to keep the results
+// instead of returning them.  The caller must be holding onto
+// ic.conn.mu.
+func (ic *importClient) lockedSend(cl *capnp.Call, yourself bool) (*question, error) {
 	if ic.closed {
-		return capnp.ErrorAnswer(errImportClosed)
+		return nil, errImportClosed
 	}
 
+	msg, msgCall, md, err := ic.conn.newCallMessage(cl.Ctx, &cl.Method)
+	if err != nil {
+		return nil, err
+	}
 	q := ic.conn.newQuestion(cl.Ctx, &cl.Method)
-	msg := newMessage(nil)
-	msgCall, _ := msg.NewCall()
 	msgCall.SetQuestionId(uint32(q.id))
 	msgCall.SetInterfaceId(cl.Method.InterfaceID)
 	msgCall.SetMethodId(cl.Method.MethodID)
 	target, _ := msgCall.NewTarget()
 	target.SetImportedCap(uint32(ic.id))
+	if yourself {
+		if err := SetSendResultsToYourself(msgCall); err != nil {
+			ic.conn.popQuestion(q.id)
+			return nil, err
+		}
+	}
 	payload, _ := msgCall.NewParams()
 	if err := ic.conn.fillParams(payload, cl); err != nil {
 		ic.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(err)
+		return nil, err
 	}
 
 	select {
-	case ic.conn.out <- msg:
+	case ic.conn.out <- outgoingMessage{msg, md}:
 	case <-cl.Ctx.Done():
 		ic.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(cl.Ctx.Err())
+		return nil, cl.Ctx.Err()
 	case <-ic.conn.bg.Done():
 		ic.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(ErrConnClosed)
+		return nil, ErrConnClosed
 	}
 	q.start()
-	return q
+	return q, nil
 }
 
 func (ic *importClient) Close() error {
@@ -138,7 +158,7 @@ func (ic *importClient) Close() error {
 	mr.SetId(uint32(ic.id))
 	mr.SetReferenceCount(uint32(i))
 	select {
-	case ic.conn.out <- msg:
+	case ic.conn.out <- outgoingMessage{msg: msg}:
 		return nil
 	case <-ic.conn.bg.Done():
 		return ErrConnClosed
@@ -2,7 +2,9 @@ package rpc
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"golang.org/x/net/context"
@@ -25,34 +27,273 @@ type Transport interface {
 	Close() error
 }
 
+// A RecvPauser is a Transport that can temporarily stop reading
+// messages, such as the transport returned by StreamTransport.
+type RecvPauser interface {
+	// PauseRecv causes subsequent calls to RecvMessage to block
+	// without reading from the underlying stream until ResumeRecv is
+	// called, the Context is done, or the transport is closed.  This
+	// lets the peer's flow control take effect when the receiver is
+	// overloaded.  A read that is already in progress is not affected.
+	PauseRecv()
+
+	// ResumeRecv undoes the effect of PauseRecv.
+	ResumeRecv()
+}
+
+// A BatchSender is a Transport that can send several messages with a
+// single write to the underlying stream, such as the transport returned
+// by StreamTransport.  A Conn uses SendMessages to flush all of its
+// queued outgoing messages at once, which coalesces bursts like a
+// Finish followed by several Releases.
+type BatchSender interface {
+	// SendMessages sends msgs in order.  Each message is still framed
+	// separately, as if sent by SendMessage.  A message that can't be
+	// encoded is skipped, the rest are still sent, and the returned
+	// error describes the skipped message.
+	SendMessages(ctx context.Context, msgs []rpccapnp.Message) error
+}
+
+// A PooledReceiver is a Transport that can decode received messages
+// into pooled buffers, such as the transport returned by
+// StreamTransport.
+type PooledReceiver interface {
+	// RecvMessagePooled is like RecvMessage, but decodes the message
+	// into a buffer taken from a process-wide pool bucketed by size
+	// class.  The message stays valid until release is called, which
+	// returns the buffer to the pool.  This bounds the memory used by
+	// a receiver that handles one message at a time without copying
+	// messages that it needs to keep for a while.
+	RecvMessagePooled(ctx context.Context) (msg rpccapnp.Message, release func(), err error)
+}
+
 type streamTransport struct {
-	rwc      io.ReadWriteCloser
-	deadline writeDeadlineSetter
+	rwc       io.ReadWriteCloser
+	deadline  writeDeadlineSetter
+	rdeadline readDeadlineSetter
 
 	enc  *capnp.Encoder
 	dec  *capnp.Decoder
 	wbuf bytes.Buffer
+	pool bool // encode into pooled buffers instead of wbuf
+
+	codec       frameCodec // nil means Cap'n Proto stream framing
+	metadata    bool       // see CallMetadata
+	packed      bool
+	recvBudget  uint64        // zero means no limit
+	maxUnpacked uint64        // zero means the decoder's default
+	bodyTimeout time.Duration // zero means no limit
+
+	mu        sync.Mutex
+	resumed   chan struct{} // nil if not paused; closed on resume
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// A frameCodec replaces the framing of a streamTransport, so that
+// transports with a different wire format share its deadlines, pausing,
+// and batching.
+type frameCodec interface {
+	// encode appends a single framed message to buf.
+	encode(buf *bytes.Buffer, msg *capnp.Message) error
+
+	// decode reads a single framed message from the stream.
+	decode() (*capnp.Message, error)
 }
 
 // StreamTransport creates a transport that sends and receives messages
-// by serializing and deserializing unpacked Cap'n Proto messages.
+// by serializing and deserializing unpacked Cap'n Proto messages, or
+// packed messages with the PackedStream option.
 // Closing the transport will close the underlying ReadWriteCloser.
-func StreamTransport(rwc io.ReadWriteCloser) Transport {
+// The returned Transport implements RecvPauser, BatchSender,
+// PooledReceiver, and MetadataTransport.
+//
+// If rwc has SetWriteDeadline or SetReadDeadline methods (like a
+// net.Conn or *tls.Conn), then the transport will set the deadlines
+// from the Context passed to SendMessage and RecvMessage respectively.
+func StreamTransport(rwc io.ReadWriteCloser, options ...StreamTransportOption) Transport {
+	s := newStreamTransport(rwc)
+	for _, o := range options {
+		o.f(s)
+	}
+	if s.packed {
+		s.dec = capnp.NewPackedDecoder(rwc)
+	} else {
+		s.dec = capnp.NewDecoder(rwc)
+	}
+	s.dec.SetMaxTotalBytes(s.recvBudget)
+	s.dec.MaxMessageSize = s.maxUnpacked
+	if !s.pool {
+		s.wbuf.Grow(4096)
+		s.enc = s.newEncoder(&s.wbuf)
+	}
+	return s
+}
+
+// newStreamTransport returns a streamTransport for rwc with only its
+// deadlines set up.
+func newStreamTransport(rwc io.ReadWriteCloser) *streamTransport {
 	d, _ := rwc.(writeDeadlineSetter)
-	s := &streamTransport{
-		rwc:      rwc,
-		deadline: d,
-		dec:      capnp.NewDecoder(rwc),
+	rd, _ := rwc.(readDeadlineSetter)
+	return &streamTransport{
+		rwc:       rwc,
+		deadline:  d,
+		rdeadline: rd,
+		closed:    make(chan struct{}),
 	}
-	s.wbuf.Grow(4096)
-	s.enc = capnp.NewEncoder(&s.wbuf)
+}
+
+// newFramedTransport returns a streamTransport for rwc that sends and
+// receives messages with codec instead of Cap'n Proto stream framing.
+func newFramedTransport(rwc io.ReadWriteCloser, codec frameCodec) *streamTransport {
+	s := newStreamTransport(rwc)
+	s.codec = codec
 	return s
 }
 
+func (s *streamTransport) newEncoder(w io.Writer) *capnp.Encoder {
+	if s.packed {
+		return capnp.NewPackedEncoder(w)
+	}
+	return capnp.NewEncoder(w)
+}
+
+// A StreamTransportOption is an option for creating a StreamTransport.
+type StreamTransportOption struct {
+	f func(*streamTransport)
+}
+
+// PooledSendBuffers encodes each outgoing message into a buffer taken
+// from a process-wide pool bucketed by size class, instead of a single
+// buffer owned by the transport.  By default, the transport's buffer
+// grows to fit the largest message it has sent and keeps that memory
+// for the transport's lifetime; with pooled buffers, a transport that
+// occasionally sends a large message does not hold onto a large buffer.
+func PooledSendBuffers() StreamTransportOption {
+	return StreamTransportOption{func(s *streamTransport) {
+		s.pool = true
+	}}
+}
+
+// RecvBudget limits the memory that receiving a single message may
+// allocate to n bytes, counting the message's segments and framing.
+// A message that would exceed the budget is rejected with an error
+// before any memory is allocated for it.  Buffers used by
+// RecvMessagePooled are rounded up to their size class, so the memory
+// they hold may be up to twice the budget.
+func RecvBudget(n uint64) StreamTransportOption {
+	return StreamTransportOption{func(s *streamTransport) {
+		s.recvBudget = n
+	}}
+}
+
+// PackedStream makes the transport send and receive packed messages,
+// as written by capnp.NewPackedEncoder, instead of unpacked ones.
+// Packing shrinks messages with many zero bytes, which is useful on
+// slow links.  Both ends of the stream must use packing.
+func PackedStream() StreamTransportOption {
+	return StreamTransportOption{func(s *streamTransport) {
+		s.packed = true
+	}}
+}
+
+// MaxUnpackedSize limits the size of a received message's segments
+// after unpacking to n bytes.  A packed stream can describe a huge
+// message in a few bytes with long runs of zeroes, so the size of the
+// input says little about the memory needed to hold the message.  The
+// transport rejects a message that would exceed the limit as soon as
+// its header has been unpacked, before allocating memory for its
+// segments.  On an unpacked stream, the limit applies to the message
+// as sent.  Zero means the default limit of capnp.Decoder.
+func MaxUnpackedSize(n uint64) StreamTransportOption {
+	return StreamTransportOption{func(s *streamTransport) {
+		s.maxUnpacked = n
+	}}
+}
+
+// CallMetadata makes the transport send each message after an
+// envelope that holds the metadata passed to SendMessageMetadata, so
+// that a Conn can carry the metadata from CallHooks.  The envelope is
+// a separate Cap'n Proto message whose root is a list of alternating
+// keys and values, which is empty for messages without metadata.  Both
+// ends of the stream must use CallMetadata.  Without it, the transport
+// drops the metadata passed to SendMessageMetadata.
+func CallMetadata() StreamTransportOption {
+	return StreamTransportOption{func(s *streamTransport) {
+		s.metadata = true
+	}}
+}
+
+// BodyReadTimeout limits how long the transport waits for the rest of
+// a message after its header has been read.  If the body does not
+// arrive within d, RecvMessage returns ErrBodyReadTimeout, which shuts
+// down a Conn using the transport.  This keeps a peer that sends a
+// header and then stalls from tying up the receiver indefinitely.  The
+// wait for the header itself is still only bounded by the Context, so
+// idle connections are not affected.
+func BodyReadTimeout(d time.Duration) StreamTransportOption {
+	return StreamTransportOption{func(s *streamTransport) {
+		s.bodyTimeout = d
+	}}
+}
+
 func (s *streamTransport) SendMessage(ctx context.Context, msg rpccapnp.Message) error {
-	s.wbuf.Reset()
-	if err := s.enc.Encode(msg.Segment().Message()); err != nil {
-		return err
+	return s.SendMessages(ctx, []rpccapnp.Message{msg})
+}
+
+func (s *streamTransport) SendMessageMetadata(ctx context.Context, msg rpccapnp.Message, md Metadata) error {
+	return s.send(ctx, []rpccapnp.Message{msg}, md)
+}
+
+func (s *streamTransport) SendMessages(ctx context.Context, msgs []rpccapnp.Message) error {
+	return s.send(ctx, msgs, nil)
+}
+
+// send writes msgs to the stream, sending md in the envelope of each
+// message if the transport uses CallMetadata.
+func (s *streamTransport) send(ctx context.Context, msgs []rpccapnp.Message, md Metadata) error {
+	var env *capnp.Message
+	if s.metadata {
+		var err error
+		if env, err = newMetadataMessage(md); err != nil {
+			return err
+		}
+	}
+	var buf *bytes.Buffer
+	var enc *capnp.Encoder
+	if s.pool {
+		n := 0
+		for _, msg := range msgs {
+			n += encodedSize(msg.Segment().Message())
+		}
+		buf = getBuffer(n)
+		defer putBuffer(buf)
+		if s.codec == nil {
+			enc = s.newEncoder(buf)
+		}
+	} else {
+		s.wbuf.Reset()
+		buf, enc = &s.wbuf, s.enc
+	}
+	var encErr *encodeError
+	for i, msg := range msgs {
+		// Encode each message on its own, so that one that fails does
+		// not keep the rest of the batch from being sent.
+		n := buf.Len()
+		var err error
+		if env != nil {
+			err = s.encode(buf, enc, env)
+		}
+		if err == nil {
+			err = s.encode(buf, enc, msg.Segment().Message())
+		}
+		if err != nil {
+			buf.Truncate(n)
+			if encErr == nil {
+				encErr = new(encodeError)
+			}
+			encErr.add(i, msg, err)
+		}
 	}
 	if s.deadline != nil {
 		// TODO(light): log errors
@@ -62,24 +303,194 @@ func (s *streamTransport) SendMessage(ctx context.Context, msg rpccapnp.Message)
 			s.deadline.SetWriteDeadline(time.Time{})
 		}
 	}
-	_, err := s.rwc.Write(s.wbuf.Bytes())
-	return err
+	if buf.Len() > 0 {
+		if _, err := s.rwc.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if encErr != nil {
+		return encErr
+	}
+	return nil
+}
+
+// encode appends a single framed message to buf, using enc if the
+// transport has no codec.
+func (s *streamTransport) encode(buf *bytes.Buffer, enc *capnp.Encoder, msg *capnp.Message) error {
+	if s.codec != nil {
+		return s.codec.encode(buf, msg)
+	}
+	return enc.Encode(msg)
+}
+
+// encodeError is returned by SendMessages when some messages in a
+// batch could not be encoded.  The other messages were still sent.
+type encodeError struct {
+	index []int // indices of the failed messages
+	errs  []error
+}
+
+func (e *encodeError) add(i int, msg rpccapnp.Message, err error) {
+	e.index = append(e.index, i)
+	e.errs = append(e.errs, fmt.Errorf("rpc: encode %v: %v", msg.Which(), err))
+}
+
+func (e *encodeError) Error() string {
+	if len(e.errs) == 1 {
+		return e.errs[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more)", e.errs[0], len(e.errs)-1)
+}
+
+// failed reports whether the i'th message of the batch was not sent.
+func (e *encodeError) failed(i int) bool {
+	for _, j := range e.index {
+		if i == j {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *streamTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, error) {
+	m, _, err := s.RecvMessageMetadata(ctx)
+	return m, err
+}
+
+func (s *streamTransport) RecvMessageMetadata(ctx context.Context) (rpccapnp.Message, Metadata, error) {
+	var md Metadata
+	m, err := s.recv(ctx, func() (*capnp.Message, error) {
+		var err error
+		if md, err = s.decodeMetadata(); err != nil {
+			return nil, err
+		}
+		return s.decode()
+	})
+	if err != nil {
+		return rpccapnp.Message{}, nil, err
+	}
+	return m, md, nil
+}
+
+// decode reads a single framed message from the stream.
+func (s *streamTransport) decode() (*capnp.Message, error) {
+	if s.codec != nil {
+		return s.codec.decode()
+	}
+	return s.dec.Decode()
+}
+
+// decodeMetadata reads the envelope that precedes a message if the
+// transport uses CallMetadata.
+func (s *streamTransport) decodeMetadata() (Metadata, error) {
+	if !s.metadata {
+		return nil, nil
+	}
+	env, err := s.decode()
+	if err != nil {
+		return nil, err
+	}
+	return readMetadata(env)
+}
+
+func (s *streamTransport) RecvMessagePooled(ctx context.Context) (rpccapnp.Message, func(), error) {
+	if s.codec != nil {
+		// Codecs allocate their own buffers.
+		m, err := s.RecvMessage(ctx)
+		if err != nil {
+			return rpccapnp.Message{}, nil, err
+		}
+		return m, func() {}, nil
+	}
+	var buf *bytes.Buffer
+	m, err := s.recv(ctx, func() (*capnp.Message, error) {
+		if _, err := s.decodeMetadata(); err != nil {
+			return nil, err
+		}
+		_, words, err := s.dec.PeekHeader()
+		if err != nil {
+			return nil, err
+		}
+		n := int(words * 8)
+		buf = getBuffer(n)
+		return s.dec.DecodeInto(buf.Bytes()[:0:buf.Cap()])
+	})
+	if err != nil {
+		// If ctx is done, the read may still be using buf, so it
+		// can't go back to the pool.
+		return rpccapnp.Message{}, nil, err
+	}
+	var once sync.Once
+	return m, func() { once.Do(func() { putBuffer(buf) }) }, nil
+}
+
+// recv waits until receiving is resumed and then calls decode in a new
+// goroutine, returning early if ctx is done.
+func (s *streamTransport) recv(ctx context.Context, decode func() (*capnp.Message, error)) (rpccapnp.Message, error) {
+	s.mu.Lock()
+	resumed := s.resumed
+	s.mu.Unlock()
+	if resumed != nil {
+		select {
+		case <-resumed:
+		case <-s.closed:
+			return rpccapnp.Message{}, ErrConnClosed
+		case <-ctx.Done():
+			return rpccapnp.Message{}, ctx.Err()
+		}
+	}
+
 	var (
 		msg *capnp.Message
 		err error
 	)
+	if s.rdeadline != nil {
+		// TODO(light): log errors
+		if d, ok := ctx.Deadline(); ok {
+			s.rdeadline.SetReadDeadline(d)
+		} else {
+			s.rdeadline.SetReadDeadline(time.Time{})
+		}
+	}
 	read := make(chan struct{})
-	go func() {
-		msg, err = s.dec.Decode()
+	var header chan struct{} // closed once the header is read
+	if s.bodyTimeout > 0 {
+		header = make(chan struct{})
+	}
+	go func(header chan<- struct{}) {
+		if header != nil {
+			if _, _, err = s.dec.PeekHeader(); err != nil {
+				close(read)
+				return
+			}
+			if s.rdeadline != nil {
+				// Unblock the body read when the timeout fires.
+				d := time.Now().Add(s.bodyTimeout)
+				if cd, ok := ctx.Deadline(); ok && cd.Before(d) {
+					d = cd
+				}
+				s.rdeadline.SetReadDeadline(d)
+			}
+			close(header)
+		}
+		msg, err = decode()
 		close(read)
-	}()
-	select {
-	case <-read:
-	case <-ctx.Done():
-		return rpccapnp.Message{}, ctx.Err()
+	}(header)
+	var timeout <-chan time.Time
+wait:
+	for {
+		select {
+		case <-read:
+			break wait
+		case <-header:
+			t := time.NewTimer(s.bodyTimeout)
+			defer t.Stop()
+			header, timeout = nil, t.C
+		case <-timeout:
+			return rpccapnp.Message{}, ErrBodyReadTimeout
+		case <-ctx.Done():
+			return rpccapnp.Message{}, ctx.Err()
+		}
 	}
 	if err != nil {
 		return rpccapnp.Message{}, err
@@ -87,23 +498,283 @@ func (s *streamTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, er
 	return rpccapnp.ReadRootMessage(msg)
 }
 
+func (s *streamTransport) PauseRecv() {
+	s.mu.Lock()
+	if s.resumed == nil {
+		s.resumed = make(chan struct{})
+	}
+	s.mu.Unlock()
+}
+
+func (s *streamTransport) ResumeRecv() {
+	s.mu.Lock()
+	if s.resumed != nil {
+		close(s.resumed)
+		s.resumed = nil
+	}
+	s.mu.Unlock()
+}
+
 func (s *streamTransport) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
 	return s.rwc.Close()
 }
 
+// Pooled buffer size classes are powers of two between
+// 1 << minBufferShift and 1 << maxBufferShift bytes.
+const (
+	minBufferShift = 10 // 1 KiB
+	maxBufferShift = 20 // 1 MiB
+)
+
+var bufferPools [maxBufferShift - minBufferShift + 1]sync.Pool
+
+// bufferClass returns the index into bufferPools of the
+// smallest size class that holds n bytes or -1 if n is too large to pool.
+func bufferClass(n int) int {
+	for i := range bufferPools {
+		if n <= 1<<uint(minBufferShift+i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// getBuffer returns an empty buffer with a capacity of at least n bytes.
+func getBuffer(n int) *bytes.Buffer {
+	i := bufferClass(n)
+	if i == -1 {
+		return bytes.NewBuffer(make([]byte, 0, n))
+	}
+	if buf, _ := bufferPools[i].Get().(*bytes.Buffer); buf != nil {
+		return buf
+	}
+	return bytes.NewBuffer(make([]byte, 0, 1<<uint(minBufferShift+i)))
+}
+
+// putBuffer returns a buffer obtained from getBuffer to its pool.
+func putBuffer(buf *bytes.Buffer) {
+	c := buf.Cap()
+	i := bufferClass(c)
+	if i == -1 || 1<<uint(minBufferShift+i) != c {
+		// Not a pooled size.
+		return
+	}
+	buf.Reset()
+	bufferPools[i].Put(buf)
+}
+
+// encodedSize returns the number of bytes in the stream encoding of m.
+func encodedSize(m *capnp.Message) int {
+	n := int(m.NumSegments())
+	size := (4*(n+1) + 7) &^ 7 // segment table
+	for i := 0; i < n; i++ {
+		s, err := m.Segment(capnp.SegmentID(i))
+		if err != nil {
+			continue
+		}
+		size += len(s.Data())
+	}
+	return size
+}
+
 type writeDeadlineSetter interface {
 	SetWriteDeadline(t time.Time) error
 }
 
+type readDeadlineSetter interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// ReceiverFilter returns a Transport that passes each message received
+// from t to filter before returning it from RecvMessage.  If filter
+// returns an error, RecvMessage returns that error instead of the
+// message, which shuts down a Conn using the transport.
+//
+// The returned Transport implements RecvPauser, BatchSender,
+// PooledReceiver, and MetadataTransport, forwarding to t where t
+// implements them.  Otherwise, pausing has no effect, SendMessages
+// sends the messages one at a time, RecvMessagePooled receives with
+// RecvMessage, and metadata is dropped.  Messages received
+// with RecvMessagePooled are filtered too, and a rejected message's
+// buffer is released.
+func ReceiverFilter(t Transport, filter func(rpccapnp.Message) error) Transport {
+	return filterTransport{t, filter}
+}
+
+type filterTransport struct {
+	Transport
+	filter func(rpccapnp.Message) error
+}
+
+func (t filterTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, error) {
+	m, err := t.Transport.RecvMessage(ctx)
+	if err != nil {
+		return rpccapnp.Message{}, err
+	}
+	if err := t.filter(m); err != nil {
+		return rpccapnp.Message{}, err
+	}
+	return m, nil
+}
+
+func (t filterTransport) RecvMessagePooled(ctx context.Context) (rpccapnp.Message, func(), error) {
+	pr, ok := t.Transport.(PooledReceiver)
+	if !ok {
+		m, err := t.RecvMessage(ctx)
+		if err != nil {
+			return rpccapnp.Message{}, nil, err
+		}
+		return m, func() {}, nil
+	}
+	m, release, err := pr.RecvMessagePooled(ctx)
+	if err != nil {
+		return rpccapnp.Message{}, nil, err
+	}
+	if err := t.filter(m); err != nil {
+		release()
+		return rpccapnp.Message{}, nil, err
+	}
+	return m, release, nil
+}
+
+func (t filterTransport) RecvMessageMetadata(ctx context.Context) (rpccapnp.Message, Metadata, error) {
+	mt, ok := t.Transport.(MetadataTransport)
+	if !ok {
+		m, err := t.RecvMessage(ctx)
+		return m, nil, err
+	}
+	m, md, err := mt.RecvMessageMetadata(ctx)
+	if err != nil {
+		return rpccapnp.Message{}, nil, err
+	}
+	if err := t.filter(m); err != nil {
+		return rpccapnp.Message{}, nil, err
+	}
+	return m, md, nil
+}
+
+func (t filterTransport) SendMessageMetadata(ctx context.Context, msg rpccapnp.Message, md Metadata) error {
+	if mt, ok := t.Transport.(MetadataTransport); ok {
+		return mt.SendMessageMetadata(ctx, msg, md)
+	}
+	return t.Transport.SendMessage(ctx, msg)
+}
+
+func (t filterTransport) SendMessages(ctx context.Context, msgs []rpccapnp.Message) error {
+	if bs, ok := t.Transport.(BatchSender); ok {
+		return bs.SendMessages(ctx, msgs)
+	}
+	var firstErr error
+	for _, m := range msgs {
+		if err := t.Transport.SendMessage(ctx, m); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t filterTransport) PauseRecv() {
+	if rp, ok := t.Transport.(RecvPauser); ok {
+		rp.PauseRecv()
+	}
+}
+
+func (t filterTransport) ResumeRecv() {
+	if rp, ok := t.Transport.(RecvPauser); ok {
+		rp.ResumeRecv()
+	}
+}
+
+// RejectCaps is a filter for ReceiverFilter that returns ErrCapsRejected
+// for any message that carries capabilities: a call or return whose
+// payload has a non-empty capability table, or any message containing
+// an interface pointer.  Use it to restrict a connection to plain data.
+// Looking for interface pointers does not count against the message's
+// read limit.
+func RejectCaps(m rpccapnp.Message) error {
+	var payload rpccapnp.Payload
+	switch m.Which() {
+	case rpccapnp.Message_Which_call:
+		call, err := m.Call()
+		if err != nil {
+			return err
+		}
+		if payload, err = call.Params(); err != nil {
+			return err
+		}
+	case rpccapnp.Message_Which_return:
+		ret, err := m.Return()
+		if err != nil {
+			return err
+		}
+		if ret.Which() == rpccapnp.Return_Which_results {
+			if payload, err = ret.Results(); err != nil {
+				return err
+			}
+		}
+	}
+	if payload.HasCapTable() {
+		ctab, err := payload.CapTable()
+		if err != nil {
+			return err
+		}
+		if ctab.Len() > 0 {
+			return ErrCapsRejected
+		}
+	}
+	caps, err := m.Segment().Message().Capabilities()
+	if err != nil {
+		return err
+	}
+	if len(caps) > 0 {
+		return ErrCapsRejected
+	}
+	return nil
+}
+
+// An outgoingMessage is a message queued to be sent, along with the
+// call metadata to send with it, if any.
+type outgoingMessage struct {
+	msg rpccapnp.Message
+	md  Metadata
+}
+
 // dispatchSend runs in its own goroutine and sends messages on a transport.
 func (c *Conn) dispatchSend() {
 	defer c.workers.Done()
+	bs, _ := c.transport.(BatchSender)
+	mt, _ := c.transport.(MetadataTransport)
+	var batch []rpccapnp.Message
 	for {
 		select {
-		case msg := <-c.out:
-			err := c.transport.SendMessage(c.bg, msg)
-			if err != nil {
-				c.errorf("writing %v: %v", msg.Which(), err)
+		case out := <-c.out:
+			if bs == nil || (mt != nil && out.md != nil) {
+				c.send(mt, out)
+				continue
+			}
+			// Send everything that is already queued in one write.
+			batch = append(batch[:0], out.msg)
+		drain:
+			for len(batch) < cap(c.out)+1 {
+				select {
+				case out := <-c.out:
+					if mt != nil && out.md != nil {
+						// Messages with metadata are sent on their own,
+						// after the ones queued before them.
+						c.sendBatch(bs, batch)
+						batch = batch[:0]
+						c.send(mt, out)
+						continue
+					}
+					batch = append(batch, out.msg)
+				default:
+					break drain
+				}
+			}
+			c.sendBatch(bs, batch)
+			for i := range batch {
+				batch[i] = rpccapnp.Message{}
 			}
 		case <-c.bg.Done():
 			return
@@ -111,12 +782,51 @@ func (c *Conn) dispatchSend() {
 	}
 }
 
+// send sends a single message, with its metadata if mt is not nil.
+func (c *Conn) send(mt MetadataTransport, out outgoingMessage) {
+	var err error
+	if mt != nil && out.md != nil {
+		err = mt.SendMessageMetadata(c.bg, out.msg, out.md)
+	} else {
+		err = c.transport.SendMessage(c.bg, out.msg)
+	}
+	if err != nil {
+		c.errorf("writing %v: %v", out.msg.Which(), err)
+	} else {
+		c.counts.add(out.msg.Which())
+	}
+}
+
+// sendBatch sends msgs with a single call to bs.SendMessages.
+func (c *Conn) sendBatch(bs BatchSender, msgs []rpccapnp.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	err := bs.SendMessages(c.bg, msgs)
+	encErr, _ := err.(*encodeError)
+	if err != nil && encErr == nil {
+		c.errorf("writing %d messages: %v", len(msgs), err)
+		return
+	}
+	for i, msg := range msgs {
+		if encErr != nil && encErr.failed(i) {
+			continue
+		}
+		c.counts.add(msg.Which())
+	}
+	if encErr != nil {
+		for _, err := range encErr.errs {
+			c.errorf("writing message: %v", err)
+		}
+	}
+}
+
 // sendMessage enqueues a message to be sent or returns an error if the
 // connection is shut down before the message is queued.  It is safe to
 // call from multiple goroutines and does not require holding c.mu.
 func (c *Conn) sendMessage(msg rpccapnp.Message) error {
 	select {
-	case c.out <- msg:
+	case c.out <- outgoingMessage{msg: msg}:
 		return nil
 	case <-c.bg.Done():
 		return ErrConnClosed
@@ -126,10 +836,21 @@ func (c *Conn) sendMessage(msg rpccapnp.Message) error {
 // dispatchRecv runs in its own goroutine and receives messages from a transport.
 func (c *Conn) dispatchRecv() {
 	defer c.workers.Done()
+	mt, _ := c.transport.(MetadataTransport)
 	for {
-		msg, err := c.transport.RecvMessage(c.bg)
+		var (
+			msg rpccapnp.Message
+			md  Metadata
+			err error
+		)
+		if mt != nil {
+			msg, md, err = mt.RecvMessageMetadata(c.bg)
+		} else {
+			msg, err = c.transport.RecvMessage(c.bg)
+		}
 		if err == nil {
-			c.handleMessage(msg)
+			c.counts.add(msg.Which())
+			c.handleMessage(msg, md)
 		} else if isTemporaryError(err) {
 			c.errorf("read temporary error: %v", err)
 		} else {
@@ -0,0 +1,878 @@
+package rpc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestStreamTransport_RecvDeadline(t *testing.T) {
+	conn := newDeadlineConn()
+	defer conn.Close()
+	tr := rpc.StreamTransport(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	want, _ := ctx.Deadline()
+	if _, err := tr.RecvMessage(ctx); err == nil {
+		t.Fatal("RecvMessage succeeded; want error")
+	}
+	if got := conn.readDeadline(); !got.Equal(want) {
+		t.Errorf("read deadline = %v; want %v", got, want)
+	}
+	select {
+	case <-conn.readReturned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read on underlying conn did not return after deadline")
+	}
+}
+
+func TestStreamTransport_RecvNoDeadline(t *testing.T) {
+	conn := newDeadlineConn()
+	conn.SetReadDeadline(time.Now())
+	defer conn.Close()
+	tr := rpc.StreamTransport(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tr.RecvMessage(ctx); err != context.Canceled {
+		t.Errorf("RecvMessage error = %v; want %v", err, context.Canceled)
+	}
+	if d := conn.readDeadline(); !d.IsZero() {
+		t.Errorf("read deadline = %v; want zero", d)
+	}
+}
+
+func TestStreamTransport_PauseRecv(t *testing.T) {
+	c1, c2 := net.Pipe()
+	sender := rpc.StreamTransport(c1)
+	defer sender.Close()
+	receiver := rpc.StreamTransport(c2)
+	defer receiver.Close()
+
+	receiver.(rpc.RecvPauser).PauseRecv()
+	recvDone := startRecvMessage(receiver)
+	sendDone := make(chan error, 1)
+	go func() {
+		sendDone <- sendMessage(context.Background(), sender, func(msg rpccapnp.Message) error {
+			ab, err := msg.NewAbort()
+			if err != nil {
+				return err
+			}
+			return ab.SetReason("hello")
+		})
+	}()
+
+	// net.Pipe is unbuffered, so the send can't finish until the receiver reads.
+	select {
+	case r := <-recvDone:
+		t.Fatalf("RecvMessage returned while paused: %v, %v", r.msg, r.err)
+	case err := <-sendDone:
+		t.Fatalf("SendMessage returned while receiver paused: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	receiver.(rpc.RecvPauser).ResumeRecv()
+	select {
+	case r := <-recvDone:
+		if r.err != nil {
+			t.Fatal("RecvMessage:", r.err)
+		}
+		if r.msg.Which() != rpccapnp.Message_Which_abort {
+			t.Errorf("received %v message; want abort", r.msg.Which())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RecvMessage did not return after ResumeRecv")
+	}
+	if err := <-sendDone; err != nil {
+		t.Error("SendMessage:", err)
+	}
+}
+
+func TestStreamTransport_ClosePaused(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	receiver := rpc.StreamTransport(c2)
+
+	receiver.(rpc.RecvPauser).PauseRecv()
+	recvDone := startRecvMessage(receiver)
+	if err := receiver.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	select {
+	case r := <-recvDone:
+		if r.err == nil {
+			t.Error("RecvMessage on closed transport succeeded; want error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RecvMessage did not return after Close")
+	}
+}
+
+func TestStreamTransport_PooledSendBuffers(t *testing.T) {
+	c1, c2 := net.Pipe()
+	sender := rpc.StreamTransport(c1, rpc.PooledSendBuffers())
+	defer sender.Close()
+	receiver := rpc.StreamTransport(c2)
+	defer receiver.Close()
+
+	for _, n := range []int{10, 100000, 10, 3 << 20} {
+		reason := strings.Repeat("x", n)
+		recvDone := startRecvMessage(receiver)
+		err := sendMessage(context.Background(), sender, func(msg rpccapnp.Message) error {
+			ab, err := msg.NewAbort()
+			if err != nil {
+				return err
+			}
+			return ab.SetReason(reason)
+		})
+		if err != nil {
+			t.Fatalf("SendMessage with %d-byte reason: %v", n, err)
+		}
+		r := <-recvDone
+		if r.err != nil {
+			t.Fatalf("RecvMessage with %d-byte reason: %v", n, r.err)
+		}
+		ab, err := r.msg.Abort()
+		if err != nil {
+			t.Fatalf("received message with %d-byte reason: %v", n, err)
+		}
+		if got, _ := ab.Reason(); got != reason {
+			t.Errorf("received %d-byte reason; want %d bytes", len(got), n)
+		}
+	}
+}
+
+func TestStreamTransport_RecvMessagePooled(t *testing.T) {
+	const (
+		reasonSize = 60000
+		nmsgs      = 50
+	)
+	ctx := context.Background()
+	reason := strings.Repeat("x", reasonSize)
+	conn := new(writeCountConn)
+	sender := rpc.StreamTransport(conn)
+	for i := 0; i < nmsgs; i++ {
+		err := sendMessage(ctx, sender, func(msg rpccapnp.Message) error {
+			ab, err := msg.NewAbort()
+			if err != nil {
+				return err
+			}
+			return ab.SetReason(reason)
+		})
+		if err != nil {
+			t.Fatal("SendMessage:", err)
+		}
+	}
+	receiver := rpc.StreamTransport(readOnlyConn{&conn.buf}).(rpc.PooledReceiver)
+	recv := func() {
+		msg, release, err := receiver.RecvMessagePooled(ctx)
+		if err != nil {
+			t.Fatal("RecvMessagePooled:", err)
+		}
+		ab, err := msg.Abort()
+		if err != nil {
+			t.Fatal("received message:", err)
+		}
+		if got, _ := ab.ReasonBytes(); string(got) != reason {
+			t.Errorf("received %d-byte reason; want %d bytes", len(got), reasonSize)
+		}
+		release()
+		release() // no-op
+	}
+	recv()
+
+	// Each later receive should reuse the buffer released by the one
+	// before it.  The pool may drop buffers, so only check that most
+	// receives don't allocate a buffer.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 1; i < nmsgs; i++ {
+		recv()
+	}
+	runtime.ReadMemStats(&after)
+	if n := (after.TotalAlloc - before.TotalAlloc) / (nmsgs - 1); n >= reasonSize/2 {
+		t.Errorf("RecvMessagePooled allocated %d bytes per message; want < %d", n, reasonSize/2)
+	}
+}
+
+func TestStreamTransport_RecvBudget(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	sender := rpc.StreamTransport(conn)
+	for _, n := range []int{10, 2000} {
+		err := sendMessage(ctx, sender, func(msg rpccapnp.Message) error {
+			ab, err := msg.NewAbort()
+			if err != nil {
+				return err
+			}
+			return ab.SetReason(strings.Repeat("x", n))
+		})
+		if err != nil {
+			t.Fatal("SendMessage:", err)
+		}
+	}
+	receiver := rpc.StreamTransport(readOnlyConn{&conn.buf}, rpc.RecvBudget(1024))
+	if _, err := receiver.RecvMessage(ctx); err != nil {
+		t.Error("RecvMessage of small message:", err)
+	}
+	if _, err := receiver.RecvMessage(ctx); err == nil {
+		t.Error("RecvMessage of message over budget succeeded")
+	}
+}
+
+func TestStreamTransport_PackedStream(t *testing.T) {
+	ctx := context.Background()
+	conn := new(writeCountConn)
+	sender := rpc.StreamTransport(conn, rpc.PackedStream())
+	if err := sender.SendMessage(ctx, newFinishMessage(t, 42)); err != nil {
+		t.Fatal("SendMessage:", err)
+	}
+	unpacked, err := newFinishMessage(t, 42).Segment().Message().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.buf.Len() >= len(unpacked) {
+		t.Errorf("packed message is %d bytes; want less than unpacked %d bytes", conn.buf.Len(), len(unpacked))
+	}
+	receiver := rpc.StreamTransport(readOnlyConn{&conn.buf}, rpc.PackedStream())
+	msg, err := receiver.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("RecvMessage:", err)
+	}
+	checkFinishMessage(t, msg.Segment().Message(), 42)
+}
+
+func TestStreamTransport_MaxUnpackedSize(t *testing.T) {
+	// A packed frame of about 8 KiB whose header claims a single 8 MiB
+	// segment, all of it zero runs.
+	const words = 1 << 20
+	var frame bytes.Buffer
+	frame.Write([]byte{0x40, 0x10}) // header: 1 segment, 0x100000 words
+	for n := 0; n < words; n += 256 {
+		frame.Write([]byte{0x00, 0xff}) // 256 zero words
+	}
+	data := frame.Bytes()
+
+	ctx := context.Background()
+	tr := rpc.StreamTransport(readOnlyConn{bytes.NewBuffer(data)}, rpc.PackedStream(), rpc.MaxUnpackedSize(1<<20))
+	if _, err := tr.RecvMessage(ctx); err == nil {
+		t.Error("RecvMessage of packed frame over MaxUnpackedSize succeeded")
+	}
+
+	// The frame itself is well-formed: without the limit, it decodes
+	// to a message with a null root.
+	tr = rpc.StreamTransport(readOnlyConn{bytes.NewBuffer(data)}, rpc.PackedStream())
+	if _, err := tr.RecvMessage(ctx); err != nil {
+		t.Error("RecvMessage without MaxUnpackedSize:", err)
+	}
+}
+
+func TestStreamTransport_BodyReadTimeout(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	tr := rpc.StreamTransport(c1, rpc.BodyReadTimeout(50*time.Millisecond))
+	defer tr.Close()
+	data, err := newFinishMessage(t, 42).Segment().Message().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Waiting longer than the timeout before the header arrives is fine.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c2.Write(data)
+	}()
+	msg, err := tr.RecvMessage(context.Background())
+	if err != nil {
+		t.Fatal("RecvMessage:", err)
+	}
+	checkFinishMessage(t, msg.Segment().Message(), 42)
+
+	// Stall after sending the header.
+	go c2.Write(data[:8])
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := tr.RecvMessage(ctx); err != rpc.ErrBodyReadTimeout {
+		t.Errorf("RecvMessage with stalled body error = %v; want %v", err, rpc.ErrBodyReadTimeout)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("RecvMessage with stalled body took %v", d)
+	}
+}
+
+func TestReceiverFilter_RejectCaps(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(rpccapnp.Message) error
+		ok    bool
+	}{
+		{
+			name: "data-only call",
+			build: func(m rpccapnp.Message) error {
+				call, err := m.NewCall()
+				if err != nil {
+					return err
+				}
+				params, err := call.NewParams()
+				if err != nil {
+					return err
+				}
+				content, err := capnp.NewStruct(m.Segment(), capnp.ObjectSize{DataSize: 8})
+				if err != nil {
+					return err
+				}
+				content.SetUint64(0, 42)
+				return params.SetContentPtr(content.ToPtr())
+			},
+			ok: true,
+		},
+		{
+			name: "call with cap table",
+			build: func(m rpccapnp.Message) error {
+				call, err := m.NewCall()
+				if err != nil {
+					return err
+				}
+				params, err := call.NewParams()
+				if err != nil {
+					return err
+				}
+				ctab, err := params.NewCapTable(1)
+				if err != nil {
+					return err
+				}
+				ctab.At(0).SetSenderHosted(0)
+				return nil
+			},
+		},
+		{
+			name: "return with interface pointer",
+			build: func(m rpccapnp.Message) error {
+				ret, err := m.NewReturn()
+				if err != nil {
+					return err
+				}
+				results, err := ret.NewResults()
+				if err != nil {
+					return err
+				}
+				return results.SetContentPtr(capnp.NewInterface(m.Segment(), 0).ToPtr())
+			},
+		},
+	}
+	for _, test := range tests {
+		c1, c2 := net.Pipe()
+		sender := rpc.StreamTransport(c1)
+		receiver := rpc.ReceiverFilter(rpc.StreamTransport(c2), rpc.RejectCaps)
+		recvDone := startRecvMessage(receiver)
+		if err := sendMessage(context.Background(), sender, test.build); err != nil {
+			t.Errorf("%s: SendMessage: %v", test.name, err)
+		} else if r := <-recvDone; test.ok && r.err != nil {
+			t.Errorf("%s: RecvMessage: %v", test.name, r.err)
+		} else if !test.ok && r.err != rpc.ErrCapsRejected {
+			t.Errorf("%s: RecvMessage error = %v; want %v", test.name, r.err, rpc.ErrCapsRejected)
+		}
+		sender.Close()
+		receiver.Close()
+	}
+}
+
+func TestReceiverFilter_OptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+	// stream returns a buffer holding a call with capabilities followed
+	// by an abort.
+	stream := func() *bytes.Buffer {
+		conn := new(writeCountConn)
+		sender := rpc.StreamTransport(conn)
+		err := sendMessage(ctx, sender, func(m rpccapnp.Message) error {
+			call, err := m.NewCall()
+			if err != nil {
+				return err
+			}
+			params, err := call.NewParams()
+			if err != nil {
+				return err
+			}
+			_, err = params.NewCapTable(1)
+			return err
+		})
+		if err != nil {
+			t.Fatal("SendMessage:", err)
+		}
+		err = sendMessage(ctx, sender, func(m rpccapnp.Message) error {
+			ab, err := m.NewAbort()
+			if err != nil {
+				return err
+			}
+			return ab.SetReason("ok")
+		})
+		if err != nil {
+			t.Fatal("SendMessage:", err)
+		}
+		return &conn.buf
+	}
+
+	// Wrapped transports with and without the optional interfaces both
+	// filter pooled receives.
+	transports := []struct {
+		name string
+		t    rpc.Transport
+	}{
+		{"stream transport", rpc.StreamTransport(readOnlyConn{stream()})},
+		{"plain transport", struct{ rpc.Transport }{rpc.StreamTransport(readOnlyConn{stream()})}},
+	}
+	for _, test := range transports {
+		receiver := rpc.ReceiverFilter(test.t, rpc.RejectCaps)
+		if _, ok := receiver.(rpc.BatchSender); !ok {
+			t.Errorf("filtered %s does not implement BatchSender", test.name)
+		}
+		rp, ok := receiver.(rpc.RecvPauser)
+		if !ok {
+			t.Errorf("filtered %s does not implement RecvPauser", test.name)
+		} else {
+			rp.PauseRecv()
+			rp.ResumeRecv()
+		}
+		pr, ok := receiver.(rpc.PooledReceiver)
+		if !ok {
+			t.Errorf("filtered %s does not implement PooledReceiver", test.name)
+			continue
+		}
+		if _, _, err := pr.RecvMessagePooled(ctx); err != rpc.ErrCapsRejected {
+			t.Errorf("%s: RecvMessagePooled(call with caps) error = %v; want %v", test.name, err, rpc.ErrCapsRejected)
+		}
+		msg, release, err := pr.RecvMessagePooled(ctx)
+		if err != nil {
+			t.Errorf("%s: RecvMessagePooled(abort): %v", test.name, err)
+			continue
+		}
+		if msg.Which() != rpccapnp.Message_Which_abort {
+			t.Errorf("%s: RecvMessagePooled(abort) = %v message", test.name, msg.Which())
+		}
+		release()
+	}
+
+	// A transport that can't send batches sends each message on its own.
+	conn := new(writeCountConn)
+	plain := struct{ rpc.Transport }{rpc.StreamTransport(conn)}
+	bs := rpc.ReceiverFilter(plain, rpc.RejectCaps).(rpc.BatchSender)
+	msgs := []rpccapnp.Message{newMultiSegmentMessage(t), newMultiSegmentMessage(t)}
+	if err := bs.SendMessages(ctx, msgs); err != nil {
+		t.Fatal("SendMessages:", err)
+	}
+	if conn.writes != len(msgs) {
+		t.Errorf("SendMessages on plain transport made %d writes; want %d", conn.writes, len(msgs))
+	}
+}
+
+func TestStreamTransport_SendMessages(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		var opts []rpc.StreamTransportOption
+		if pooled {
+			opts = append(opts, rpc.PooledSendBuffers())
+		}
+		conn := new(writeCountConn)
+		tr := rpc.StreamTransport(conn, opts...)
+		var msgs []rpccapnp.Message
+		for i := 0; i < 3; i++ {
+			_, s, err := capnp.NewMessage(capnp.SingleSegment(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := rpccapnp.NewRootMessage(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				fin, _ := m.NewFinish()
+				fin.SetQuestionId(5)
+			} else {
+				rel, _ := m.NewRelease()
+				rel.SetId(uint32(i))
+				rel.SetReferenceCount(1)
+			}
+			msgs = append(msgs, m)
+		}
+		if err := tr.(rpc.BatchSender).SendMessages(context.Background(), msgs); err != nil {
+			t.Errorf("pooled=%t: SendMessages: %v", pooled, err)
+			continue
+		}
+		if conn.writes != 1 {
+			t.Errorf("pooled=%t: SendMessages made %d writes; want 1", pooled, conn.writes)
+		}
+		dec := capnp.NewDecoder(&conn.buf)
+		for i, want := range []rpccapnp.Message_Which{rpccapnp.Message_Which_finish, rpccapnp.Message_Which_release, rpccapnp.Message_Which_release} {
+			msg, err := dec.Decode()
+			if err != nil {
+				t.Errorf("pooled=%t: decode message %d: %v", pooled, i, err)
+				break
+			}
+			m, err := rpccapnp.ReadRootMessage(msg)
+			if err != nil {
+				t.Errorf("pooled=%t: read message %d: %v", pooled, i, err)
+				break
+			}
+			if m.Which() != want {
+				t.Errorf("pooled=%t: message %d is a %v; want %v", pooled, i, m.Which(), want)
+			}
+		}
+	}
+}
+
+func TestSendMessages_EncodeError(t *testing.T) {
+	ctx := context.Background()
+	n := 0
+	compress := func(b []byte) ([]byte, error) {
+		n++
+		if n == 2 {
+			return nil, errors.New("boom")
+		}
+		return b, nil
+	}
+	identity := func(b []byte) ([]byte, error) { return b, nil }
+	conn := new(writeCountConn)
+	sender := rpc.NewGRPCFramedTransport(conn, rpc.GRPCCompression(compress, nil)).(rpc.BatchSender)
+	msgs := []rpccapnp.Message{newFinishMessage(t, 1), newFinishMessage(t, 2), newFinishMessage(t, 3)}
+	if err := sender.SendMessages(ctx, msgs); err == nil {
+		t.Error("SendMessages with a failing message returned nil error")
+	} else if !strings.Contains(err.Error(), "finish") {
+		t.Errorf("SendMessages error = %q; want it to name the finish message", err)
+	}
+	if conn.writes != 1 {
+		t.Errorf("SendMessages made %d writes; want 1", conn.writes)
+	}
+	receiver := rpc.NewGRPCFramedTransport(readOnlyConn{&conn.buf}, rpc.GRPCCompression(nil, identity))
+	for _, qid := range []uint32{1, 3} {
+		msg, err := receiver.RecvMessage(ctx)
+		if err != nil {
+			t.Fatal("RecvMessage:", err)
+		}
+		checkFinishMessage(t, msg.Segment().Message(), qid)
+	}
+}
+
+func TestConnBatchesFinishAndReleases(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c1, c2 := net.Pipe()
+	gc := &gatedConn{Conn: c1}
+	conn := rpc.NewConn(rpc.StreamTransport(gc), rpc.ConnLog(testLogger{t}))
+	defer conn.Close()
+	p := rpc.StreamTransport(c2)
+	defer p.Close()
+	client := bootstrapAndFulfill(t, ctx, conn, p, false)
+	call := func() capnp.Answer {
+		return client.Call(&capnp.Call{
+			Ctx:        ctx,
+			Method:     capnp.Method{InterfaceID: interfaceID, MethodID: methodID},
+			ParamsSize: capnp.ObjectSize{},
+		})
+	}
+
+	ans := call()
+	msg, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("read call:", err)
+	}
+	mc, err := msg.Call()
+	if err != nil {
+		t.Fatal(err)
+	}
+	qid := mc.QuestionId()
+
+	// Hold up the writer with a second call, so that what the Conn
+	// sends next queues up behind it.
+	blocked := gc.close()
+	call()
+	<-blocked
+	err = sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		ret, err := msg.NewReturn()
+		if err != nil {
+			return err
+		}
+		ret.SetAnswerId(qid)
+		payload, err := ret.NewResults()
+		if err != nil {
+			return err
+		}
+		content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{PointerCount: 2})
+		if err != nil {
+			return err
+		}
+		content.SetPtr(0, capnp.NewInterface(msg.Segment(), 0).ToPtr())
+		content.SetPtr(1, capnp.NewInterface(msg.Segment(), 1).ToPtr())
+		if err := payload.SetContent(content); err != nil {
+			return err
+		}
+		ctab, err := payload.NewCapTable(2)
+		if err != nil {
+			return err
+		}
+		ctab.At(0).SetSenderHosted(100)
+		ctab.At(1).SetSenderHosted(101)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("send return:", err)
+	}
+	results, err := ans.Struct()
+	if err != nil {
+		t.Fatal("call:", err)
+	}
+	for i := uint16(0); i < 2; i++ {
+		p, err := results.Ptr(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Interface().Client().Close(); err != nil {
+			t.Errorf("closing result cap %d: %v", i, err)
+		}
+	}
+	gc.open()
+
+	want := []rpccapnp.Message_Which{
+		rpccapnp.Message_Which_call,
+		rpccapnp.Message_Which_finish,
+		rpccapnp.Message_Which_release,
+		rpccapnp.Message_Which_release,
+	}
+	for i, w := range want {
+		msg, err := p.RecvMessage(ctx)
+		if err != nil {
+			t.Fatalf("read message %d: %v", i, err)
+		}
+		if msg.Which() != w {
+			t.Fatalf("message %d is a %v; want %v", i, msg.Which(), w)
+		}
+	}
+	// The call was the write in progress; everything after it was
+	// queued together.
+	writes := gc.writes()
+	if len(writes) < 2 {
+		t.Fatalf("Conn made %d writes after blocking; want 2", len(writes))
+	}
+	dec := capnp.NewDecoder(bytes.NewReader(writes[1]))
+	for i, w := range want[1:] {
+		msg, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("second write: decode message %d: %v", i, err)
+		}
+		m, err := rpccapnp.ReadRootMessage(msg)
+		if err != nil {
+			t.Fatalf("second write: read message %d: %v", i, err)
+		}
+		if m.Which() != w {
+			t.Errorf("second write: message %d is a %v; want %v", i, m.Which(), w)
+		}
+	}
+}
+
+// gatedConn is a net.Conn whose writes can be held up.  It records
+// the writes made after it was first closed.
+type gatedConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	gate    chan struct{} // nil if open; closed on open
+	blocked chan struct{} // closed when a write waits at the gate
+	w       [][]byte
+	record  bool
+}
+
+// close makes subsequent writes wait until open is called.  The
+// returned channel is closed once a write is waiting.
+func (c *gatedConn) close() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gate = make(chan struct{})
+	c.blocked = make(chan struct{})
+	c.record = true
+	return c.blocked
+}
+
+func (c *gatedConn) open() {
+	c.mu.Lock()
+	close(c.gate)
+	c.gate = nil
+	c.mu.Unlock()
+}
+
+func (c *gatedConn) writes() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.w
+}
+
+func (c *gatedConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	gate, blocked := c.gate, c.blocked
+	if gate != nil {
+		c.blocked = nil
+	}
+	if c.record {
+		c.w = append(c.w, append([]byte(nil), p...))
+	}
+	c.mu.Unlock()
+	if gate != nil {
+		if blocked != nil {
+			close(blocked)
+		}
+		<-gate
+	}
+	return c.Conn.Write(p)
+}
+
+func TestStreamTransport_SendMultiSegment(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		var opts []rpc.StreamTransportOption
+		if pooled {
+			opts = append(opts, rpc.PooledSendBuffers())
+		}
+		conn := new(writeCountConn)
+		tr := rpc.StreamTransport(conn, opts...)
+		m := newMultiSegmentMessage(t)
+		if n := m.Segment().Message().NumSegments(); n < 3 {
+			t.Fatalf("message has %d segments; want at least 3", n)
+		}
+		if err := tr.SendMessage(context.Background(), m); err != nil {
+			t.Errorf("pooled=%t: SendMessage: %v", pooled, err)
+			continue
+		}
+		if conn.writes != 1 {
+			t.Errorf("pooled=%t: SendMessage made %d writes; want 1", pooled, conn.writes)
+		}
+		msg, err := capnp.NewDecoder(&conn.buf).Decode()
+		if err != nil {
+			t.Errorf("pooled=%t: decode: %v", pooled, err)
+			continue
+		}
+		if got, want := msg.NumSegments(), m.Segment().Message().NumSegments(); got != want {
+			t.Errorf("pooled=%t: decoded message has %d segments; want %d", pooled, got, want)
+		}
+	}
+}
+
+// newMultiSegmentMessage returns a Call message spread across several
+// small segments.
+func newMultiSegmentMessage(tb testing.TB) rpccapnp.Message {
+	_, s, err := capnp.NewMessage(capnp.MultiSegment(nil, capnp.MaxSegmentSize(64)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	m, err := rpccapnp.NewRootMessage(s)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	call, err := m.NewCall()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	params, err := call.NewParams()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := params.NewCapTable(3); err != nil {
+		tb.Fatal(err)
+	}
+	return m
+}
+
+// writeCountConn is a stub connection that records everything written
+// to it and counts the calls to Write.
+type writeCountConn struct {
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *writeCountConn) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (c *writeCountConn) Write(p []byte) (int, error) {
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *writeCountConn) Close() error {
+	return nil
+}
+
+// deadlineConn is a stub connection in the style of *tls.Conn whose
+// reads block until the read deadline passes or the connection is closed.
+type deadlineConn struct {
+	mu       sync.Mutex
+	deadline time.Time
+	changed  chan struct{}
+	closed   chan struct{}
+
+	readReturned chan struct{}
+	readOnce     sync.Once
+	closeOnce    sync.Once
+}
+
+func newDeadlineConn() *deadlineConn {
+	return &deadlineConn{
+		changed:      make(chan struct{}),
+		closed:       make(chan struct{}),
+		readReturned: make(chan struct{}),
+	}
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	defer c.readOnce.Do(func() { close(c.readReturned) })
+	for {
+		c.mu.Lock()
+		d, changed := c.deadline, c.changed
+		c.mu.Unlock()
+		var timeout <-chan time.Time
+		if !d.IsZero() {
+			timeout = time.After(time.Until(d))
+		}
+		select {
+		case <-timeout:
+			return 0, errDeadlineExceeded
+		case <-changed:
+		case <-c.closed:
+			return 0, errors.New("read on closed connection")
+		}
+	}
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (c *deadlineConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *deadlineConn) readDeadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline
+}
+
+var errDeadlineExceeded = errors.New("i/o timeout")
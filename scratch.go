@@ -0,0 +1,61 @@
+package capnp
+
+import "sync"
+
+// A ScratchPool hands out empty single-segment messages for building
+// transient objects, such as a struct that is built once and then
+// copied into another message.  Returning a message to the pool with
+// Put lets a later Get reuse its buffer instead of growing a new one.
+//
+// Objects are deep-copied out of a scratch message by setting them as
+// a pointer field in the destination message, e.g. with Struct.SetPtr
+// or a generated setter.  The copy must be made before the scratch
+// message is returned to the pool.
+//
+// The zero value is an empty pool.  It is safe to use a ScratchPool
+// from multiple goroutines.
+type ScratchPool struct {
+	// MaxSize is the largest buffer that Put keeps for reuse, in bytes.
+	// Larger buffers are left for the garbage collector so that one
+	// unusually large scratch message does not pin its memory.  If
+	// MaxSize is zero, buffers of any size are kept.
+	MaxSize Size
+
+	pool sync.Pool
+}
+
+// Get returns an empty message and its first segment, with the root
+// pointer already allocated, as from NewMessage.  The message should be
+// returned to the pool with Put when it is no longer needed.
+func (p *ScratchPool) Get() (*Message, *Segment, error) {
+	var buf []byte
+	if b, _ := p.pool.Get().(*[]byte); b != nil {
+		buf = (*b)[:0]
+	}
+	return NewMessage(SingleSegment(buf))
+}
+
+// Put returns a message obtained from Get to the pool.  Put resets the
+// message, so the message and any objects in it must not be used after
+// calling Put.
+func (p *ScratchPool) Put(msg *Message) {
+	if _, ok := msg.Arena.(*singleSegmentArena); !ok {
+		// Not from Get, or the arena was replaced.
+		msg.Reset(nil)
+		return
+	}
+	msg.mu.Lock()
+	var buf []byte
+	if seg := msg.segment(0); seg != nil {
+		buf = seg.data
+	} else {
+		buf, _ = msg.Arena.Data(0)
+	}
+	msg.mu.Unlock()
+	msg.Reset(nil)
+	if buf == nil || p.MaxSize > 0 && Size(cap(buf)) > p.MaxSize {
+		return
+	}
+	buf = buf[:0]
+	p.pool.Put(&buf)
+}
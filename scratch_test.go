@@ -0,0 +1,127 @@
+package capnp
+
+import "testing"
+
+func TestScratchPool(t *testing.T) {
+	var pool ScratchPool
+	_, dstSeg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := NewRootStruct(dstSeg, ObjectSize{PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint16(0); i < 2; i++ {
+		scratch, seg, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Get #%d: %v", i+1, err)
+		}
+		if root, err := scratch.RootPtr(); err != nil {
+			t.Fatalf("Get #%d: RootPtr: %v", i+1, err)
+		} else if root.IsValid() {
+			t.Errorf("Get #%d: message has a root", i+1)
+		}
+		if n := len(seg.Data()); n != int(wordSize) {
+			t.Errorf("Get #%d: len(seg.Data()) = %d; want %d", i+1, n, wordSize)
+		}
+		s, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetUint64(0, 42+uint64(i))
+		if err := s.SetText(0, "hello"); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.SetPtr(i, s.ToPtr()); err != nil {
+			t.Fatalf("SetPtr(%d): %v", i, err)
+		}
+		pool.Put(scratch)
+	}
+
+	for i := uint16(0); i < 2; i++ {
+		p, err := dst.Ptr(i)
+		if err != nil {
+			t.Fatalf("dst.Ptr(%d): %v", i, err)
+		}
+		s := p.Struct()
+		if s.Segment().Message() != dstSeg.Message() {
+			t.Errorf("dst.Ptr(%d) is not in the destination message", i)
+		}
+		if got, want := s.Uint64(0), 42+uint64(i); got != want {
+			t.Errorf("dst.Ptr(%d).Uint64(0) = %d; want %d", i, got, want)
+		}
+		tp, err := s.Ptr(0)
+		if err != nil {
+			t.Fatalf("dst.Ptr(%d).Ptr(0): %v", i, err)
+		}
+		if got := tp.Text(); got != "hello" {
+			t.Errorf("dst.Ptr(%d).Ptr(0).Text() = %q; want \"hello\"", i, got)
+		}
+	}
+}
+
+func TestScratchPoolMaxSize(t *testing.T) {
+	pool := ScratchPool{MaxSize: 2048}
+	scratch, seg, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewData(seg, make([]byte, 4096)); err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(scratch)
+	_, seg, err = pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cap(seg.Data()); c >= 4096 {
+		t.Errorf("cap(seg.Data()) = %d after Put of oversized buffer; want < 4096", c)
+	}
+}
+
+func BenchmarkScratch(b *testing.B) {
+	const n = 100
+	build := func(b *testing.B, dst Struct, seg *Segment) {
+		s, err := NewStruct(seg, ObjectSize{DataSize: 16, PointerCount: 1})
+		if err != nil {
+			b.Fatal(err)
+		}
+		s.SetUint64(0, 0xdeadbeef)
+		if err := s.SetText(0, "transient"); err != nil {
+			b.Fatal(err)
+		}
+		if err := dst.SetPtr(0, s.ToPtr()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("Pooled", func(b *testing.B) {
+		var pool ScratchPool
+		for i := 0; i < b.N; i++ {
+			_, dstSeg, _ := NewMessage(SingleSegment(nil))
+			dst, _ := NewRootStruct(dstSeg, ObjectSize{PointerCount: 1})
+			for j := 0; j < n; j++ {
+				scratch, seg, err := pool.Get()
+				if err != nil {
+					b.Fatal(err)
+				}
+				build(b, dst, seg)
+				pool.Put(scratch)
+			}
+		}
+	})
+	b.Run("Fresh", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, dstSeg, _ := NewMessage(SingleSegment(nil))
+			dst, _ := NewRootStruct(dstSeg, ObjectSize{PointerCount: 1})
+			for j := 0; j < n; j++ {
+				_, seg, err := NewMessage(SingleSegment(nil))
+				if err != nil {
+					b.Fatal(err)
+				}
+				build(b, dst, seg)
+			}
+		}
+	})
+}
@@ -4,8 +4,10 @@ package server // import "zombiezen.com/go/capnproto2/server"
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
@@ -22,6 +24,38 @@ type Method struct {
 // A Func is a function that implements a single method.
 type Func func(ctx context.Context, options capnp.CallOptions, params, results capnp.Struct) error
 
+// An Interceptor wraps calls to a server method, in the manner of HTTP
+// middleware.  It is passed the method being called and next, the
+// function that continues the call.  An interceptor may reject the
+// call by returning an error without calling next, or it may observe
+// the call by doing work before and after calling next.
+type Interceptor func(ctx context.Context, m *capnp.Method, options capnp.CallOptions, params, results capnp.Struct, next Func) error
+
+// Intercept returns a copy of methods with each method's Impl wrapped
+// by the given interceptors.  The interceptors are called in the order
+// given, so the first interceptor sees the call first.
+//
+// Example:
+//
+//	methods := server.Intercept(schema.MyServer_Methods(nil, s), authorize, logCalls)
+//	c := server.New(methods, nil)
+func Intercept(methods []Method, interceptors ...Interceptor) []Method {
+	wrapped := make([]Method, len(methods))
+	for i := range methods {
+		wrapped[i] = methods[i]
+		for j := len(interceptors) - 1; j >= 0; j-- {
+			wrapped[i].Impl = intercept(&wrapped[i].Method, interceptors[j], wrapped[i].Impl)
+		}
+	}
+	return wrapped
+}
+
+func intercept(m *capnp.Method, ic Interceptor, next Func) Func {
+	return func(ctx context.Context, options capnp.CallOptions, params, results capnp.Struct) error {
+		return ic(ctx, m, options, params, results, next)
+	}
+}
+
 // Closer is the interface that wraps the Close method.
 type Closer interface {
 	Close() error
@@ -82,13 +116,22 @@ func (s *server) startCall(cl *call) error {
 		return err
 	}
 	acksig := newAckSignal()
-	opts := cl.Options.With([]capnp.CallOption{capnp.SetOptionValue(ackSignalKey, acksig)})
+	f := &Fulfiller{ans: &cl.ans, results: results, ack: acksig}
+	opts := cl.Options.With([]capnp.CallOption{
+		capnp.SetOptionValue(ackSignalKey, acksig),
+		capnp.SetOptionValue(fulfillerKey, f),
+	})
 	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				f.Reject(fmt.Errorf("server: panic: %v", r))
+			}
+		}()
 		err := cl.method.Impl(cl.Ctx, opts, cl.Params, results)
-		if err == nil {
-			cl.ans.Fulfill(results)
-		} else {
-			cl.ans.Reject(err)
+		if err != nil {
+			f.Reject(err)
+		} else if !f.isAsync() {
+			f.Fulfill()
 		}
 	}()
 	select {
@@ -159,6 +202,75 @@ func Ack(opts capnp.CallOptions) {
 	}
 }
 
+// Async marks a server call as asynchronous and returns a Fulfiller
+// that completes it.  It is intended to be used inside the
+// implementation of a server function that needs to wait on something
+// else, like a database query, without tying up its goroutine.  After
+// calling Async, the function may return nil immediately; the call's
+// answer is not resolved until the Fulfiller's Fulfill or Reject method
+// is called.  If the function returns an error, the call is rejected
+// with that error and the Fulfiller does nothing.
+//
+// Async acknowledges delivery of the call, as if by Ack.  Calling Async
+// on options that aren't from a server method implementation returns
+// nil.
+//
+// Example:
+//
+//	func (my *myServer) MyMethod(call schema.MyServer_myMethod) error {
+//		f := server.Async(call.Options)
+//		go func() {
+//			n, err := my.db.Lookup()
+//			if err != nil {
+//				f.Reject(err)
+//				return
+//			}
+//			call.Results.SetN(n)
+//			f.Fulfill()
+//		}()
+//		return nil
+//	}
+func Async(opts capnp.CallOptions) *Fulfiller {
+	f, _ := opts.Value(fulfillerKey).(*Fulfiller)
+	if f == nil {
+		return nil
+	}
+	atomic.StoreInt32(&f.async, 1)
+	f.ack.signal()
+	return f
+}
+
+// A Fulfiller resolves the answer to an asynchronous server call.
+// It is safe to use from multiple goroutines; only the first call to
+// Fulfill or Reject has any effect.
+type Fulfiller struct {
+	ans     *fulfiller.Fulfiller
+	results capnp.Struct
+	ack     *ackSignal
+	async   int32
+	once    sync.Once
+}
+
+// Fulfill resolves the call with the results struct that was passed to
+// the server function.  The results must be filled in before calling
+// Fulfill.
+func (f *Fulfiller) Fulfill() {
+	f.once.Do(func() {
+		f.ans.Fulfill(f.results)
+	})
+}
+
+// Reject resolves the call with the error err, which must not be nil.
+func (f *Fulfiller) Reject(err error) {
+	f.once.Do(func() {
+		f.ans.Reject(err)
+	})
+}
+
+func (f *Fulfiller) isAsync() bool {
+	return atomic.LoadInt32(&f.async) != 0
+}
+
 type call struct {
 	*capnp.Call
 	ans    fulfiller.Fulfiller
@@ -226,6 +338,7 @@ type callOptionKey int
 // Predefined call options
 const (
 	ackSignalKey callOptionKey = iota + 1
+	fulfillerKey
 )
 
 var errClosed = errors.New("capnp: server closed")
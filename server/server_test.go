@@ -1,10 +1,14 @@
 package server_test
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
 	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
 	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
 	. "zombiezen.com/go/capnproto2/server"
 )
@@ -43,6 +47,143 @@ func TestServerCall(t *testing.T) {
 	}
 }
 
+type panicEcho struct{}
+
+func (panicEcho) Echo(call air.Echo_echo) error {
+	panic("echo is broken")
+}
+
+func TestServerCallPanic(t *testing.T) {
+	echo := air.Echo_ServerToClient(panicEcho{})
+	defer echo.Client.Close()
+
+	_, err := echo.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn("foo")
+	}).Struct()
+	if err == nil {
+		t.Fatal("echo.Echo() succeeded; want panic error")
+	}
+	if !strings.Contains(err.Error(), "echo is broken") {
+		t.Errorf("echo.Echo() error = %q; want it to mention the panic value", err)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	methods := air.Echo_Methods(nil, echoImpl{})
+	methods = air.CallSequence_Methods(methods, new(callSeq))
+	var mu sync.Mutex
+	var log []string
+	record := func(ctx context.Context, m *capnp.Method, opts capnp.CallOptions, params, results capnp.Struct, next Func) error {
+		mu.Lock()
+		log = append(log, m.MethodName)
+		mu.Unlock()
+		return next(ctx, opts, params, results)
+	}
+	errDenied := errors.New("permission denied")
+	deny := func(ctx context.Context, m *capnp.Method, opts capnp.CallOptions, params, results capnp.Struct, next Func) error {
+		if m.InterfaceID == air.Echo_TypeID && m.MethodID == 0 {
+			return &capnp.MethodError{Method: m, Err: errDenied}
+		}
+		return next(ctx, opts, params, results)
+	}
+	c := New(Intercept(methods, record, deny), nil)
+	defer func() {
+		if err := c.Close(); err != nil {
+			t.Error("Close:", err)
+		}
+	}()
+	ctx := context.Background()
+
+	_, err := air.Echo{Client: c}.Echo(ctx, func(p air.Echo_echo_Params) error {
+		return p.SetIn("foo")
+	}).Struct()
+	if me, ok := err.(*capnp.MethodError); !ok || me.Err != errDenied {
+		t.Errorf("echo.Echo() error = %v; want %v", err, errDenied)
+	}
+	result, err := air.CallSequence{Client: c}.GetNumber(ctx, nil).Struct()
+	if err != nil {
+		t.Errorf("seq.GetNumber() error: %v", err)
+	} else if n := result.N(); n != 0 {
+		t.Errorf("seq.GetNumber() = %d; want 0", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"echo", "getNumber"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("interceptor saw calls %q; want %q", log, want)
+	}
+}
+
+type asyncEcho struct {
+	returned chan struct{}
+	release  chan struct{}
+}
+
+func (ae asyncEcho) Echo(call air.Echo_echo) error {
+	defer close(ae.returned)
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	f := Async(call.Options)
+	go func() {
+		<-ae.release
+		if err := call.Results.SetOut(in + in); err != nil {
+			f.Reject(err)
+			return
+		}
+		f.Fulfill()
+	}()
+	return nil
+}
+
+func TestAsync(t *testing.T) {
+	impl := asyncEcho{
+		returned: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	echo := air.Echo_ServerToClient(impl)
+	defer func() {
+		if err := echo.Client.Close(); err != nil {
+			t.Error("Close:", err)
+		}
+	}()
+
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := echo.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+			return p.SetIn("foo")
+		}).Struct()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		out, err := res.Out()
+		done <- result{out, err}
+	}()
+	<-impl.returned
+	select {
+	case r := <-done:
+		t.Fatalf("call resolved to %q, %v before Fulfill", r.out, r.err)
+	default:
+	}
+	close(impl.release)
+	r := <-done
+	if r.err != nil {
+		t.Errorf("echo.Echo() error: %v", r.err)
+	} else if r.out != "foofoo" {
+		t.Errorf("echo.Echo() = %q; want %q", r.out, "foofoo")
+	}
+
+	if f := Async(capnp.NewCallOptions(nil)); f != nil {
+		t.Errorf("Async(non-server options) = %v; want nil", f)
+	}
+}
+
 type callSeq uint32
 
 func (seq *callSeq) GetNumber(call air.CallSequence_getNumber) error {
@@ -24,13 +24,23 @@ using Go = import "/go.capnp";
 EOF
 }
 
+gen_flags() {
+	# Print the capnpc-go flags for the package named $1.  The rpc
+	# package reads exceptions from peers that may use a newer schema,
+	# so it gets checked enum accessors.
+	case "$1" in
+		rpc) printf '%s' "-checkedenums" ;;
+	esac
+}
+
 gen_go_src() {
 	# Generate go source code from the schema file $1. Create the package
 	# directory if necessary.
 	file="$1"
 	package_name="$(infer_package_name $file)"
 	mkdir -p $package_name || return 1
-	capnp compile -I"$(dirname $PWD)" -ogo:$package_name $file
+	capnp compile -I"$(dirname $PWD)" -o- $file |
+		(cd $package_name && capnpc-go $(gen_flags $package_name))
 }
 
 usage() {
@@ -113,7 +113,7 @@ func (s JsonValue) HasString_() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue) String_Bytes() ([]byte, error) {
@@ -139,7 +139,7 @@ func (s JsonValue) HasArray() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue) SetArray(v JsonValue_List) error {
@@ -172,7 +172,7 @@ func (s JsonValue) HasObject() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue) SetObject(v JsonValue_Field_List) error {
@@ -205,7 +205,7 @@ func (s JsonValue) HasCall() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue) SetCall(v JsonValue_Call) error {
@@ -236,8 +236,33 @@ func NewJsonValue_List(s *capnp.Segment, sz int32) (JsonValue_List, error) {
 
 func (s JsonValue_List) At(i int) JsonValue { return JsonValue{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s JsonValue_List) AtChecked(i int) (JsonValue, error) {
+	p, err := s.List.StructChecked(i)
+	return JsonValue{p}, err
+}
+
 func (s JsonValue_List) Set(i int, v JsonValue) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s JsonValue_List) ToSlice() []JsonValue {
+	v := make([]JsonValue, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s JsonValue_List) Range(f func(i int, v JsonValue) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s JsonValue_List) String() string {
 	str, _ := text.MarshalList(0x8825ffaa852cda72, s.List)
 	return str
@@ -287,7 +312,7 @@ func (s JsonValue_Field) Name() (string, error) {
 
 func (s JsonValue_Field) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue_Field) NameBytes() ([]byte, error) {
@@ -306,7 +331,7 @@ func (s JsonValue_Field) Value() (JsonValue, error) {
 
 func (s JsonValue_Field) HasValue() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue_Field) SetValue(v JsonValue) error {
@@ -335,10 +360,35 @@ func NewJsonValue_Field_List(s *capnp.Segment, sz int32) (JsonValue_Field_List,
 
 func (s JsonValue_Field_List) At(i int) JsonValue_Field { return JsonValue_Field{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s JsonValue_Field_List) AtChecked(i int) (JsonValue_Field, error) {
+	p, err := s.List.StructChecked(i)
+	return JsonValue_Field{p}, err
+}
+
 func (s JsonValue_Field_List) Set(i int, v JsonValue_Field) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s JsonValue_Field_List) ToSlice() []JsonValue_Field {
+	v := make([]JsonValue_Field, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s JsonValue_Field_List) Range(f func(i int, v JsonValue_Field) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s JsonValue_Field_List) String() string {
 	str, _ := text.MarshalList(0xc27855d853a937cc, s.List)
 	return str
@@ -388,7 +438,7 @@ func (s JsonValue_Call) Function() (string, error) {
 
 func (s JsonValue_Call) HasFunction() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue_Call) FunctionBytes() ([]byte, error) {
@@ -407,7 +457,7 @@ func (s JsonValue_Call) Params() (JsonValue_List, error) {
 
 func (s JsonValue_Call) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JsonValue_Call) SetParams(v JsonValue_List) error {
@@ -436,8 +486,33 @@ func NewJsonValue_Call_List(s *capnp.Segment, sz int32) (JsonValue_Call_List, er
 
 func (s JsonValue_Call_List) At(i int) JsonValue_Call { return JsonValue_Call{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s JsonValue_Call_List) AtChecked(i int) (JsonValue_Call, error) {
+	p, err := s.List.StructChecked(i)
+	return JsonValue_Call{p}, err
+}
+
 func (s JsonValue_Call_List) Set(i int, v JsonValue_Call) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s JsonValue_Call_List) ToSlice() []JsonValue_Call {
+	v := make([]JsonValue_Call, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s JsonValue_Call_List) Range(f func(i int, v JsonValue_Call) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s JsonValue_Call_List) String() string {
 	str, _ := text.MarshalList(0x9bbf84153dd4bb60, s.List)
 	return str
@@ -108,13 +108,23 @@ func (s Persistent_SaveParams) SealFor() (capnp.Pointer, error) {
 
 func (s Persistent_SaveParams) HasSealFor() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Persistent_SaveParams) SealForPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// SealForStruct returns the sealFor field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Persistent_SaveParams) SealForStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.SealForPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Persistent_SaveParams) SetSealFor(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -136,10 +146,35 @@ func (s Persistent_SaveParams_List) At(i int) Persistent_SaveParams {
 	return Persistent_SaveParams{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Persistent_SaveParams_List) AtChecked(i int) (Persistent_SaveParams, error) {
+	p, err := s.List.StructChecked(i)
+	return Persistent_SaveParams{p}, err
+}
+
 func (s Persistent_SaveParams_List) Set(i int, v Persistent_SaveParams) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Persistent_SaveParams_List) ToSlice() []Persistent_SaveParams {
+	v := make([]Persistent_SaveParams, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Persistent_SaveParams_List) Range(f func(i int, v Persistent_SaveParams) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Persistent_SaveParams_List) String() string {
 	str, _ := text.MarshalList(0xf76fba59183073a5, s.List)
 	return str
@@ -188,13 +223,23 @@ func (s Persistent_SaveResults) SturdyRef() (capnp.Pointer, error) {
 
 func (s Persistent_SaveResults) HasSturdyRef() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Persistent_SaveResults) SturdyRefPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// SturdyRefStruct returns the sturdyRef field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Persistent_SaveResults) SturdyRefStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.SturdyRefPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Persistent_SaveResults) SetSturdyRef(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -216,10 +261,35 @@ func (s Persistent_SaveResults_List) At(i int) Persistent_SaveResults {
 	return Persistent_SaveResults{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Persistent_SaveResults_List) AtChecked(i int) (Persistent_SaveResults, error) {
+	p, err := s.List.StructChecked(i)
+	return Persistent_SaveResults{p}, err
+}
+
 func (s Persistent_SaveResults_List) Set(i int, v Persistent_SaveResults) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Persistent_SaveResults_List) ToSlice() []Persistent_SaveResults {
+	v := make([]Persistent_SaveResults, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Persistent_SaveResults_List) Range(f func(i int, v Persistent_SaveResults) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Persistent_SaveResults_List) String() string {
 	str, _ := text.MarshalList(0xb76848c18c40efbf, s.List)
 	return str
@@ -378,7 +448,7 @@ func (s RealmGateway_import_Params) Cap() Persistent {
 
 func (s RealmGateway_import_Params) HasCap() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s RealmGateway_import_Params) SetCap(v Persistent) error {
@@ -397,7 +467,7 @@ func (s RealmGateway_import_Params) Params() (Persistent_SaveParams, error) {
 
 func (s RealmGateway_import_Params) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s RealmGateway_import_Params) SetParams(v Persistent_SaveParams) error {
@@ -428,10 +498,35 @@ func (s RealmGateway_import_Params_List) At(i int) RealmGateway_import_Params {
 	return RealmGateway_import_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s RealmGateway_import_Params_List) AtChecked(i int) (RealmGateway_import_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return RealmGateway_import_Params{p}, err
+}
+
 func (s RealmGateway_import_Params_List) Set(i int, v RealmGateway_import_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s RealmGateway_import_Params_List) ToSlice() []RealmGateway_import_Params {
+	v := make([]RealmGateway_import_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s RealmGateway_import_Params_List) Range(f func(i int, v RealmGateway_import_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s RealmGateway_import_Params_List) String() string {
 	str, _ := text.MarshalList(0xf0c2cc1d3909574d, s.List)
 	return str
@@ -485,7 +580,7 @@ func (s RealmGateway_export_Params) Cap() Persistent {
 
 func (s RealmGateway_export_Params) HasCap() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s RealmGateway_export_Params) SetCap(v Persistent) error {
@@ -504,7 +599,7 @@ func (s RealmGateway_export_Params) Params() (Persistent_SaveParams, error) {
 
 func (s RealmGateway_export_Params) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s RealmGateway_export_Params) SetParams(v Persistent_SaveParams) error {
@@ -535,10 +630,35 @@ func (s RealmGateway_export_Params_List) At(i int) RealmGateway_export_Params {
 	return RealmGateway_export_Params{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s RealmGateway_export_Params_List) AtChecked(i int) (RealmGateway_export_Params, error) {
+	p, err := s.List.StructChecked(i)
+	return RealmGateway_export_Params{p}, err
+}
+
 func (s RealmGateway_export_Params_List) Set(i int, v RealmGateway_export_Params) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s RealmGateway_export_Params_List) ToSlice() []RealmGateway_export_Params {
+	v := make([]RealmGateway_export_Params, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s RealmGateway_export_Params_List) Range(f func(i int, v RealmGateway_export_Params) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s RealmGateway_export_Params_List) String() string {
 	str, _ := text.MarshalList(0xecafa18b482da3aa, s.List)
 	return str
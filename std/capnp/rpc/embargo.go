@@ -0,0 +1,27 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EmbargoID returns the ID carried by the context's current variant:
+// the embargo ID for senderLoopback and receiverLoopback, or the
+// question ID of the Provide for provide.  It returns an error for
+// accept, which carries no ID, and for variants unknown to this
+// version of the schema.  The variants' IDs share a field, so reading
+// the ID through the wrong accessor would give a misleading value.
+func (s Disembargo_context) EmbargoID() (uint32, error) {
+	switch w := s.Which(); w {
+	case Disembargo_context_Which_senderLoopback:
+		return s.SenderLoopback(), nil
+	case Disembargo_context_Which_receiverLoopback:
+		return s.ReceiverLoopback(), nil
+	case Disembargo_context_Which_provide:
+		return s.Provide(), nil
+	case Disembargo_context_Which_accept:
+		return 0, errors.New("rpc: disembargo context accept has no ID")
+	default:
+		return 0, fmt.Errorf("rpc: unknown disembargo context %v", w)
+	}
+}
@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestDisembargoContextEmbargoID(t *testing.T) {
+	tests := []struct {
+		name   string
+		set    func(Disembargo_context)
+		id     uint32
+		wantOK bool
+	}{
+		{"senderLoopback", func(c Disembargo_context) { c.SetSenderLoopback(7) }, 7, true},
+		{"receiverLoopback", func(c Disembargo_context) { c.SetReceiverLoopback(8) }, 8, true},
+		{"provide", func(c Disembargo_context) { c.SetProvide(9) }, 9, true},
+		{"accept", func(c Disembargo_context) { c.SetAccept() }, 0, false},
+		{"unknown", func(c Disembargo_context) { c.Struct.SetUint16(4, 42) }, 0, false},
+	}
+	for _, test := range tests {
+		_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := NewRootDisembargo(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.set(d.Context())
+		id, err := d.Context().EmbargoID()
+		switch {
+		case test.wantOK && err != nil:
+			t.Errorf("%s: EmbargoID() error: %v", test.name, err)
+		case test.wantOK && id != test.id:
+			t.Errorf("%s: EmbargoID() = %d; want %d", test.name, id, test.id)
+		case !test.wantOK && err == nil:
+			t.Errorf("%s: EmbargoID() = %d, <nil>; want error", test.name, id)
+		}
+	}
+}
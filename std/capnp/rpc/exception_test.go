@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestExceptionTypeChecked(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exc, err := NewRootException(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exc.SetType(Exception_Type_disconnected)
+	if typ, err := exc.TypeChecked(); err != nil || typ != Exception_Type_disconnected {
+		t.Errorf("TypeChecked() = %v, %v; want %v, <nil>", typ, err, Exception_Type_disconnected)
+	}
+
+	// A peer running a newer schema may send a type this one lacks.
+	exc.SetType(Exception_Type(7))
+	typ, err := exc.TypeChecked()
+	if typ != 7 {
+		t.Errorf("TypeChecked() type = %d; want 7", typ)
+	}
+	ev, ok := err.(*capnp.EnumValueError)
+	if !ok {
+		t.Fatalf("TypeChecked() error = %v; want *capnp.EnumValueError", err)
+	}
+	if ev.TypeID != Exception_Type_TypeID || ev.Value != 7 {
+		t.Errorf("TypeChecked() error = %+v; want TypeID %#x, Value 7", ev, uint64(Exception_Type_TypeID))
+	}
+}
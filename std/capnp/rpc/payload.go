@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"errors"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// NewCapTableIn is like NewCapTable, but creates the list in seg, which
+// must belong to the same message as the payload.  Keeping a large
+// capability table in its own segment (see capnp.Message.NewSegment)
+// leaves the content's segment free of descriptors.  The list is only
+// placed elsewhere in the message if seg does not have room for it.
+func (s Payload) NewCapTableIn(seg *capnp.Segment, n int32) (CapDescriptor_List, error) {
+	if seg.Message() != s.Segment().Message() {
+		return CapDescriptor_List{}, errForeignSegment
+	}
+	l, err := NewCapDescriptor_List(seg, n)
+	if err != nil {
+		return CapDescriptor_List{}, err
+	}
+	err = s.SetCapTable(l)
+	return l, err
+}
+
+// NewContentIn allocates a struct of the given size in seg, which must
+// belong to the same message as the payload, and sets it as the
+// payload's content.  As with NewCapTableIn, the struct is only placed
+// elsewhere in the message if seg does not have room for it.
+func (s Payload) NewContentIn(seg *capnp.Segment, sz capnp.ObjectSize) (capnp.Struct, error) {
+	if seg.Message() != s.Segment().Message() {
+		return capnp.Struct{}, errForeignSegment
+	}
+	st, err := capnp.NewStruct(seg, sz)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	err = s.SetContentPtr(st.ToPtr())
+	return st, err
+}
+
+var errForeignSegment = errors.New("rpc: segment belongs to a different message than the payload")
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestPayloadSegmentPlacement(t *testing.T) {
+	msg, seg, err := capnp.NewMessage(capnp.MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewRootMessage(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := m.NewCall()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := call.NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const ncaps = 100
+	capSeg, err := msg.NewSegment(8 + ncaps*16)
+	if err != nil {
+		t.Fatal("NewSegment for caps:", err)
+	}
+	contentSeg, err := msg.NewSegment(1024)
+	if err != nil {
+		t.Fatal("NewSegment for content:", err)
+	}
+
+	ctab, err := payload.NewCapTableIn(capSeg, ncaps)
+	if err != nil {
+		t.Fatal("NewCapTableIn:", err)
+	}
+	for i := 0; i < ncaps; i++ {
+		ctab.At(i).SetSenderHosted(uint32(i))
+	}
+	content, err := payload.NewContentIn(contentSeg, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal("NewContentIn:", err)
+	}
+	content.SetUint64(0, 0xfeed)
+	if err := content.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if id := ctab.Segment().ID(); id != capSeg.ID() {
+		t.Errorf("cap table in segment %d; want %d", id, capSeg.ID())
+	}
+	if id := content.Segment().ID(); id != contentSeg.ID() {
+		t.Errorf("content in segment %d; want %d", id, contentSeg.ID())
+	}
+	if n := len(capSeg.Data()); n != 8+ncaps*16 {
+		t.Errorf("cap segment holds %d bytes; want %d", n, 8+ncaps*16)
+	}
+
+	// Read the payload back from the wire form.
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	msg2, err := capnp.Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	m2, err := ReadRootMessage(msg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call2, err := m2.Call()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload2, err := call2.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctab2, err := payload2.CapTable()
+	if err != nil {
+		t.Fatal("CapTable:", err)
+	}
+	if id := ctab2.Segment().ID(); id != capSeg.ID() {
+		t.Errorf("decoded cap table in segment %d; want %d", id, capSeg.ID())
+	}
+	if ctab2.Len() != ncaps {
+		t.Fatalf("decoded cap table has %d entries; want %d", ctab2.Len(), ncaps)
+	}
+	if id := ctab2.At(ncaps - 1).SenderHosted(); id != ncaps-1 {
+		t.Errorf("decoded cap table[%d].SenderHosted() = %d; want %d", ncaps-1, id, ncaps-1)
+	}
+	content2, err := payload2.ContentPtr()
+	if err != nil {
+		t.Fatal("ContentPtr:", err)
+	}
+	if id := content2.Struct().Segment().ID(); id != contentSeg.ID() {
+		t.Errorf("decoded content in segment %d; want %d", id, contentSeg.ID())
+	}
+	if v := content2.Struct().Uint64(0); v != 0xfeed {
+		t.Errorf("decoded content data = %#x; want 0xfeed", v)
+	}
+}
+
+func TestPayloadSegmentPlacement_ForeignSegment(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := NewRootPayload(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := payload.NewCapTableIn(other, 1); err == nil {
+		t.Error("NewCapTableIn with a segment from another message succeeded")
+	}
+	if _, err := payload.NewContentIn(other, capnp.ObjectSize{DataSize: 8}); err == nil {
+		t.Error("NewContentIn with a segment from another message succeeded")
+	}
+}
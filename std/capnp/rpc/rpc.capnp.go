@@ -104,7 +104,7 @@ func (s Message) HasUnimplemented() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetUnimplemented(v Message) error {
@@ -137,7 +137,7 @@ func (s Message) HasAbort() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetAbort(v Exception) error {
@@ -170,7 +170,7 @@ func (s Message) HasBootstrap() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetBootstrap(v Bootstrap) error {
@@ -203,7 +203,7 @@ func (s Message) HasCall() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetCall(v Call) error {
@@ -236,7 +236,7 @@ func (s Message) HasReturn() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetReturn(v Return) error {
@@ -269,7 +269,7 @@ func (s Message) HasFinish() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetFinish(v Finish) error {
@@ -302,7 +302,7 @@ func (s Message) HasResolve() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetResolve(v Resolve) error {
@@ -335,7 +335,7 @@ func (s Message) HasRelease() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetRelease(v Release) error {
@@ -368,7 +368,7 @@ func (s Message) HasDisembargo() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetDisembargo(v Disembargo) error {
@@ -400,13 +400,23 @@ func (s Message) HasObsoleteSave() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) ObsoleteSavePtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// ObsoleteSaveStruct returns the obsoleteSave field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Message) ObsoleteSaveStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ObsoleteSavePtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Message) SetObsoleteSave(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 7)
 	return s.Struct.SetPointer(0, v)
@@ -429,13 +439,23 @@ func (s Message) HasObsoleteDelete() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) ObsoleteDeletePtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// ObsoleteDeleteStruct returns the obsoleteDelete field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Message) ObsoleteDeleteStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ObsoleteDeletePtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Message) SetObsoleteDelete(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 9)
 	return s.Struct.SetPointer(0, v)
@@ -459,7 +479,7 @@ func (s Message) HasProvide() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetProvide(v Provide) error {
@@ -492,7 +512,7 @@ func (s Message) HasAccept() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetAccept(v Accept) error {
@@ -525,7 +545,7 @@ func (s Message) HasJoin() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Message) SetJoin(v Join) error {
@@ -556,8 +576,33 @@ func NewMessage_List(s *capnp.Segment, sz int32) (Message_List, error) {
 
 func (s Message_List) At(i int) Message { return Message{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Message_List) AtChecked(i int) (Message, error) {
+	p, err := s.List.StructChecked(i)
+	return Message{p}, err
+}
+
 func (s Message_List) Set(i int, v Message) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Message_List) ToSlice() []Message {
+	v := make([]Message, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Message_List) Range(f func(i int, v Message) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Message_List) String() string {
 	str, _ := text.MarshalList(0x91b79f1f808db032, s.List)
 	return str
@@ -666,13 +711,23 @@ func (s Bootstrap) DeprecatedObjectId() (capnp.Pointer, error) {
 
 func (s Bootstrap) HasDeprecatedObjectId() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Bootstrap) DeprecatedObjectIdPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// DeprecatedObjectIdStruct returns the deprecatedObjectId field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Bootstrap) DeprecatedObjectIdStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.DeprecatedObjectIdPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Bootstrap) SetDeprecatedObjectId(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -692,8 +747,33 @@ func NewBootstrap_List(s *capnp.Segment, sz int32) (Bootstrap_List, error) {
 
 func (s Bootstrap_List) At(i int) Bootstrap { return Bootstrap{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Bootstrap_List) AtChecked(i int) (Bootstrap, error) {
+	p, err := s.List.StructChecked(i)
+	return Bootstrap{p}, err
+}
+
 func (s Bootstrap_List) Set(i int, v Bootstrap) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Bootstrap_List) ToSlice() []Bootstrap {
+	v := make([]Bootstrap, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Bootstrap_List) Range(f func(i int, v Bootstrap) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Bootstrap_List) String() string {
 	str, _ := text.MarshalList(0xe94ccf8031176ec4, s.List)
 	return str
@@ -773,7 +853,7 @@ func (s Call) Target() (MessageTarget, error) {
 
 func (s Call) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Call) SetTarget(v MessageTarget) error {
@@ -822,7 +902,7 @@ func (s Call) Params() (Payload, error) {
 
 func (s Call) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Call) SetParams(v Payload) error {
@@ -867,13 +947,23 @@ func (s Call_sendResultsTo) HasThirdParty() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Call_sendResultsTo) ThirdPartyPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(2)
 }
 
+// ThirdPartyStruct returns the thirdParty field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Call_sendResultsTo) ThirdPartyStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ThirdPartyPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Call_sendResultsTo) SetThirdParty(v capnp.Pointer) error {
 	s.Struct.SetUint16(6, 2)
 	return s.Struct.SetPointer(2, v)
@@ -895,8 +985,33 @@ func NewCall_List(s *capnp.Segment, sz int32) (Call_List, error) {
 
 func (s Call_List) At(i int) Call { return Call{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Call_List) AtChecked(i int) (Call, error) {
+	p, err := s.List.StructChecked(i)
+	return Call{p}, err
+}
+
 func (s Call_List) Set(i int, v Call) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Call_List) ToSlice() []Call {
+	v := make([]Call, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Call_List) Range(f func(i int, v Call) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Call_List) String() string {
 	str, _ := text.MarshalList(0x836a53ce789d4cd4, s.List)
 	return str
@@ -1021,7 +1136,7 @@ func (s Return) HasResults() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Return) SetResults(v Payload) error {
@@ -1054,7 +1169,7 @@ func (s Return) HasException() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Return) SetException(v Exception) error {
@@ -1108,13 +1223,23 @@ func (s Return) HasAcceptFromThirdParty() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Return) AcceptFromThirdPartyPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// AcceptFromThirdPartyStruct returns the acceptFromThirdParty field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Return) AcceptFromThirdPartyStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.AcceptFromThirdPartyPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Return) SetAcceptFromThirdParty(v capnp.Pointer) error {
 	s.Struct.SetUint16(6, 5)
 	return s.Struct.SetPointer(0, v)
@@ -1136,8 +1261,33 @@ func NewReturn_List(s *capnp.Segment, sz int32) (Return_List, error) {
 
 func (s Return_List) At(i int) Return { return Return{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Return_List) AtChecked(i int) (Return, error) {
+	p, err := s.List.StructChecked(i)
+	return Return{p}, err
+}
+
 func (s Return_List) Set(i int, v Return) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Return_List) ToSlice() []Return {
+	v := make([]Return, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Return_List) Range(f func(i int, v Return) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Return_List) String() string {
 	str, _ := text.MarshalList(0x9e19b28d3db3573a, s.List)
 	return str
@@ -1215,8 +1365,33 @@ func NewFinish_List(s *capnp.Segment, sz int32) (Finish_List, error) {
 
 func (s Finish_List) At(i int) Finish { return Finish{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Finish_List) AtChecked(i int) (Finish, error) {
+	p, err := s.List.StructChecked(i)
+	return Finish{p}, err
+}
+
 func (s Finish_List) Set(i int, v Finish) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Finish_List) ToSlice() []Finish {
+	v := make([]Finish, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Finish_List) Range(f func(i int, v Finish) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Finish_List) String() string {
 	str, _ := text.MarshalList(0xd37d2eb2c2f80e63, s.List)
 	return str
@@ -1297,7 +1472,7 @@ func (s Resolve) HasCap() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Resolve) SetCap(v CapDescriptor) error {
@@ -1330,7 +1505,7 @@ func (s Resolve) HasException() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Resolve) SetException(v Exception) error {
@@ -1361,8 +1536,33 @@ func NewResolve_List(s *capnp.Segment, sz int32) (Resolve_List, error) {
 
 func (s Resolve_List) At(i int) Resolve { return Resolve{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Resolve_List) AtChecked(i int) (Resolve, error) {
+	p, err := s.List.StructChecked(i)
+	return Resolve{p}, err
+}
+
 func (s Resolve_List) Set(i int, v Resolve) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Resolve_List) ToSlice() []Resolve {
+	v := make([]Resolve, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Resolve_List) Range(f func(i int, v Resolve) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Resolve_List) String() string {
 	str, _ := text.MarshalList(0xbbc29655fa89086e, s.List)
 	return str
@@ -1436,8 +1636,33 @@ func NewRelease_List(s *capnp.Segment, sz int32) (Release_List, error) {
 
 func (s Release_List) At(i int) Release { return Release{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Release_List) AtChecked(i int) (Release, error) {
+	p, err := s.List.StructChecked(i)
+	return Release{p}, err
+}
+
 func (s Release_List) Set(i int, v Release) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Release_List) ToSlice() []Release {
+	v := make([]Release, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Release_List) Range(f func(i int, v Release) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Release_List) String() string {
 	str, _ := text.MarshalList(0xad1a6c0d7dd07497, s.List)
 	return str
@@ -1508,7 +1733,7 @@ func (s Disembargo) Target() (MessageTarget, error) {
 
 func (s Disembargo) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Disembargo) SetTarget(v MessageTarget) error {
@@ -1583,8 +1808,33 @@ func NewDisembargo_List(s *capnp.Segment, sz int32) (Disembargo_List, error) {
 
 func (s Disembargo_List) At(i int) Disembargo { return Disembargo{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Disembargo_List) AtChecked(i int) (Disembargo, error) {
+	p, err := s.List.StructChecked(i)
+	return Disembargo{p}, err
+}
+
 func (s Disembargo_List) Set(i int, v Disembargo) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Disembargo_List) ToSlice() []Disembargo {
+	v := make([]Disembargo, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Disembargo_List) Range(f func(i int, v Disembargo) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Disembargo_List) String() string {
 	str, _ := text.MarshalList(0xf964368b0fbd3711, s.List)
 	return str
@@ -1654,7 +1904,7 @@ func (s Provide) Target() (MessageTarget, error) {
 
 func (s Provide) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Provide) SetTarget(v MessageTarget) error {
@@ -1678,13 +1928,23 @@ func (s Provide) Recipient() (capnp.Pointer, error) {
 
 func (s Provide) HasRecipient() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Provide) RecipientPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(1)
 }
 
+// RecipientStruct returns the recipient field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Provide) RecipientStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.RecipientPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Provide) SetRecipient(v capnp.Pointer) error {
 	return s.Struct.SetPointer(1, v)
 }
@@ -1704,8 +1964,33 @@ func NewProvide_List(s *capnp.Segment, sz int32) (Provide_List, error) {
 
 func (s Provide_List) At(i int) Provide { return Provide{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Provide_List) AtChecked(i int) (Provide, error) {
+	p, err := s.List.StructChecked(i)
+	return Provide{p}, err
+}
+
 func (s Provide_List) Set(i int, v Provide) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Provide_List) ToSlice() []Provide {
+	v := make([]Provide, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Provide_List) Range(f func(i int, v Provide) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Provide_List) String() string {
 	str, _ := text.MarshalList(0x9c6a046bfbc1ac5a, s.List)
 	return str
@@ -1766,13 +2051,23 @@ func (s Accept) Provision() (capnp.Pointer, error) {
 
 func (s Accept) HasProvision() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Accept) ProvisionPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// ProvisionStruct returns the provision field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Accept) ProvisionStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ProvisionPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Accept) SetProvision(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -1800,8 +2095,33 @@ func NewAccept_List(s *capnp.Segment, sz int32) (Accept_List, error) {
 
 func (s Accept_List) At(i int) Accept { return Accept{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Accept_List) AtChecked(i int) (Accept, error) {
+	p, err := s.List.StructChecked(i)
+	return Accept{p}, err
+}
+
 func (s Accept_List) Set(i int, v Accept) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Accept_List) ToSlice() []Accept {
+	v := make([]Accept, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Accept_List) Range(f func(i int, v Accept) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Accept_List) String() string {
 	str, _ := text.MarshalList(0xd4c9b56290554016, s.List)
 	return str
@@ -1859,7 +2179,7 @@ func (s Join) Target() (MessageTarget, error) {
 
 func (s Join) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Join) SetTarget(v MessageTarget) error {
@@ -1883,13 +2203,23 @@ func (s Join) KeyPart() (capnp.Pointer, error) {
 
 func (s Join) HasKeyPart() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Join) KeyPartPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(1)
 }
 
+// KeyPartStruct returns the keyPart field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Join) KeyPartStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.KeyPartPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Join) SetKeyPart(v capnp.Pointer) error {
 	return s.Struct.SetPointer(1, v)
 }
@@ -1909,8 +2239,33 @@ func NewJoin_List(s *capnp.Segment, sz int32) (Join_List, error) {
 
 func (s Join_List) At(i int) Join { return Join{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Join_List) AtChecked(i int) (Join, error) {
+	p, err := s.List.StructChecked(i)
+	return Join{p}, err
+}
+
 func (s Join_List) Set(i int, v Join) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Join_List) ToSlice() []Join {
+	v := make([]Join, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Join_List) Range(f func(i int, v Join) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Join_List) String() string {
 	str, _ := text.MarshalList(0xfbe1980490e001af, s.List)
 	return str
@@ -2003,7 +2358,7 @@ func (s MessageTarget) HasPromisedAnswer() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s MessageTarget) SetPromisedAnswer(v PromisedAnswer) error {
@@ -2034,8 +2389,33 @@ func NewMessageTarget_List(s *capnp.Segment, sz int32) (MessageTarget_List, erro
 
 func (s MessageTarget_List) At(i int) MessageTarget { return MessageTarget{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s MessageTarget_List) AtChecked(i int) (MessageTarget, error) {
+	p, err := s.List.StructChecked(i)
+	return MessageTarget{p}, err
+}
+
 func (s MessageTarget_List) Set(i int, v MessageTarget) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s MessageTarget_List) ToSlice() []MessageTarget {
+	v := make([]MessageTarget, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s MessageTarget_List) Range(f func(i int, v MessageTarget) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s MessageTarget_List) String() string {
 	str, _ := text.MarshalList(0x95bc14545813fbc1, s.List)
 	return str
@@ -2084,13 +2464,23 @@ func (s Payload) Content() (capnp.Pointer, error) {
 
 func (s Payload) HasContent() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Payload) ContentPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// ContentStruct returns the content field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Payload) ContentStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ContentPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Payload) SetContent(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -2106,7 +2496,7 @@ func (s Payload) CapTable() (CapDescriptor_List, error) {
 
 func (s Payload) HasCapTable() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Payload) SetCapTable(v CapDescriptor_List) error {
@@ -2135,8 +2525,33 @@ func NewPayload_List(s *capnp.Segment, sz int32) (Payload_List, error) {
 
 func (s Payload_List) At(i int) Payload { return Payload{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Payload_List) AtChecked(i int) (Payload, error) {
+	p, err := s.List.StructChecked(i)
+	return Payload{p}, err
+}
+
 func (s Payload_List) Set(i int, v Payload) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Payload_List) ToSlice() []Payload {
+	v := make([]Payload, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Payload_List) Range(f func(i int, v Payload) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Payload_List) String() string {
 	str, _ := text.MarshalList(0x9a0e61223d96743b, s.List)
 	return str
@@ -2266,7 +2681,7 @@ func (s CapDescriptor) HasReceiverAnswer() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CapDescriptor) SetReceiverAnswer(v PromisedAnswer) error {
@@ -2299,7 +2714,7 @@ func (s CapDescriptor) HasThirdPartyHosted() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CapDescriptor) SetThirdPartyHosted(v ThirdPartyCapDescriptor) error {
@@ -2330,8 +2745,33 @@ func NewCapDescriptor_List(s *capnp.Segment, sz int32) (CapDescriptor_List, erro
 
 func (s CapDescriptor_List) At(i int) CapDescriptor { return CapDescriptor{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CapDescriptor_List) AtChecked(i int) (CapDescriptor, error) {
+	p, err := s.List.StructChecked(i)
+	return CapDescriptor{p}, err
+}
+
 func (s CapDescriptor_List) Set(i int, v CapDescriptor) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CapDescriptor_List) ToSlice() []CapDescriptor {
+	v := make([]CapDescriptor, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CapDescriptor_List) Range(f func(i int, v CapDescriptor) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CapDescriptor_List) String() string {
 	str, _ := text.MarshalList(0x8523ddc40b86b8b0, s.List)
 	return str
@@ -2393,7 +2833,7 @@ func (s PromisedAnswer) Transform() (PromisedAnswer_Op_List, error) {
 
 func (s PromisedAnswer) HasTransform() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s PromisedAnswer) SetTransform(v PromisedAnswer_Op_List) error {
@@ -2422,8 +2862,33 @@ func NewPromisedAnswer_List(s *capnp.Segment, sz int32) (PromisedAnswer_List, er
 
 func (s PromisedAnswer_List) At(i int) PromisedAnswer { return PromisedAnswer{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s PromisedAnswer_List) AtChecked(i int) (PromisedAnswer, error) {
+	p, err := s.List.StructChecked(i)
+	return PromisedAnswer{p}, err
+}
+
 func (s PromisedAnswer_List) Set(i int, v PromisedAnswer) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s PromisedAnswer_List) ToSlice() []PromisedAnswer {
+	v := make([]PromisedAnswer, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s PromisedAnswer_List) Range(f func(i int, v PromisedAnswer) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s PromisedAnswer_List) String() string {
 	str, _ := text.MarshalList(0xd800b1d6cd6f1ca0, s.List)
 	return str
@@ -2513,10 +2978,35 @@ func (s PromisedAnswer_Op_List) At(i int) PromisedAnswer_Op {
 	return PromisedAnswer_Op{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s PromisedAnswer_Op_List) AtChecked(i int) (PromisedAnswer_Op, error) {
+	p, err := s.List.StructChecked(i)
+	return PromisedAnswer_Op{p}, err
+}
+
 func (s PromisedAnswer_Op_List) Set(i int, v PromisedAnswer_Op) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s PromisedAnswer_Op_List) ToSlice() []PromisedAnswer_Op {
+	v := make([]PromisedAnswer_Op, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s PromisedAnswer_Op_List) Range(f func(i int, v PromisedAnswer_Op) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s PromisedAnswer_Op_List) String() string {
 	str, _ := text.MarshalList(0xf316944415569081, s.List)
 	return str
@@ -2561,13 +3051,23 @@ func (s ThirdPartyCapDescriptor) Id() (capnp.Pointer, error) {
 
 func (s ThirdPartyCapDescriptor) HasId() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s ThirdPartyCapDescriptor) IdPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// IdStruct returns the id field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s ThirdPartyCapDescriptor) IdStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.IdPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s ThirdPartyCapDescriptor) SetId(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -2597,10 +3097,35 @@ func (s ThirdPartyCapDescriptor_List) At(i int) ThirdPartyCapDescriptor {
 	return ThirdPartyCapDescriptor{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s ThirdPartyCapDescriptor_List) AtChecked(i int) (ThirdPartyCapDescriptor, error) {
+	p, err := s.List.StructChecked(i)
+	return ThirdPartyCapDescriptor{p}, err
+}
+
 func (s ThirdPartyCapDescriptor_List) Set(i int, v ThirdPartyCapDescriptor) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s ThirdPartyCapDescriptor_List) ToSlice() []ThirdPartyCapDescriptor {
+	v := make([]ThirdPartyCapDescriptor, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s ThirdPartyCapDescriptor_List) Range(f func(i int, v ThirdPartyCapDescriptor) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s ThirdPartyCapDescriptor_List) String() string {
 	str, _ := text.MarshalList(0xd37007fde1f0027d, s.List)
 	return str
@@ -2650,7 +3175,7 @@ func (s Exception) Reason() (string, error) {
 
 func (s Exception) HasReason() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Exception) ReasonBytes() ([]byte, error) {
@@ -2670,6 +3195,16 @@ func (s Exception) SetType(v Exception_Type) {
 	s.Struct.SetUint16(4, uint16(v))
 }
 
+// TypeChecked returns the type field or an error if
+// its value is not defined in the schema for Exception_Type.
+func (s Exception) TypeChecked() (Exception_Type, error) {
+	v := s.Type()
+	if uint16(v) >= 4 {
+		return v, &capnp.EnumValueError{TypeID: 0xb28c96e23f4cbd58, Value: uint16(v)}
+	}
+	return v, nil
+}
+
 func (s Exception) ObsoleteIsCallersFault() bool {
 	return s.Struct.Bit(0)
 }
@@ -2697,8 +3232,33 @@ func NewException_List(s *capnp.Segment, sz int32) (Exception_List, error) {
 
 func (s Exception_List) At(i int) Exception { return Exception{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Exception_List) AtChecked(i int) (Exception, error) {
+	p, err := s.List.StructChecked(i)
+	return Exception{p}, err
+}
+
 func (s Exception_List) Set(i int, v Exception) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Exception_List) ToSlice() []Exception {
+	v := make([]Exception, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Exception_List) Range(f func(i int, v Exception) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Exception_List) String() string {
 	str, _ := text.MarshalList(0xd625b7063acf691a, s.List)
 	return str
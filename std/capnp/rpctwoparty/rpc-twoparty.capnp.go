@@ -107,8 +107,33 @@ func NewVatId_List(s *capnp.Segment, sz int32) (VatId_List, error) {
 
 func (s VatId_List) At(i int) VatId { return VatId{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s VatId_List) AtChecked(i int) (VatId, error) {
+	p, err := s.List.StructChecked(i)
+	return VatId{p}, err
+}
+
 func (s VatId_List) Set(i int, v VatId) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s VatId_List) ToSlice() []VatId {
+	v := make([]VatId, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s VatId_List) Range(f func(i int, v VatId) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s VatId_List) String() string {
 	str, _ := text.MarshalList(0xd20b909fee733a8e, s.List)
 	return str
@@ -166,8 +191,33 @@ func NewProvisionId_List(s *capnp.Segment, sz int32) (ProvisionId_List, error) {
 
 func (s ProvisionId_List) At(i int) ProvisionId { return ProvisionId{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s ProvisionId_List) AtChecked(i int) (ProvisionId, error) {
+	p, err := s.List.StructChecked(i)
+	return ProvisionId{p}, err
+}
+
 func (s ProvisionId_List) Set(i int, v ProvisionId) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s ProvisionId_List) ToSlice() []ProvisionId {
+	v := make([]ProvisionId, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s ProvisionId_List) Range(f func(i int, v ProvisionId) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s ProvisionId_List) String() string {
 	str, _ := text.MarshalList(0xb88d09a9c5f39817, s.List)
 	return str
@@ -217,8 +267,33 @@ func NewRecipientId_List(s *capnp.Segment, sz int32) (RecipientId_List, error) {
 
 func (s RecipientId_List) At(i int) RecipientId { return RecipientId{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s RecipientId_List) AtChecked(i int) (RecipientId, error) {
+	p, err := s.List.StructChecked(i)
+	return RecipientId{p}, err
+}
+
 func (s RecipientId_List) Set(i int, v RecipientId) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s RecipientId_List) ToSlice() []RecipientId {
+	v := make([]RecipientId, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s RecipientId_List) Range(f func(i int, v RecipientId) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s RecipientId_List) String() string {
 	str, _ := text.MarshalList(0x89f389b6fd4082c1, s.List)
 	return str
@@ -268,10 +343,35 @@ func NewThirdPartyCapId_List(s *capnp.Segment, sz int32) (ThirdPartyCapId_List,
 
 func (s ThirdPartyCapId_List) At(i int) ThirdPartyCapId { return ThirdPartyCapId{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s ThirdPartyCapId_List) AtChecked(i int) (ThirdPartyCapId, error) {
+	p, err := s.List.StructChecked(i)
+	return ThirdPartyCapId{p}, err
+}
+
 func (s ThirdPartyCapId_List) Set(i int, v ThirdPartyCapId) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s ThirdPartyCapId_List) ToSlice() []ThirdPartyCapId {
+	v := make([]ThirdPartyCapId, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s ThirdPartyCapId_List) Range(f func(i int, v ThirdPartyCapId) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s ThirdPartyCapId_List) String() string {
 	str, _ := text.MarshalList(0xb47f4979672cb59d, s.List)
 	return str
@@ -345,8 +445,33 @@ func NewJoinKeyPart_List(s *capnp.Segment, sz int32) (JoinKeyPart_List, error) {
 
 func (s JoinKeyPart_List) At(i int) JoinKeyPart { return JoinKeyPart{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s JoinKeyPart_List) AtChecked(i int) (JoinKeyPart, error) {
+	p, err := s.List.StructChecked(i)
+	return JoinKeyPart{p}, err
+}
+
 func (s JoinKeyPart_List) Set(i int, v JoinKeyPart) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s JoinKeyPart_List) ToSlice() []JoinKeyPart {
+	v := make([]JoinKeyPart, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s JoinKeyPart_List) Range(f func(i int, v JoinKeyPart) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s JoinKeyPart_List) String() string {
 	str, _ := text.MarshalList(0x95b29059097fca83, s.List)
 	return str
@@ -407,13 +532,23 @@ func (s JoinResult) Cap() (capnp.Pointer, error) {
 
 func (s JoinResult) HasCap() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s JoinResult) CapPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// CapStruct returns the cap field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s JoinResult) CapStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.CapPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s JoinResult) SetCap(v capnp.Pointer) error {
 	return s.Struct.SetPointer(0, v)
 }
@@ -433,8 +568,33 @@ func NewJoinResult_List(s *capnp.Segment, sz int32) (JoinResult_List, error) {
 
 func (s JoinResult_List) At(i int) JoinResult { return JoinResult{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s JoinResult_List) AtChecked(i int) (JoinResult, error) {
+	p, err := s.List.StructChecked(i)
+	return JoinResult{p}, err
+}
+
 func (s JoinResult_List) Set(i int, v JoinResult) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s JoinResult_List) ToSlice() []JoinResult {
+	v := make([]JoinResult, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s JoinResult_List) Range(f func(i int, v JoinResult) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s JoinResult_List) String() string {
 	str, _ := text.MarshalList(0x9d263a3630b7ebee, s.List)
 	return str
@@ -93,7 +93,7 @@ func (s Node) DisplayName() (string, error) {
 
 func (s Node) HasDisplayName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) DisplayNameBytes() ([]byte, error) {
@@ -128,7 +128,7 @@ func (s Node) Parameters() (Node_Parameter_List, error) {
 
 func (s Node) HasParameters() bool {
 	p, err := s.Struct.Ptr(5)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) SetParameters(v Node_Parameter_List) error {
@@ -161,7 +161,7 @@ func (s Node) NestedNodes() (Node_NestedNode_List, error) {
 
 func (s Node) HasNestedNodes() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) SetNestedNodes(v Node_NestedNode_List) error {
@@ -186,7 +186,7 @@ func (s Node) Annotations() (Annotation_List, error) {
 
 func (s Node) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node) SetAnnotations(v Annotation_List) error {
@@ -270,7 +270,7 @@ func (s Node_structNode) Fields() (Field_List, error) {
 
 func (s Node_structNode) HasFields() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_structNode) SetFields(v Field_List) error {
@@ -301,7 +301,7 @@ func (s Node_enum) Enumerants() (Enumerant_List, error) {
 
 func (s Node_enum) HasEnumerants() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_enum) SetEnumerants(v Enumerant_List) error {
@@ -332,7 +332,7 @@ func (s Node_interface) Methods() (Method_List, error) {
 
 func (s Node_interface) HasMethods() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_interface) SetMethods(v Method_List) error {
@@ -357,7 +357,7 @@ func (s Node_interface) Superclasses() (Superclass_List, error) {
 
 func (s Node_interface) HasSuperclasses() bool {
 	p, err := s.Struct.Ptr(4)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_interface) SetSuperclasses(v Superclass_List) error {
@@ -388,7 +388,7 @@ func (s Node_const) Type() (Type, error) {
 
 func (s Node_const) HasType() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_const) SetType(v Type) error {
@@ -413,7 +413,7 @@ func (s Node_const) Value() (Value, error) {
 
 func (s Node_const) HasValue() bool {
 	p, err := s.Struct.Ptr(4)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_const) SetValue(v Value) error {
@@ -444,7 +444,7 @@ func (s Node_annotation) Type() (Type, error) {
 
 func (s Node_annotation) HasType() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_annotation) SetType(v Type) error {
@@ -569,8 +569,33 @@ func NewNode_List(s *capnp.Segment, sz int32) (Node_List, error) {
 
 func (s Node_List) At(i int) Node { return Node{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Node_List) AtChecked(i int) (Node, error) {
+	p, err := s.List.StructChecked(i)
+	return Node{p}, err
+}
+
 func (s Node_List) Set(i int, v Node) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Node_List) ToSlice() []Node {
+	v := make([]Node, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Node_List) Range(f func(i int, v Node) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Node_List) String() string {
 	str, _ := text.MarshalList(0xe682ab4cf923a417, s.List)
 	return str
@@ -678,7 +703,7 @@ func (s Node_Parameter) Name() (string, error) {
 
 func (s Node_Parameter) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_Parameter) NameBytes() ([]byte, error) {
@@ -701,8 +726,33 @@ func NewNode_Parameter_List(s *capnp.Segment, sz int32) (Node_Parameter_List, er
 
 func (s Node_Parameter_List) At(i int) Node_Parameter { return Node_Parameter{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Node_Parameter_List) AtChecked(i int) (Node_Parameter, error) {
+	p, err := s.List.StructChecked(i)
+	return Node_Parameter{p}, err
+}
+
 func (s Node_Parameter_List) Set(i int, v Node_Parameter) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Node_Parameter_List) ToSlice() []Node_Parameter {
+	v := make([]Node_Parameter, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Node_Parameter_List) Range(f func(i int, v Node_Parameter) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Node_Parameter_List) String() string {
 	str, _ := text.MarshalList(0xb9521bccf10fa3b1, s.List)
 	return str
@@ -748,7 +798,7 @@ func (s Node_NestedNode) Name() (string, error) {
 
 func (s Node_NestedNode) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Node_NestedNode) NameBytes() ([]byte, error) {
@@ -779,10 +829,35 @@ func NewNode_NestedNode_List(s *capnp.Segment, sz int32) (Node_NestedNode_List,
 
 func (s Node_NestedNode_List) At(i int) Node_NestedNode { return Node_NestedNode{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Node_NestedNode_List) AtChecked(i int) (Node_NestedNode, error) {
+	p, err := s.List.StructChecked(i)
+	return Node_NestedNode{p}, err
+}
+
 func (s Node_NestedNode_List) Set(i int, v Node_NestedNode) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Node_NestedNode_List) ToSlice() []Node_NestedNode {
+	v := make([]Node_NestedNode, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Node_NestedNode_List) Range(f func(i int, v Node_NestedNode) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Node_NestedNode_List) String() string {
 	str, _ := text.MarshalList(0xdebf55bbfa0fc242, s.List)
 	return str
@@ -871,7 +946,7 @@ func (s Field) Name() (string, error) {
 
 func (s Field) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field) NameBytes() ([]byte, error) {
@@ -898,7 +973,7 @@ func (s Field) Annotations() (Annotation_List, error) {
 
 func (s Field) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field) SetAnnotations(v Annotation_List) error {
@@ -945,7 +1020,7 @@ func (s Field_slot) Type() (Type, error) {
 
 func (s Field_slot) HasType() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field_slot) SetType(v Type) error {
@@ -970,7 +1045,7 @@ func (s Field_slot) DefaultValue() (Value, error) {
 
 func (s Field_slot) HasDefaultValue() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Field_slot) SetDefaultValue(v Value) error {
@@ -1043,8 +1118,33 @@ func NewField_List(s *capnp.Segment, sz int32) (Field_List, error) {
 
 func (s Field_List) At(i int) Field { return Field{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Field_List) AtChecked(i int) (Field, error) {
+	p, err := s.List.StructChecked(i)
+	return Field{p}, err
+}
+
 func (s Field_List) Set(i int, v Field) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Field_List) ToSlice() []Field {
+	v := make([]Field, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Field_List) Range(f func(i int, v Field) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Field_List) String() string {
 	str, _ := text.MarshalList(0x9aad50a41f4af45f, s.List)
 	return str
@@ -1128,7 +1228,7 @@ func (s Enumerant) Name() (string, error) {
 
 func (s Enumerant) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Enumerant) NameBytes() ([]byte, error) {
@@ -1155,7 +1255,7 @@ func (s Enumerant) Annotations() (Annotation_List, error) {
 
 func (s Enumerant) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Enumerant) SetAnnotations(v Annotation_List) error {
@@ -1184,8 +1284,33 @@ func NewEnumerant_List(s *capnp.Segment, sz int32) (Enumerant_List, error) {
 
 func (s Enumerant_List) At(i int) Enumerant { return Enumerant{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Enumerant_List) AtChecked(i int) (Enumerant, error) {
+	p, err := s.List.StructChecked(i)
+	return Enumerant{p}, err
+}
+
 func (s Enumerant_List) Set(i int, v Enumerant) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Enumerant_List) ToSlice() []Enumerant {
+	v := make([]Enumerant, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Enumerant_List) Range(f func(i int, v Enumerant) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Enumerant_List) String() string {
 	str, _ := text.MarshalList(0x978a7cebdc549a4d, s.List)
 	return str
@@ -1239,7 +1364,7 @@ func (s Superclass) Brand() (Brand, error) {
 
 func (s Superclass) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Superclass) SetBrand(v Brand) error {
@@ -1268,8 +1393,33 @@ func NewSuperclass_List(s *capnp.Segment, sz int32) (Superclass_List, error) {
 
 func (s Superclass_List) At(i int) Superclass { return Superclass{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Superclass_List) AtChecked(i int) (Superclass, error) {
+	p, err := s.List.StructChecked(i)
+	return Superclass{p}, err
+}
+
 func (s Superclass_List) Set(i int, v Superclass) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Superclass_List) ToSlice() []Superclass {
+	v := make([]Superclass, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Superclass_List) Range(f func(i int, v Superclass) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Superclass_List) String() string {
 	str, _ := text.MarshalList(0xa9962a9ed0a4d7f8, s.List)
 	return str
@@ -1319,7 +1469,7 @@ func (s Method) Name() (string, error) {
 
 func (s Method) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) NameBytes() ([]byte, error) {
@@ -1346,7 +1496,7 @@ func (s Method) ImplicitParameters() (Node_Parameter_List, error) {
 
 func (s Method) HasImplicitParameters() bool {
 	p, err := s.Struct.Ptr(4)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetImplicitParameters(v Node_Parameter_List) error {
@@ -1379,7 +1529,7 @@ func (s Method) ParamBrand() (Brand, error) {
 
 func (s Method) HasParamBrand() bool {
 	p, err := s.Struct.Ptr(2)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetParamBrand(v Brand) error {
@@ -1412,7 +1562,7 @@ func (s Method) ResultBrand() (Brand, error) {
 
 func (s Method) HasResultBrand() bool {
 	p, err := s.Struct.Ptr(3)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetResultBrand(v Brand) error {
@@ -1437,7 +1587,7 @@ func (s Method) Annotations() (Annotation_List, error) {
 
 func (s Method) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Method) SetAnnotations(v Annotation_List) error {
@@ -1466,8 +1616,33 @@ func NewMethod_List(s *capnp.Segment, sz int32) (Method_List, error) {
 
 func (s Method_List) At(i int) Method { return Method{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Method_List) AtChecked(i int) (Method, error) {
+	p, err := s.List.StructChecked(i)
+	return Method{p}, err
+}
+
 func (s Method_List) Set(i int, v Method) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Method_List) ToSlice() []Method {
+	v := make([]Method, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Method_List) Range(f func(i int, v Method) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Method_List) String() string {
 	str, _ := text.MarshalList(0x9500cce23b334d80, s.List)
 	return str
@@ -1724,7 +1899,7 @@ func (s Type_list) ElementType() (Type, error) {
 
 func (s Type_list) HasElementType() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_list) SetElementType(v Type) error {
@@ -1763,7 +1938,7 @@ func (s Type_enum) Brand() (Brand, error) {
 
 func (s Type_enum) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_enum) SetBrand(v Brand) error {
@@ -1802,7 +1977,7 @@ func (s Type_structType) Brand() (Brand, error) {
 
 func (s Type_structType) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_structType) SetBrand(v Brand) error {
@@ -1841,7 +2016,7 @@ func (s Type_interface) Brand() (Brand, error) {
 
 func (s Type_interface) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Type_interface) SetBrand(v Brand) error {
@@ -1948,8 +2123,33 @@ func NewType_List(s *capnp.Segment, sz int32) (Type_List, error) {
 
 func (s Type_List) At(i int) Type { return Type{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Type_List) AtChecked(i int) (Type, error) {
+	p, err := s.List.StructChecked(i)
+	return Type{p}, err
+}
+
 func (s Type_List) Set(i int, v Type) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Type_List) ToSlice() []Type {
+	v := make([]Type, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Type_List) Range(f func(i int, v Type) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Type_List) String() string {
 	str, _ := text.MarshalList(0xd07378ede1f9cc60, s.List)
 	return str
@@ -2097,7 +2297,7 @@ func (s Brand) Scopes() (Brand_Scope_List, error) {
 
 func (s Brand) HasScopes() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Brand) SetScopes(v Brand_Scope_List) error {
@@ -2126,8 +2326,33 @@ func NewBrand_List(s *capnp.Segment, sz int32) (Brand_List, error) {
 
 func (s Brand_List) At(i int) Brand { return Brand{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Brand_List) AtChecked(i int) (Brand, error) {
+	p, err := s.List.StructChecked(i)
+	return Brand{p}, err
+}
+
 func (s Brand_List) Set(i int, v Brand) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Brand_List) ToSlice() []Brand {
+	v := make([]Brand, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Brand_List) Range(f func(i int, v Brand) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Brand_List) String() string {
 	str, _ := text.MarshalList(0x903455f06065422b, s.List)
 	return str
@@ -2208,7 +2433,7 @@ func (s Brand_Scope) HasBind() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Brand_Scope) SetBind(v Brand_Binding_List) error {
@@ -2244,8 +2469,33 @@ func NewBrand_Scope_List(s *capnp.Segment, sz int32) (Brand_Scope_List, error) {
 
 func (s Brand_Scope_List) At(i int) Brand_Scope { return Brand_Scope{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Brand_Scope_List) AtChecked(i int) (Brand_Scope, error) {
+	p, err := s.List.StructChecked(i)
+	return Brand_Scope{p}, err
+}
+
 func (s Brand_Scope_List) Set(i int, v Brand_Scope) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Brand_Scope_List) ToSlice() []Brand_Scope {
+	v := make([]Brand_Scope, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Brand_Scope_List) Range(f func(i int, v Brand_Scope) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Brand_Scope_List) String() string {
 	str, _ := text.MarshalList(0xabd73485a9636bc9, s.List)
 	return str
@@ -2323,7 +2573,7 @@ func (s Brand_Binding) HasType() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Brand_Binding) SetType(v Type) error {
@@ -2354,8 +2604,33 @@ func NewBrand_Binding_List(s *capnp.Segment, sz int32) (Brand_Binding_List, erro
 
 func (s Brand_Binding_List) At(i int) Brand_Binding { return Brand_Binding{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Brand_Binding_List) AtChecked(i int) (Brand_Binding, error) {
+	p, err := s.List.StructChecked(i)
+	return Brand_Binding{p}, err
+}
+
 func (s Brand_Binding_List) Set(i int, v Brand_Binding) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Brand_Binding_List) ToSlice() []Brand_Binding {
+	v := make([]Brand_Binding, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Brand_Binding_List) Range(f func(i int, v Brand_Binding) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Brand_Binding_List) String() string {
 	str, _ := text.MarshalList(0xc863cd16969ee7fc, s.List)
 	return str
@@ -2620,7 +2895,7 @@ func (s Value) HasText() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) TextBytes() ([]byte, error) {
@@ -2646,7 +2921,7 @@ func (s Value) HasData() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) SetData(v []byte) error {
@@ -2666,13 +2941,23 @@ func (s Value) HasList() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) ListPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// ListStruct returns the list field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Value) ListStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.ListPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Value) SetList(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 14)
 	return s.Struct.SetPointer(0, v)
@@ -2707,13 +2992,23 @@ func (s Value) HasStructValue() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) StructValuePtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// StructValueStruct returns the structValue field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Value) StructValueStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.StructValuePtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Value) SetStructValue(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 16)
 	return s.Struct.SetPointer(0, v)
@@ -2741,13 +3036,23 @@ func (s Value) HasAnyPointer() bool {
 		return false
 	}
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Value) AnyPointerPtr() (capnp.Ptr, error) {
 	return s.Struct.Ptr(0)
 }
 
+// AnyPointerStruct returns the anyPointer field as a struct
+// of at least the expected size.  See capnp.Ptr.StructOfSize.
+func (s Value) AnyPointerStruct(expected capnp.ObjectSize) (capnp.Struct, error) {
+	p, err := s.AnyPointerPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.StructOfSize(expected)
+}
+
 func (s Value) SetAnyPointer(v capnp.Pointer) error {
 	s.Struct.SetUint16(0, 18)
 	return s.Struct.SetPointer(0, v)
@@ -2769,8 +3074,33 @@ func NewValue_List(s *capnp.Segment, sz int32) (Value_List, error) {
 
 func (s Value_List) At(i int) Value { return Value{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Value_List) AtChecked(i int) (Value, error) {
+	p, err := s.List.StructChecked(i)
+	return Value{p}, err
+}
+
 func (s Value_List) Set(i int, v Value) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Value_List) ToSlice() []Value {
+	v := make([]Value, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Value_List) Range(f func(i int, v Value) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Value_List) String() string {
 	str, _ := text.MarshalList(0xce23dcd2d7b00c9b, s.List)
 	return str
@@ -2836,7 +3166,7 @@ func (s Annotation) Brand() (Brand, error) {
 
 func (s Annotation) HasBrand() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Annotation) SetBrand(v Brand) error {
@@ -2861,7 +3191,7 @@ func (s Annotation) Value() (Value, error) {
 
 func (s Annotation) HasValue() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s Annotation) SetValue(v Value) error {
@@ -2890,8 +3220,33 @@ func NewAnnotation_List(s *capnp.Segment, sz int32) (Annotation_List, error) {
 
 func (s Annotation_List) At(i int) Annotation { return Annotation{s.List.Struct(i)} }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s Annotation_List) AtChecked(i int) (Annotation, error) {
+	p, err := s.List.StructChecked(i)
+	return Annotation{p}, err
+}
+
 func (s Annotation_List) Set(i int, v Annotation) error { return s.List.SetStruct(i, v.Struct) }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s Annotation_List) ToSlice() []Annotation {
+	v := make([]Annotation, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s Annotation_List) Range(f func(i int, v Annotation) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s Annotation_List) String() string {
 	str, _ := text.MarshalList(0xf1c8950dab257542, s.List)
 	return str
@@ -3030,7 +3385,7 @@ func (s CodeGeneratorRequest) Nodes() (Node_List, error) {
 
 func (s CodeGeneratorRequest) HasNodes() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest) SetNodes(v Node_List) error {
@@ -3055,7 +3410,7 @@ func (s CodeGeneratorRequest) RequestedFiles() (CodeGeneratorRequest_RequestedFi
 
 func (s CodeGeneratorRequest) HasRequestedFiles() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest) SetRequestedFiles(v CodeGeneratorRequest_RequestedFile_List) error {
@@ -3086,10 +3441,35 @@ func (s CodeGeneratorRequest_List) At(i int) CodeGeneratorRequest {
 	return CodeGeneratorRequest{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CodeGeneratorRequest_List) AtChecked(i int) (CodeGeneratorRequest, error) {
+	p, err := s.List.StructChecked(i)
+	return CodeGeneratorRequest{p}, err
+}
+
 func (s CodeGeneratorRequest_List) Set(i int, v CodeGeneratorRequest) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CodeGeneratorRequest_List) ToSlice() []CodeGeneratorRequest {
+	v := make([]CodeGeneratorRequest, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CodeGeneratorRequest_List) Range(f func(i int, v CodeGeneratorRequest) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CodeGeneratorRequest_List) String() string {
 	str, _ := text.MarshalList(0xbfc546f6210ad7ce, s.List)
 	return str
@@ -3143,7 +3523,7 @@ func (s CodeGeneratorRequest_RequestedFile) Filename() (string, error) {
 
 func (s CodeGeneratorRequest_RequestedFile) HasFilename() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest_RequestedFile) FilenameBytes() ([]byte, error) {
@@ -3162,7 +3542,7 @@ func (s CodeGeneratorRequest_RequestedFile) Imports() (CodeGeneratorRequest_Requ
 
 func (s CodeGeneratorRequest_RequestedFile) HasImports() bool {
 	p, err := s.Struct.Ptr(1)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest_RequestedFile) SetImports(v CodeGeneratorRequest_RequestedFile_Import_List) error {
@@ -3193,10 +3573,35 @@ func (s CodeGeneratorRequest_RequestedFile_List) At(i int) CodeGeneratorRequest_
 	return CodeGeneratorRequest_RequestedFile{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CodeGeneratorRequest_RequestedFile_List) AtChecked(i int) (CodeGeneratorRequest_RequestedFile, error) {
+	p, err := s.List.StructChecked(i)
+	return CodeGeneratorRequest_RequestedFile{p}, err
+}
+
 func (s CodeGeneratorRequest_RequestedFile_List) Set(i int, v CodeGeneratorRequest_RequestedFile) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CodeGeneratorRequest_RequestedFile_List) ToSlice() []CodeGeneratorRequest_RequestedFile {
+	v := make([]CodeGeneratorRequest_RequestedFile, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CodeGeneratorRequest_RequestedFile_List) Range(f func(i int, v CodeGeneratorRequest_RequestedFile) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CodeGeneratorRequest_RequestedFile_List) String() string {
 	str, _ := text.MarshalList(0xcfea0eb02e810062, s.List)
 	return str
@@ -3250,7 +3655,7 @@ func (s CodeGeneratorRequest_RequestedFile_Import) Name() (string, error) {
 
 func (s CodeGeneratorRequest_RequestedFile_Import) HasName() bool {
 	p, err := s.Struct.Ptr(0)
-	return p.IsValid() || err != nil
+	return err == nil && p.IsValid()
 }
 
 func (s CodeGeneratorRequest_RequestedFile_Import) NameBytes() ([]byte, error) {
@@ -3275,10 +3680,35 @@ func (s CodeGeneratorRequest_RequestedFile_Import_List) At(i int) CodeGeneratorR
 	return CodeGeneratorRequest_RequestedFile_Import{s.List.Struct(i)}
 }
 
+// AtChecked is like At, but returns an error if element i cannot be read.
+func (s CodeGeneratorRequest_RequestedFile_Import_List) AtChecked(i int) (CodeGeneratorRequest_RequestedFile_Import, error) {
+	p, err := s.List.StructChecked(i)
+	return CodeGeneratorRequest_RequestedFile_Import{p}, err
+}
+
 func (s CodeGeneratorRequest_RequestedFile_Import_List) Set(i int, v CodeGeneratorRequest_RequestedFile_Import) error {
 	return s.List.SetStruct(i, v.Struct)
 }
 
+// ToSlice returns the elements of the list as a slice.  Only the struct
+// references are copied: the elements still read from the message.
+func (s CodeGeneratorRequest_RequestedFile_Import_List) ToSlice() []CodeGeneratorRequest_RequestedFile_Import {
+	v := make([]CodeGeneratorRequest_RequestedFile_Import, s.Len())
+	for i := range v {
+		v[i] = s.At(i)
+	}
+	return v
+}
+
+// Range calls f for each element of the list in order until f returns false.
+func (s CodeGeneratorRequest_RequestedFile_Import_List) Range(f func(i int, v CodeGeneratorRequest_RequestedFile_Import) bool) {
+	for i, n := 0, s.Len(); i < n; i++ {
+		if !f(i, s.At(i)) {
+			return
+		}
+	}
+}
+
 func (s CodeGeneratorRequest_RequestedFile_Import_List) String() string {
 	str, _ := text.MarshalList(0xae504193122357e5, s.List)
 	return str
@@ -123,3 +123,7 @@ func (ssa *singleSegmentArena) String() string {
 func (msa *multiSegmentArena) String() string {
 	return fmt.Sprintf("multi-segment arena [%d segments]", len(*msa))
 }
+
+func (lmsa *limitedMultiSegmentArena) String() string {
+	return fmt.Sprintf("multi-segment arena [%d segments, max size=%d]", len(lmsa.multiSegmentArena), lmsa.max)
+}
@@ -1,5 +1,7 @@
 package capnp
 
+import "fmt"
+
 // Struct is a pointer to a struct.
 type Struct struct {
 	seg        *Segment
@@ -14,6 +16,9 @@ func NewStruct(s *Segment, sz ObjectSize) (Struct, error) {
 	if !sz.isValid() {
 		return Struct{}, errObjectSize
 	}
+	if err := s.msg.checkPointerCount(sz); err != nil {
+		return Struct{}, err
+	}
 	sz.DataSize = sz.DataSize.padToWord()
 	seg, addr, err := alloc(s, sz.totalSize())
 	if err != nil {
@@ -83,6 +88,68 @@ func (p Struct) IsValid() bool {
 	return p.seg != nil
 }
 
+// DataBytes returns the struct's data section, or nil if the struct is
+// invalid.  It is meant for reading or writing many fields at once in
+// performance-critical code, for example with encoding/binary.
+//
+// The slice aliases the message: writes to it modify the struct, and it
+// is only valid while the message's arena is.  Fields are stored in
+// little-endian byte order at the offsets assigned by the schema, and
+// each field's value is XORed with its default value.  Fields past the
+// end of the data section, as in structs written with an older version
+// of the schema, are not present and must be treated as their defaults.
+//
+// Since the caller may write to the slice, DataBytes clears the
+// message's validated flag (see Message.Validated).  The slice of a
+// read-only message, such as one decoded with Decoder.AliasBuffer, must
+// not be written to.
+func (p Struct) DataBytes() []byte {
+	if p.seg == nil {
+		return nil
+	}
+	p.seg.aliased()
+	b := p.seg.slice(p.off, p.size.DataSize)
+	return b[:len(b):len(b)]
+}
+
+// RawBytes returns the struct's data and pointer sections followed by
+// the bytes of every object reachable from it, in depth-first order of
+// the pointer sections.  Each object is copied as it is laid out in its
+// segment, including list tags and the pointers themselves, so RawBytes
+// is much cheaper than Canonicalize.
+//
+// The result depends on the layout of the message, not just its
+// content: pointers encode relative offsets, so the same values built
+// in a different order, read from a different encoding, or split
+// across segments differently produce different bytes.  RawBytes is
+// only suitable as a key for deduplicating structs built the same way
+// within a process.  Use Canonicalize for a key that is stable across
+// builds.  An invalid struct returns nil.
+func (p Struct) RawBytes() ([]byte, error) {
+	if p.seg == nil {
+		return nil, nil
+	}
+	var buf []byte
+	err := walkPtr(p.ToPtr(), p.seg.msg.copyDepthLimit(), func(q Ptr) error {
+		switch q.flags.ptrType() {
+		case structPtrType:
+			buf = append(buf, q.seg.slice(q.off, q.size.totalSize())...)
+		case listPtrType:
+			l := q.List()
+			off := l.off
+			if l.flags&isCompositeList != 0 {
+				off -= Address(wordSize)
+			}
+			buf = append(buf, l.seg.slice(off, l.allocSize())...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
 // Address returns the address the pointer references.
 //
 // Deprecated: The return value is not well-defined.  Use SamePtr if you
@@ -123,6 +190,12 @@ func (p Struct) Pointer(i uint16) (Pointer, error) {
 }
 
 // Ptr returns the i'th pointer in the struct.
+//
+// Each call resolves the pointer again, following any far pointers and
+// counting the target's size against the message's read limit.  The
+// returned Ptr is fully resolved and remains valid as long as the
+// message is not modified, so callers that access the same field many
+// times should hold onto the result instead of calling Ptr repeatedly.
 func (p Struct) Ptr(i uint16) (Ptr, error) {
 	if p.seg == nil || i >= p.size.PointerCount {
 		return Ptr{}, nil
@@ -140,11 +213,34 @@ func (p Struct) SetPointer(i uint16, src Pointer) error {
 // SetPtr sets the i'th pointer in the struct to src.
 func (p Struct) SetPtr(i uint16, src Ptr) error {
 	if p.seg == nil || i >= p.size.PointerCount {
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
+	}
+	if p.seg.msg.CheckPtrOverlap && p.overlaps(src) {
+		return errPtrOverlap
 	}
 	return p.seg.writePtr(p.pointerAddress(i), src, false)
 }
 
+// overlaps reports whether src would be stored in p by reference and
+// its object shares memory with p.
+func (p Struct) overlaps(src Ptr) bool {
+	if src.seg != p.seg {
+		// Objects in other segments or messages can't overlap, and
+		// objects from other messages are copied.
+		return false
+	}
+	if src.flags.ptrType() == structPtrType && src.Struct().flags&isListMember != 0 {
+		// Copied by writePtr.
+		return false
+	}
+	start, end, ok := src.region()
+	if !ok {
+		return false
+	}
+	pend, _ := p.off.addSize(p.size.totalSize())
+	return start < pend && p.off < end
+}
+
 // SetText sets the i'th pointer to a newly allocated text or null if v is empty.
 func (p Struct) SetText(i uint16, v string) error {
 	if v == "" {
@@ -210,7 +306,7 @@ func (p Struct) Bit(n BitOffset) bool {
 // SetBit sets the bit that is n bits from the start of the struct to v.
 func (p Struct) SetBit(n BitOffset, v bool) {
 	if !p.bitInData(n) {
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	addr := p.off.addOffset(n.offset())
 	b := p.seg.readUint8(addr)
@@ -269,7 +365,7 @@ func (p Struct) Uint64(off DataOffset) uint64 {
 func (p Struct) SetUint8(off DataOffset, v uint8) {
 	addr, ok := p.dataAddress(off, 1)
 	if !ok {
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	p.seg.writeUint8(addr, v)
 }
@@ -278,16 +374,23 @@ func (p Struct) SetUint8(off DataOffset, v uint8) {
 func (p Struct) SetUint16(off DataOffset, v uint16) {
 	addr, ok := p.dataAddress(off, 2)
 	if !ok {
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	p.seg.writeUint16(addr, v)
 }
 
+// SetEnumField sets the enum field that is off bytes from the start of
+// the struct to the enumerant with ordinal v.  Enums are stored as
+// 16-bit integers, so this is the same as SetUint16.
+func (p Struct) SetEnumField(off DataOffset, v uint16) {
+	p.SetUint16(off, v)
+}
+
 // SetUint32 sets the 32-bit integer that is off bytes from the start of the struct to v.
 func (p Struct) SetUint32(off DataOffset, v uint32) {
 	addr, ok := p.dataAddress(off, 4)
 	if !ok {
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	p.seg.writeUint32(addr, v)
 }
@@ -296,11 +399,54 @@ func (p Struct) SetUint32(off DataOffset, v uint32) {
 func (p Struct) SetUint64(off DataOffset, v uint64) {
 	addr, ok := p.dataAddress(off, 8)
 	if !ok {
-		panic(errOutOfBounds)
+		panic(ErrOutOfBounds)
 	}
 	p.seg.writeUint64(addr, v)
 }
 
+// A FieldInfo describes where a field is stored in a struct.  capnpc-go
+// emits a table of FieldInfo for each struct type when run with the
+// -fieldtables flag, so that codecs can map field names to locations
+// without decoding the schema at runtime.
+type FieldInfo struct {
+	// Name is the field's name as it appears in the schema.
+	Name string
+
+	// Offset is the bit offset of a data field from the start of the
+	// data section or the index of a pointer field in the pointer
+	// section.  It is zero for void and group fields.
+	Offset uint32
+
+	// Bits is the width of a data field in bits.  It is zero for void,
+	// pointer, and group fields.
+	Bits uint8
+
+	// IsPointer reports whether the field is stored in the pointer
+	// section.
+	IsPointer bool
+
+	// IsGroup reports whether the field is a group.  A group's fields
+	// are described by the group type's own table.
+	IsGroup bool
+
+	// Discriminant is the union discriminant value of the field or
+	// 0xffff if the field is not a member of a union.
+	Discriminant uint16
+}
+
+// EnumValueError is returned by the checked enum accessors that
+// capnpc-go generates with the -checkedenums flag when a field holds a
+// value that is not defined in the schema, as happens when reading a
+// message written with a newer version of the schema.
+type EnumValueError struct {
+	TypeID uint64 // ID of the enum type
+	Value  uint16
+}
+
+func (e *EnumValueError) Error() string {
+	return fmt.Sprintf("capnp: unknown value %d for enum @%#x", e.Value, e.TypeID)
+}
+
 // structFlags is a bitmask of flags for a pointer.
 type structFlags uint8
 
@@ -309,11 +455,25 @@ const (
 	isListMember structFlags = 1 << iota
 )
 
-// copyStruct makes a deep copy of src into dst.
+// copyStruct makes a deep copy of src into dst.  It returns an error
+// if src contains a pointer cycle or is nested more deeply than dst's
+// message allows.
 func copyStruct(dst, src Struct) error {
+	return copyStructPath(dst, src, nil)
+}
+
+// copyStructPath is copyStruct with the set of structs that are being
+// copied further up the stack.  path may be nil.  src is only added to
+// the path, which is allocated if needed, once one of its pointers
+// leads to an object with pointers of its own, since only then can the
+// copy come back to src.
+func copyStructPath(dst, src Struct, path objectPath) error {
 	if dst.seg == nil {
 		return nil
 	}
+	if err := dst.seg.writable(); err != nil {
+		return err
+	}
 
 	// Q: how does version handling happen here, when the
 	//    destination toData[] slice can be bigger or smaller
@@ -327,6 +487,7 @@ func copyStruct(dst, src Struct) error {
 	//
 
 	// data section:
+	dst.seg.modified()
 	srcData := src.seg.slice(src.off, src.size.DataSize)
 	dstData := dst.seg.slice(dst.off, dst.size.DataSize)
 	copyCount := copy(dstData, srcData)
@@ -344,6 +505,7 @@ func copyStruct(dst, src Struct) error {
 	dstPtrSect, _ := dst.off.addSize(dst.size.DataSize)
 	numSrcPtrs := src.size.PointerCount
 	numDstPtrs := dst.size.PointerCount
+	entered := false
 	for j := uint16(0); j < numSrcPtrs && j < numDstPtrs; j++ {
 		srcAddr, _ := srcPtrSect.element(int32(j), wordSize)
 		dstAddr, _ := dstPtrSect.element(int32(j), wordSize)
@@ -351,7 +513,18 @@ func copyStruct(dst, src Struct) error {
 		if err != nil {
 			return err
 		}
-		err = dst.seg.writePtr(dstAddr, m, true)
+		if !entered && hasChildPointers(m) {
+			if path == nil {
+				path = make(objectPath)
+			}
+			k := objectKey{seg: src.seg, off: src.off, size: src.size}
+			if err := path.enter(k, dst.seg.msg.copyDepthLimit()); err != nil {
+				return err
+			}
+			defer path.pop(k)
+			entered = true
+		}
+		err = dst.seg.writePtrPath(dstAddr, m, true, path)
 		if err != nil {
 			return err
 		}